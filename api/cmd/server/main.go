@@ -8,18 +8,26 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/mymindmap/api/internal/attachments"
 	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/collab"
+	"github.com/mymindmap/api/internal/render"
 	"github.com/mymindmap/api/internal/http/handlers"
+	"github.com/mymindmap/api/internal/trash"
+	"github.com/mymindmap/api/models"
 	"github.com/mymindmap/api/repository"
 )
 
 type Config struct {
-	PostgresURL string
-	JWTSecret   string
+	PostgresURL    string
+	JWTSecret      string
+	TrashRetention time.Duration // Сколько удаленные карты и посты хранятся в корзине
 }
 
 func loadConfig() (*Config, error) {
@@ -40,14 +48,24 @@ func loadConfig() (*Config, error) {
 		log.Println("WARNING: Using default JWT secret. Set JWT_SECRET env variable in production!")
 	}
 
+	trashRetention := trash.DefaultRetention
+	if v := os.Getenv("TRASH_RETENTION_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 {
+			return nil, fmt.Errorf("invalid TRASH_RETENTION_DAYS: %q", v)
+		}
+		trashRetention = time.Duration(days) * 24 * time.Hour
+	}
+
 	connStr := fmt.Sprintf(
 		"postgres://%s:%s@%s/%s?sslmode=disable",
 		user, pass, host, db,
 	)
 
 	return &Config{
-		PostgresURL: connStr,
-		JWTSecret:   jwtSecret,
+		PostgresURL:    connStr,
+		JWTSecret:      jwtSecret,
+		TrashRetention: trashRetention,
 	}, nil
 }
 
@@ -79,6 +97,38 @@ func main() {
 	postRepo := repository.NewPostRepository(dbpool)
 	userRepo := repository.NewUserRepository(dbpool)
 	mindMapRepo := repository.NewMindMapRepository(dbpool)
+	revisionRepo := repository.NewMindMapRevisionRepository(dbpool)
+	opsRepo := repository.NewMindMapOpsRepository(dbpool)
+	memberRepo := repository.NewMindMapMemberRepository(dbpool)
+	linkRepo := repository.NewMindMapShareLinkRepository(dbpool)
+	searchRepo := repository.NewMindMapSearchRepository(dbpool)
+	thumbRepo := repository.NewMindMapThumbnailRepository(dbpool)
+	teamRepo := repository.NewTeamRepository(dbpool)
+	templateRepo := repository.NewMindMapTemplateRepository(dbpool)
+	folderRepo := repository.NewFolderRepository(dbpool)
+	tagRepo := repository.NewTagRepository(dbpool)
+	activityRepo := repository.NewMindMapActivityRepository(dbpool)
+	attachmentRepo := repository.NewAttachmentRepository(dbpool)
+	commentRepo := repository.NewCommentRepository(dbpool)
+	taskRepo := repository.NewTaskRepository(dbpool)
+	nodeLinkRepo := repository.NewNodeLinkRepository(dbpool)
+
+	// Файлы узлов
+	attachmentStorage, err := attachments.NewStorageFromEnv()
+	if err != nil {
+		log.Fatalf("attachments storage error: %v", err)
+	}
+	attachmentService := attachments.NewService(attachmentStorage, attachmentRepo)
+
+	// Совместное редактирование
+	collabHub := collab.NewHub(mindMapRepo, attachmentService, attachmentRepo, log.Default())
+
+	// Превью карт для списка
+	go render.NewThumbnailer(thumbRepo, log.Default()).Run(ctx)
+
+	// Очистка корзины
+	trashStores := map[string]trash.Store{models.TrashTypeMindMap: mindMapRepo, models.TrashTypePost: postRepo}
+	go trash.NewPurger(trashStores, conf.TrashRetention, log.Default()).Run(ctx)
 
 	// Конфигурация аутентификации
 	authConfig, err := auth.NewConfigFromEnv(slog.Default())
@@ -100,13 +150,50 @@ func main() {
 	// Handlers
 	authHandler := handlers.NewAuthHandler(authService, userRepo, log.Default())
 	postHandler := handlers.NewPostHandler(postRepo, authService, log.Default())
-	mindMapHandler := handlers.NewMindMapHandler(mindMapRepo, authService, log.Default())
+	mindMapHandler := handlers.NewMindMapHandler(handlers.MindMapDeps{
+		MindMaps:    mindMapRepo,
+		Revisions:   revisionRepo,
+		Ops:         opsRepo,
+		Members:     memberRepo,
+		ShareLinks:  linkRepo,
+		Users:       userRepo,
+		Thumbnails:  thumbRepo,
+		Templates:   templateRepo,
+		Folders:     folderRepo,
+		Tags:        tagRepo,
+		Activity:    activityRepo,
+		Attachments: attachmentRepo,
+		Files:       attachmentService,
+		Comments:    commentRepo,
+		NodeLinks:   nodeLinkRepo,
+		Collab:      collabHub,
+		Auth:        authService,
+		Logger:      log.Default(),
+	})
+	publicHandler := handlers.NewPublicHandler(mindMapRepo, log.Default())
+	searchHandler := handlers.NewSearchHandler(searchRepo, authService, log.Default())
+	teamHandler := handlers.NewTeamHandler(teamRepo, userRepo, authService, log.Default())
+	templateHandler := handlers.NewTemplateHandler(templateRepo, teamRepo, mindMapRepo, memberRepo, authService, log.Default())
+	folderHandler := handlers.NewFolderHandler(folderRepo, collabHub, authService, log.Default())
+	tagHandler := handlers.NewTagHandler(tagRepo, authService, log.Default())
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentRepo, attachmentService, authService, log.Default())
+	trashHandler := handlers.NewTrashHandler(mindMapRepo, postRepo, conf.TrashRetention, authService, log.Default())
+	taskHandler := handlers.NewTaskHandler(taskRepo, authService, log.Default())
 
 	// Router
 	mux := http.NewServeMux()
 	authHandler.RegisterRoutes(mux)
 	postHandler.RegisterRoutes(mux)
 	mindMapHandler.RegisterRoutes(mux)
+	publicHandler.RegisterRoutes(mux)
+	searchHandler.RegisterRoutes(mux)
+	teamHandler.RegisterRoutes(mux)
+	templateHandler.RegisterRoutes(mux)
+	folderHandler.RegisterRoutes(mux)
+	tagHandler.RegisterRoutes(mux)
+	trashHandler.RegisterRoutes(mux)
+	attachmentHandler.RegisterRoutes(mux)
+	taskHandler.RegisterRoutes(mux)
 
 	// Healthcheck
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
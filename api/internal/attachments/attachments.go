@@ -0,0 +1,125 @@
+// Package attachments хранит файлы узлов карт: картинки и вложения.
+//
+// Файл адресуется SHA-256 своего содержимого, поэтому одинаковые файлы
+// хранятся один раз, сколько бы карт на них ни ссылалось. Содержимое лежит
+// в Storage (локальный диск или S3-совместимое хранилище), а метаданные и
+// связи с картами - в БД. Документ карты ссылается на файл адресом URL(hash).
+package attachments
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/mymindmap/api/models"
+)
+
+// MaxSize - максимальный размер одного файла
+const MaxSize = 10 << 20
+
+// URLPrefix - начало адреса файла в документе карты
+const URLPrefix = "/api/attachments/"
+
+var (
+	// ErrNotFound - файла нет в хранилище
+	ErrNotFound = errors.New("attachment not found")
+	// ErrTooLarge - файл больше MaxSize
+	ErrTooLarge = errors.New("attachment is too large")
+)
+
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Storage - хранилище содержимого файлов. Ключи строит Key, повторная запись
+// по тому же ключу записывает то же содержимое.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// MetaStore - метаданные файлов. Реализуется repository.AttachmentRepository.
+type MetaStore interface {
+	Get(ctx context.Context, hash string) (*models.Attachment, error)
+	// Create сохраняет метаданные нового файла. Если файл уже есть,
+	// заполняет a сохраненными метаданными.
+	Create(ctx context.Context, a *models.Attachment) error
+	// AddUploader записывает повторную загрузку уже сохраненного файла пользователем
+	AddUploader(ctx context.Context, hash string, userID int) error
+}
+
+// Service сохраняет и читает файлы
+type Service struct {
+	storage Storage
+	meta    MetaStore
+}
+
+func NewService(storage Storage, meta MetaStore) *Service {
+	return &Service{storage: storage, meta: meta}
+}
+
+// Save сохраняет файл и возвращает его метаданные. Уже сохраненный файл
+// повторно не записывается.
+func (s *Service) Save(ctx context.Context, contentType string, data []byte, userID *int) (*models.Attachment, error) {
+	if len(data) > MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	hash := Hash(data)
+	existing, err := s.meta.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		// Загрузивший файл получает к нему доступ, даже если файл уже сохранил другой пользователь
+		if userID != nil {
+			if err := s.meta.AddUploader(ctx, hash, *userID); err != nil {
+				return nil, err
+			}
+		}
+		existing.URL = URL(hash)
+		return existing, nil
+	}
+
+	if err := s.storage.Put(ctx, Key(hash), data, contentType); err != nil {
+		return nil, fmt.Errorf("store attachment: %w", err)
+	}
+	a := &models.Attachment{Hash: hash, ContentType: contentType, Size: int64(len(data)), CreatedBy: userID}
+	if err := s.meta.Create(ctx, a); err != nil {
+		return nil, err
+	}
+	a.URL = URL(hash)
+	return a, nil
+}
+
+// Open открывает содержимое файла. Метаданные и права доступа проверяет вызывающий.
+func (s *Service) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	if !ValidHash(hash) {
+		return nil, ErrNotFound
+	}
+	return s.storage.Get(ctx, Key(hash))
+}
+
+// Hash возвращает адрес содержимого: SHA-256 в hex
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidHash проверяет, что строка - адрес содержимого
+func ValidHash(hash string) bool {
+	return hashPattern.MatchString(hash)
+}
+
+// Key возвращает ключ файла в хранилище. Первые два символа хэша выносятся
+// в каталог, чтобы файлы не лежали в одном каталоге.
+func Key(hash string) string {
+	return hash[:2] + "/" + hash
+}
+
+// URL возвращает адрес файла, которым на него ссылается документ карты
+func URL(hash string) string {
+	return URLPrefix + hash
+}
@@ -0,0 +1,126 @@
+package attachments
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/models"
+)
+
+type fakeMeta struct {
+	items     map[string]*models.Attachment
+	creates   int
+	uploaders map[string][]int
+}
+
+func (m *fakeMeta) Get(_ context.Context, hash string) (*models.Attachment, error) {
+	a, ok := m.items[hash]
+	if !ok {
+		return nil, nil
+	}
+	c := *a
+	return &c, nil
+}
+
+func (m *fakeMeta) Create(_ context.Context, a *models.Attachment) error {
+	m.creates++
+	c := *a
+	m.items[a.Hash] = &c
+	return nil
+}
+
+func (m *fakeMeta) AddUploader(_ context.Context, hash string, userID int) error {
+	if m.uploaders == nil {
+		m.uploaders = map[string][]int{}
+	}
+	m.uploaders[hash] = append(m.uploaders[hash], userID)
+	return nil
+}
+
+type countingStorage struct {
+	Storage
+	puts int
+}
+
+func (s *countingStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	s.puts++
+	return s.Storage.Put(ctx, key, data, contentType)
+}
+
+func TestServiceSaveDeduplicates(t *testing.T) {
+	local, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	storage := &countingStorage{Storage: local}
+	meta := &fakeMeta{items: map[string]*models.Attachment{}}
+	s := NewService(storage, meta)
+	userID := 7
+
+	a, err := s.Save(context.Background(), "image/png", []byte("png bytes"), &userID)
+	require.NoError(t, err)
+	assert.Equal(t, Hash([]byte("png bytes")), a.Hash)
+	assert.Equal(t, "/api/attachments/"+a.Hash, a.URL)
+	assert.Equal(t, int64(9), a.Size)
+	assert.Equal(t, &userID, a.CreatedBy)
+
+	again, err := s.Save(context.Background(), "image/png", []byte("png bytes"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, a.Hash, again.Hash)
+	assert.Equal(t, a.URL, again.URL)
+	assert.Equal(t, 1, storage.puts)
+	assert.Equal(t, 1, meta.creates)
+	assert.Empty(t, meta.uploaders, "anonymous upload is not recorded")
+
+	otherID := 8
+	_, err = s.Save(context.Background(), "image/png", []byte("png bytes"), &otherID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, storage.puts)
+	assert.Equal(t, []int{otherID}, meta.uploaders[a.Hash])
+
+	r, err := s.Open(context.Background(), a.Hash)
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "png bytes", string(data))
+}
+
+func TestServiceSaveTooLarge(t *testing.T) {
+	s := NewService(nil, &fakeMeta{items: map[string]*models.Attachment{}})
+	_, err := s.Save(context.Background(), "image/png", make([]byte, MaxSize+1), nil)
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestServiceOpenMissing(t *testing.T) {
+	local, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	s := NewService(local, &fakeMeta{items: map[string]*models.Attachment{}})
+
+	_, err = s.Open(context.Background(), Hash([]byte("missing")))
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Open(context.Background(), "../../etc/passwd")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStorageLayout(t *testing.T) {
+	root := t.TempDir()
+	local, err := NewLocalStorage(root)
+	require.NoError(t, err)
+
+	hash := Hash([]byte("x"))
+	require.NoError(t, local.Put(context.Background(), Key(hash), []byte("x"), "text/plain"))
+	data, err := os.ReadFile(filepath.Join(root, hash[:2], hash))
+	require.NoError(t, err)
+	assert.Equal(t, "x", string(data))
+
+	entries, err := os.ReadDir(filepath.Join(root, hash[:2]))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file must be renamed")
+
+	assert.Error(t, local.Put(context.Background(), "../escape", []byte("x"), ""))
+}
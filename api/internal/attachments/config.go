@@ -0,0 +1,35 @@
+package attachments
+
+import (
+	"fmt"
+	"os"
+)
+
+// DefaultDir - каталог локального хранилища по умолчанию
+const DefaultDir = "data/attachments"
+
+// NewStorageFromEnv создает хранилище по переменным окружения:
+// ATTACHMENTS_STORAGE=local (по умолчанию) с каталогом ATTACHMENTS_DIR или
+// ATTACHMENTS_STORAGE=s3 с параметрами S3_ENDPOINT, S3_REGION, S3_BUCKET,
+// S3_PREFIX, S3_ACCESS_KEY_ID и S3_SECRET_ACCESS_KEY.
+func NewStorageFromEnv() (Storage, error) {
+	switch kind := os.Getenv("ATTACHMENTS_STORAGE"); kind {
+	case "", "local":
+		dir := os.Getenv("ATTACHMENTS_DIR")
+		if dir == "" {
+			dir = DefaultDir
+		}
+		return NewLocalStorage(dir)
+	case "s3":
+		return NewS3Storage(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			Prefix:          os.Getenv("S3_PREFIX"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown ATTACHMENTS_STORAGE %q", kind)
+	}
+}
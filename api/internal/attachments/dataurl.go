@@ -0,0 +1,173 @@
+package attachments
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// refPattern находит ссылки на файлы в полях узла
+var refPattern = regexp.MustCompile(regexp.QuoteMeta(URLPrefix) + `([0-9a-f]{64})`)
+
+// ParseDataURL разбирает data URL (RFC 2397). Возвращает тип содержимого и
+// данные; ok = false, если s не data URL или его не удалось декодировать.
+func ParseDataURL(s string) (contentType string, data []byte, ok bool) {
+	rest, found := strings.CutPrefix(s, "data:")
+	if !found {
+		return "", nil, false
+	}
+	meta, payload, found := strings.Cut(rest, ",")
+	if !found {
+		return "", nil, false
+	}
+
+	isBase64 := false
+	if m, cut := strings.CutSuffix(meta, ";base64"); cut {
+		meta, isBase64 = m, true
+	}
+	contentType = "text/plain"
+	if meta != "" {
+		mediaType, _, err := mime.ParseMediaType(meta)
+		if err != nil {
+			return "", nil, false
+		}
+		contentType = mediaType
+	}
+
+	var err error
+	if isBase64 {
+		data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(payload))
+		if err != nil {
+			// Встречаются data URL без выравнивания '='
+			data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(payload), "="))
+		}
+	} else {
+		var decoded string
+		decoded, err = url.PathUnescape(payload)
+		data = []byte(decoded)
+	}
+	if err != nil {
+		return "", nil, false
+	}
+	return contentType, data, true
+}
+
+// Extract заменяет картинки узлов, записанные data URL, ссылками на файлы.
+// save сохраняет содержимое и возвращает адрес файла. Одинаковые картинки
+// сохраняются один раз. Возвращает число замененных картинок.
+func Extract(doc *mindmap.Document, save func(contentType string, data []byte) (string, error)) (int, error) {
+	e := &extractor{save: save, saved: make(map[string]string)}
+	e.walk(doc.Root)
+	return e.replaced, e.err
+}
+
+// ExtractOps делает то же, что Extract, с операциями над узлами: картинки
+// вставляемых поддеревьев и значения, которые set записывает в image.
+// Поддеревья копируются, переданные узлы не меняются.
+func ExtractOps(ops []mindmap.Op, save func(contentType string, data []byte) (string, error)) (int, error) {
+	e := &extractor{save: save, saved: make(map[string]string)}
+	for i := range ops {
+		op := &ops[i]
+		switch {
+		case op.Op == mindmap.OpInsert && op.Node != nil:
+			op.Node = op.Node.Clone()
+			e.walk(op.Node)
+		case op.Op == mindmap.OpSet && op.Field == "image":
+			var image string
+			if json.Unmarshal(op.Value, &image) != nil {
+				continue
+			}
+			if ref, ok := e.replace(image); ok {
+				op.Value, _ = json.Marshal(ref)
+			}
+		}
+		if e.err != nil {
+			break
+		}
+	}
+	return e.replaced, e.err
+}
+
+// HasDataURLs сообщает, есть ли в операциях картинки, которые вынес бы ExtractOps
+func HasDataURLs(ops []mindmap.Op) bool {
+	for _, op := range ops {
+		switch {
+		case op.Op == mindmap.OpInsert && op.Node != nil:
+			found := false
+			(&mindmap.Document{Root: op.Node}).Walk(func(n, _ *mindmap.Node, _ int) bool {
+				found = found || strings.HasPrefix(n.Data.Image, "data:")
+				return !found
+			})
+			if found {
+				return true
+			}
+		case op.Op == mindmap.OpSet && op.Field == "image":
+			var image string
+			if json.Unmarshal(op.Value, &image) == nil && strings.HasPrefix(image, "data:") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractor сохраняет картинки из data URL, запоминая уже сохраненные
+type extractor struct {
+	save     func(contentType string, data []byte) (string, error)
+	saved    map[string]string // data URL -> адрес файла
+	replaced int
+	err      error
+}
+
+func (e *extractor) walk(root *mindmap.Node) {
+	(&mindmap.Document{Root: root}).Walk(func(n, _ *mindmap.Node, _ int) bool {
+		if ref, ok := e.replace(n.Data.Image); ok {
+			n.Data.Image = ref
+		}
+		return e.err == nil
+	})
+}
+
+// replace возвращает адрес файла для картинки image; ok = false, если image
+// не data URL или сохранить его не удалось
+func (e *extractor) replace(image string) (string, bool) {
+	if e.err != nil || !strings.HasPrefix(image, "data:") {
+		return "", false
+	}
+	ref, ok := e.saved[image]
+	if !ok {
+		contentType, data, valid := ParseDataURL(image)
+		if !valid {
+			return "", false
+		}
+		if ref, e.err = e.save(contentType, data); e.err != nil {
+			return "", false
+		}
+		e.saved[image] = ref
+	}
+	e.replaced++
+	return ref, true
+}
+
+// Refs возвращает хэши файлов, на которые ссылаются картинки, ссылки и заметки узлов
+func Refs(doc *mindmap.Document) []string {
+	var hashes []string
+	seen := make(map[string]bool)
+	doc.Walk(func(n, _ *mindmap.Node, _ int) bool {
+		for _, field := range []string{n.Data.Image, n.Data.Hyperlink, n.Data.Note} {
+			for _, m := range refPattern.FindAllStringSubmatch(field, -1) {
+				if !seen[m[1]] {
+					seen[m[1]] = true
+					hashes = append(hashes, m[1])
+				}
+			}
+		}
+		return true
+	})
+	return hashes
+}
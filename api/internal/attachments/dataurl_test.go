@@ -0,0 +1,125 @@
+package attachments
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func TestParseDataURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		contentType string
+		data        string
+		ok          bool
+	}{
+		{"base64", "data:image/png;base64,aGVsbG8=", "image/png", "hello", true},
+		{"base64 without padding", "data:image/png;base64,aGVsbG8", "image/png", "hello", true},
+		{"params", "data:image/svg+xml;charset=utf-8,%3Csvg%2F%3E", "image/svg+xml", "<svg/>", true},
+		{"default type", "data:,hi", "text/plain", "hi", true},
+		{"not data url", "https://example.com/a.png", "", "", false},
+		{"no comma", "data:image/png;base64", "", "", false},
+		{"bad base64", "data:image/png;base64,!!!", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, data, ok := ParseDataURL(tt.in)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.contentType, contentType)
+				assert.Equal(t, tt.data, string(data))
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	doc, err := mindmap.ParseString(`{"root":{"data":{"text":"Root","uid":"r","image":"data:image/png;base64,aGVsbG8="},"children":[
+		{"data":{"text":"A","uid":"a","image":"data:image/png;base64,aGVsbG8="},"children":[]},
+		{"data":{"text":"B","uid":"b","image":"https://example.com/b.png"},"children":[]},
+		{"data":{"text":"C","uid":"c","image":"data:broken"},"children":[]}
+	]}}`, mindmap.DefaultLimits)
+	require.NoError(t, err)
+
+	var saved []string
+	n, err := Extract(doc, func(contentType string, data []byte) (string, error) {
+		saved = append(saved, contentType+":"+string(data))
+		return URL(Hash(data)), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"image/png:hello"}, saved, "identical images are saved once")
+
+	ref := URL(Hash([]byte("hello")))
+	root, _ := doc.Find("r")
+	a, _ := doc.Find("a")
+	b, _ := doc.Find("b")
+	c, _ := doc.Find("c")
+	assert.Equal(t, ref, root.Data.Image)
+	assert.Equal(t, ref, a.Data.Image)
+	assert.Equal(t, "https://example.com/b.png", b.Data.Image)
+	assert.Equal(t, "data:broken", c.Data.Image)
+
+	assert.Equal(t, []string{Hash([]byte("hello"))}, Refs(doc))
+}
+
+func TestExtractError(t *testing.T) {
+	doc, err := mindmap.ParseString(`{"root":{"data":{"text":"Root","image":"data:image/png;base64,aGVsbG8="},"children":[]}}`, mindmap.DefaultLimits)
+	require.NoError(t, err)
+
+	_, err = Extract(doc, func(string, []byte) (string, error) { return "", ErrTooLarge })
+	assert.True(t, errors.Is(err, ErrTooLarge))
+	assert.Equal(t, "data:image/png;base64,aGVsbG8=", doc.Root.Data.Image)
+}
+
+func TestExtractOps(t *testing.T) {
+	var ops []mindmap.Op
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"op":"insert","parent":"r","node":{"data":{"text":"A","image":"data:image/png;base64,aGVsbG8="},"children":[
+			{"data":{"text":"A1","image":"data:image/png;base64,aGVsbG8="}}
+		]}},
+		{"op":"set","uid":"b","field":"image","value":"data:image/png;base64,aGVsbG8="},
+		{"op":"set","uid":"b","field":"note","value":"data:image/png;base64,aGVsbG8="},
+		{"op":"set","uid":"c","field":"image","value":null}
+	]`), &ops))
+	original := ops[0].Node
+
+	assert.True(t, HasDataURLs(ops[:1]))
+	assert.True(t, HasDataURLs(ops[1:2]))
+	assert.False(t, HasDataURLs(ops[2:]))
+
+	saves := 0
+	n, err := ExtractOps(ops, func(contentType string, data []byte) (string, error) {
+		saves++
+		return URL(Hash(data)), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 1, saves)
+
+	ref := URL(Hash([]byte("hello")))
+	assert.Equal(t, ref, ops[0].Node.Data.Image)
+	assert.Equal(t, ref, ops[0].Node.Children[0].Data.Image)
+	assert.Equal(t, "data:image/png;base64,aGVsbG8=", original.Data.Image, "inserted node is copied")
+	assert.JSONEq(t, `"`+ref+`"`, string(ops[1].Value))
+	assert.JSONEq(t, `"data:image/png;base64,aGVsbG8="`, string(ops[2].Value), "only image is extracted")
+	assert.Equal(t, "null", string(ops[3].Value))
+}
+
+func TestRefs(t *testing.T) {
+	h1, h2 := Hash([]byte("1")), Hash([]byte("2"))
+	doc, err := mindmap.ParseString(`{"root":{"data":{"text":"Root","image":"/api/attachments/`+h1+`"},"children":[
+		{"data":{"text":"file","hyperlink":"https://app.example.com/api/attachments/`+h2+`"},"children":[]},
+		{"data":{"text":"note","note":"see <img src=\"/api/attachments/`+h1+`\">"},"children":[]},
+		{"data":{"text":"other","image":"/api/attachments/not-a-hash"},"children":[]}
+	]}}`, mindmap.DefaultLimits)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{h1, h2}, Refs(doc))
+}
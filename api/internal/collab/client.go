@@ -0,0 +1,146 @@
+package collab
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	sendBufferSize = 64
+)
+
+// client - одно WebSocket-подключение участника
+type client struct {
+	id       string
+	userID   int
+	name     string
+	readOnly bool     // Защищено мьютексом комнаты: права меняются при смене роли
+	selected []string // Защищено мьютексом комнаты
+
+	conn      *websocket.Conn
+	send      chan []byte
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newClient(conn *websocket.Conn, userID int, name string, readOnly bool) *client {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return &client{
+		id:       hex.EncodeToString(b[:]),
+		userID:   userID,
+		name:     name,
+		readOnly: readOnly,
+		conn:     conn,
+		send:     make(chan []byte, sendBufferSize),
+		done:     make(chan struct{}),
+	}
+}
+
+// sendMessage ставит сообщение в очередь отправки. Клиент, который не успевает
+// читать, отключается, чтобы не задерживать остальных участников.
+func (c *client) sendMessage(msg outMessage) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	c.sendRaw(payload)
+}
+
+func (c *client) sendRaw(payload []byte) {
+	select {
+	case <-c.done:
+	case c.send <- payload:
+	default:
+		c.close()
+	}
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// readPump читает сообщения клиента и передает их комнате до закрытия соединения
+func (c *client) readPump(rm *room) {
+	defer c.close()
+
+	c.conn.SetReadLimit(int64(mindmap.DefaultLimits.MaxBytes))
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, payload, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg inMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			c.sendMessage(outMessage{Type: msgError, Error: "invalid json"})
+			continue
+		}
+
+		switch msg.Type {
+		case msgOps:
+			rm.applyOps(c, msg)
+		case msgSelect:
+			rm.selectNodes(c, msg.Selected)
+		case msgPing:
+			c.sendMessage(outMessage{Type: msgPong})
+		default:
+			c.sendMessage(outMessage{Type: msgError, Error: "unknown message type"})
+		}
+	}
+}
+
+// writePump отправляет сообщения из очереди и пингует клиента
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			// Дописываем то, что уже в очереди (например, сообщение об ошибке)
+			for {
+				select {
+				case payload := <-c.send:
+					_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+					_ = c.conn.WriteMessage(websocket.TextMessage, payload)
+				default:
+					_ = c.conn.WriteMessage(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+					return
+				}
+			}
+		}
+	}
+}
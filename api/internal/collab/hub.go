@@ -0,0 +1,171 @@
+// Package collab реализует совместное редактирование карты через WebSocket.
+//
+// Для каждой открытой карты создается комната. Комната держит актуальный
+// документ и применяет операции участников в порядке поступления: порядок
+// задает сервер, поэтому все участники сходятся к одному состоянию. Операции
+// адресованы uid узлов, и операция, ставшая неприменимой из-за правки другого
+// участника (например, правка удаленного узла), отбрасывается, а не ломает
+// пакет. Клиент применяет свои операции сразу, а получив от сервера чужие
+// операции, перекладывает свои неподтвержденные операции поверх них.
+//
+// Изменения периодически сохраняются через репозиторий карт вместе с
+// журналом операций.
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/mymindmap/api/models"
+)
+
+const (
+	flushInterval = 5 * time.Second
+	storeTimeout  = 10 * time.Second
+)
+
+// Store - хранилище карт, через которое комнаты загружают и сохраняют документ.
+// Реализуется repository.MindMapRepository.
+type Store interface {
+	GetByID(ctx context.Context, id int) (*models.MindMap, error)
+	UpdateWithOps(ctx context.Context, mindMap *models.MindMap, authorID int, ops json.RawMessage) (*models.MindMapOpsBatch, error)
+}
+
+// FileSaver сохраняет файлы. Реализуется attachments.Service.
+type FileSaver interface {
+	Save(ctx context.Context, contentType string, data []byte, userID *int) (*models.Attachment, error)
+}
+
+// FileLinker связывает файлы с картой. Реализуется repository.AttachmentRepository.
+type FileLinker interface {
+	Link(ctx context.Context, mindMapID int, hashes []string) error
+}
+
+// Hub - реестр комнат совместного редактирования
+type Hub struct {
+	store    Store
+	files    FileSaver // nil - картинки из data URL остаются в документе
+	links    FileLinker
+	logger   *log.Logger
+	upgrader websocket.Upgrader
+
+	flushInterval time.Duration
+
+	mu    sync.Mutex
+	rooms map[int]*room
+}
+
+// NewHub создает реестр комнат. Картинки, которые участники присылают в
+// операциях data URL, сохраняются через files и связываются с картой через links,
+// как при сохранении карты через REST API.
+func NewHub(store Store, files FileSaver, links FileLinker, logger *log.Logger) *Hub {
+	return &Hub{
+		store:         store,
+		files:         files,
+		links:         links,
+		logger:        logger,
+		upgrader:      websocket.Upgrader{ReadBufferSize: 4096, WriteBufferSize: 4096},
+		flushInterval: flushInterval,
+		rooms:         make(map[int]*room),
+	}
+}
+
+// Serve переводит запрос в WebSocket и подключает пользователя к комнате карты.
+// Права доступа к карте проверяет вызывающий обработчик; при readOnly участник
+// видит изменения и выделения других, но не может отправлять операции.
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, mindMapID, userID int, userName string, readOnly bool) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade сам отвечает клиенту с ошибкой
+		return
+	}
+
+	c := newClient(conn, userID, userName, readOnly)
+	go c.writePump()
+
+	rm := h.acquire(mindMapID)
+	defer h.release(rm)
+
+	if err := rm.join(c); err != nil {
+		h.logger.Printf("collab: join mindmap %d: %v", mindMapID, err)
+		c.sendMessage(outMessage{Type: msgError, Error: "mindmap is not available"})
+		c.close()
+		return
+	}
+	defer rm.leave(c)
+
+	c.readPump(rm)
+}
+
+// SetReadOnly меняет права уже подключенных клиентов пользователя в комнате карты,
+// например при смене роли участника. Новые подключения получают права от
+// вызывающего обработчика.
+func (h *Hub) SetReadOnly(mindMapID, userID int, readOnly bool) {
+	if rm := h.room(mindMapID); rm != nil {
+		rm.setReadOnly(userID, readOnly)
+	}
+}
+
+// Revoke отключает клиентов пользователя от комнаты карты, когда он теряет доступ
+func (h *Hub) Revoke(mindMapID, userID int) {
+	if rm := h.room(mindMapID); rm != nil {
+		rm.revoke(userID)
+	}
+}
+
+// Close отключает всех участников карты, например когда ее удалили в корзину.
+// Несохраненные операции отбрасываются.
+func (h *Hub) Close(mindMapID int) {
+	if rm := h.room(mindMapID); rm != nil {
+		rm.shutdown()
+	}
+}
+
+// Connections возвращает количество подключений к карте
+func (h *Hub) Connections(mindMapID int) int {
+	rm := h.room(mindMapID)
+	if rm == nil {
+		return 0
+	}
+	return rm.size()
+}
+
+func (h *Hub) room(id int) *room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rooms[id]
+}
+
+func (h *Hub) acquire(id int) *room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	rm, ok := h.rooms[id]
+	if !ok {
+		rm = newRoom(h, id)
+		h.rooms[id] = rm
+		go rm.run(h.flushInterval)
+	}
+	rm.refs++
+	return rm
+}
+
+func (h *Hub) release(rm *room) {
+	h.mu.Lock()
+	rm.refs--
+	last := rm.refs == 0
+	if last {
+		delete(h.rooms, rm.id)
+	}
+	h.mu.Unlock()
+
+	if last {
+		rm.close()
+	}
+}
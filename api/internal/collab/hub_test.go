@@ -0,0 +1,434 @@
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/attachments"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+const testDoc = `{"root":{"data":{"text":"Root","uid":"r"},"children":[{"data":{"text":"A","uid":"a"},"children":[]}]}}`
+
+// memStore - хранилище карт в памяти
+type memStore struct {
+	mu    sync.Mutex
+	maps  map[int]models.MindMap
+	saves int
+}
+
+func newMemStore() *memStore {
+	return &memStore{maps: map[int]models.MindMap{
+		1: {ID: 1, UserID: 1, Title: "Test", Data: testDoc, Version: 1},
+	}}
+}
+
+func (s *memStore) GetByID(_ context.Context, id int) (*models.MindMap, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.maps[id]
+	if !ok {
+		return nil, nil
+	}
+	return &m, nil
+}
+
+func (s *memStore) UpdateWithOps(_ context.Context, m *models.MindMap, authorID int, ops json.RawMessage) (*models.MindMapOpsBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.maps[m.ID]
+	if !ok {
+		return nil, repository.ErrMindMapNotFound
+	}
+	if cur.Version != m.Version {
+		return nil, repository.ErrVersionConflict
+	}
+	m.Version++
+	s.maps[m.ID] = *m
+	s.saves++
+	return &models.MindMapOpsBatch{MindMapID: m.ID, BaseVersion: m.Version - 1, Version: m.Version, Ops: ops}, nil
+}
+
+// edit меняет карту в обход комнаты, как это делает REST API
+func (s *memStore) edit(id int, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.maps[id]
+	m.Data = data
+	m.Version++
+	s.maps[id] = m
+}
+
+// trash убирает карту в корзину в обход комнаты
+func (s *memStore) trash(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.maps, id)
+}
+
+func (s *memStore) get(id int) models.MindMap {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maps[id]
+}
+
+// memFiles - файлы в памяти
+type memFiles struct {
+	mu     sync.Mutex
+	saved  map[string]int // хэш -> загрузивший
+	linked map[int][]string
+}
+
+func (f *memFiles) Save(_ context.Context, contentType string, data []byte, userID *int) (*models.Attachment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	hash := attachments.Hash(data)
+	f.saved[hash] = *userID
+	return &models.Attachment{Hash: hash, ContentType: contentType, URL: attachments.URL(hash)}, nil
+}
+
+func (f *memFiles) Link(_ context.Context, mindMapID int, hashes []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.linked[mindMapID] = append(f.linked[mindMapID], hashes...)
+	return nil
+}
+
+func newTestHub(t *testing.T, store Store) (*Hub, *httptest.Server) {
+	t.Helper()
+	return newTestHubWithFiles(t, store, nil)
+}
+
+func newTestHubWithFiles(t *testing.T, store Store, files *memFiles) (*Hub, *httptest.Server) {
+	t.Helper()
+	hub := NewHub(store, nil, nil, log.New(io.Discard, "", 0))
+	if files != nil {
+		hub.files, hub.links = files, files
+	}
+	hub.flushInterval = time.Hour // Сохранение вызывается тестами явно
+
+	userID := 0
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		userID++
+		id := userID
+		mu.Unlock()
+		hub.Serve(w, r, 1, id, "user", r.URL.Query().Get("readonly") != "")
+	}))
+	t.Cleanup(srv.Close)
+	return hub, srv
+}
+
+func dial(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	t.Helper()
+	return dialURL(t, "ws"+strings.TrimPrefix(srv.URL, "http"))
+}
+
+func dialURL(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// readUntil читает сообщения, пока не встретит сообщение нужного типа
+func readUntil(t *testing.T, conn *websocket.Conn, msgType string) outMessage {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for {
+		var msg outMessage
+		require.NoError(t, conn.ReadJSON(&msg))
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func waitConnections(t *testing.T, hub *Hub, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return hub.Connections(1) == n }, 5*time.Second, 10*time.Millisecond)
+}
+
+func currentRoom(hub *Hub) *room {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	return hub.rooms[1]
+}
+
+func TestHub_BroadcastsOpsAndAcks(t *testing.T) {
+	store := newMemStore()
+	hub, srv := newTestHub(t, store)
+
+	alice := dial(t, srv)
+	snap := readUntil(t, alice, msgSnapshot)
+	assert.Equal(t, 1, snap.Version)
+	require.NotNil(t, snap.Document)
+	assert.Equal(t, "Root", snap.Document.Root.Data.Text)
+
+	bob := dial(t, srv)
+	readUntil(t, bob, msgSnapshot)
+	waitConnections(t, hub, 2)
+
+	require.NoError(t, alice.WriteJSON(inMessage{
+		Type:      msgOps,
+		ClientSeq: 1,
+		Ops: []mindmap.Op{
+			{Op: mindmap.OpSet, UID: "a", Field: "text", Value: json.RawMessage(`"A2"`)},
+			{Op: mindmap.OpDelete, UID: "missing"},
+		},
+	}))
+
+	ack := readUntil(t, alice, msgAck)
+	assert.Equal(t, 1, ack.ClientSeq)
+	assert.Equal(t, 1, ack.Seq)
+	assert.Len(t, ack.Ops, 1)
+	require.Len(t, ack.Rejected, 1)
+	assert.Equal(t, 1, ack.Rejected[0].Index)
+
+	ops := readUntil(t, bob, msgOps)
+	assert.Equal(t, 1, ops.Seq)
+	require.Len(t, ops.Ops, 1)
+	assert.Equal(t, "a", ops.Ops[0].UID)
+
+	currentRoom(hub).flush()
+	saved := store.get(1)
+	assert.Equal(t, 2, saved.Version)
+	assert.Contains(t, saved.Data, `"A2"`)
+}
+
+func TestHub_Presence(t *testing.T) {
+	hub, srv := newTestHub(t, newMemStore())
+
+	alice := dial(t, srv)
+	readUntil(t, alice, msgSnapshot)
+	bob := dial(t, srv)
+	readUntil(t, bob, msgSnapshot)
+	waitConnections(t, hub, 2)
+
+	require.NoError(t, bob.WriteJSON(inMessage{Type: msgSelect, Selected: []string{"a"}}))
+	for {
+		msg := readUntil(t, alice, msgPresence)
+		if len(msg.Users) == 2 && (len(msg.Users[0].Selected) == 1 || len(msg.Users[1].Selected) == 1) {
+			break
+		}
+	}
+
+	require.NoError(t, bob.Close())
+	waitConnections(t, hub, 1)
+	msg := readUntil(t, alice, msgPresence)
+	for len(msg.Users) != 1 {
+		msg = readUntil(t, alice, msgPresence)
+	}
+}
+
+func TestHub_RebasesPendingOpsOnConflict(t *testing.T) {
+	store := newMemStore()
+	hub, srv := newTestHub(t, store)
+
+	alice := dial(t, srv)
+	readUntil(t, alice, msgSnapshot)
+	waitConnections(t, hub, 1)
+
+	require.NoError(t, alice.WriteJSON(inMessage{
+		Type:      msgOps,
+		ClientSeq: 1,
+		Ops:       []mindmap.Op{{Op: mindmap.OpSet, UID: "a", Field: "text", Value: json.RawMessage(`"live"`)}},
+	}))
+	readUntil(t, alice, msgAck)
+
+	// Параллельное сохранение через REST: добавлен узел b
+	store.edit(1, `{"root":{"data":{"text":"Root","uid":"r"},"children":[{"data":{"text":"A","uid":"a"},"children":[]},{"data":{"text":"B","uid":"b"},"children":[]}]}}`)
+
+	currentRoom(hub).flush()
+
+	snap := readUntil(t, alice, msgSnapshot)
+	assert.Equal(t, 2, snap.Version)
+
+	saved := store.get(1)
+	assert.Equal(t, 3, saved.Version)
+	doc, err := mindmap.ParseString(saved.Data, mindmap.Limits{})
+	require.NoError(t, err)
+	a, _ := doc.Find("a")
+	b, _ := doc.Find("b")
+	require.NotNil(t, a)
+	require.NotNil(t, b)
+	assert.Equal(t, "live", a.Data.Text)
+}
+
+func TestHub_FlushesWhenLastClientLeaves(t *testing.T) {
+	store := newMemStore()
+	hub, srv := newTestHub(t, store)
+
+	alice := dial(t, srv)
+	readUntil(t, alice, msgSnapshot)
+	waitConnections(t, hub, 1)
+
+	require.NoError(t, alice.WriteJSON(inMessage{
+		Type: msgOps,
+		Ops:  []mindmap.Op{{Op: mindmap.OpInsert, Parent: "r", Node: &mindmap.Node{Data: mindmap.NodeData{Text: "C"}}}},
+	}))
+	readUntil(t, alice, msgAck)
+	require.NoError(t, alice.Close())
+
+	require.Eventually(t, func() bool { return store.get(1).Version == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, store.get(1).Data, `"C"`)
+	assert.Equal(t, 0, hub.Connections(1))
+}
+
+func TestHub_DisconnectsWhenMindMapTrashedBeforeSave(t *testing.T) {
+	store := newMemStore()
+	hub, srv := newTestHub(t, store)
+
+	alice := dial(t, srv)
+	readUntil(t, alice, msgSnapshot)
+	waitConnections(t, hub, 1)
+
+	require.NoError(t, alice.WriteJSON(inMessage{
+		Type: msgOps,
+		Ops:  []mindmap.Op{{Op: mindmap.OpSet, UID: "a", Field: "text", Value: json.RawMessage(`"A2"`)}},
+	}))
+	readUntil(t, alice, msgAck)
+
+	store.trash(1)
+	currentRoom(hub).flush()
+
+	msg := readUntil(t, alice, msgError)
+	assert.Equal(t, "mindmap was deleted", msg.Error)
+	waitConnections(t, hub, 0)
+}
+
+func TestHub_UnknownMindMap(t *testing.T) {
+	store := newMemStore()
+	delete(store.maps, 1)
+	_, srv := newTestHub(t, store)
+
+	conn := dial(t, srv)
+	msg := readUntil(t, conn, msgError)
+	assert.Equal(t, "mindmap is not available", msg.Error)
+}
+
+func TestHub_ReadOnlyClientCannotSendOps(t *testing.T) {
+	store := newMemStore()
+	_, srv := newTestHub(t, store)
+
+	viewer := dialURL(t, "ws"+strings.TrimPrefix(srv.URL, "http")+"?readonly=1")
+	readUntil(t, viewer, msgSnapshot)
+
+	require.NoError(t, viewer.WriteJSON(inMessage{
+		Type:      msgOps,
+		ClientSeq: 7,
+		Ops:       []mindmap.Op{{Op: mindmap.OpDelete, UID: "a"}},
+	}))
+	msg := readUntil(t, viewer, msgError)
+	assert.Equal(t, 7, msg.ClientSeq)
+	assert.Equal(t, "read-only access", msg.Error)
+}
+
+func TestHub_AccessChangesReachOpenConnections(t *testing.T) {
+	store := newMemStore()
+	hub, srv := newTestHub(t, store)
+
+	alice := dial(t, srv) // userID 1
+	readUntil(t, alice, msgSnapshot)
+	bob := dial(t, srv) // userID 2
+	readUntil(t, bob, msgSnapshot)
+	waitConnections(t, hub, 2)
+
+	// Роль Боба понизили до просмотра
+	hub.SetReadOnly(1, 2, true)
+	require.NoError(t, bob.WriteJSON(inMessage{
+		Type:      msgOps,
+		ClientSeq: 1,
+		Ops:       []mindmap.Op{{Op: mindmap.OpDelete, UID: "a"}},
+	}))
+	msg := readUntil(t, bob, msgError)
+	assert.Equal(t, "read-only access", msg.Error)
+
+	// Алису удалили из участников
+	hub.Revoke(1, 1)
+	msg = readUntil(t, alice, msgError)
+	assert.Equal(t, "access revoked", msg.Error)
+	waitConnections(t, hub, 1)
+
+	// Карту удалили в корзину
+	hub.Close(1)
+	msg = readUntil(t, bob, msgError)
+	assert.Equal(t, "mindmap was deleted", msg.Error)
+	waitConnections(t, hub, 0)
+	assert.Equal(t, 1, store.get(1).Version, "nothing is saved")
+}
+
+func TestHub_ExtractsDataURLImages(t *testing.T) {
+	store := newMemStore()
+	files := &memFiles{saved: map[string]int{}, linked: map[int][]string{}}
+	hub, srv := newTestHubWithFiles(t, store, files)
+
+	alice := dial(t, srv)
+	readUntil(t, alice, msgSnapshot)
+	bob := dial(t, srv)
+	readUntil(t, bob, msgSnapshot)
+	waitConnections(t, hub, 2)
+
+	const image = "data:image/png;base64,aGVsbG8="
+	require.NoError(t, alice.WriteJSON(inMessage{
+		Type: msgOps,
+		Ops: []mindmap.Op{
+			{Op: mindmap.OpInsert, Parent: "r", Node: &mindmap.Node{Data: mindmap.NodeData{Text: "C", Image: image}}},
+			{Op: mindmap.OpSet, UID: "a", Field: "image", Value: json.RawMessage(`"` + image + `"`)},
+		},
+	}))
+	ack := readUntil(t, alice, msgAck)
+	ref := attachments.URL(attachments.Hash([]byte("hello")))
+	require.Len(t, ack.Ops, 2)
+	assert.Equal(t, ref, ack.Ops[0].Node.Data.Image)
+	assert.JSONEq(t, `"`+ref+`"`, string(ack.Ops[1].Value))
+
+	ops := readUntil(t, bob, msgOps)
+	assert.Equal(t, ref, ops.Ops[0].Node.Data.Image)
+
+	currentRoom(hub).flush()
+	assert.NotContains(t, store.get(1).Data, "data:image")
+	assert.Equal(t, 1, files.saved[attachments.Hash([]byte("hello"))])
+	assert.Contains(t, files.linked[1], attachments.Hash([]byte("hello")))
+}
+
+func TestHub_RejectedOpsDoNotSaveImages(t *testing.T) {
+	store := newMemStore()
+	files := &memFiles{saved: map[string]int{}, linked: map[int][]string{}}
+	hub, srv := newTestHubWithFiles(t, store, files)
+
+	alice := dial(t, srv)
+	readUntil(t, alice, msgSnapshot)
+	waitConnections(t, hub, 1)
+
+	require.NoError(t, alice.WriteJSON(inMessage{
+		Type: msgOps,
+		Ops: []mindmap.Op{
+			{Op: mindmap.OpInsert, Parent: "missing", Node: &mindmap.Node{Data: mindmap.NodeData{Text: "C", Image: "data:image/png;base64,aGVsbG8="}}},
+			{Op: mindmap.OpSet, UID: "a", Field: "image", Value: json.RawMessage(`"data:image/png;base64,d29ybGQ="`)},
+		},
+	}))
+	ack := readUntil(t, alice, msgAck)
+	require.Len(t, ack.Rejected, 1)
+	assert.Equal(t, 0, ack.Rejected[0].Index)
+	require.Len(t, ack.Ops, 1)
+
+	assert.Zero(t, files.saved[attachments.Hash([]byte("hello"))])
+	assert.Equal(t, 1, files.saved[attachments.Hash([]byte("world"))])
+}
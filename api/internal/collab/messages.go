@@ -0,0 +1,50 @@
+package collab
+
+import "github.com/mymindmap/api/internal/mindmap"
+
+// Типы сообщений протокола
+const (
+	// Клиент -> сервер
+	msgOps    = "ops"    // Пакет операций над узлами
+	msgSelect = "select" // Выделенные участником узлы
+	msgPing   = "ping"
+
+	// Сервер -> клиент
+	msgSnapshot = "snapshot" // Документ целиком: при подключении и после внешнего изменения карты
+	msgAck      = "ack"      // Подтверждение пакета отправителю
+	msgPresence = "presence" // Список участников
+	msgPong     = "pong"
+	msgError    = "error"
+	// msgOps также рассылается остальным участникам
+)
+
+// inMessage - сообщение от клиента
+type inMessage struct {
+	Type      string       `json:"type"`
+	ClientSeq int          `json:"client_seq,omitempty"` // Номер пакета на клиенте, возвращается в ack
+	Ops       []mindmap.Op `json:"ops,omitempty"`
+	Selected  []string     `json:"selected,omitempty"`
+}
+
+// outMessage - сообщение клиенту
+type outMessage struct {
+	Type      string               `json:"type"`
+	Seq       int                  `json:"seq,omitempty"`       // Порядковый номер состояния комнаты
+	ClientID  string               `json:"client_id,omitempty"` // Отправитель операций или адресат snapshot
+	ClientSeq int                  `json:"client_seq,omitempty"`
+	Ops       []mindmap.Op         `json:"ops,omitempty"`
+	Rejected  []mindmap.OpError    `json:"rejected,omitempty"`
+	Document  *mindmap.Document    `json:"document,omitempty"`
+	Version   int                  `json:"version,omitempty"` // Сохраненная версия карты
+	Users     []Presence           `json:"users,omitempty"`
+	Error     string               `json:"error,omitempty"`
+	Fields    []mindmap.FieldError `json:"fields,omitempty"`
+}
+
+// Presence - участник, подключенный к карте
+type Presence struct {
+	ClientID string   `json:"client_id"`
+	UserID   int      `json:"user_id"`
+	Name     string   `json:"name"`
+	Selected []string `json:"selected"`
+}
@@ -0,0 +1,441 @@
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mymindmap/api/internal/attachments"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// maxFlushAttempts - сколько раз комната пытается сохранить изменения,
+// если карту параллельно изменили через REST API
+const maxFlushAttempts = 3
+
+// room - состояние совместного редактирования одной карты
+type room struct {
+	id  int
+	hub *Hub
+
+	refs int // Защищено hub.mu
+
+	mu         sync.Mutex
+	mindMap    *models.MindMap   // Последняя сохраненная запись карты
+	doc        *mindmap.Document // Актуальный документ с несохраненными операциями
+	seq        int               // Номер последнего состояния, разосланного участникам
+	pending    []mindmap.Op      // Операции, примененные после последнего сохранения
+	lastAuthor int
+	clients    map[*client]bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+func newRoom(h *Hub, id int) *room {
+	return &room{
+		id:      id,
+		hub:     h,
+		clients: make(map[*client]bool),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// run периодически сохраняет изменения комнаты, пока она не закрыта
+func (rm *room) run(interval time.Duration) {
+	defer close(rm.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rm.flush()
+		case <-rm.stop:
+			rm.flush()
+			return
+		}
+	}
+}
+
+// close останавливает комнату после финального сохранения
+func (rm *room) close() {
+	rm.stopOnce.Do(func() { close(rm.stop) })
+	<-rm.stopped
+}
+
+func (rm *room) size() int {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return len(rm.clients)
+}
+
+// join подключает участника: загружает карту, если комната новая,
+// и отправляет ему документ целиком
+func (rm *room) join(c *client) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.doc == nil {
+		if err := rm.load(); err != nil {
+			return err
+		}
+	}
+
+	rm.clients[c] = true
+	c.sendMessage(outMessage{
+		Type:     msgSnapshot,
+		Seq:      rm.seq,
+		ClientID: c.id,
+		Document: rm.doc,
+		Version:  rm.mindMap.Version,
+	})
+	rm.broadcastPresence()
+	return nil
+}
+
+func (rm *room) leave(c *client) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	delete(rm.clients, c)
+	rm.broadcastPresence()
+}
+
+// applyOps применяет пакет участника, подтверждает его отправителю
+// и рассылает примененные операции остальным
+func (rm *room) applyOps(c *client, msg inMessage) {
+	if !rm.extractFiles(c, msg) {
+		return
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if c.readOnly {
+		c.sendMessage(outMessage{Type: msgError, ClientSeq: msg.ClientSeq, Error: "read-only access"})
+		return
+	}
+	if rm.doc == nil {
+		return // Карта удалена, клиент уже отключается
+	}
+
+	doc, applied, rejected := mindmap.ApplyOpsLenient(rm.doc, msg.Ops)
+	if err := doc.CheckLimits(mindmap.DefaultLimits); err != nil {
+		out := outMessage{Type: msgError, ClientSeq: msg.ClientSeq, Error: err.Error()}
+		var verr *mindmap.ValidationError
+		if errors.As(err, &verr) {
+			out.Fields = verr.Errors
+		}
+		c.sendMessage(out)
+		return
+	}
+
+	if len(applied) > 0 {
+		rm.doc = doc
+		rm.seq++
+		rm.pending = append(rm.pending, applied...)
+		rm.lastAuthor = c.userID
+
+		rm.broadcast(outMessage{
+			Type:      msgOps,
+			Seq:       rm.seq,
+			ClientID:  c.id,
+			ClientSeq: msg.ClientSeq,
+			Ops:       applied,
+		}, c)
+	}
+
+	c.sendMessage(outMessage{
+		Type:      msgAck,
+		Seq:       rm.seq,
+		ClientSeq: msg.ClientSeq,
+		Ops:       applied,
+		Rejected:  rejected,
+	})
+}
+
+// extractFiles выносит картинки из data URL в операциях в файлы карты, чтобы они
+// не попадали в документ и журнал. Файлы сохраняются без блокировки комнаты:
+// загрузка не должна задерживать остальных участников. Чтобы отклоненные
+// операции не оставляли файлов, на которые ничего не ссылается, операции
+// сначала пробно применяются к текущему документу, и картинки выносятся только
+// из применимых. При ошибке отвечает отправителю и возвращает false.
+func (rm *room) extractFiles(c *client, msg inMessage) bool {
+	if rm.hub.files == nil || !attachments.HasDataURLs(msg.Ops) {
+		return true
+	}
+
+	rm.mu.Lock()
+	if c.readOnly || rm.doc == nil {
+		rm.mu.Unlock()
+		return true // Ошибку вернет applyOps
+	}
+	doc, _, rejected := mindmap.ApplyOpsLenient(rm.doc, msg.Ops)
+	overLimits := doc.CheckLimits(mindmap.DefaultLimits) != nil
+	rm.mu.Unlock()
+	if overLimits {
+		return true
+	}
+
+	skip := make(map[int]bool, len(rejected))
+	for _, r := range rejected {
+		skip[r.Index] = true
+	}
+	var accepted []mindmap.Op
+	var indexes []int
+	for i, op := range msg.Ops {
+		if !skip[i] {
+			accepted = append(accepted, op)
+			indexes = append(indexes, i)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	userID := c.userID
+	var hashes []string
+	_, err := attachments.ExtractOps(accepted, func(contentType string, data []byte) (string, error) {
+		a, err := rm.hub.files.Save(ctx, contentType, data, &userID)
+		if err != nil {
+			return "", err
+		}
+		hashes = append(hashes, a.Hash)
+		return a.URL, nil
+	})
+	for i, op := range accepted {
+		msg.Ops[indexes[i]] = op
+	}
+	// Файлы загружены в эту карту, как через /api/mindmaps/{id}/attachments:
+	// связь не зависит от того, кто из участников сохранит комнату
+	if err == nil && len(hashes) > 0 {
+		err = rm.hub.links.Link(ctx, rm.id, hashes)
+	}
+	if err != nil {
+		rm.hub.logger.Printf("collab: mindmap %d: save attachments: %v", rm.id, err)
+		errMsg := "attachment cannot be saved"
+		if errors.Is(err, attachments.ErrTooLarge) {
+			errMsg = err.Error()
+		}
+		c.sendMessage(outMessage{Type: msgError, ClientSeq: msg.ClientSeq, Error: errMsg})
+		return false
+	}
+	return true
+}
+
+// setReadOnly меняет права клиентов пользователя
+func (rm *room) setReadOnly(userID int, readOnly bool) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	for c := range rm.clients {
+		if c.userID == userID {
+			c.readOnly = readOnly
+		}
+	}
+}
+
+// revoke отключает клиентов пользователя. Уже принятые от них операции сохраняются.
+func (rm *room) revoke(userID int) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	for c := range rm.clients {
+		if c.userID == userID {
+			c.readOnly = true
+			c.sendMessage(outMessage{Type: msgError, Error: "access revoked"})
+			c.close()
+		}
+	}
+}
+
+// shutdown отключает всех участников удаленной карты
+func (rm *room) shutdown() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.reset(context.Background(), nil)
+}
+
+// selectNodes обновляет выделение участника
+func (rm *room) selectNodes(c *client, uids []string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if uids == nil {
+		uids = []string{}
+	}
+	c.selected = uids
+	rm.broadcastPresence()
+}
+
+// flush сохраняет накопленные операции. Если карту успели изменить в обход
+// комнаты, операции перекладываются поверх свежей версии и сохранение повторяется.
+// Без накопленных операций проверяет, не изменилась ли карта извне.
+func (rm *room) flush() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.doc == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if len(rm.pending) == 0 {
+		fresh, err := rm.hub.store.GetByID(ctx, rm.id)
+		if err != nil {
+			rm.hub.logger.Printf("collab: check mindmap %d: %v", rm.id, err)
+			return
+		}
+		if fresh == nil || fresh.Version != rm.mindMap.Version {
+			rm.reset(ctx, fresh)
+		}
+		return
+	}
+
+	for attempt := 0; attempt < maxFlushAttempts; attempt++ {
+		err := rm.save(ctx)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, repository.ErrMindMapNotFound) {
+			rm.reset(ctx, nil)
+			return
+		}
+		if !errors.Is(err, repository.ErrVersionConflict) {
+			rm.hub.logger.Printf("collab: save mindmap %d: %v", rm.id, err)
+			return
+		}
+
+		fresh, err := rm.hub.store.GetByID(ctx, rm.id)
+		if err != nil {
+			rm.hub.logger.Printf("collab: reload mindmap %d: %v", rm.id, err)
+			return
+		}
+		rm.reset(ctx, fresh)
+		if rm.doc == nil {
+			return
+		}
+	}
+	rm.hub.logger.Printf("collab: mindmap %d: giving up after %d conflicting saves", rm.id, maxFlushAttempts)
+}
+
+func (rm *room) save(ctx context.Context) error {
+	data, err := rm.doc.Encode()
+	if err != nil {
+		return err
+	}
+	ops, err := json.Marshal(rm.pending)
+	if err != nil {
+		return err
+	}
+
+	m := *rm.mindMap
+	m.Data = data
+	if _, err := rm.hub.store.UpdateWithOps(ctx, &m, rm.lastAuthor, ops); err != nil {
+		return err
+	}
+
+	rm.mindMap = &m
+	rm.pending = nil
+	return nil
+}
+
+// reset переносит несохраненные операции на свежую версию карты и рассылает
+// участникам новый документ. Если карта удалена, отключает участников.
+func (rm *room) reset(ctx context.Context, fresh *models.MindMap) {
+	if fresh == nil {
+		rm.broadcast(outMessage{Type: msgError, Error: "mindmap was deleted"}, nil)
+		for c := range rm.clients {
+			c.close()
+		}
+		rm.doc, rm.pending = nil, nil
+		return
+	}
+
+	doc, err := parseStored(fresh)
+	if err != nil {
+		rm.hub.logger.Printf("collab: mindmap %d: %v", rm.id, err)
+		return
+	}
+	doc, rm.pending, _ = mindmap.ApplyOpsLenient(doc, rm.pending)
+
+	rm.mindMap, rm.doc = fresh, doc
+	rm.seq++
+	for c := range rm.clients {
+		c.sendMessage(outMessage{
+			Type:     msgSnapshot,
+			Seq:      rm.seq,
+			ClientID: c.id,
+			Document: rm.doc,
+			Version:  fresh.Version,
+		})
+	}
+}
+
+func (rm *room) load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	m, err := rm.hub.store.GetByID(ctx, rm.id)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return fmt.Errorf("mindmap %d not found", rm.id)
+	}
+	doc, err := parseStored(m)
+	if err != nil {
+		return err
+	}
+
+	rm.mindMap, rm.doc = m, doc
+	return nil
+}
+
+func parseStored(m *models.MindMap) (*mindmap.Document, error) {
+	doc, err := mindmap.ParseString(m.Data, mindmap.Limits{})
+	if err != nil {
+		return nil, fmt.Errorf("parse stored mindmap: %w", err)
+	}
+	mindmap.EnsureUIDs(doc)
+	return doc, nil
+}
+
+func (rm *room) broadcastPresence() {
+	users := make([]Presence, 0, len(rm.clients))
+	for c := range rm.clients {
+		selected := c.selected
+		if selected == nil {
+			selected = []string{}
+		}
+		users = append(users, Presence{ClientID: c.id, UserID: c.userID, Name: c.name, Selected: selected})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ClientID < users[j].ClientID })
+	rm.broadcast(outMessage{Type: msgPresence, Users: users}, nil)
+}
+
+// broadcast отправляет сообщение всем участникам, кроме except
+func (rm *room) broadcast(msg outMessage, except *client) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	for c := range rm.clients {
+		if c != except {
+			c.sendRaw(payload)
+		}
+	}
+}
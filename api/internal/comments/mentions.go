@@ -0,0 +1,28 @@
+// Package comments содержит правила комментариев к узлам карт, не зависящие от БД:
+// разбор упоминаний пользователей в тексте комментария.
+package comments
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern - упоминание вида @user@example.com. Упоминание начинается
+// в начале текста или после пробела или открывающей скобки, чтобы адрес
+// почты в тексте не считался упоминанием.
+var mentionPattern = regexp.MustCompile(`(?:^|[\s(\[])@([^\s@()\[\]<>,;:!?"']+@[^\s@()\[\]<>,;:!?"']+\.[^\s@()\[\]<>,;:!?"']+)`)
+
+// Mentions возвращает адреса почты упомянутых пользователей в нижнем регистре,
+// без повторов, в порядке первого упоминания
+func Mentions(body string) []string {
+	var emails []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		email := strings.ToLower(strings.TrimRight(m[1], "."))
+		if !seen[email] {
+			seen[email] = true
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
@@ -0,0 +1,29 @@
+package comments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"none", "looks good", nil},
+		{"single", "@alice@example.com please check", []string{"alice@example.com"}},
+		{"punctuation", "ask @Bob@Example.com, or (@carol@example.org).", []string{"bob@example.com", "carol@example.org"}},
+		{"trailing dot", "thanks @dave@example.com.", []string{"dave@example.com"}},
+		{"duplicates", "@a@x.io and @A@X.io again", []string{"a@x.io"}},
+		{"plain email is not a mention", "write to alice@example.com", nil},
+		{"no domain", "@alice hi", nil},
+		{"multiline", "first\n@erin@example.com", []string{"erin@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Mentions(tt.body))
+		})
+	}
+}
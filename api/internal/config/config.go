@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +15,7 @@ type Config struct {
 	PostgresUser    string
 	PostgresPass    string
 	PostgresHost    string
+	TrashRetentionDays int
 }
 
 func Load() *Config {
@@ -27,6 +29,10 @@ func Load() *Config {
 		PostgresHost: os.Getenv("POSTGRES_HOST"),
 	}
 
+	if days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && days > 0 {
+		cfg.TrashRetentionDays = days
+	}
+
 	cfg.PostgresURL = fmt.Sprintf(
 		"postgres://%s:%s@%s/%s?sslmode=disable",
 		cfg.PostgresUser, cfg.PostgresPass, cfg.PostgresHost, cfg.PostgresDB,
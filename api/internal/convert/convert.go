@@ -0,0 +1,157 @@
+// Package convert переводит документ карты (mindmap.Document) в другие
+// форматы и обратно: Markdown, OPML, текст с отступами, XMind и FreeMind.
+//
+// Импорт строит только дерево узлов: uid не назначаются, документ нужно
+// проверить и нормализовать так же, как документ от редактора.
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// Format - формат файла карты
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+
+	// Export сериализует документ. nil - формат только для импорта.
+	Export func(doc *mindmap.Document) ([]byte, error)
+	// Import строит документ из файла. nil - формат только для экспорта.
+	Import func(data []byte) (*mindmap.Document, error)
+}
+
+// ErrUnknownFormat - формат не поддерживается
+var ErrUnknownFormat = errors.New("unknown format")
+
+// ErrEmpty - в файле нет ни одного узла
+var ErrEmpty = errors.New("no nodes found")
+
+var formats = map[string]*Format{}
+
+// register добавляет формат в реестр; вызывается из init файлов форматов
+func register(f *Format) {
+	formats[f.Name] = f
+}
+
+// ByExtension возвращает формат по расширению имени файла
+func ByExtension(filename string) (*Format, error) {
+	ext := strings.ToLower(path.Ext(filename))
+	for _, f := range formats {
+		if f.Extension == ext {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownFormat, ext)
+}
+
+// Lookup возвращает формат по имени
+func Lookup(name string) (*Format, error) {
+	f, ok := formats[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, name)
+	}
+	return f, nil
+}
+
+// ExportFormats - имена форматов, поддерживающих экспорт
+func ExportFormats() []string {
+	return names(func(f *Format) bool { return f.Export != nil })
+}
+
+// ImportFormats - имена форматов, поддерживающих импорт
+func ImportFormats() []string {
+	return names(func(f *Format) bool { return f.Import != nil })
+}
+
+func names(keep func(*Format) bool) []string {
+	var result []string
+	for name, f := range formats {
+		if keep(f) {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// newNode создает узел импортированного документа
+func newNode(text string) *mindmap.Node {
+	return &mindmap.Node{Data: mindmap.NodeData{Text: text}, Children: []*mindmap.Node{}}
+}
+
+// newDocument собирает документ из узлов верхнего уровня. Если такой узел один,
+// он становится корнем; иначе создается корень с пустым текстом, который
+// вызывающий может заполнить названием карты.
+func newDocument(roots []*mindmap.Node) (*mindmap.Document, error) {
+	switch len(roots) {
+	case 0:
+		return nil, ErrEmpty
+	case 1:
+		return &mindmap.Document{Root: roots[0], Layout: mindmap.DefaultLayout}, nil
+	default:
+		root := newNode("")
+		root.Children = roots
+		return &mindmap.Document{Root: root, Layout: mindmap.DefaultLayout}, nil
+	}
+}
+
+// singleLine - текст узла в одну строку для построчных форматов
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// splitLines разбивает файл на строки, понимая \r\n
+func splitLines(data []byte) []string {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+	return strings.Split(text, "\n")
+}
+
+// indentWidth - ширина отступа строки; табуляция считается за 4 пробела
+func indentWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// indentStack строит дерево по отступам: узел становится ребенком ближайшего
+// узла выше с меньшим отступом
+type indentStack struct {
+	items []indentItem
+	roots []*mindmap.Node
+}
+
+type indentItem struct {
+	indent int
+	node   *mindmap.Node
+}
+
+// push добавляет узел с отступом indent
+func (s *indentStack) push(indent int, n *mindmap.Node) {
+	for len(s.items) > 0 && s.items[len(s.items)-1].indent >= indent {
+		s.items = s.items[:len(s.items)-1]
+	}
+	if len(s.items) > 0 {
+		top := s.items[len(s.items)-1].node
+		top.Children = append(top.Children, n)
+	} else {
+		s.roots = append(s.roots, n)
+	}
+	s.items = append(s.items, indentItem{indent: indent, node: n})
+}
@@ -0,0 +1,224 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// shape - узел без uid и служебных полей для сравнения деревьев
+type shape struct {
+	Text     string
+	Note     string
+	Link     string
+	Children []shape
+}
+
+func shapeOf(n *mindmap.Node) shape {
+	s := shape{Text: n.Data.Text, Note: n.Data.Note, Link: n.Data.Hyperlink}
+	for _, child := range n.Children {
+		s.Children = append(s.Children, shapeOf(child))
+	}
+	return s
+}
+
+func parseDoc(t *testing.T, data string) *mindmap.Document {
+	t.Helper()
+	doc, err := mindmap.ParseString(data, mindmap.Limits{})
+	require.NoError(t, err)
+	return doc
+}
+
+func roundTrip(t *testing.T, format string, doc *mindmap.Document) *mindmap.Document {
+	t.Helper()
+	f, err := Lookup(format)
+	require.NoError(t, err)
+	data, err := f.Export(doc)
+	require.NoError(t, err)
+	result, err := f.Import(data)
+	require.NoError(t, err, string(data))
+	return result
+}
+
+// deepDoc - карта с заметками, ссылкой, разметкой в тексте и вложенностью глубже шести уровней
+func deepDoc(t *testing.T) *mindmap.Document {
+	return parseDoc(t, `{"root":{"data":{"text":"Проект","note":"Цели на год\n\n- не список\n# не заголовок"},"children":[
+		{"data":{"text":"# решетка","hyperlink":"https://example.com/a"},"children":[]},
+		{"data":{"text":"1. нумерация"},"children":[
+			{"data":{"text":"C# и F #"},"children":[
+				{"data":{"text":"> цитата"},"children":[
+					{"data":{"text":"\\обратный слеш"},"children":[
+						{"data":{"text":"уровень 6","note":"`+"```\\n# код\\n```"+`"},"children":[
+							{"data":{"text":"уровень 7","note":"строка 1\n- строка 2"},"children":[
+								{"data":{"text":"уровень 8"},"children":[]}
+							]},
+							{"data":{"text":"- тоже 7"},"children":[]}
+						]}
+					]}
+				]}
+			]}
+		]},
+		{"data":{"text":"[не ссылка](x)"},"children":[]}
+	]}}`)
+}
+
+// awkwardTexts - тексты узлов, похожие на разметку или экранирование форматов диаграмм
+var awkwardTexts = []string{
+	`"кавычки" и 'апострофы'`,
+	`обратный \ слеш \n \N \\ в конце \`,
+	"скобки (круглые) [квадратные] {фигурные} ((двойные)) ))наоборот((",
+	"коды #35; #quot; &amp; &#42; <U+0041> <b>тег</b> <br> &",
+	`**жирный** //курсив// --зачеркнутый-- __подчеркнутый__ ""моно"" ~тильда~`,
+	"a -> b -- c; d",
+	"%% не комментарий",
+	"::icon(fa fa-book) :::класс",
+	"mindmap",
+	"@endmindmap",
+	"* звездочка [#red] цвет",
+	"`код` в обратных кавычках",
+	"; точка с запятой;",
+	"Юникод ✓ 😀",
+	"",
+}
+
+// awkwardDoc - карта с неудобными текстами, вложенностью и многострочным узлом
+func awkwardDoc() *mindmap.Document {
+	root := newNode("Корень (главный)")
+	for _, text := range awkwardTexts {
+		root.Children = append(root.Children, newNode(text))
+	}
+	nested := newNode("строка 1;\n@end строка 2\n**строка 3**")
+	nested.Children = append(nested.Children, newNode("внук"))
+	nested.Children[0].Children = append(nested.Children[0].Children, newNode("правнук \"1\""))
+	root.Children[0].Children = append(root.Children[0].Children, nested)
+	return &mindmap.Document{Root: root, Layout: mindmap.DefaultLayout}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	doc := deepDoc(t)
+
+	result := roundTrip(t, "markdown", doc)
+	assert.Equal(t, shapeOf(doc.Root), shapeOf(result.Root))
+}
+
+func TestMarkdownExport(t *testing.T) {
+	doc := parseDoc(t, `{"root":{"data":{"text":"<p>Корень</p>","richText":true,"note":"заметка"},"children":[
+		{"data":{"text":"Ветка"},"children":[]}
+	]}}`)
+
+	data, err := exportMarkdown(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "# Корень\n\nзаметка\n\n## Ветка\n", string(data))
+}
+
+func TestMarkdownImport(t *testing.T) {
+	doc, err := importMarkdown([]byte("Вступление\r\n\r\n# Заголовок #\n\nтекст\n\n1. первый\n   продолжение\n2) второй\n   * вложенный\n### Глубже\n## Второй\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, shape{
+		Text: "Заголовок",
+		Note: "Вступление\n\nтекст",
+		Children: []shape{
+			{Text: "первый", Note: " продолжение"},
+			{Text: "второй", Children: []shape{{Text: "вложенный"}}},
+			{Text: "Глубже"},
+			{Text: "Второй"},
+		},
+	}, shapeOf(doc.Root))
+}
+
+func TestMarkdownImportSeveralRoots(t *testing.T) {
+	doc, err := importMarkdown([]byte("- a\n- b\n  - c\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, shape{Children: []shape{
+		{Text: "a"},
+		{Text: "b", Children: []shape{{Text: "c"}}},
+	}}, shapeOf(doc.Root))
+}
+
+func TestImportEmpty(t *testing.T) {
+	for format, empty := range map[string]string{
+		"markdown": "\n\n",
+		"txt":      "  \n",
+		"opml":     `<opml version="2.0"><head/><body/></opml>`,
+	} {
+		f, err := Lookup(format)
+		require.NoError(t, err)
+
+		_, err = f.Import([]byte(empty))
+		assert.ErrorIs(t, err, ErrEmpty, format)
+	}
+}
+
+func TestOPMLRoundTrip(t *testing.T) {
+	doc := deepDoc(t)
+
+	result := roundTrip(t, "opml", doc)
+	assert.Equal(t, shapeOf(doc.Root), shapeOf(result.Root))
+}
+
+func TestOPMLImport(t *testing.T) {
+	doc, err := importOPML([]byte(`<?xml version="1.0"?>
+<opml version="1.0"><head><title>План</title></head><body>
+	<outline text="Один" _note="строка&#10;вторая"><outline title="Вложенный"/></outline>
+	<outline text="Сайт" type="link" url="https://example.com"/>
+</body></opml>`))
+	require.NoError(t, err)
+
+	assert.Equal(t, shape{
+		Text: "План",
+		Children: []shape{
+			{Text: "Один", Note: "строка\nвторая", Children: []shape{{Text: "Вложенный"}}},
+			{Text: "Сайт", Link: "https://example.com"},
+		},
+	}, shapeOf(doc.Root))
+
+	_, err = importOPML([]byte("<opml><body>"))
+	assert.Error(t, err)
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	doc := deepDoc(t)
+	// Текстовый формат хранит только тексты
+	doc.Walk(func(n, _ *mindmap.Node, _ int) bool {
+		n.Data.Note, n.Data.Hyperlink = "", ""
+		return true
+	})
+
+	result := roundTrip(t, "txt", doc)
+	assert.Equal(t, shapeOf(doc.Root), shapeOf(result.Root))
+}
+
+func TestTextImport(t *testing.T) {
+	doc, err := importText([]byte("\ufeffКорень\n    a\n\tb\n        c\n\n  d\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, shape{Text: "Корень", Children: []shape{
+		{Text: "a"},
+		{Text: "b", Children: []shape{{Text: "c"}}},
+		{Text: "d"},
+	}}, shapeOf(doc.Root))
+}
+
+func TestLookup(t *testing.T) {
+	f, err := Lookup("Markdown")
+	require.NoError(t, err)
+	assert.Equal(t, ".md", f.Extension)
+
+	_, err = Lookup("docx")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	f, err = ByExtension("archive/Plan.XMind")
+	require.NoError(t, err)
+	assert.Equal(t, "xmind", f.Name)
+
+	_, err = ByExtension("plan")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	assert.Equal(t, []string{"dot", "freemind", "markdown", "mermaid", "opml", "plantuml", "txt", "xmind"}, ExportFormats())
+	assert.Equal(t, ExportFormats(), ImportFormats())
+}
@@ -0,0 +1,557 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func init() {
+	register(&Format{
+		Name:        "dot",
+		ContentType: "text/vnd.graphviz; charset=utf-8",
+		Extension:   ".dot",
+		Export:      exportDOT,
+		Import:      importDOT,
+	})
+}
+
+// dotColor - цвета, которые Graphviz понимает так же, как CSS
+var dotColor = regexp.MustCompile(`^(#[0-9A-Fa-f]{3,8}|[A-Za-z]+)$`)
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "", "\n", `\n`)
+
+// dotQuote - строка DOT в кавычках
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+// exportDOT пишет ориентированный граф слева направо: узел на строку, затем ребра.
+// Заметка становится подсказкой (tooltip), ссылка - атрибутом URL.
+func exportDOT(doc *mindmap.Document) ([]byte, error) {
+	if doc.Root == nil {
+		return nil, ErrEmpty
+	}
+
+	var nodes, edges strings.Builder
+	ids := map[*mindmap.Node]string{}
+	doc.Walk(func(n, parent *mindmap.Node, _ int) bool {
+		id := "n" + strconv.Itoa(len(ids)+1)
+		ids[n] = id
+
+		attrs := []string{"label=" + dotQuote(strings.TrimSpace(n.Data.PlainText()))}
+		if n.Data.Hyperlink != "" {
+			attrs = append(attrs, "URL="+dotQuote(n.Data.Hyperlink))
+		}
+		if n.Data.Note != "" {
+			attrs = append(attrs, "tooltip="+dotQuote(n.Data.Note))
+		}
+		style := styleOf(n.Data)
+		if dotColor.MatchString(style.FillColor) {
+			attrs = append(attrs, `style="rounded,filled"`, "fillcolor="+dotQuote(style.FillColor))
+		}
+		if dotColor.MatchString(style.Color) {
+			attrs = append(attrs, "fontcolor="+dotQuote(style.Color))
+		}
+		if dotColor.MatchString(style.BorderColor) {
+			attrs = append(attrs, "color="+dotQuote(style.BorderColor))
+		}
+		nodes.WriteString("\t" + id + " [" + strings.Join(attrs, ", ") + "];\n")
+
+		if parent != nil {
+			edges.WriteString("\t" + ids[parent] + " -> " + id + ";\n")
+		}
+		return true
+	})
+
+	var b strings.Builder
+	b.WriteString("digraph mindmap {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, style=rounded];\n\n")
+	b.WriteString(nodes.String())
+	if edges.Len() > 0 {
+		b.WriteByte('\n')
+		b.WriteString(edges.String())
+	}
+	b.WriteString("}\n")
+	return []byte(b.String()), nil
+}
+
+// importDOT читает первый граф файла. Ребро a -> b делает b ребенком a; если в
+// узел входит несколько ребер, учитывается первое, а циклы разрываются. Узлы
+// без входящих ребер становятся ветками верхнего уровня.
+func importDOT(data []byte) (*mindmap.Document, error) {
+	tokens, err := dotTokens(strings.TrimPrefix(string(data), "\ufeff"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid dot graph: %w", err)
+	}
+	p := &dotParser{tokens: tokens, nodes: map[string]*dotNode{}}
+	if err := p.parseGraph(); err != nil {
+		return nil, fmt.Errorf("invalid dot graph: %w", err)
+	}
+
+	visited := map[*dotNode]bool{}
+	var build func(n *dotNode) *mindmap.Node
+	build = func(n *dotNode) *mindmap.Node {
+		visited[n] = true
+		node := n.mindMapNode(p.name)
+		for _, child := range n.children {
+			if !visited[child] {
+				node.Children = append(node.Children, build(child))
+			}
+		}
+		return node
+	}
+
+	var roots []*mindmap.Node
+	for _, n := range p.order {
+		if !n.hasParent && !visited[n] {
+			roots = append(roots, build(n))
+		}
+	}
+	// Узлы, до которых не дойти от корней, лежат на циклах
+	for _, n := range p.order {
+		if !visited[n] {
+			roots = append(roots, build(n))
+		}
+	}
+	return newDocument(roots)
+}
+
+// --- Лексер ---
+
+// Виды лексем DOT. Знаки препинания хранятся своим символом.
+const (
+	dotID     = 'i' // Идентификатор или число
+	dotString = 'q' // Строка в кавычках
+	dotHTML   = 'h' // HTML-строка <...>
+	dotEdge   = 'e' // -> или --
+)
+
+type dotToken struct {
+	kind  byte
+	value string
+}
+
+func dotTokens(src string) ([]dotToken, error) {
+	var tokens []dotToken
+	lineStart := true
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			lineStart = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '#' && lineStart:
+			// Строки препроцессора C
+			i = dotSkipLine(src, i)
+			continue
+		case strings.HasPrefix(src[i:], "//"):
+			i = dotSkipLine(src, i)
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, errors.New("unterminated comment")
+			}
+			i += end + 4
+			continue
+		}
+		lineStart = false
+
+		switch {
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			tokens = append(tokens, dotToken{kind: dotEdge, value: src[i : i+2]})
+			i += 2
+		case c == '"':
+			value, n, err := dotReadString(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			// "a" + "b" - одна строка
+			if len(tokens) >= 2 && tokens[len(tokens)-1].kind == '+' && tokens[len(tokens)-2].kind == dotString {
+				tokens = tokens[:len(tokens)-1]
+				tokens[len(tokens)-1].value += value
+			} else {
+				tokens = append(tokens, dotToken{kind: dotString, value: value})
+			}
+			i += n
+		case c == '<':
+			depth, j := 0, i
+			for ; j < len(src); j++ {
+				if src[j] == '<' {
+					depth++
+				} else if src[j] == '>' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if depth != 0 {
+				return nil, errors.New("unterminated html string")
+			}
+			tokens = append(tokens, dotToken{kind: dotHTML, value: src[i+1 : j]})
+			i = j + 1
+		case strings.IndexByte("{}[];,=:+", c) >= 0:
+			tokens = append(tokens, dotToken{kind: c})
+			i++
+		case dotIDByte(c, true) || c == '.' || c == '-' || c >= utf8.RuneSelf:
+			j := i + 1
+			for j < len(src) && (dotIDByte(src[j], false) || src[j] >= utf8.RuneSelf) {
+				j++
+			}
+			tokens = append(tokens, dotToken{kind: dotID, value: src[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+func dotIDByte(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || !first && c == '.'
+}
+
+func dotSkipLine(src string, i int) int {
+	if end := strings.IndexByte(src[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(src)
+}
+
+// dotReadString читает строку в кавычках. Лексер снимает только экранирование
+// кавычки и переноса строки; \\ и прочие последовательности остаются для dotUnescape.
+func dotReadString(src string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '"':
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case '"':
+				b.WriteByte('"')
+			case '\n':
+			case '\r':
+				if i+1 < len(src) && src[i+1] == '\n' {
+					i++
+				}
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+// dotUnescape раскрывает escString Graphviz: переносы строк \n, \l, \r и имена \N, \G
+func dotUnescape(s, node, graph string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'l', 'r':
+			b.WriteByte('\n')
+		case 'N':
+			b.WriteString(node)
+		case 'G':
+			b.WriteString(graph)
+		case 'E', 'T', 'H':
+			// Имена ребра и его концов, у узла их нет
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	// Выравнивание последней строки (\l в конце) не делает ее пустой строкой текста
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// --- Парсер ---
+
+type dotNode struct {
+	name      string
+	attrs     map[string]dotToken
+	children  []*dotNode
+	hasParent bool
+}
+
+type dotParser struct {
+	tokens []dotToken
+	pos    int
+	name   string
+	nodes  map[string]*dotNode
+	order  []*dotNode
+}
+
+func (p *dotParser) peek() dotToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return dotToken{}
+}
+
+func (p *dotParser) next() dotToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *dotParser) expect(kind byte) error {
+	if t := p.next(); t.kind != kind {
+		return fmt.Errorf("expected %q, got %s", kind, t)
+	}
+	return nil
+}
+
+// keyword - следующая лексема является ключевым словом (без учета регистра)
+func (p *dotParser) keyword(words ...string) bool {
+	t := p.peek()
+	if t.kind != dotID {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.value, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t dotToken) String() string {
+	switch t.kind {
+	case 0:
+		return "end of file"
+	case dotID, dotString, dotEdge:
+		return strconv.Quote(t.value)
+	case dotHTML:
+		return "html string"
+	}
+	return strconv.Quote(string(t.kind))
+}
+
+func (t dotToken) isID() bool {
+	return t.kind == dotID || t.kind == dotString || t.kind == dotHTML
+}
+
+// parseGraph: [strict] (graph | digraph) [ID] '{' stmt_list '}'
+func (p *dotParser) parseGraph() error {
+	if p.keyword("strict") {
+		p.next()
+	}
+	if !p.keyword("graph", "digraph") {
+		return fmt.Errorf("expected graph or digraph, got %s", p.peek())
+	}
+	p.next()
+	if p.peek().isID() {
+		p.name = p.next().value
+	}
+	if err := p.expect('{'); err != nil {
+		return err
+	}
+	_, err := p.parseStatements()
+	return err
+}
+
+// parseStatements читает операторы до закрывающей скобки и возвращает узлы,
+// упомянутые в них: подграф может быть концом ребра
+func (p *dotParser) parseStatements() ([]*dotNode, error) {
+	var mentioned []*dotNode
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == 0:
+			return nil, errors.New("unexpected end of file, expected '}'")
+		case t.kind == '}':
+			p.next()
+			return mentioned, nil
+		case t.kind == ';' || t.kind == ',':
+			p.next()
+			continue
+		case p.keyword("graph", "node", "edge"):
+			// Атрибуты по умолчанию не переносятся
+			p.next()
+			if _, err := p.parseAttributes(); err != nil {
+				return nil, err
+			}
+			continue
+		case t.isID() && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].kind == '=':
+			// Атрибут графа: ID = ID
+			p.pos += 2
+			if !p.next().isID() {
+				return nil, errors.New("expected attribute value")
+			}
+			continue
+		}
+
+		nodes, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		mentioned = append(mentioned, nodes...)
+	}
+}
+
+// parseStatement - узел или цепочка ребер: operand (edgeop operand)* [attr_list]
+func (p *dotParser) parseStatement() ([]*dotNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	mentioned := left
+	isEdge := false
+	for p.peek().kind == dotEdge {
+		p.next()
+		isEdge = true
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		for _, from := range left {
+			for _, to := range right {
+				from.children = append(from.children, to)
+				if to != from {
+					to.hasParent = true
+				}
+			}
+		}
+		mentioned = append(mentioned, right...)
+		left = right
+	}
+
+	attrs, err := p.parseAttributes()
+	if err != nil {
+		return nil, err
+	}
+	// Атрибуты ребер не переносятся
+	if !isEdge {
+		for _, n := range left {
+			for key, value := range attrs {
+				n.attrs[key] = value
+			}
+		}
+	}
+	return mentioned, nil
+}
+
+// parseOperand - узел с необязательным портом или подграф
+func (p *dotParser) parseOperand() ([]*dotNode, error) {
+	if p.keyword("subgraph") || p.peek().kind == '{' {
+		if p.keyword("subgraph") {
+			p.next()
+			if p.peek().isID() {
+				p.next()
+			}
+		}
+		if err := p.expect('{'); err != nil {
+			return nil, err
+		}
+		return p.parseStatements()
+	}
+
+	t := p.next()
+	if !t.isID() {
+		return nil, fmt.Errorf("expected node id, got %s", t)
+	}
+	// Порт: id:port или id:port:compass
+	for i := 0; i < 2 && p.peek().kind == ':'; i++ {
+		p.next()
+		if !p.next().isID() {
+			return nil, errors.New("expected port name")
+		}
+	}
+	return []*dotNode{p.node(t.value)}, nil
+}
+
+// parseAttributes: ('[' (ID '=' ID [;|,])* ']')*
+func (p *dotParser) parseAttributes() (map[string]dotToken, error) {
+	attrs := map[string]dotToken{}
+	for p.peek().kind == '[' {
+		p.next()
+		for p.peek().kind != ']' {
+			key := p.next()
+			if !key.isID() {
+				return nil, fmt.Errorf("expected attribute name, got %s", key)
+			}
+			if err := p.expect('='); err != nil {
+				return nil, err
+			}
+			value := p.next()
+			if !value.isID() {
+				return nil, fmt.Errorf("expected attribute value, got %s", value)
+			}
+			attrs[key.value] = value
+			if k := p.peek().kind; k == ';' || k == ',' {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	return attrs, nil
+}
+
+func (p *dotParser) node(name string) *dotNode {
+	n, ok := p.nodes[name]
+	if !ok {
+		n = &dotNode{name: name, attrs: map[string]dotToken{}}
+		p.nodes[name] = n
+		p.order = append(p.order, n)
+	}
+	return n
+}
+
+// mindMapNode - узел карты из атрибутов узла графа
+func (n *dotNode) mindMapNode(graph string) *mindmap.Node {
+	text := func(key string) string {
+		t, ok := n.attrs[key]
+		switch {
+		case !ok:
+			return ""
+		case t.kind == dotHTML:
+			return strings.TrimSpace(mindmap.StripHTML(t.value))
+		}
+		return dotUnescape(t.value, n.name, graph)
+	}
+
+	label := n.name
+	if _, ok := n.attrs["label"]; ok {
+		label = text("label")
+	}
+	node := newNode(label)
+	node.Data.Hyperlink = text("URL")
+	if node.Data.Hyperlink == "" {
+		node.Data.Hyperlink = text("href")
+	}
+	node.Data.Note = text("tooltip")
+
+	color := func(key string) string {
+		if c := text(key); dotColor.MatchString(c) {
+			return c
+		}
+		return ""
+	}
+	applyStyle(&node.Data, nodeStyle{
+		FillColor:   color("fillcolor"),
+		Color:       color("fontcolor"),
+		BorderColor: color("color"),
+	})
+	return node
+}
@@ -0,0 +1,99 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func TestDOTRoundTrip(t *testing.T) {
+	for _, doc := range []*mindmap.Document{awkwardDoc(), deepDoc(t)} {
+		result := roundTrip(t, "dot", doc)
+		assert.Equal(t, shapeOf(doc.Root), shapeOf(result.Root))
+	}
+}
+
+func TestDOTExport(t *testing.T) {
+	doc := parseDoc(t, `{"root":{"data":{"text":"План \"А\"","fillColor":"#ffcc00"},"children":[
+		{"data":{"text":"C:\\new\nстрока","hyperlink":"https://example.com/?a=1&b=\"2\"","note":"заметка"},"children":[]},
+		{"data":{"text":"Цвет","color":"red\"; shape=none"},"children":[]}
+	]}}`)
+
+	data, err := exportDOT(doc)
+	require.NoError(t, err)
+	assert.Equal(t, `digraph mindmap {
+	rankdir=LR;
+	node [shape=box, style=rounded];
+
+	n1 [label="План \"А\"", style="rounded,filled", fillcolor="#ffcc00"];
+	n2 [label="C:\\new\nстрока", URL="https://example.com/?a=1&b=\"2\"", tooltip="заметка"];
+	n3 [label="Цвет"];
+
+	n1 -> n2;
+	n1 -> n3;
+}
+`, string(data))
+}
+
+func TestDOTImport(t *testing.T) {
+	doc, err := importDOT([]byte(`# препроцессор
+/* план */
+strict digraph "Цели" {
+	graph [rankdir=LR]; // комментарий
+	node [shape=box];
+	root [label="Корень\N: \G", fillcolor="#eee" fontcolor=blue];
+	root -> { a; b } [color=red];
+	a [label=<<b>Жирный</b> текст>, URL="https://example.com", tooltip="строка 1\nстрока 2"];
+	b:port:e -> c -> "d" + " e";
+	c -> a; c -> c;
+	subgraph cluster_x { x1 -> x2 }
+	e1 -> e2 -> e1;
+	"d e" [label="слева\lсправа\r"];
+}
+graph second { z }
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, shape{Children: []shape{
+		{Text: "Кореньroot: Цели", Children: []shape{
+			{Text: "Жирный текст", Note: "строка 1\nстрока 2", Link: "https://example.com"},
+			{Text: "b", Children: []shape{{Text: "c", Children: []shape{{Text: "слева\nсправа"}}}}},
+		}},
+		{Text: "x1", Children: []shape{{Text: "x2"}}},
+		{Text: "e1", Children: []shape{{Text: "e2"}}},
+	}}, shapeOf(doc.Root))
+
+	root := doc.Root.Children[0].Data
+	assert.Equal(t, "#eee", fieldString(root, "fillColor"))
+	assert.Equal(t, "blue", fieldString(root, "color"))
+}
+
+func TestDOTImportUndirected(t *testing.T) {
+	doc, err := importDOT([]byte("graph { a -- b -- c; a -- d }"))
+	require.NoError(t, err)
+	assert.Equal(t, shape{Text: "a", Children: []shape{
+		{Text: "b", Children: []shape{{Text: "c"}}},
+		{Text: "d"},
+	}}, shapeOf(doc.Root))
+}
+
+func TestDOTImportInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"flowchart { a }",
+		`digraph { a -> }`,
+		`digraph { a [label="x }`,
+		`digraph { a [label=<<b>] }`,
+		`digraph { a -> b`,
+		`digraph { a [label] }`,
+	} {
+		_, err := importDOT([]byte(input))
+		assert.Error(t, err, input)
+	}
+
+	_, err := importDOT([]byte("digraph {}"))
+	assert.ErrorIs(t, err, ErrEmpty)
+}
@@ -0,0 +1,193 @@
+package convert
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func init() {
+	register(&Format{
+		Name:        "freemind",
+		ContentType: "application/x-freemind",
+		Extension:   ".mm",
+		Export:      exportFreeMind,
+		Import:      importFreeMind,
+	})
+}
+
+type freeMindMap struct {
+	XMLName xml.Name      `xml:"map"`
+	Version string        `xml:"version,attr"`
+	Node    *freeMindNode `xml:"node"`
+}
+
+type freeMindNode struct {
+	ID         string          `xml:"ID,attr,omitempty"`
+	Text       string          `xml:"TEXT,attr,omitempty"`
+	Link       string          `xml:"LINK,attr,omitempty"`
+	Folded     string          `xml:"FOLDED,attr,omitempty"`
+	Color      string          `xml:"COLOR,attr,omitempty"`
+	Background string          `xml:"BACKGROUND_COLOR,attr,omitempty"`
+	Rich       []freeMindRich  `xml:"richcontent"`
+	Font       *freeMindFont   `xml:"font"`
+	Icons      []freeMindIcon  `xml:"icon"`
+	Edge       *freeMindEdge   `xml:"edge"`
+	Nodes      []*freeMindNode `xml:"node"`
+}
+
+// freeMindRich - HTML-содержимое узла: TYPE="NODE" - текст, "NOTE" - заметка
+type freeMindRich struct {
+	Type string `xml:"TYPE,attr"`
+	HTML string `xml:",innerxml"`
+}
+
+type freeMindFont struct {
+	Name   string `xml:"NAME,attr,omitempty"`
+	Size   string `xml:"SIZE,attr,omitempty"`
+	Bold   string `xml:"BOLD,attr,omitempty"`
+	Italic string `xml:"ITALIC,attr,omitempty"`
+}
+
+type freeMindIcon struct {
+	Builtin string `xml:"BUILTIN,attr"`
+}
+
+type freeMindEdge struct {
+	Color string `xml:"COLOR,attr,omitempty"`
+}
+
+func exportFreeMind(doc *mindmap.Document) ([]byte, error) {
+	if doc.Root == nil {
+		return nil, ErrEmpty
+	}
+
+	data, err := xml.MarshalIndent(freeMindMap{Version: "1.0.1", Node: toFreeMindNode(doc.Root)}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode freemind: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func toFreeMindNode(n *mindmap.Node) *freeMindNode {
+	style := styleOf(n.Data)
+	fm := &freeMindNode{
+		ID:         "ID_" + strings.ReplaceAll(n.Data.UID, "-", ""),
+		Text:       n.Data.PlainText(),
+		Link:       n.Data.Hyperlink,
+		Color:      style.Color,
+		Background: style.FillColor,
+	}
+	if n.Data.UID == "" {
+		fm.ID = ""
+	}
+	if collapsed(n.Data) {
+		fm.Folded = "true"
+	}
+	if style.BorderColor != "" {
+		fm.Edge = &freeMindEdge{Color: style.BorderColor}
+	}
+	if style.FontFamily != "" || style.FontSize > 0 || style.Bold || style.Italic {
+		fm.Font = &freeMindFont{Name: style.FontFamily}
+		if style.FontSize > 0 {
+			fm.Font.Size = strconv.Itoa(style.FontSize)
+		}
+		if style.Bold {
+			fm.Font.Bold = "true"
+		}
+		if style.Italic {
+			fm.Font.Italic = "true"
+		}
+	}
+	if n.Data.Note != "" {
+		fm.Rich = append(fm.Rich, freeMindRich{Type: "NOTE", HTML: noteHTML(n.Data.Note)})
+	}
+	for _, icon := range mapIcons(n.Data.Icon, freeMindNames) {
+		fm.Icons = append(fm.Icons, freeMindIcon{Builtin: icon})
+	}
+	for _, child := range n.Children {
+		fm.Nodes = append(fm.Nodes, toFreeMindNode(child))
+	}
+	return fm
+}
+
+func importFreeMind(data []byte) (*mindmap.Document, error) {
+	var in freeMindMap
+	if err := xml.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("invalid freemind map: %w", err)
+	}
+	if in.Node == nil {
+		return nil, ErrEmpty
+	}
+	return newDocument([]*mindmap.Node{fromFreeMindNode(in.Node)})
+}
+
+func fromFreeMindNode(fm *freeMindNode) *mindmap.Node {
+	n := newNode(fm.Text)
+	n.Data.Hyperlink = fm.Link
+	for _, rich := range fm.Rich {
+		switch rich.Type {
+		case "NODE":
+			if n.Data.Text == "" {
+				n.Data.Text = htmlText(rich.HTML)
+			}
+		case "NOTE", "DETAILS":
+			if note := htmlText(rich.HTML); note != "" {
+				if n.Data.Note != "" {
+					note = n.Data.Note + "\n\n" + note
+				}
+				n.Data.Note = note
+			}
+		}
+	}
+
+	style := nodeStyle{Color: fm.Color, FillColor: fm.Background}
+	if fm.Edge != nil {
+		style.BorderColor = fm.Edge.Color
+	}
+	if fm.Font != nil {
+		style.FontFamily = fm.Font.Name
+		style.FontSize, _ = strconv.Atoi(fm.Font.Size)
+		style.Bold = fm.Font.Bold == "true"
+		style.Italic = fm.Font.Italic == "true"
+	}
+	applyStyle(&n.Data, style)
+	setCollapsed(&n.Data, fm.Folded == "true")
+
+	var icons []string
+	for _, icon := range fm.Icons {
+		icons = append(icons, icon.Builtin)
+	}
+	n.Data.Icon = mapIcons(icons, freeMindIcons)
+
+	for _, child := range fm.Nodes {
+		n.Children = append(n.Children, fromFreeMindNode(child))
+	}
+	return n
+}
+
+// noteHTML - заметка в HTML для richcontent, абзац на строку
+func noteHTML(note string) string {
+	var b strings.Builder
+	b.WriteString("<html><head></head><body>")
+	for _, line := range strings.Split(note, "\n") {
+		b.WriteString("<p>" + html.EscapeString(line) + "</p>")
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+// htmlText - простой текст из HTML richcontent без заголовка документа
+func htmlText(s string) string {
+	if i := strings.Index(strings.ToLower(s), "<body"); i >= 0 {
+		s = s[i:]
+	}
+	if i := strings.Index(strings.ToLower(s), "</body>"); i >= 0 {
+		s = s[:i]
+	}
+	return mindmap.StripHTML(s)
+}
@@ -0,0 +1,52 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeMindRoundTrip(t *testing.T) {
+	doc := styledDoc(t)
+
+	result := roundTrip(t, "freemind", doc)
+
+	expected := comparableOf(doc.Root)
+	// Тегов в FreeMind нет, из иконок есть аналоги только у приоритета и завершения
+	expected.Children[0].Children[0].Tags = nil
+	expected.Children[1].Icons = nil
+	assert.Equal(t, expected, comparableOf(result.Root))
+}
+
+func TestFreeMindImport(t *testing.T) {
+	doc, err := importFreeMind([]byte(`<map version="1.0.1">
+<node TEXT="Корень" COLOR="#990000">
+  <richcontent TYPE="NOTE"><html><head><style>p {}</style></head><body><p>первая</p><p>вторая &amp; третья</p></body></html></richcontent>
+  <node FOLDED="true" LINK="https://example.com">
+    <richcontent TYPE="NODE"><html><body><p><b>Жирный</b> текст</p></body></html></richcontent>
+    <icon BUILTIN="full-3"/><icon BUILTIN="idea"/>
+    <font NAME="SansSerif" SIZE="16" BOLD="true"/>
+    <node TEXT="Лист"/>
+  </node>
+</node>
+</map>`))
+	require.NoError(t, err)
+
+	assert.Equal(t, nodeSummary{
+		Text:  "Корень",
+		Note:  "первая\nвторая & третья",
+		Style: nodeStyle{Color: "#990000"},
+		Children: []nodeSummary{{
+			Text:      "Жирный текст",
+			Link:      "https://example.com",
+			Icons:     []string{"priority_3"},
+			Style:     nodeStyle{FontFamily: "SansSerif", FontSize: 16, Bold: true},
+			Collapsed: true,
+			Children:  []nodeSummary{{Text: "Лист"}},
+		}},
+	}, comparableOf(doc.Root))
+
+	_, err = importFreeMind([]byte(`<map version="1.0.1"></map>`))
+	assert.ErrorIs(t, err, ErrEmpty)
+}
@@ -0,0 +1,229 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// maxHeadingLevel - глубже заголовков Markdown нет, дальше узлы пишутся списком
+const maxHeadingLevel = 6
+
+// listIndentBase - отступ элементов списка в стеке импорта. Больше любого
+// уровня заголовка, поэтому заголовок закрывает все списки под собой.
+const listIndentBase = 100
+
+var (
+	mdHeading  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdListItem = regexp.MustCompile(`^([ \t]*)(?:[-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$`)
+	mdLink     = regexp.MustCompile(`^\[(.*)\]\((\S*)\)$`)
+	mdEscaped  = regexp.MustCompile(`^([ \t]*)\\([#\-*+>\[\\])`)
+	mdNumbered = regexp.MustCompile(`^(\d+)([.)])`)
+	mdEscNum   = regexp.MustCompile(`^([ \t]*\d+)\\([.)])`)
+	mdHashTail = regexp.MustCompile(`(^|[ \t])\\?(#+)$`)
+)
+
+func init() {
+	register(&Format{
+		Name:        "markdown",
+		ContentType: "text/markdown; charset=utf-8",
+		Extension:   ".md",
+		Export:      exportMarkdown,
+		Import:      importMarkdown,
+	})
+}
+
+// exportMarkdown пишет узлы первых шести уровней заголовками, более глубокие -
+// вложенными списками. Заметка идет абзацем под своим узлом.
+func exportMarkdown(doc *mindmap.Document) ([]byte, error) {
+	if doc.Root == nil {
+		return nil, ErrEmpty
+	}
+
+	var b strings.Builder
+	inList := false
+	doc.Walk(func(n, _ *mindmap.Node, level int) bool {
+		text := markdownText(n.Data)
+		if level <= maxHeadingLevel {
+			inList = false
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(strings.Repeat("#", level))
+			if text != "" {
+				b.WriteString(" " + text)
+			}
+			b.WriteByte('\n')
+			if note := strings.Trim(n.Data.Note, "\n"); note != "" {
+				b.WriteByte('\n')
+				writeMarkdownNote(&b, note, "", true)
+			}
+			return true
+		}
+
+		indent := strings.Repeat("  ", level-maxHeadingLevel-1)
+		if !inList {
+			b.WriteByte('\n')
+			inList = true
+		}
+		b.WriteString(indent + "-")
+		if text != "" {
+			b.WriteString(" " + text)
+		}
+		b.WriteByte('\n')
+		if note := strings.Trim(n.Data.Note, "\n"); note != "" {
+			// Пустая строка разорвала бы список, поэтому внутри списка абзацы склеиваются
+			writeMarkdownNote(&b, note, indent+"  ", false)
+		}
+		return true
+	})
+	return []byte(b.String()), nil
+}
+
+// markdownText - текст узла одной строкой; ссылка узла оформляется ссылкой Markdown
+func markdownText(d mindmap.NodeData) string {
+	text := singleLine(d.PlainText())
+	if d.Hyperlink != "" && !strings.ContainsAny(d.Hyperlink, " \t\n()") {
+		return "[" + strings.NewReplacer("[", `\[`, "]", `\]`).Replace(text) + "](" + d.Hyperlink + ")"
+	}
+	// Решетки в конце заголовка Markdown считает закрывающими
+	text = mdHashTail.ReplaceAllString(text, `$1\$2`)
+	return escapeMarkdown(text)
+}
+
+// writeMarkdownNote пишет строки заметки с отступом indent. Строки внутри
+// блоков кода ``` не экранируются.
+func writeMarkdownNote(b *strings.Builder, note, indent string, keepBlank bool) {
+	inFence := false
+	for _, line := range strings.Split(strings.ReplaceAll(note, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			if keepBlank {
+				b.WriteByte('\n')
+			}
+			continue
+		}
+		fence := isFence(line)
+		if !inFence && !fence {
+			line = escapeMarkdown(line)
+		}
+		if fence {
+			inFence = !inFence
+		}
+		b.WriteString(indent + line + "\n")
+	}
+}
+
+// escapeMarkdown экранирует начало строки, которое Markdown принял бы за разметку
+func escapeMarkdown(line string) string {
+	rest := strings.TrimLeft(line, " \t")
+	lead := line[:len(line)-len(rest)]
+	if rest == "" {
+		return line
+	}
+	switch rest[0] {
+	case '#', '-', '*', '+', '>', '[', '\\':
+		return lead + `\` + rest
+	}
+	if m := mdNumbered.FindStringSubmatchIndex(rest); m != nil {
+		return lead + rest[:m[3]] + `\` + rest[m[3]:]
+	}
+	return line
+}
+
+// unescapeMarkdown снимает экранирование, добавленное escapeMarkdown
+func unescapeMarkdown(line string) string {
+	if mdEscaped.MatchString(line) {
+		return mdEscaped.ReplaceAllString(line, "$1$2")
+	}
+	return mdEscNum.ReplaceAllString(line, "$1$2")
+}
+
+func isFence(line string) bool {
+	rest := strings.TrimLeft(line, " \t")
+	return strings.HasPrefix(rest, "```") || strings.HasPrefix(rest, "~~~")
+}
+
+// importMarkdown строит дерево из заголовков и списков. Заголовок вкладывается в
+// ближайший заголовок уровнем выше, список - в последний заголовок. Прочие
+// строки становятся заметкой предыдущего узла; текст до первого узла - заметкой корня.
+func importMarkdown(data []byte) (*mindmap.Document, error) {
+	var (
+		stack    indentStack
+		last     *mindmap.Node
+		note     []string
+		preamble []string
+		strip    int
+		inFence  bool
+	)
+
+	flush := func() {
+		text := strings.Trim(strings.Join(note, "\n"), "\n")
+		if last == nil {
+			preamble = append(preamble, note...)
+		} else if strings.TrimSpace(text) != "" {
+			last.Data.Note = text
+		}
+		note = nil
+	}
+	add := func(indent int, text string) {
+		flush()
+		n := newNode("")
+		if m := mdLink.FindStringSubmatch(text); m != nil {
+			n.Data.Text = strings.NewReplacer(`\[`, "[", `\]`, "]").Replace(m[1])
+			n.Data.Hyperlink = m[2]
+		} else {
+			n.Data.Text = mdHashTail.ReplaceAllString(unescapeMarkdown(text), "$1$2")
+		}
+		stack.push(indent, n)
+		last = n
+	}
+
+	for _, line := range splitLines(data) {
+		if inFence || isFence(line) {
+			if isFence(line) {
+				inFence = !inFence
+			}
+			note = append(note, stripIndent(line, strip))
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			add(len(m[1]), strings.TrimSpace(m[2]))
+			strip = 0
+			continue
+		}
+		if m := mdListItem.FindStringSubmatch(line); m != nil {
+			indent := indentWidth(m[1])
+			add(listIndentBase+indent, strings.TrimSpace(m[2]))
+			strip = indent + 2
+			continue
+		}
+		note = append(note, unescapeMarkdown(stripIndent(line, strip)))
+	}
+	flush()
+
+	doc, err := newDocument(stack.roots)
+	if err != nil {
+		return nil, err
+	}
+	if text := strings.Trim(strings.Join(preamble, "\n"), "\n"); strings.TrimSpace(text) != "" {
+		if doc.Root.Data.Note != "" {
+			text += "\n\n" + doc.Root.Data.Note
+		}
+		doc.Root.Data.Note = text
+	}
+	return doc, nil
+}
+
+// stripIndent убирает до width пробелов отступа продолжения элемента списка
+func stripIndent(line string, width int) string {
+	for width > 0 && line != "" && (line[0] == ' ' || line[0] == '\t') {
+		if line[0] == '\t' {
+			width -= 4
+		} else {
+			width--
+		}
+		line = line[1:]
+	}
+	return line
+}
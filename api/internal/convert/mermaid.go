@@ -0,0 +1,169 @@
+package convert
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func init() {
+	register(&Format{
+		Name:        "mermaid",
+		ContentType: "text/vnd.mermaid; charset=utf-8",
+		Extension:   ".mmd",
+		Export:      exportMermaid,
+		Import:      importMermaid,
+	})
+}
+
+var (
+	// mermaidPlain - символы, допустимые в узле без формы и кавычек
+	mermaidPlain = regexp.MustCompile("^[^()\\[\\]{}\"#<>&`]+$")
+	// mermaidEntity - код символа Mermaid: #quot; или #35;
+	mermaidEntity = regexp.MustCompile(`#(\w+);`)
+	mermaidBreak  = regexp.MustCompile(`(?i)<br\s*/?>`)
+)
+
+// mermaidShapes - формы узлов: открывающий и закрывающий ограничители.
+// Длинные ограничители проверяются раньше коротких.
+var mermaidShapes = [][2]string{
+	{"((", "))"},
+	{"))", "(("},
+	{"{{", "}}"},
+	{"(-", "-)"},
+	{"[", "]"},
+	{"(", ")"},
+	{")", "("},
+}
+
+var mermaidEscaper = strings.NewReplacer(
+	"#", "#35;",
+	`"`, "#quot;",
+	"&", "#amp;",
+	"<", "#lt;",
+	">", "#gt;",
+	"`", "#96;",
+	"::", "#58;#58;",
+	"\n", "<br>",
+)
+
+// exportMermaid пишет диаграмму mindmap: вложенность - отступами, корень - кругом.
+// Заметки и ссылки в Mermaid не переносятся.
+func exportMermaid(doc *mindmap.Document) ([]byte, error) {
+	if doc.Root == nil {
+		return nil, ErrEmpty
+	}
+
+	var b strings.Builder
+	b.WriteString("mindmap\n")
+	id := 0
+	doc.Walk(func(n, _ *mindmap.Node, depth int) bool {
+		b.WriteString(strings.Repeat("  ", depth))
+		if depth == 1 {
+			b.WriteString("root((" + mermaidText(n.Data.PlainText(), true) + "))")
+		} else if text := mermaidText(n.Data.PlainText(), false); !strings.HasPrefix(text, `"`) {
+			b.WriteString(text)
+		} else {
+			id++
+			b.WriteString("n" + strconv.Itoa(id) + "[" + text + "]")
+		}
+		b.WriteByte('\n')
+		return true
+	})
+	return []byte(b.String()), nil
+}
+
+// mermaidText - текст узла для Mermaid. Текст, который парсер принял бы за
+// разметку, берется в кавычки, а спецсимволы заменяются кодами #...;
+func mermaidText(text string, quoteAlways bool) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = singleLine(line)
+	}
+	text = strings.Join(lines, "\n")
+	if !quoteAlways && mermaidIsPlain(text) {
+		return text
+	}
+	if text == "" {
+		// Пустая строка в кавычках - синтаксическая ошибка Mermaid
+		text = " "
+	}
+	return `"` + mermaidEscaper.Replace(text) + `"`
+}
+
+// mermaidIsPlain - можно ли записать текст без кавычек: в нем нет форм,
+// комментариев (%%), иконок и классов (::)
+func mermaidIsPlain(text string) bool {
+	return mermaidPlain.MatchString(text) && text != "mindmap" && !strings.ContainsAny(text, "\n") &&
+		!strings.Contains(text, "%%") && !strings.Contains(text, "::")
+}
+
+// importMermaid читает диаграмму mindmap. Иконки (::icon) и классы (:::) пропускаются.
+func importMermaid(data []byte) (*mindmap.Document, error) {
+	var (
+		stack       indentStack
+		header      bool
+		frontmatter bool
+	)
+	for i, line := range splitLines(data) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case i == 0 && trimmed == "---":
+			frontmatter = true
+			continue
+		case frontmatter:
+			frontmatter = trimmed != "---"
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "%%") || strings.HasPrefix(trimmed, "::"):
+			continue
+		case !header:
+			if trimmed != "mindmap" {
+				return nil, errors.New("invalid mermaid diagram: expected mindmap")
+			}
+			header = true
+			continue
+		}
+		stack.push(indentWidth(line), newNode(mermaidNodeText(trimmed)))
+	}
+	return newDocument(stack.roots)
+}
+
+// mermaidNodeText - текст узла из строки вида id[текст], id((текст)) или просто текст
+func mermaidNodeText(line string) string {
+	// Класс и иконка могут идти в той же строке после узла
+	if i := strings.Index(line, ":::"); i > 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+
+	text := line
+	if i := strings.IndexAny(line, "([{)"); i >= 0 {
+		rest := line[i:]
+		for _, shape := range mermaidShapes {
+			if len(rest) >= len(shape[0])+len(shape[1]) && strings.HasPrefix(rest, shape[0]) && strings.HasSuffix(rest, shape[1]) {
+				text = strings.TrimSpace(rest[len(shape[0]) : len(rest)-len(shape[1])])
+				break
+			}
+		}
+	}
+
+	if len(text) >= 2 && strings.HasPrefix(text, `"`) && strings.HasSuffix(text, `"`) {
+		text = text[1 : len(text)-1]
+		// Строка Markdown: "`текст`"
+		if len(text) >= 2 && strings.HasPrefix(text, "`") && strings.HasSuffix(text, "`") {
+			text = text[1 : len(text)-1]
+		}
+	}
+	text = mermaidBreak.ReplaceAllString(text, "\n")
+	text = mermaidEntity.ReplaceAllStringFunc(text, func(code string) string {
+		code = code[1:]
+		if _, err := strconv.Atoi(strings.TrimSuffix(code, ";")); err == nil {
+			code = "#" + code
+		}
+		return html.UnescapeString("&" + code)
+	})
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,77 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMermaidRoundTrip(t *testing.T) {
+	doc := awkwardDoc()
+
+	result := roundTrip(t, "mermaid", doc)
+	assert.Equal(t, shapeOf(doc.Root), shapeOf(result.Root))
+}
+
+func TestMermaidExport(t *testing.T) {
+	doc := parseDoc(t, `{"root":{"data":{"text":"План"},"children":[
+		{"data":{"text":"Простая ветка: итоги"},"children":[
+			{"data":{"text":"Цена (руб.)"},"children":[]}
+		]},
+		{"data":{"text":"Две\nстроки \"в кавычках\""},"children":[]},
+		{"data":{"text":""},"children":[]}
+	]}}`)
+
+	data, err := exportMermaid(doc)
+	require.NoError(t, err)
+	assert.Equal(t, `mindmap
+  root(("План"))
+    Простая ветка: итоги
+      n1["Цена (руб.)"]
+    n2["Две<br>строки #quot;в кавычках#quot;"]
+    n3[" "]
+`, string(data))
+}
+
+func TestMermaidImport(t *testing.T) {
+	doc, err := importMermaid([]byte(`---
+title: План
+---
+%% комментарий
+mindmap
+  root((Цели))
+    Origins
+      Long history
+      ::icon(fa fa-book)
+      id1[Квадрат]
+    id2(Скругленный)
+      id3))Взрыв((
+      id4{{Шестиугольник}}
+      id5)Облако(
+    id6["` + "`**Markdown** строка`" + `"]
+    Текст с классом
+    :::urgent
+    id7("Строка<br/>с #quot;кавычками#quot; #35;1")
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, shape{
+		Text: "Цели",
+		Children: []shape{
+			{Text: "Origins", Children: []shape{{Text: "Long history"}, {Text: "Квадрат"}}},
+			{Text: "Скругленный", Children: []shape{{Text: "Взрыв"}, {Text: "Шестиугольник"}, {Text: "Облако"}}},
+			{Text: "**Markdown** строка"},
+			{Text: "Текст с классом"},
+			{Text: "Строка\nс \"кавычками\" #1"},
+		},
+	}, shapeOf(doc.Root))
+}
+
+func TestMermaidImportInvalid(t *testing.T) {
+	_, err := importMermaid([]byte("graph TD\n  A --> B\n"))
+	assert.Error(t, err)
+
+	_, err = importMermaid([]byte("mindmap\n"))
+	assert.ErrorIs(t, err, ErrEmpty)
+}
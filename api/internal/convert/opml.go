@@ -0,0 +1,116 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func init() {
+	register(&Format{
+		Name:        "opml",
+		ContentType: "text/x-opml; charset=utf-8",
+		Extension:   ".opml",
+		Export:      exportOPML,
+		Import:      importOPML,
+	})
+}
+
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title string `xml:"title"`
+}
+
+type opmlBody struct {
+	Outlines []*opmlOutline `xml:"outline"`
+}
+
+// opmlOutline - узел OPML. Заметка хранится в атрибуте _note, как в OmniOutliner и Workflowy.
+type opmlOutline struct {
+	Text     string         `xml:"text,attr"`
+	Title    string         `xml:"title,attr,omitempty"`
+	Note     string         `xml:"_note,attr,omitempty"`
+	Type     string         `xml:"type,attr,omitempty"`
+	URL      string         `xml:"url,attr,omitempty"`
+	Outlines []*opmlOutline `xml:"outline"`
+}
+
+func exportOPML(doc *mindmap.Document) ([]byte, error) {
+	if doc.Root == nil {
+		return nil, ErrEmpty
+	}
+
+	out := opmlDocument{
+		Version: "2.0",
+		Head:    opmlHead{Title: singleLine(doc.Root.Data.PlainText())},
+		Body:    opmlBody{Outlines: []*opmlOutline{toOutline(doc.Root)}},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return nil, fmt.Errorf("encode opml: %w", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func toOutline(n *mindmap.Node) *opmlOutline {
+	o := &opmlOutline{
+		Text: n.Data.PlainText(),
+		Note: n.Data.Note,
+	}
+	if n.Data.Hyperlink != "" {
+		o.Type = "link"
+		o.URL = n.Data.Hyperlink
+	}
+	for _, child := range n.Children {
+		o.Outlines = append(o.Outlines, toOutline(child))
+	}
+	return o
+}
+
+func importOPML(data []byte) (*mindmap.Document, error) {
+	var in opmlDocument
+	if err := xml.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("invalid opml: %w", err)
+	}
+
+	roots := make([]*mindmap.Node, 0, len(in.Body.Outlines))
+	for _, o := range in.Body.Outlines {
+		roots = append(roots, fromOutline(o))
+	}
+	doc, err := newDocument(roots)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Root.Data.Text == "" {
+		doc.Root.Data.Text = strings.TrimSpace(in.Head.Title)
+	}
+	return doc, nil
+}
+
+func fromOutline(o *opmlOutline) *mindmap.Node {
+	text := o.Text
+	if text == "" {
+		text = o.Title
+	}
+	n := newNode(text)
+	n.Data.Note = strings.ReplaceAll(o.Note, "\r\n", "\n")
+	n.Data.Hyperlink = o.URL
+	for _, child := range o.Outlines {
+		n.Children = append(n.Children, fromOutline(child))
+	}
+	return n
+}
@@ -0,0 +1,215 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func init() {
+	register(&Format{
+		Name:        "plantuml",
+		ContentType: "text/vnd.plantuml; charset=utf-8",
+		Extension:   ".puml",
+		Export:      exportPlantUML,
+		Import:      importPlantUML,
+	})
+}
+
+var (
+	// plantUMLNode - узел: звездочки (или +/- по сторонам), цвет, "_" без рамки,
+	// затем текст после пробела или многострочный текст после ":"
+	plantUMLNode = regexp.MustCompile(`^\s*(\*+|\++|-+|#+)(?:\[(#[^\]]*)\])?(_)?(?:(:)(.*)|\s+(.*)|)$`)
+	// plantUMLCreole - разметка Creole: парные маркеры, тильда, теги и коды символов
+	plantUMLCreole = regexp.MustCompile(`\*\*+|//+|""+|--+|__+|~|<|&`)
+	plantUMLCode   = regexp.MustCompile(`&#(\d+);|<U\+([0-9A-Fa-f]{1,6})>|~(.)`)
+	plantUMLColor  = regexp.MustCompile(`^#[0-9A-Fa-f]{3,8}$`)
+)
+
+// plantUMLBlocks - многострочные элементы диаграммы, не относящиеся к узлам
+var plantUMLBlocks = map[string]string{
+	"<style>": "</style>",
+	"legend":  "endlegend",
+	"title":   "end title",
+	"header":  "endheader",
+	"footer":  "endfooter",
+}
+
+// exportPlantUML пишет диаграмму @startmindmap в нотации OrgMode (*, **, ...).
+// Цвет заливки узла переносится, заметки и ссылки - нет.
+func exportPlantUML(doc *mindmap.Document) ([]byte, error) {
+	if doc.Root == nil {
+		return nil, ErrEmpty
+	}
+
+	var b strings.Builder
+	b.WriteString("@startmindmap\n")
+	doc.Walk(func(n, _ *mindmap.Node, depth int) bool {
+		b.WriteString(strings.Repeat("*", depth))
+		if fill := styleOf(n.Data).FillColor; plantUMLColor.MatchString(fill) {
+			b.WriteString("[" + fill + "]")
+		}
+		lines := strings.Split(strings.TrimSpace(n.Data.PlainText()), "\n")
+		if len(lines) == 1 {
+			b.WriteString(" " + plantUMLText(singleLine(lines[0])) + "\n")
+			return true
+		}
+		// Многострочный текст заканчивается ";" в конце строки, поэтому ";" в конце
+		// строк текста экранируется
+		for i, line := range lines {
+			line = plantUMLText(strings.TrimSpace(line))
+			if strings.HasSuffix(line, ";") {
+				line = strings.TrimSuffix(line, ";") + plantUMLCodes(";")
+			}
+			// Строка с @ в начале выглядела бы как @endmindmap
+			if strings.HasPrefix(line, "@") {
+				line = plantUMLCodes("@") + line[1:]
+			}
+			if i == 0 {
+				b.WriteString(":")
+			}
+			b.WriteString(line)
+			if i == len(lines)-1 {
+				b.WriteString(";")
+			}
+			b.WriteByte('\n')
+		}
+		return true
+	})
+	b.WriteString("@endmindmap\n")
+	return []byte(b.String()), nil
+}
+
+// plantUMLText экранирует разметку Creole кодами символов
+func plantUMLText(text string) string {
+	return plantUMLCreole.ReplaceAllStringFunc(text, plantUMLCodes)
+}
+
+// plantUMLCodes - символы строки кодами <U+XXXX>. Коды &#N; не подходят:
+// ";" в конце строки закончил бы многострочный узел.
+func plantUMLCodes(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		fmt.Fprintf(&b, "<U+%04X>", r)
+	}
+	return b.String()
+}
+
+// importPlantUML читает первую диаграмму @startmindmap. Поддерживаются нотации
+// OrgMode (*) и арифметическая (+/-); заголовки, легенды, стили и комментарии пропускаются.
+func importPlantUML(data []byte) (*mindmap.Document, error) {
+	var (
+		stack     indentStack
+		closing   string   // Конец пропускаемого блока
+		multiline []string // Строки многострочного узла
+		multiNode *mindmap.Node
+	)
+
+	// Без @startmindmap диаграммой считается весь текст
+	lines := splitLines(data)
+	started := true
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "@start") {
+			started = false
+			break
+		}
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !started {
+			started = strings.HasPrefix(trimmed, "@start")
+			if started && trimmed != "@startmindmap" && !strings.HasPrefix(trimmed, "@startmindmap ") {
+				return nil, errors.New("invalid plantuml diagram: expected @startmindmap")
+			}
+			continue
+		}
+		if multiNode != nil {
+			if strings.HasSuffix(trimmed, ";") {
+				multiline = append(multiline, strings.TrimSuffix(trimmed, ";"))
+				multiNode.Data.Text = plantUMLUnescape(strings.Join(multiline, "\n"))
+				multiNode, multiline = nil, nil
+			} else {
+				multiline = append(multiline, line)
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "@end") {
+			break
+		}
+		if closing != "" {
+			if strings.EqualFold(trimmed, closing) {
+				closing = ""
+			}
+			continue
+		}
+
+		m := plantUMLNode.FindStringSubmatch(line)
+		if m == nil {
+			if end, ok := plantUMLBlock(trimmed); ok {
+				closing = end
+			}
+			// Комментарии, skinparam, направления сторон и прочие команды
+			continue
+		}
+
+		n := newNode(plantUMLUnescape(strings.TrimSpace(m[6])))
+		if color := m[2]; color != "" {
+			if !plantUMLColor.MatchString(color) {
+				// Именованный цвет: [#Orange]
+				color = strings.ToLower(strings.TrimPrefix(color, "#"))
+			}
+			applyStyle(&n.Data, nodeStyle{FillColor: color})
+		}
+		if m[4] == ":" {
+			if text := strings.TrimSpace(m[5]); strings.HasSuffix(text, ";") {
+				n.Data.Text = plantUMLUnescape(strings.TrimSuffix(text, ";"))
+			} else {
+				multiNode, multiline = n, []string{m[5]}
+			}
+		}
+		stack.push(len(m[1]), n)
+	}
+	if multiNode != nil {
+		multiNode.Data.Text = plantUMLUnescape(strings.Join(multiline, "\n"))
+	}
+	return newDocument(stack.roots)
+}
+
+// plantUMLBlock - начало многострочного блока и его окончание. Однострочные
+// варианты (title Текст) блок не открывают.
+func plantUMLBlock(line string) (string, bool) {
+	lower := strings.ToLower(line)
+	for start, end := range plantUMLBlocks {
+		if lower == start || (start == "legend" && strings.HasPrefix(lower, "legend ")) {
+			return end, true
+		}
+	}
+	return "", false
+}
+
+// plantUMLUnescape заменяет коды символов (&#N;, <U+XXXX>) и экранирование
+// тильдой на сами символы
+func plantUMLUnescape(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(plantUMLCode.ReplaceAllStringFunc(line, func(s string) string {
+			m := plantUMLCode.FindStringSubmatch(s)
+			switch {
+			case m[1] != "":
+				return html.UnescapeString(s)
+			case m[2] != "":
+				code, _ := strconv.ParseUint(m[2], 16, 32)
+				return string(rune(code))
+			default:
+				return m[3]
+			}
+		}))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,87 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlantUMLRoundTrip(t *testing.T) {
+	doc := awkwardDoc()
+
+	result := roundTrip(t, "plantuml", doc)
+	assert.Equal(t, shapeOf(doc.Root), shapeOf(result.Root))
+}
+
+func TestPlantUMLExport(t *testing.T) {
+	doc := parseDoc(t, `{"root":{"data":{"text":"План","fillColor":"#ffcc00"},"children":[
+		{"data":{"text":"**Не жирный** ~"},"children":[
+			{"data":{"text":"Первая;\nвторая;"},"children":[]}
+		]},
+		{"data":{"text":"Ветка","fillColor":"red; background"},"children":[]}
+	]}}`)
+
+	data, err := exportPlantUML(doc)
+	require.NoError(t, err)
+	assert.Equal(t, `@startmindmap
+*[#ffcc00] План
+** <U+002A><U+002A>Не жирный<U+002A><U+002A> <U+007E>
+***:Первая<U+003B>
+вторая<U+003B>;
+** Ветка
+@endmindmap
+`, string(data))
+}
+
+func TestPlantUMLImport(t *testing.T) {
+	doc, err := importPlantUML([]byte(`' диаграмма из вики
+@startmindmap
+title Цели на год
+<style>
+node {
+  * { BackgroundColor white }
+}
+</style>
+legend right
+  * не узел
+endlegend
++[#Orange] Корень
+++ Справа ~* экранировано
++++_ Без рамки
+-- Слева <U+2713>
+---:Многострочный
+  текст;
+-- &#60;b&#62;
+@endmindmap
+
+@startmindmap
+* Вторая диаграмма
+@endmindmap
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, shape{
+		Text: "Корень",
+		Children: []shape{
+			{Text: "Справа * экранировано", Children: []shape{{Text: "Без рамки"}}},
+			{Text: "Слева ✓", Children: []shape{{Text: "Многострочный\nтекст"}}},
+			{Text: "<b>"},
+		},
+	}, shapeOf(doc.Root))
+	assert.Equal(t, "orange", fieldString(doc.Root.Data, "fillColor"))
+}
+
+func TestPlantUMLImportWithoutTags(t *testing.T) {
+	doc, err := importPlantUML([]byte("* Корень\n** Ветка\n"))
+	require.NoError(t, err)
+	assert.Equal(t, shape{Text: "Корень", Children: []shape{{Text: "Ветка"}}}, shapeOf(doc.Root))
+}
+
+func TestPlantUMLImportInvalid(t *testing.T) {
+	_, err := importPlantUML([]byte("@startuml\nAlice -> Bob\n@enduml\n"))
+	assert.Error(t, err)
+
+	_, err = importPlantUML([]byte("@startmindmap\n@endmindmap\n"))
+	assert.ErrorIs(t, err, ErrEmpty)
+}
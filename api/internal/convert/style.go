@@ -0,0 +1,133 @@
+package convert
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// nodeStyle - стили узла simple-mind-map, у которых есть аналоги в XMind и FreeMind
+type nodeStyle struct {
+	Color       string
+	FillColor   string
+	BorderColor string
+	FontFamily  string
+	FontSize    int
+	Bold        bool
+	Italic      bool
+}
+
+func (s nodeStyle) empty() bool {
+	return s == nodeStyle{}
+}
+
+// styleOf читает стили из дополнительных полей узла
+func styleOf(d mindmap.NodeData) nodeStyle {
+	return nodeStyle{
+		Color:       fieldString(d, "color"),
+		FillColor:   fieldString(d, "fillColor"),
+		BorderColor: fieldString(d, "borderColor"),
+		FontFamily:  fieldString(d, "fontFamily"),
+		FontSize:    fieldInt(d, "fontSize"),
+		Bold:        fieldString(d, "fontWeight") == "bold",
+		Italic:      fieldString(d, "fontStyle") == "italic",
+	}
+}
+
+// applyStyle записывает непустые стили в дополнительные поля узла
+func applyStyle(d *mindmap.NodeData, s nodeStyle) {
+	setField(d, "color", s.Color, s.Color != "")
+	setField(d, "fillColor", s.FillColor, s.FillColor != "")
+	setField(d, "borderColor", s.BorderColor, s.BorderColor != "")
+	setField(d, "fontFamily", s.FontFamily, s.FontFamily != "")
+	setField(d, "fontSize", s.FontSize, s.FontSize > 0)
+	setField(d, "fontWeight", "bold", s.Bold)
+	setField(d, "fontStyle", "italic", s.Italic)
+}
+
+// collapsed - свернут ли узел в редакторе (expand: false)
+func collapsed(d mindmap.NodeData) bool {
+	return string(d.Fields["expand"]) == "false"
+}
+
+func setCollapsed(d *mindmap.NodeData, value bool) {
+	setField(d, "expand", false, value)
+}
+
+func fieldString(d mindmap.NodeData, key string) string {
+	var s string
+	if err := json.Unmarshal(d.Fields[key], &s); err != nil {
+		return ""
+	}
+	return s
+}
+
+func fieldInt(d mindmap.NodeData, key string) int {
+	var f float64
+	if err := json.Unmarshal(d.Fields[key], &f); err == nil {
+		return int(f)
+	}
+	// Размер шрифта иногда сохраняется строкой
+	n, _ := strconv.Atoi(fieldString(d, key))
+	return n
+}
+
+func setField(d *mindmap.NodeData, key string, value any, ok bool) {
+	if !ok {
+		return
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if d.Fields == nil {
+		d.Fields = make(map[string]json.RawMessage)
+	}
+	d.Fields[key] = raw
+}
+
+// Соответствие иконок simple-mind-map маркерам XMind и иконкам FreeMind.
+// Иконки без аналога при конвертации пропускаются.
+var (
+	xmindMarkers   = map[string]string{}
+	xmindIcons     = map[string]string{}
+	freeMindIcons  = map[string]string{}
+	freeMindNames  = map[string]string{}
+	xmindTaskNames = []string{"task-oct", "task-quarter", "task-3oct", "task-half", "task-5oct", "task-3quar", "task-7oct", "task-done"}
+)
+
+func init() {
+	pair := func(icon, marker, freeMind string) {
+		if marker != "" {
+			xmindMarkers[icon] = marker
+			xmindIcons[marker] = icon
+		}
+		if freeMind != "" {
+			freeMindNames[icon] = freeMind
+			freeMindIcons[freeMind] = icon
+		}
+	}
+	for i := 1; i <= 9; i++ {
+		n := strconv.Itoa(i)
+		pair("priority_"+n, "priority-"+n, "full-"+n)
+	}
+	for i, task := range xmindTaskNames {
+		freeMind := ""
+		if task == "task-done" {
+			freeMind = "button_ok"
+		}
+		pair("progress_"+strconv.Itoa(i+1), task, freeMind)
+	}
+}
+
+// mapIcons переводит имена иконок по таблице, пропуская неизвестные
+func mapIcons(names []string, table map[string]string) []string {
+	var result []string
+	for _, name := range names {
+		if mapped, ok := table[name]; ok {
+			result = append(result, mapped)
+		}
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package convert
+
+import (
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func init() {
+	register(&Format{
+		Name:        "txt",
+		ContentType: "text/plain; charset=utf-8",
+		Extension:   ".txt",
+		Export:      exportText,
+		Import:      importText,
+	})
+}
+
+// exportText пишет узел на строку, вложенность - табуляцией. Заметки не сохраняются.
+func exportText(doc *mindmap.Document) ([]byte, error) {
+	if doc.Root == nil {
+		return nil, ErrEmpty
+	}
+
+	var b strings.Builder
+	doc.Walk(func(n, _ *mindmap.Node, depth int) bool {
+		b.WriteString(strings.Repeat("\t", depth-1))
+		b.WriteString(singleLine(n.Data.PlainText()))
+		b.WriteByte('\n')
+		return true
+	})
+	return []byte(b.String()), nil
+}
+
+// importText строит дерево по отступам строк (пробелы или табуляция), пустые строки пропускаются
+func importText(data []byte) (*mindmap.Document, error) {
+	var stack indentStack
+	for _, line := range splitLines(data) {
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		stack.push(indentWidth(line), newNode(text))
+	}
+	return newDocument(stack.roots)
+}
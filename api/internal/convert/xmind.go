@@ -0,0 +1,339 @@
+package convert
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// maxArchiveEntry - предел распакованного размера файла из архива XMind
+const maxArchiveEntry = 50 << 20
+
+func init() {
+	register(&Format{
+		Name:        "xmind",
+		ContentType: "application/vnd.xmind.workbook",
+		Extension:   ".xmind",
+		Export:      exportXMind,
+		Import:      importXMind,
+	})
+}
+
+// --- content.json (XMind Zen и новее) ---
+
+type xmindSheet struct {
+	ID        string      `json:"id"`
+	Class     string      `json:"class"`
+	Title     string      `json:"title"`
+	RootTopic *xmindTopic `json:"rootTopic"`
+}
+
+type xmindTopic struct {
+	ID       string         `json:"id"`
+	Class    string         `json:"class,omitempty"`
+	Title    string         `json:"title"`
+	Href     string         `json:"href,omitempty"`
+	Branch   string         `json:"branch,omitempty"` // "folded" - ветка свернута
+	Notes    *xmindNotes    `json:"notes,omitempty"`
+	Labels   []string       `json:"labels,omitempty"`
+	Markers  []xmindMarker  `json:"markers,omitempty"`
+	Style    *xmindStyle    `json:"style,omitempty"`
+	Children *xmindChildren `json:"children,omitempty"`
+}
+
+type xmindNotes struct {
+	Plain *xmindPlain `json:"plain,omitempty"`
+}
+
+type xmindPlain struct {
+	Content string `json:"content"`
+}
+
+type xmindMarker struct {
+	MarkerID string `json:"markerId"`
+}
+
+type xmindStyle struct {
+	ID         string            `json:"id,omitempty"`
+	Properties map[string]string `json:"properties"`
+}
+
+type xmindChildren struct {
+	Attached []*xmindTopic `json:"attached,omitempty"`
+}
+
+// --- content.xml (XMind 8 и старше) ---
+
+type xmindLegacyContent struct {
+	XMLName xml.Name           `xml:"xmap-content"`
+	Sheets  []xmindLegacySheet `xml:"sheet"`
+}
+
+type xmindLegacySheet struct {
+	Title string            `xml:"title"`
+	Topic *xmindLegacyTopic `xml:"topic"`
+}
+
+type xmindLegacyTopic struct {
+	Title   string `xml:"title"`
+	Href    string `xml:"href,attr"`
+	Branch  string `xml:"branch,attr"`
+	Notes   string `xml:"notes>plain"`
+	Markers []struct {
+		ID string `xml:"marker-id,attr"`
+	} `xml:"marker-refs>marker-ref"`
+	Labels   []string `xml:"labels>label"`
+	Children []struct {
+		Type   string              `xml:"type,attr"`
+		Topics []*xmindLegacyTopic `xml:"topic"`
+	} `xml:"children>topics"`
+}
+
+// exportXMind пишет архив в формате XMind Zen: content.json, metadata.json, manifest.json
+func exportXMind(doc *mindmap.Document) ([]byte, error) {
+	if doc.Root == nil {
+		return nil, ErrEmpty
+	}
+
+	content, err := json.Marshal([]xmindSheet{{
+		ID:        mindmap.NewUID(),
+		Class:     "sheet",
+		Title:     singleLine(doc.Root.Data.PlainText()),
+		RootTopic: toXMindTopic(doc.Root, true),
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("encode xmind content: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"content.json", content},
+		{"metadata.json", []byte(`{"creator":{"name":"mymindmap"}}`)},
+		{"manifest.json", []byte(`{"file-entries":{"content.json":{},"metadata.json":{}}}`)},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, fmt.Errorf("write xmind archive: %w", err)
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return nil, fmt.Errorf("write xmind archive: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("write xmind archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func toXMindTopic(n *mindmap.Node, root bool) *xmindTopic {
+	t := &xmindTopic{
+		ID:    n.Data.UID,
+		Title: n.Data.PlainText(),
+		Href:  n.Data.Hyperlink,
+	}
+	if t.ID == "" {
+		t.ID = mindmap.NewUID()
+	}
+	if root {
+		t.Class = "topic"
+	}
+	if collapsed(n.Data) {
+		t.Branch = "folded"
+	}
+	if n.Data.Note != "" {
+		t.Notes = &xmindNotes{Plain: &xmindPlain{Content: n.Data.Note}}
+	}
+	t.Labels = n.Data.TagTexts()
+	if len(t.Labels) == 0 {
+		t.Labels = nil
+	}
+	for _, marker := range mapIcons(n.Data.Icon, xmindMarkers) {
+		t.Markers = append(t.Markers, xmindMarker{MarkerID: marker})
+	}
+	if style := styleOf(n.Data); !style.empty() {
+		t.Style = &xmindStyle{ID: mindmap.NewUID(), Properties: xmindProperties(style)}
+	}
+	if len(n.Children) > 0 {
+		t.Children = &xmindChildren{}
+		for _, child := range n.Children {
+			t.Children.Attached = append(t.Children.Attached, toXMindTopic(child, false))
+		}
+	}
+	return t
+}
+
+// importXMind читает первый лист архива. Если content.json нет, читается
+// content.xml старых версий; стили из styles.xml при этом не переносятся.
+func importXMind(data []byte) (*mindmap.Document, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid xmind archive: %w", err)
+	}
+
+	content, err := readZipEntry(zr, "content.json")
+	if err == nil {
+		return importXMindJSON(content)
+	}
+	if !errors.Is(err, errNoEntry) {
+		return nil, err
+	}
+
+	content, err = readZipEntry(zr, "content.xml")
+	if errors.Is(err, errNoEntry) {
+		return nil, errors.New("invalid xmind archive: no content.json or content.xml")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return importXMindXML(content)
+}
+
+func importXMindJSON(content []byte) (*mindmap.Document, error) {
+	var sheets []xmindSheet
+	if err := json.Unmarshal(content, &sheets); err != nil {
+		return nil, fmt.Errorf("invalid xmind content.json: %w", err)
+	}
+	if len(sheets) == 0 || sheets[0].RootTopic == nil {
+		return nil, ErrEmpty
+	}
+	return newDocument([]*mindmap.Node{fromXMindTopic(sheets[0].RootTopic)})
+}
+
+func fromXMindTopic(t *xmindTopic) *mindmap.Node {
+	n := newNode(t.Title)
+	n.Data.Hyperlink = t.Href
+	if t.Notes != nil && t.Notes.Plain != nil {
+		n.Data.Note = t.Notes.Plain.Content
+	}
+	for _, label := range t.Labels {
+		n.Data.Tag = append(n.Data.Tag, mindmap.Tag{Text: label})
+	}
+	var markers []string
+	for _, m := range t.Markers {
+		markers = append(markers, m.MarkerID)
+	}
+	n.Data.Icon = mapIcons(markers, xmindIcons)
+	if t.Style != nil {
+		applyStyle(&n.Data, xmindStyleOf(t.Style.Properties))
+	}
+	setCollapsed(&n.Data, t.Branch == "folded")
+	if t.Children != nil {
+		for _, child := range t.Children.Attached {
+			n.Children = append(n.Children, fromXMindTopic(child))
+		}
+	}
+	return n
+}
+
+func importXMindXML(content []byte) (*mindmap.Document, error) {
+	var in xmindLegacyContent
+	if err := xml.Unmarshal(content, &in); err != nil {
+		return nil, fmt.Errorf("invalid xmind content.xml: %w", err)
+	}
+	if len(in.Sheets) == 0 || in.Sheets[0].Topic == nil {
+		return nil, ErrEmpty
+	}
+	return newDocument([]*mindmap.Node{fromXMindLegacyTopic(in.Sheets[0].Topic)})
+}
+
+// fromXMindLegacyTopic переносит только прикрепленные ветки: плавающие темы
+// (detached) в simple-mind-map не имеют аналога
+func fromXMindLegacyTopic(t *xmindLegacyTopic) *mindmap.Node {
+	n := newNode(t.Title)
+	n.Data.Hyperlink = t.Href
+	n.Data.Note = strings.ReplaceAll(t.Notes, "\r\n", "\n")
+	for _, label := range t.Labels {
+		n.Data.Tag = append(n.Data.Tag, mindmap.Tag{Text: label})
+	}
+	var markers []string
+	for _, m := range t.Markers {
+		markers = append(markers, m.ID)
+	}
+	n.Data.Icon = mapIcons(markers, xmindIcons)
+	setCollapsed(&n.Data, t.Branch == "folded")
+	for _, group := range t.Children {
+		if group.Type != "" && group.Type != "attached" {
+			continue
+		}
+		for _, child := range group.Topics {
+			n.Children = append(n.Children, fromXMindLegacyTopic(child))
+		}
+	}
+	return n
+}
+
+// xmindProperties - стили узла в свойствах XMind
+func xmindProperties(s nodeStyle) map[string]string {
+	props := map[string]string{}
+	set := func(key, value string) {
+		if value != "" {
+			props[key] = value
+		}
+	}
+	set("fo:color", s.Color)
+	set("svg:fill", s.FillColor)
+	set("border-line-color", s.BorderColor)
+	set("fo:font-family", s.FontFamily)
+	if s.FontSize > 0 {
+		props["fo:font-size"] = strconv.Itoa(s.FontSize) + "pt"
+	}
+	if s.Bold {
+		props["fo:font-weight"] = "bold"
+	}
+	if s.Italic {
+		props["fo:font-style"] = "italic"
+	}
+	return props
+}
+
+func xmindStyleOf(props map[string]string) nodeStyle {
+	size, _ := strconv.Atoi(strings.TrimSuffix(props["fo:font-size"], "pt"))
+	return nodeStyle{
+		Color:       props["fo:color"],
+		FillColor:   props["svg:fill"],
+		BorderColor: props["border-line-color"],
+		FontFamily:  props["fo:font-family"],
+		FontSize:    size,
+		Bold:        props["fo:font-weight"] == "bold" || props["fo:font-weight"] == "700",
+		Italic:      props["fo:font-style"] == "italic",
+	}
+}
+
+var errNoEntry = errors.New("no such entry")
+
+// readZipEntry читает файл архива, ограничивая распакованный размер
+func readZipEntry(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, err)
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(io.LimitReader(rc, maxArchiveEntry+1))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		if len(data) > maxArchiveEntry {
+			return nil, fmt.Errorf("%s is larger than %d bytes", name, maxArchiveEntry)
+		}
+		return data, nil
+	}
+	return nil, errNoEntry
+}
@@ -0,0 +1,139 @@
+package convert
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// styledDoc - карта со стилями, иконками, тегами и свернутой веткой
+func styledDoc(t *testing.T) *mindmap.Document {
+	return parseDoc(t, `{"root":{"data":{"text":"Релиз","note":"строка 1\nстрока 2","color":"#ff0000","fontSize":24,"fontWeight":"bold"},"children":[
+		{"data":{"text":"Бэкенд","hyperlink":"https://example.com","icon":["priority_1","progress_8"],"fillColor":"#eeeeee","fontStyle":"italic","expand":false},"children":[
+			{"data":{"text":"API","tag":["срочно"],"borderColor":"#0000ff","fontFamily":"Arial"},"children":[]}
+		]},
+		{"data":{"text":"Фронтенд","icon":["expression_1"]},"children":[]}
+	]}}`)
+}
+
+// nodeSummary - данные узлов, которые должны пережить конвертацию
+type nodeSummary struct {
+	Text      string
+	Note      string
+	Link      string
+	Icons     []string
+	Tags      []string
+	Style     nodeStyle
+	Collapsed bool
+	Children  []nodeSummary
+}
+
+func comparableOf(n *mindmap.Node) nodeSummary {
+	c := nodeSummary{
+		Text:      n.Data.Text,
+		Note:      n.Data.Note,
+		Link:      n.Data.Hyperlink,
+		Icons:     n.Data.Icon,
+		Tags:      n.Data.TagTexts(),
+		Style:     styleOf(n.Data),
+		Collapsed: collapsed(n.Data),
+	}
+	if len(c.Tags) == 0 {
+		c.Tags = nil
+	}
+	for _, child := range n.Children {
+		c.Children = append(c.Children, comparableOf(child))
+	}
+	return c
+}
+
+func TestXMindRoundTrip(t *testing.T) {
+	doc := styledDoc(t)
+
+	result := roundTrip(t, "xmind", doc)
+
+	expected := comparableOf(doc.Root)
+	// У иконки expression_1 нет маркера XMind
+	expected.Children[1].Icons = nil
+	assert.Equal(t, expected, comparableOf(result.Root))
+}
+
+func TestXMindExportArchive(t *testing.T) {
+	data, err := exportXMind(styledDoc(t))
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	content, err := readZipEntry(zr, "content.json")
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"markerId":"priority-1"`)
+	assert.Contains(t, string(content), `"markerId":"task-done"`)
+	assert.Contains(t, string(content), `"fo:font-size":"24pt"`)
+	_, err = readZipEntry(zr, "manifest.json")
+	assert.NoError(t, err)
+}
+
+func TestXMindImportLegacy(t *testing.T) {
+	data := zipArchive(t, map[string]string{"content.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<xmap-content xmlns="urn:xmind:xmap:xmlns:content:2.0" xmlns:xlink="http://www.w3.org/1999/xlink" version="2.0">
+  <sheet id="s1"><title>Лист</title>
+    <topic id="t1"><title>Корень</title>
+      <notes><plain>заметка</plain></notes>
+      <children>
+        <topics type="attached">
+          <topic id="t2" xlink:href="https://example.com" branch="folded"><title>Ссылка</title>
+            <marker-refs><marker-ref marker-id="priority-2"/><marker-ref marker-id="flag-red"/></marker-refs>
+            <labels><label>метка</label></labels>
+          </topic>
+        </topics>
+        <topics type="detached"><topic id="t3"><title>Плавающая</title></topic></topics>
+      </children>
+    </topic>
+  </sheet>
+</xmap-content>`})
+
+	doc, err := importXMind(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, nodeSummary{
+		Text: "Корень",
+		Note: "заметка",
+		Children: []nodeSummary{{
+			Text:      "Ссылка",
+			Link:      "https://example.com",
+			Icons:     []string{"priority_2"},
+			Tags:      []string{"метка"},
+			Collapsed: true,
+		}},
+	}, comparableOf(doc.Root))
+}
+
+func TestXMindImportInvalid(t *testing.T) {
+	_, err := importXMind([]byte("not a zip"))
+	assert.Error(t, err)
+
+	_, err = importXMind(zipArchive(t, map[string]string{"other.txt": "x"}))
+	assert.Error(t, err)
+
+	_, err = importXMind(zipArchive(t, map[string]string{"content.json": "[]"}))
+	assert.ErrorIs(t, err, ErrEmpty)
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
@@ -0,0 +1,77 @@
+// Package dbtest - база PostgreSQL для тестов репозиториев и обработчиков
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+// New подключается к базе из TEST_DATABASE_URL и накатывает миграции в
+// отдельную схему, которая удаляется после теста. Без TEST_DATABASE_URL тест
+// пропускается.
+func New(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+
+	admin, err := pgx.Connect(ctx, url)
+	require.NoError(t, err)
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	_, err = admin.Exec(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		_ = admin.Close(ctx)
+	})
+
+	cfg, err := pgxpool.ParseConfig(url)
+	require.NoError(t, err)
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	files, err := filepath.Glob(filepath.Join(migrationsDir(), "*.up.sql"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files, "migrations not found")
+	sort.Strings(files)
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		require.NoError(t, err)
+		_, err = pool.Exec(ctx, string(sql))
+		require.NoError(t, err, file)
+	}
+	return pool
+}
+
+// CreateUser добавляет пользователя и возвращает его id
+func CreateUser(t *testing.T, db *pgxpool.Pool, name string) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(context.Background(),
+		`INSERT INTO users (name, email, password) VALUES ($1, $2, 'x') RETURNING id`,
+		name, name+"@example.com",
+	).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+// migrationsDir - каталог миграций относительно этого файла, чтобы тесты
+// находили его из любого пакета
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "repository", "migrations")
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mymindmap/api/internal/attachments"
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// AttachmentHandler - скачивание файлов узлов. Загрузка - через
+// /api/mindmaps/{id}/attachments и при сохранении карты.
+type AttachmentHandler struct {
+	attachRepo  *repository.AttachmentRepository
+	attachments *attachments.Service
+	authService *auth.AuthService
+	logger      *log.Logger
+}
+
+func NewAttachmentHandler(attachRepo *repository.AttachmentRepository, attachmentService *attachments.Service, authService *auth.AuthService, logger *log.Logger) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachRepo:  attachRepo,
+		attachments: attachmentService,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+func (h *AttachmentHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(attachments.URLPrefix, middleware.AuthMiddleware(h.authService, h.handleAttachment)) // GET by hash, без авторизации - файлы публичных карт
+}
+
+// handleAttachment -> /api/attachments/{hash}
+func (h *AttachmentHandler) handleAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.GetAttachment(w, r, strings.Trim(strings.TrimPrefix(r.URL.Path, attachments.URLPrefix), "/"))
+}
+
+// GetAttachment - содержимое файла. Доступно участникам карт, в которые файл
+// загружен или на которые ссылается, всем - для публичных карт. Без доступа -
+// 404, чтобы не раскрывать, что файл существует. Открывшим карту по ссылке
+// доступа файлы отдает /api/shared/{token}/attachments/{hash}.
+func (h *AttachmentHandler) GetAttachment(w http.ResponseWriter, r *http.Request, hash string) {
+	if !attachments.ValidHash(hash) {
+		h.respondError(w, http.StatusNotFound, attachments.ErrNotFound.Error())
+		return
+	}
+
+	attachment, err := h.attachRepo.Get(r.Context(), hash)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if attachment == nil {
+		h.respondError(w, http.StatusNotFound, attachments.ErrNotFound.Error())
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil || user.Role != "admin" {
+		userID := 0
+		if user != nil {
+			userID = user.UserID
+		}
+		allowed, err := h.attachRepo.CanView(r.Context(), hash, userID)
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !allowed {
+			h.respondError(w, http.StatusNotFound, attachments.ErrNotFound.Error())
+			return
+		}
+	}
+
+	writeAttachment(w, r, h.attachments, attachment, h.logger, h.respondError)
+}
+
+// writeAttachment отдает содержимое файла, доступ к которому уже проверен
+func writeAttachment(w http.ResponseWriter, r *http.Request, service *attachments.Service, attachment *models.Attachment, logger *log.Logger, respondError func(w http.ResponseWriter, status int, msg string)) {
+	// Содержимое по хэшу не меняется, но доступ к нему может пропасть:
+	// кэшировать можно только в браузере
+	etag := `"` + attachment.Hash + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, err := service.Open(r.Context(), attachment.Hash)
+	if errors.Is(err, attachments.ErrNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer body.Close()
+
+	// Загруженный файл может оказаться HTML или SVG со скриптом: браузер не
+	// должен выполнять его в контексте приложения
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		logger.Printf("attachment %s: write error: %v", attachment.Hash, err)
+	}
+}
+
+func (h *AttachmentHandler) respondJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Printf("json encode error: %v", err)
+	}
+}
+
+func (h *AttachmentHandler) respondError(w http.ResponseWriter, status int, msg string) {
+	h.respondJSON(w, status, map[string]string{"error": msg})
+}
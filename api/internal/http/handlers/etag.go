@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag формирует ETag по версии записи
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag добавляет ETag текущей версии в ответ
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// ifMatch проверяет заголовок If-Match. Запрос без заголовка проходит проверку.
+func ifMatch(r *http.Request, version int) bool {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return true
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// Слабые ETag не подходят для If-Match (RFC 9110, 13.1.1)
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified проверяет If-None-Match и при совпадении отвечает 304
+func notModified(w http.ResponseWriter, r *http.Request, version int) bool {
+	header := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if header == "" {
+		return false
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			setETag(w, version)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/collab"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+const maxFolderNameLen = 255 // Символов в названии папки
+
+// FolderHandler - папки пользователя для карт
+type FolderHandler struct {
+	folderRepo  *repository.FolderRepository
+	collab      *collab.Hub
+	authService *auth.AuthService
+	logger      *log.Logger
+}
+
+func NewFolderHandler(folderRepo *repository.FolderRepository, collabHub *collab.Hub, authService *auth.AuthService, logger *log.Logger) *FolderHandler {
+	return &FolderHandler{
+		folderRepo:  folderRepo,
+		collab:      collabHub,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+func (h *FolderHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/folders", middleware.AuthMiddleware(h.authService, h.handleFolders))       // GET tree, POST create
+	mux.HandleFunc("/api/folders/", middleware.AuthMiddleware(h.authService, h.handleSingleFolder)) // PUT, DELETE by id, POST move
+}
+
+// handleFolders -> /api/folders
+func (h *FolderHandler) handleFolders(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetFolderTree(w, r)
+	case http.MethodPost:
+		h.CreateFolder(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSingleFolder -> /api/folders/{id}, /api/folders/{id}/move
+func (h *FolderHandler) handleSingleFolder(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/folders/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid folder id")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodPut:
+			h.RenameFolder(w, r, id)
+		case http.MethodDelete:
+			h.DeleteFolder(w, r, id)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(parts) == 2 && parts[1] == "move":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.MoveFolder(w, r, id)
+	default:
+		h.respondError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// GetFolderTree - дерево папок пользователя с числом карт в каждой папке
+// и числом своих карт вне папок
+func (h *FolderHandler) GetFolderTree(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	tree, err := h.folderRepo.Tree(r.Context(), user.UserID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, tree)
+}
+
+// CreateFolder - новая папка в корне или в parent_id
+func (h *FolderHandler) CreateFolder(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     string `json:"name"`
+		ParentID *int   `json:"parent_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	name, ok := h.folderName(w, req.Name)
+	if !ok {
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	folder := &models.Folder{UserID: user.UserID, ParentID: req.ParentID, Name: name}
+	if err := h.folderRepo.Create(r.Context(), folder); err != nil {
+		h.respondFolderError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, folder)
+}
+
+// RenameFolder - меняет название папки
+func (h *FolderHandler) RenameFolder(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	name, ok := h.folderName(w, req.Name)
+	if !ok {
+		return
+	}
+
+	folder, ok := h.loadFolder(w, r, id)
+	if !ok {
+		return
+	}
+
+	folder.Name = name
+	if err := h.folderRepo.Rename(r.Context(), folder); err != nil {
+		h.respondFolderError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, folder)
+}
+
+// MoveFolder - переносит папку в parent_id, null - в корень
+func (h *FolderHandler) MoveFolder(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		ParentID *int `json:"parent_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	folder, ok := h.loadFolder(w, r, id)
+	if !ok {
+		return
+	}
+
+	if err := h.folderRepo.Move(r.Context(), folder, req.ParentID); err != nil {
+		h.respondFolderError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, folder)
+}
+
+// DeleteFolder - удаляет папку. По умолчанию только пустую: для папки с картами
+// или вложенными папками - 409. С ?contents=trash удаляет папку со вложенными,
+// а их карты переносит в корзину.
+func (h *FolderHandler) DeleteFolder(w http.ResponseWriter, r *http.Request, id int) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.URL.Query().Get("contents") {
+	case "":
+		if err := h.folderRepo.Delete(r.Context(), id, user.UserID); err != nil {
+			h.respondFolderError(w, err)
+			return
+		}
+		h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+	case "trash":
+		trashed, err := h.folderRepo.DeleteWithContents(r.Context(), id, user.UserID)
+		if err != nil {
+			h.respondFolderError(w, err)
+			return
+		}
+		for _, mindMapID := range trashed {
+			h.collab.Close(mindMapID)
+		}
+		h.respondJSON(w, http.StatusOK, map[string]any{"success": true, "trashed_mindmaps": len(trashed)})
+	default:
+		h.respondError(w, http.StatusBadRequest, "contents must be trash")
+	}
+}
+
+// --- Helpers ---
+
+// loadFolder загружает папку пользователя. При ошибке сам пишет ответ и возвращает false.
+func (h *FolderHandler) loadFolder(w http.ResponseWriter, r *http.Request, id int) (*models.Folder, bool) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+
+	folder, err := h.folderRepo.Get(r.Context(), id, user.UserID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if folder == nil {
+		h.respondError(w, http.StatusNotFound, repository.ErrFolderNotFound.Error())
+		return nil, false
+	}
+	return folder, true
+}
+
+// folderName проверяет название папки. При ошибке сам пишет ответ и возвращает false.
+func (h *FolderHandler) folderName(w http.ResponseWriter, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		h.respondError(w, http.StatusBadRequest, "name required")
+		return "", false
+	}
+	if utf8.RuneCountInString(name) > maxFolderNameLen {
+		h.respondError(w, http.StatusBadRequest, "name is too long")
+		return "", false
+	}
+	return name, true
+}
+
+func (h *FolderHandler) respondFolderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrFolderNotFound):
+		h.respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, repository.ErrFolderNotEmpty), errors.Is(err, repository.ErrFolderCycle):
+		h.respondError(w, http.StatusConflict, err.Error())
+	default:
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (h *FolderHandler) respondJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Printf("json encode error: %v", err)
+	}
+}
+
+func (h *FolderHandler) respondError(w http.ResponseWriter, status int, msg string) {
+	h.respondJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/mymindmap/api/internal/attachments"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+// maxUploadBody - тело запроса загрузки: файл и служебные части multipart
+const maxUploadBody = attachments.MaxSize + 64<<10
+
+// handleMindMapAttachments -> /api/mindmaps/{id}/attachments
+func (h *MindMapHandler) handleMindMapAttachments(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.UploadAttachment(w, r, id)
+}
+
+// UploadAttachment - загрузка файла узла (multipart, поле file). Возвращает
+// метаданные с url, которым файл подставляется в image или hyperlink узла.
+// Одинаковые файлы хранятся один раз.
+func (h *MindMapHandler) UploadAttachment(w http.ResponseWriter, r *http.Request, id int) {
+	_, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleEditor)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBody)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondError(w, http.StatusRequestEntityTooLarge, attachments.ErrTooLarge.Error())
+			return
+		}
+		h.respondError(w, http.StatusBadRequest, "file required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, attachments.MaxSize+1))
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid file")
+		return
+	}
+
+	attachment, err := h.attachments.Save(r.Context(), uploadContentType(header.Header.Get("Content-Type"), data), data, &user.UserID)
+	if err != nil {
+		h.respondAttachmentError(w, err)
+		return
+	}
+	if err := h.attachRepo.Link(r.Context(), id, []string{attachment.Hash}); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, attachment)
+}
+
+// extractAttachments выносит картинки узлов из data URL в файлы.
+// При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) extractAttachments(w http.ResponseWriter, r *http.Request, doc *mindmap.Document) bool {
+	if _, err := attachments.Extract(doc, h.attachmentSaver(r)); err != nil {
+		h.respondAttachmentError(w, err)
+		return false
+	}
+	return true
+}
+
+// extractOpAttachments выносит в файлы картинки из data URL во вставляемых
+// узлах и в set image, чтобы в журнал попали операции с теми же ссылками, что
+// и в сохраненном документе. Возвращает количество замененных картинок.
+// При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) extractOpAttachments(w http.ResponseWriter, r *http.Request, ops []mindmap.Op) (int, bool) {
+	replaced, err := attachments.ExtractOps(ops, h.attachmentSaver(r))
+	if err != nil {
+		h.respondAttachmentError(w, err)
+		return 0, false
+	}
+	return replaced, true
+}
+
+// attachmentSaver сохраняет файлы от имени пользователя запроса
+func (h *MindMapHandler) attachmentSaver(r *http.Request) func(contentType string, data []byte) (string, error) {
+	var userID *int
+	if user := middleware.GetUserFromContext(r.Context()); user != nil {
+		userID = &user.UserID
+	}
+	return func(contentType string, data []byte) (string, error) {
+		attachment, err := h.attachments.Save(r.Context(), contentType, data, userID)
+		if err != nil {
+			return "", err
+		}
+		return attachment.URL, nil
+	}
+}
+
+func (h *MindMapHandler) respondAttachmentError(w http.ResponseWriter, err error) {
+	if errors.Is(err, attachments.ErrTooLarge) {
+		h.respondError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	h.respondError(w, http.StatusInternalServerError, err.Error())
+}
+
+// uploadContentType - тип файла из заголовка части multipart без параметров,
+// а если его нет - определенный по содержимому
+func uploadContentType(header string, data []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(header); err == nil && mediaType != "application/octet-stream" {
+		return mediaType
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	return mediaType
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/comments"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// Ограничения комментариев
+const (
+	maxCommentLen      = 10000 // Символов в сообщении
+	maxCommentNodeText = 200   // Символов текста узла, сохраняемых в обсуждении
+)
+
+// handleComments -> /api/mindmaps/{id}/comments, /api/mindmaps/{id}/comments/{threadID}[/resolve|/{commentID}]
+func (h *MindMapHandler) handleComments(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetCommentThreads(w, r, id)
+		case http.MethodPost:
+			h.CreateCommentThread(w, r, id)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	threadID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid thread id")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			h.GetCommentThread(w, r, id, threadID)
+		case http.MethodPost:
+			h.ReplyToCommentThread(w, r, id, threadID)
+		case http.MethodDelete:
+			h.DeleteCommentThread(w, r, id, threadID)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(parts) == 2 && parts[1] == "resolve":
+		switch r.Method {
+		case http.MethodPut:
+			h.ResolveCommentThread(w, r, id, threadID, true)
+		case http.MethodDelete:
+			h.ResolveCommentThread(w, r, id, threadID, false)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(parts) == 2:
+		commentID, err := strconv.Atoi(parts[1])
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid comment id")
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateComment(w, r, id, threadID, commentID)
+		case http.MethodDelete:
+			h.DeleteComment(w, r, id, threadID, commentID)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	default:
+		h.respondError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// GetCommentThreads - обсуждения карты. ?node= - только обсуждения узла,
+// ?status=open|resolved|orphaned|all (по умолчанию all). Обсуждения удаленных
+// узлов возвращаются с orphaned_at.
+func (h *MindMapHandler) GetCommentThreads(w http.ResponseWriter, r *http.Request, id int) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	switch status {
+	case "":
+		status = models.CommentStatusAll
+	case models.CommentStatusOpen, models.CommentStatusResolved, models.CommentStatusOrphaned, models.CommentStatusAll:
+	default:
+		h.respondError(w, http.StatusBadRequest, "status must be open, resolved, orphaned or all")
+		return
+	}
+
+	if _, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer); !ok {
+		return
+	}
+
+	threads, err := h.commentRepo.ListThreads(r.Context(), id, query.Get("node"), status)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, threads)
+}
+
+// GetCommentThread - одно обсуждение со всеми сообщениями
+func (h *MindMapHandler) GetCommentThread(w http.ResponseWriter, r *http.Request, id, threadID int) {
+	if _, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer); !ok {
+		return
+	}
+	thread, ok := h.loadCommentThread(w, r, id, threadID)
+	if !ok {
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, thread)
+}
+
+// CreateCommentThread - новое обсуждение узла. Комментировать могут участники
+// с ролью не ниже комментатора.
+func (h *MindMapHandler) CreateCommentThread(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		NodeUID string `json:"node_uid"`
+		Body    string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	body, ok := h.commentBody(w, req.Body)
+	if !ok {
+		return
+	}
+	if req.NodeUID == "" {
+		h.respondError(w, http.StatusBadRequest, "node_uid required")
+		return
+	}
+
+	m, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleCommenter)
+	if !ok {
+		return
+	}
+
+	doc, err := mindmap.ParseString(m.Data, mindmap.Limits{})
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	node, _ := doc.Find(req.NodeUID)
+	if node == nil {
+		h.respondError(w, http.StatusNotFound, "node not found")
+		return
+	}
+
+	thread := &models.CommentThread{
+		MindMapID: id,
+		NodeUID:   req.NodeUID,
+		NodeText:  truncateRunes(node.Data.PlainText(), maxCommentNodeText),
+		CreatedBy: &user.UserID,
+	}
+	comment := &models.Comment{UserID: &user.UserID, Body: body}
+	if err := h.commentRepo.CreateThread(r.Context(), thread, comment, comments.Mentions(body)); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, thread)
+}
+
+// ReplyToCommentThread - ответ в обсуждении. Отвечать можно и в решенном
+// обсуждении, и в обсуждении удаленного узла.
+func (h *MindMapHandler) ReplyToCommentThread(w http.ResponseWriter, r *http.Request, id, threadID int) {
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	body, ok := h.commentBody(w, req.Body)
+	if !ok {
+		return
+	}
+
+	_, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleCommenter)
+	if !ok {
+		return
+	}
+
+	comment := &models.Comment{ThreadID: threadID, UserID: &user.UserID, Body: body}
+	if err := h.commentRepo.AddComment(r.Context(), id, comment, comments.Mentions(body)); err != nil {
+		h.respondCommentError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, comment)
+}
+
+// UpdateComment - правка сообщения. Править может только автор, пока у него
+// есть право комментировать.
+func (h *MindMapHandler) UpdateComment(w http.ResponseWriter, r *http.Request, id, threadID, commentID int) {
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	body, ok := h.commentBody(w, req.Body)
+	if !ok {
+		return
+	}
+
+	_, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleCommenter)
+	if !ok {
+		return
+	}
+	if _, ok := h.loadCommentThread(w, r, id, threadID); !ok {
+		return
+	}
+	comment, ok := h.loadComment(w, r, threadID, commentID)
+	if !ok {
+		return
+	}
+	if !isCommentAuthor(comment.UserID, user) {
+		h.respondError(w, http.StatusForbidden, "only the author can edit a comment")
+		return
+	}
+
+	comment.Body = body
+	if err := h.commentRepo.UpdateComment(r.Context(), id, comment, comments.Mentions(body)); err != nil {
+		h.respondCommentError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, comment)
+}
+
+// DeleteComment - удаление сообщения автором или владельцем карты. Обсуждение
+// без сообщений удаляется.
+func (h *MindMapHandler) DeleteComment(w http.ResponseWriter, r *http.Request, id, threadID, commentID int) {
+	m, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+	if _, ok := h.loadCommentThread(w, r, id, threadID); !ok {
+		return
+	}
+	comment, ok := h.loadComment(w, r, threadID, commentID)
+	if !ok {
+		return
+	}
+	if !isCommentAuthor(comment.UserID, user) && m.Role != models.MindMapRoleOwner {
+		h.respondError(w, http.StatusForbidden, "only the author or the owner can delete a comment")
+		return
+	}
+
+	threadDeleted, err := h.commentRepo.DeleteComment(r.Context(), threadID, commentID)
+	if err != nil {
+		h.respondCommentError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true, "thread_deleted": threadDeleted})
+}
+
+// DeleteCommentThread - удаление обсуждения целиком его автором или владельцем карты
+func (h *MindMapHandler) DeleteCommentThread(w http.ResponseWriter, r *http.Request, id, threadID int) {
+	m, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+	thread, ok := h.loadCommentThread(w, r, id, threadID)
+	if !ok {
+		return
+	}
+	if !isCommentAuthor(thread.CreatedBy, user) && m.Role != models.MindMapRoleOwner {
+		h.respondError(w, http.StatusForbidden, "only the author or the owner can delete a thread")
+		return
+	}
+
+	if err := h.commentRepo.DeleteThread(r.Context(), id, threadID); err != nil {
+		h.respondCommentError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// ResolveCommentThread - помечает обсуждение решенным или открывает снова.
+// Может автор обсуждения и редакторы карты.
+func (h *MindMapHandler) ResolveCommentThread(w http.ResponseWriter, r *http.Request, id, threadID int, resolved bool) {
+	m, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+	thread, ok := h.loadCommentThread(w, r, id, threadID)
+	if !ok {
+		return
+	}
+	if !isCommentAuthor(thread.CreatedBy, user) && !models.MindMapRoleAllows(m.Role, models.MindMapRoleEditor) {
+		h.respondError(w, http.StatusForbidden, "only the author or an editor can resolve a thread")
+		return
+	}
+
+	var err error
+	if resolved {
+		err = h.commentRepo.Resolve(r.Context(), thread, user.UserID)
+	} else {
+		err = h.commentRepo.Reopen(r.Context(), thread)
+	}
+	if err != nil {
+		h.respondCommentError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, thread)
+}
+
+// --- Helpers ---
+
+// loadCommentThread загружает обсуждение карты. При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) loadCommentThread(w http.ResponseWriter, r *http.Request, id, threadID int) (*models.CommentThread, bool) {
+	thread, err := h.commentRepo.GetThread(r.Context(), id, threadID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if thread == nil {
+		h.respondError(w, http.StatusNotFound, repository.ErrCommentThreadNotFound.Error())
+		return nil, false
+	}
+	return thread, true
+}
+
+// loadComment загружает сообщение обсуждения. При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) loadComment(w http.ResponseWriter, r *http.Request, threadID, commentID int) (*models.Comment, bool) {
+	comment, err := h.commentRepo.GetComment(r.Context(), threadID, commentID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if comment == nil {
+		h.respondError(w, http.StatusNotFound, repository.ErrCommentNotFound.Error())
+		return nil, false
+	}
+	return comment, true
+}
+
+// commentBody проверяет текст сообщения. При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) commentBody(w http.ResponseWriter, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		h.respondError(w, http.StatusBadRequest, "body required")
+		return "", false
+	}
+	if utf8.RuneCountInString(body) > maxCommentLen {
+		h.respondError(w, http.StatusBadRequest, "body is too long")
+		return "", false
+	}
+	return body, true
+}
+
+func (h *MindMapHandler) respondCommentError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrCommentThreadNotFound) || errors.Is(err, repository.ErrCommentNotFound) {
+		h.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.respondError(w, http.StatusInternalServerError, err.Error())
+}
+
+// isCommentAuthor - сообщение или обсуждение создано пользователем
+func isCommentAuthor(authorID *int, user *auth.Claims) bool {
+	return authorID != nil && *authorID == user.UserID
+}
+
+// truncateRunes обрезает строку до n символов
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
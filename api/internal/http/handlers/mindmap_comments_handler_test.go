@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/dbtest"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+func TestComments_RequireCommenterRole(t *testing.T) {
+	h, db := newTestMindMapHandler(t)
+	ctx := context.Background()
+	members := repository.NewMindMapMemberRepository(db)
+	owner := dbtest.CreateUser(t, db, "owner")
+
+	m := &models.MindMap{Title: "Plan", Data: mergeDoc("A", "B"), UserID: owner}
+	require.NoError(t, h.mindMapRepo.CreateMindMap(ctx, m))
+
+	w := httptest.NewRecorder()
+	h.CreateCommentThread(w, userRequest(http.MethodPost, fmt.Sprintf("/api/mindmaps/%d/comments", m.ID),
+		map[string]any{"node_uid": "a", "body": "Start"}, owner), m.ID)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var thread models.CommentThread
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &thread))
+
+	tests := []struct {
+		role string
+		want int
+	}{
+		{models.MindMapRoleViewer, http.StatusForbidden},
+		{models.MindMapRoleCommenter, http.StatusCreated},
+		{models.MindMapRoleEditor, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			user := dbtest.CreateUser(t, db, tt.role)
+			require.NoError(t, members.Add(ctx, &models.MindMapMember{MindMapID: m.ID, UserID: user, Role: tt.role}))
+
+			w := httptest.NewRecorder()
+			h.CreateCommentThread(w, userRequest(http.MethodPost, fmt.Sprintf("/api/mindmaps/%d/comments", m.ID),
+				map[string]any{"node_uid": "b", "body": "New"}, user), m.ID)
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+
+			w = httptest.NewRecorder()
+			h.ReplyToCommentThread(w, userRequest(http.MethodPost, fmt.Sprintf("/api/mindmaps/%d/comments/%d", m.ID, thread.ID),
+				map[string]any{"body": "Reply"}, user), m.ID, thread.ID)
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestUpdateComment_AuthorLosesCommenterRole(t *testing.T) {
+	h, db := newTestMindMapHandler(t)
+	ctx := context.Background()
+	members := repository.NewMindMapMemberRepository(db)
+	owner := dbtest.CreateUser(t, db, "owner")
+	bob := dbtest.CreateUser(t, db, "bob")
+
+	m := &models.MindMap{Title: "Plan", Data: mergeDoc("A", "B"), UserID: owner}
+	require.NoError(t, h.mindMapRepo.CreateMindMap(ctx, m))
+	require.NoError(t, members.Add(ctx, &models.MindMapMember{MindMapID: m.ID, UserID: bob, Role: models.MindMapRoleCommenter}))
+
+	w := httptest.NewRecorder()
+	h.CreateCommentThread(w, userRequest(http.MethodPost, fmt.Sprintf("/api/mindmaps/%d/comments", m.ID),
+		map[string]any{"node_uid": "a", "body": "Mine"}, bob), m.ID)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var thread models.CommentThread
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &thread))
+	require.Len(t, thread.Comments, 1)
+	commentID := thread.Comments[0].ID
+
+	require.NoError(t, members.UpdateRole(ctx, m.ID, bob, models.MindMapRoleViewer))
+	w = httptest.NewRecorder()
+	h.UpdateComment(w, userRequest(http.MethodPut, fmt.Sprintf("/api/mindmaps/%d/comments/%d/%d", m.ID, thread.ID, commentID),
+		map[string]any{"body": "Edited"}, bob), m.ID, thread.ID, commentID)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+}
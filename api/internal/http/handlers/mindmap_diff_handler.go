@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/mymindmap/api/internal/mapdiff"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+// handleDiff -> /api/mindmaps/{id}/diff?from=&to=
+func (h *MindMapHandler) handleDiff(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.GetDiff(w, r, id)
+}
+
+// GetDiff - изменения узлов между версиями карты from и to (по умолчанию - текущей)
+func (h *MindMapHandler) GetDiff(w http.ResponseWriter, r *http.Request, id int) {
+	mindmap, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := strconv.Atoi(query.Get("from"))
+	if err != nil || from < 1 || from > mindmap.Version {
+		h.respondError(w, http.StatusBadRequest, "invalid from")
+		return
+	}
+	to := mindmap.Version
+	if v := query.Get("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil || to < 1 || to > mindmap.Version {
+			h.respondError(w, http.StatusBadRequest, "invalid to")
+			return
+		}
+	}
+
+	fromTitle, fromDoc, ok := h.loadVersion(w, r, mindmap, from)
+	if !ok {
+		return
+	}
+	toTitle, toDoc, ok := h.loadVersion(w, r, mindmap, to)
+	if !ok {
+		return
+	}
+
+	diff := mapdiff.Compute(fromDoc, toDoc)
+	resp := map[string]any{
+		"from":           from,
+		"to":             to,
+		"changes":        diff.Changes,
+		"layout_changed": diff.LayoutChanged,
+		"theme_changed":  diff.ThemeChanged,
+	}
+	if fromTitle != toTitle {
+		resp["title"] = map[string]string{"old": fromTitle, "new": toTitle}
+	}
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+// loadVersion возвращает название и документ карты в версии version: текущую
+// берет из самой карты, старые - из ревизий. При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) loadVersion(w http.ResponseWriter, r *http.Request, m *models.MindMap, version int) (string, *mindmap.Document, bool) {
+	title, data := m.Title, m.Data
+	if version != m.Version {
+		revision, err := h.revisionRepo.Get(r.Context(), m.ID, version)
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return "", nil, false
+		}
+		if revision == nil {
+			h.respondError(w, http.StatusNotFound, "revision not found")
+			return "", nil, false
+		}
+		title, data = revision.Title, revision.Data
+	}
+
+	doc, err := mindmap.ParseString(data, mindmap.Limits{})
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return "", nil, false
+	}
+	return title, doc, true
+}
+
+// mergeWithBase сливает название и документ, которые клиент редактировал начиная
+// с версии base, с текущей версией карты. ours в конфликтах - сохраненная версия,
+// theirs - присланная клиентом. При конфликте отвечает 409 со списком конфликтов
+// и текущей версией; при ошибке тоже сам пишет ответ и возвращает false.
+func (h *MindMapHandler) mergeWithBase(w http.ResponseWriter, r *http.Request, current *models.MindMap, base int, title, data string) (string, string, bool) {
+	if base < 1 || base > current.Version {
+		h.respondError(w, http.StatusBadRequest, "invalid base_version")
+		return "", "", false
+	}
+
+	revision, err := h.revisionRepo.Get(r.Context(), current.ID, base)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return "", "", false
+	}
+	if revision == nil {
+		setETag(w, current.Version)
+		h.respondJSON(w, http.StatusConflict, map[string]any{
+			"error":   "base version is no longer available",
+			"version": current.Version,
+		})
+		return "", "", false
+	}
+
+	var docs [3]*mindmap.Document
+	for i, s := range []string{revision.Data, current.Data, data} {
+		if docs[i], err = mindmap.ParseString(s, mindmap.Limits{}); err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return "", "", false
+		}
+	}
+
+	merged, conflicts, err := mapdiff.Merge(docs[0], docs[1], docs[2])
+	if err != nil {
+		h.respondMergeConflict(w, current.Version, err.Error(), []mapdiff.Conflict{})
+		return "", "", false
+	}
+
+	switch {
+	case current.Title == revision.Title:
+	case title == revision.Title, title == current.Title:
+		title = current.Title
+	default:
+		ours, _ := json.Marshal(current.Title)
+		theirs, _ := json.Marshal(title)
+		conflicts = append(conflicts, mapdiff.Conflict{Type: mapdiff.ConflictDocument, Field: "title", Ours: ours, Theirs: theirs})
+	}
+	if len(conflicts) > 0 {
+		h.respondMergeConflict(w, current.Version, "merge conflict", conflicts)
+		return "", "", false
+	}
+
+	encoded, err := merged.Encode()
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return "", "", false
+	}
+	// Результат слияния проверяется по тем же ограничениям, что и присланный документ
+	if data, ok := h.normalizeData(w, r, encoded); ok {
+		return title, data, true
+	}
+	return "", "", false
+}
+
+// respondMergeConflict - ответ 409 с конфликтами слияния и текущей версией карты
+func (h *MindMapHandler) respondMergeConflict(w http.ResponseWriter, version int, msg string, conflicts []mapdiff.Conflict) {
+	setETag(w, version)
+	h.respondJSON(w, http.StatusConflict, map[string]any{
+		"error":     msg,
+		"version":   version,
+		"conflicts": conflicts,
+	})
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/dbtest"
+	"github.com/mymindmap/api/internal/mapdiff"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+func mergeDoc(a, b string) string {
+	return fmt.Sprintf(`{"root":{"data":{"text":"Root","uid":"r"},"children":[`+
+		`{"data":{"text":%q,"uid":"a"},"children":[]},`+
+		`{"data":{"text":%q,"uid":"b"},"children":[]}]}}`, a, b)
+}
+
+func TestUpdateMindMap_MergeOnSave(t *testing.T) {
+	h, db := newTestMindMapHandler(t)
+	ctx := context.Background()
+	alice := dbtest.CreateUser(t, db, "alice")
+
+	tests := []struct {
+		name        string
+		serverTitle string // Название после правки на сервере (версия 2)
+		title, a, b string // Присланные клиентом название и тексты узлов
+		baseVersion int    // 0 - без base_version
+		pruneBase   bool   // Ревизия base удалена политикой хранения
+		status      int
+		wantTitle   string
+		wantA       string
+		wantB       string
+		conflicts   []string // Поля конфликтов в ответе 409
+	}{
+		{
+			name:  "stale If-Match without base_version",
+			title: "Plan", a: "A", b: "B client",
+			status: http.StatusPreconditionFailed,
+		},
+		{
+			name:  "base_version skips If-Match and merges",
+			title: "Plan", a: "A", b: "B client", baseVersion: 1,
+			status: http.StatusOK, wantTitle: "Plan", wantA: "A server", wantB: "B client",
+		},
+		{
+			name:  "conflicting node edits",
+			title: "Plan", a: "A client", b: "B", baseVersion: 1,
+			status: http.StatusConflict, conflicts: []string{"text"},
+		},
+		{
+			name:        "title changed on the server only",
+			serverTitle: "Plan v2",
+			title:       "Plan", a: "A", b: "B client", baseVersion: 1,
+			status: http.StatusOK, wantTitle: "Plan v2", wantA: "A server", wantB: "B client",
+		},
+		{
+			name:  "title changed by the client only",
+			title: "Plan client", a: "A", b: "B", baseVersion: 1,
+			status: http.StatusOK, wantTitle: "Plan client", wantA: "A server", wantB: "B",
+		},
+		{
+			name:        "conflicting titles",
+			serverTitle: "Plan v2",
+			title:       "Plan client", a: "A", b: "B", baseVersion: 1,
+			status: http.StatusConflict, conflicts: []string{"title"},
+		},
+		{
+			name:  "base revision pruned",
+			title: "Plan", a: "A", b: "B client", baseVersion: 1, pruneBase: true,
+			status: http.StatusConflict,
+		},
+		{
+			name:  "base_version from the future",
+			title: "Plan", a: "A", b: "B client", baseVersion: 5,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &models.MindMap{Title: "Plan", Data: mergeDoc("A", "B"), UserID: alice}
+			require.NoError(t, h.mindMapRepo.CreateMindMap(ctx, m))
+			m.Data = mergeDoc("A server", "B")
+			if tt.serverTitle != "" {
+				m.Title = tt.serverTitle
+			}
+			require.NoError(t, h.mindMapRepo.Update(ctx, m, alice))
+			require.Equal(t, 2, m.Version)
+			if tt.pruneBase {
+				_, err := db.Exec(ctx, `DELETE FROM mindmap_revisions WHERE mindmap_id = $1 AND revision = 1`, m.ID)
+				require.NoError(t, err)
+			}
+
+			body := map[string]any{"title": tt.title, "data": mergeDoc(tt.a, tt.b)}
+			if tt.baseVersion != 0 {
+				body["base_version"] = tt.baseVersion
+			}
+			r := userRequest(http.MethodPut, fmt.Sprintf("/api/mindmaps/%d", m.ID), body, alice)
+			r.Header.Set("If-Match", `"1"`)
+			w := httptest.NewRecorder()
+			h.UpdateMindMap(w, r, m.ID)
+
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			switch tt.status {
+			case http.StatusOK:
+				var saved models.MindMap
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &saved))
+				assert.Equal(t, 3, saved.Version)
+				assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+				assert.Equal(t, tt.wantTitle, saved.Title)
+				doc, err := mindmap.ParseString(saved.Data, mindmap.Limits{})
+				require.NoError(t, err)
+				a, _ := doc.Find("a")
+				b, _ := doc.Find("b")
+				require.NotNil(t, a)
+				require.NotNil(t, b)
+				assert.Equal(t, tt.wantA, a.Data.Text)
+				assert.Equal(t, tt.wantB, b.Data.Text)
+			case http.StatusConflict, http.StatusPreconditionFailed:
+				var resp struct {
+					Error     string             `json:"error"`
+					Version   int                `json:"version"`
+					Conflicts []mapdiff.Conflict `json:"conflicts"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, 2, resp.Version)
+				assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+				var fields []string
+				for _, c := range resp.Conflicts {
+					fields = append(fields, c.Field)
+				}
+				assert.Equal(t, tt.conflicts, fields)
+				if tt.pruneBase {
+					assert.Equal(t, "base version is no longer available", resp.Error)
+				}
+			}
+
+			current, err := h.mindMapRepo.GetByID(ctx, m.ID)
+			require.NoError(t, err)
+			if tt.status != http.StatusOK {
+				assert.Equal(t, 2, current.Version, "rejected save must not change the map")
+			}
+		})
+	}
+}
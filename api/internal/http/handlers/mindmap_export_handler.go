@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/mymindmap/api/internal/convert"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+// defaultImportTitle - название импортированной карты, если его нет ни в запросе, ни в файле
+const defaultImportTitle = "Imported map"
+
+// Ограничения пакетного импорта
+const (
+	maxBulkImportFiles = 50
+	maxBulkImportBytes = 100 << 20
+)
+
+// importContentTypes - формат импорта по Content-Type, если ?format= не указан
+var importContentTypes = map[string]string{
+	"text/markdown":   "markdown",
+	"text/x-markdown": "markdown",
+	"text/x-opml":     "opml",
+	"text/xml":        "opml",
+	"application/xml": "opml",
+	"text/plain":      "txt",
+
+	"application/x-freemind":         "freemind",
+	"application/vnd.xmind.workbook": "xmind",
+	"application/zip":                "xmind",
+
+	"text/vnd.mermaid":  "mermaid",
+	"text/vnd.plantuml": "plantuml",
+	"text/vnd.graphviz": "dot",
+}
+
+// handleExport -> /api/mindmaps/{id}/export?format= (любой из convert.ExportFormats)
+func (h *MindMapHandler) handleExport(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.ExportMindMap(w, r, id)
+}
+
+// handleImport -> /api/mindmaps/import?format=&title=, /api/mindmaps/import/bulk
+func (h *MindMapHandler) handleImport(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) > 1 || len(parts) == 1 && parts[0] != "bulk" {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(parts) == 1 {
+		h.BulkImportMindMaps(w, r)
+		return
+	}
+	h.ImportMindMap(w, r)
+}
+
+// ExportMindMap - карта файлом в выбранном формате
+func (h *MindMapHandler) ExportMindMap(w http.ResponseWriter, r *http.Request, id int) {
+	format, err := convert.Lookup(r.URL.Query().Get("format"))
+	if err != nil || format.Export == nil {
+		h.respondError(w, http.StatusBadRequest, "format must be one of: "+strings.Join(convert.ExportFormats(), ", "))
+		return
+	}
+
+	mindmap, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+
+	doc, err := parseStored(mindmap.Data)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	body, err := format.Export(doc)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": exportFileName(mindmap.Title) + format.Extension,
+	}))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		h.logger.Printf("export mindmap %d: %v", id, err)
+	}
+}
+
+// ImportMindMap - создает карту из файла в теле запроса. Формат берется из ?format=
+// или из Content-Type; название - из ?title= или из корня документа.
+func (h *MindMapHandler) ImportMindMap(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		name = importContentTypes[mediaType]
+	}
+	format, err := convert.Lookup(name)
+	if err != nil || format.Import == nil {
+		h.respondError(w, http.StatusBadRequest, "format must be one of: "+strings.Join(convert.ImportFormats(), ", "))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(mindmap.DefaultLimits.MaxBytes)))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondError(w, http.StatusRequestEntityTooLarge, "file is too large")
+			return
+		}
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	doc, err := format.Import(body)
+	if err != nil {
+		h.respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	mindmap, err := importedMindMap(doc, r.URL.Query().Get("title"), user.UserID)
+	if err != nil {
+		h.respondImportError(w, err)
+		return
+	}
+	if err := h.mindMapRepo.CreateMindMap(r.Context(), mindmap); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setETag(w, mindmap.Version)
+	h.respondJSON(w, http.StatusCreated, mindmap)
+}
+
+// BulkImportMindMaps - создает по карте на каждый файл из multipart-поля files.
+// Формат определяется по расширению, название - по корню документа.
+// Файлы импортируются независимо: ошибка в одном не отменяет остальные.
+func (h *MindMapHandler) BulkImportMindMaps(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkImportBytes)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondError(w, http.StatusRequestEntityTooLarge, "upload is too large")
+			return
+		}
+		h.respondError(w, http.StatusBadRequest, "multipart form with files required")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["files"]
+	if len(files) == 0 {
+		h.respondError(w, http.StatusBadRequest, "files required")
+		return
+	}
+	if len(files) > maxBulkImportFiles {
+		h.respondError(w, http.StatusBadRequest, fmt.Sprintf("at most %d files per request", maxBulkImportFiles))
+		return
+	}
+
+	type imported struct {
+		File  string `json:"file"`
+		ID    int    `json:"id"`
+		Title string `json:"title"`
+	}
+	type failed struct {
+		File  string `json:"file"`
+		Error string `json:"error"`
+	}
+	result := struct {
+		Imported []imported `json:"imported"`
+		Failed   []failed   `json:"failed"`
+	}{Imported: []imported{}, Failed: []failed{}}
+
+	for _, fh := range files {
+		mindmap, err := h.importFile(r, fh, user.UserID)
+		if err != nil {
+			result.Failed = append(result.Failed, failed{File: fh.Filename, Error: err.Error()})
+			continue
+		}
+		result.Imported = append(result.Imported, imported{File: fh.Filename, ID: mindmap.ID, Title: mindmap.Title})
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+// importFile создает карту из одного файла пакетного импорта
+func (h *MindMapHandler) importFile(r *http.Request, fh *multipart.FileHeader, userID int) (*models.MindMap, error) {
+	format, err := convert.ByExtension(fh.Filename)
+	if err != nil || format.Import == nil {
+		return nil, fmt.Errorf("unsupported file type, expected one of: %s", strings.Join(convert.ImportFormats(), ", "))
+	}
+	if fh.Size > int64(mindmap.DefaultLimits.MaxBytes) {
+		return nil, errors.New("file is too large")
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	body, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := format.Import(body)
+	if err != nil {
+		return nil, err
+	}
+	mindmap, err := importedMindMap(doc, "", userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.mindMapRepo.CreateMindMap(r.Context(), mindmap); err != nil {
+		h.logger.Printf("import %q: %v", fh.Filename, err)
+		return nil, errors.New("failed to save mindmap")
+	}
+	return mindmap, nil
+}
+
+// importedMindMap готовит новую карту из импортированного документа: выбирает
+// название (параметр, корень документа или название по умолчанию), проверяет
+// ограничения и назначает uid узлам
+func importedMindMap(doc *mindmap.Document, title string, userID int) (*models.MindMap, error) {
+	title = singleLineTitle(title)
+	if title == "" {
+		title = singleLineTitle(doc.Root.Data.Text)
+	}
+	if title == "" {
+		title = defaultImportTitle
+	}
+	if strings.TrimSpace(doc.Root.Data.Text) == "" {
+		doc.Root.Data.Text = title
+	}
+
+	encoded, err := doc.Encode()
+	if err != nil {
+		return nil, err
+	}
+	// Повторный разбор проверяет документ так же, как документ от редактора
+	doc, err = mindmap.ParseString(encoded, mindmap.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
+	mindmap.EnsureUIDs(doc)
+	data, err := doc.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MindMap{
+		Title:  title,
+		Data:   data,
+		UserID: userID,
+		Role:   models.MindMapRoleOwner,
+	}, nil
+}
+
+// respondImportError - ошибка ограничений документа в том же виде, что и при сохранении
+func (h *MindMapHandler) respondImportError(w http.ResponseWriter, err error) {
+	var verr *mindmap.ValidationError
+	if errors.As(err, &verr) {
+		h.respondJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"error":  "invalid mindmap data",
+			"fields": verr.Errors,
+		})
+		return
+	}
+	h.respondError(w, http.StatusInternalServerError, err.Error())
+}
+
+// parseStored разбирает сохраненный документ карты без ограничений размера:
+// он уже прошел проверку при сохранении
+func parseStored(data string) (*mindmap.Document, error) {
+	return mindmap.ParseString(data, mindmap.Limits{})
+}
+
+// singleLineTitle - название карты из текста узла
+func singleLineTitle(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// exportFileName - имя файла без расширения из названия карты
+func exportFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r < ' ', r == 0x7f:
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		return "mindmap"
+	}
+	return name
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// handleMindMapFolder -> /api/mindmaps/{id}/folder
+func (h *MindMapHandler) handleMindMapFolder(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.MoveMindMapToFolder(w, r, id)
+}
+
+// MoveMindMapToFolder - переносит карту в папку владельца, folder_id = null - в корень.
+// Папки принадлежат владельцу карты, поэтому переносить может только он.
+func (h *MindMapHandler) MoveMindMapToFolder(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		FolderID *int `json:"folder_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	mindmap, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleOwner)
+	if !ok {
+		return
+	}
+
+	if err := h.folderRepo.MoveMindMap(r.Context(), id, mindmap.UserID, req.FolderID); err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			h.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"id": id, "folder_id": req.FolderID})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+// handleFork -> /api/mindmaps/{id}/fork
+func (h *MindMapHandler) handleFork(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.ForkMindMap(w, r, id)
+}
+
+// ForkMindMap - копия карты во владение пользователя. Копировать можно любую карту,
+// которую пользователь может читать, в том числе чужую публичную. Узлы копии получают
+// новые uid, копия создается непубличной и запоминает исходную карту и ее ревизию.
+// Название по умолчанию - название исходной карты.
+func (h *MindMapHandler) ForkMindMap(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	source, err := h.mindMapRepo.GetMindMapByID(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if source == nil {
+		h.respondError(w, http.StatusNotFound, "mindmap not found")
+		return
+	}
+	if !source.IsPublic {
+		role, err := h.mindMapRole(r, source, user)
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !models.MindMapRoleAllows(role, models.MindMapRoleViewer) {
+			h.respondError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+	}
+
+	doc, err := mindmap.ParseString(source.Data, mindmap.Limits{})
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	mindmap.RegenerateUIDs(doc.Root)
+	data, err := doc.Encode()
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = source.Title
+	}
+	// Номер ревизии совпадает с версией карты: каждое сохранение пишет одну ревизию
+	revision := source.Version
+
+	fork := &models.MindMap{
+		Title:              title,
+		Data:               data,
+		UserID:             user.UserID,
+		Role:               models.MindMapRoleOwner,
+		ForkedFromID:       &source.ID,
+		ForkedFromRevision: &revision,
+	}
+	if err := h.mindMapRepo.CreateMindMap(r.Context(), fork); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setETag(w, fork.Version)
+	h.respondJSON(w, http.StatusCreated, fork)
+}
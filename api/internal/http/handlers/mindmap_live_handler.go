@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/mymindmap/api/models"
+)
+
+// handleLive -> /api/mindmaps/{id}/live
+func (h *MindMapHandler) handleLive(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.LiveMindMap(w, r, id)
+}
+
+// LiveMindMap - подключает пользователя к совместному редактированию карты по WebSocket.
+// Участники без права редактирования подключаются только для просмотра.
+func (h *MindMapHandler) LiveMindMap(w http.ResponseWriter, r *http.Request, id int) {
+	mindmap, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+	readOnly := !models.MindMapRoleAllows(mindmap.Role, models.MindMapRoleEditor)
+	h.collab.Serve(w, r, id, user.UserID, user.Name, readOnly)
+}
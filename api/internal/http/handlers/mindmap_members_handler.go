@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// handleMembers -> /api/mindmaps/{id}/members[/{userId}]
+func (h *MindMapHandler) handleMembers(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetMembers(w, r, id)
+		case http.MethodPost:
+			h.InviteMember(w, r, id)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+	if len(parts) > 1 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		h.UpdateMember(w, r, id, userID)
+	case http.MethodDelete:
+		h.RemoveMember(w, r, id, userID)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTransfer -> /api/mindmaps/{id}/transfer
+func (h *MindMapHandler) handleTransfer(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.TransferMindMap(w, r, id)
+}
+
+// GetMembers - участники карты
+func (h *MindMapHandler) GetMembers(w http.ResponseWriter, r *http.Request, id int) {
+	if _, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer); !ok {
+		return
+	}
+
+	members, err := h.memberRepo.List(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, members)
+}
+
+// InviteMember - дает доступ к карте зарегистрированному пользователю по email
+func (h *MindMapHandler) InviteMember(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.MindMapRoleViewer
+	}
+	if !validMemberRole(req.Role) {
+		h.respondError(w, http.StatusBadRequest, "role must be viewer, commenter or editor")
+		return
+	}
+
+	mindmap, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleOwner)
+	if !ok {
+		return
+	}
+
+	invitee, ok := h.findUserByEmail(w, r, req.Email)
+	if !ok {
+		return
+	}
+	if invitee.ID == mindmap.UserID {
+		h.respondError(w, http.StatusConflict, "user already owns this mindmap")
+		return
+	}
+
+	member := &models.MindMapMember{
+		MindMapID: id,
+		UserID:    invitee.ID,
+		Name:      invitee.Name,
+		Email:     invitee.Email,
+		Role:      req.Role,
+		InvitedBy: &user.UserID,
+	}
+	if err := h.memberRepo.Add(r.Context(), member); err != nil {
+		if errors.Is(err, repository.ErrMemberExists) {
+			h.respondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, member)
+}
+
+// UpdateMember - меняет роль участника
+func (h *MindMapHandler) UpdateMember(w http.ResponseWriter, r *http.Request, id, userID int) {
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if !validMemberRole(req.Role) {
+		h.respondError(w, http.StatusBadRequest, "role must be viewer, commenter or editor")
+		return
+	}
+
+	if _, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleOwner); !ok {
+		return
+	}
+
+	if err := h.memberRepo.UpdateRole(r.Context(), id, userID, req.Role); err != nil {
+		h.respondMemberError(w, err)
+		return
+	}
+	// Открытые подключения к совместному редактированию получают новые права сразу
+	h.collab.SetReadOnly(id, userID, !models.MindMapRoleAllows(req.Role, models.MindMapRoleEditor))
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"user_id": userID, "role": req.Role})
+}
+
+// RemoveMember - отзывает доступ. Участник может удалить и сам себя.
+func (h *MindMapHandler) RemoveMember(w http.ResponseWriter, r *http.Request, id, userID int) {
+	required := models.MindMapRoleOwner
+	if user := middleware.GetUserFromContext(r.Context()); user != nil && user.UserID == userID {
+		required = models.MindMapRoleViewer
+	}
+	if _, _, ok := h.loadMindMap(w, r, id, required); !ok {
+		return
+	}
+
+	if err := h.memberRepo.Remove(r.Context(), id, userID); err != nil {
+		h.respondMemberError(w, err)
+		return
+	}
+	h.collab.Revoke(id, userID)
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// TransferMindMap - передает карту другому пользователю. Прежний владелец становится редактором.
+func (h *MindMapHandler) TransferMindMap(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	mindmap, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleOwner)
+	if !ok {
+		return
+	}
+
+	newOwner, ok := h.findUserByEmail(w, r, req.Email)
+	if !ok {
+		return
+	}
+	if newOwner.ID == mindmap.UserID {
+		h.respondError(w, http.StatusConflict, "user already owns this mindmap")
+		return
+	}
+
+	if err := h.memberRepo.TransferOwnership(r.Context(), id, mindmap.UserID, newOwner.ID); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// Новый владелец мог быть подключен зрителем; прежний остается редактором
+	h.collab.SetReadOnly(id, newOwner.ID, false)
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"id": id, "user_id": newOwner.ID})
+}
+
+// findUserByEmail ищет пользователя по email. При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) findUserByEmail(w http.ResponseWriter, r *http.Request, email string) (*models.User, bool) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		h.respondError(w, http.StatusBadRequest, "email required")
+		return nil, false
+	}
+
+	user, err := h.userRepo.GetUserByEmail(r.Context(), strings.ToLower(email))
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if user == nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *MindMapHandler) respondMemberError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrMemberNotFound) {
+		h.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.respondError(w, http.StatusInternalServerError, err.Error())
+}
+
+// validMemberRole - роли, которые можно выдать приглашением. Владелец меняется только передачей карты.
+func validMemberRole(role string) bool {
+	return role != models.MindMapRoleOwner && models.ValidMindMapRole(role)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/models"
+)
+
+// handleBacklinks -> /api/mindmaps/{id}/backlinks
+func (h *MindMapHandler) handleBacklinks(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.GetBacklinks(w, r, id)
+}
+
+// handleOutlinks -> /api/mindmaps/{id}/outlinks
+func (h *MindMapHandler) handleOutlinks(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.GetOutlinks(w, r, id)
+}
+
+// handleGraph -> /api/mindmaps/graph
+func (h *MindMapHandler) handleGraph(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.GetMapGraph(w, r)
+}
+
+// GetBacklinks - «что ссылается сюда»: узлы карт, которые ссылаются на карту,
+// ?node= - на ее узел. Ссылки из карт, недоступных пользователю, не показываются.
+func (h *MindMapHandler) GetBacklinks(w http.ResponseWriter, r *http.Request, id int) {
+	_, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+
+	links, err := h.nodeLinkRepo.Backlinks(r.Context(), user.UserID, user.Role == "admin", id, r.URL.Query().Get("node"))
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"backlinks": links})
+}
+
+// GetOutlinks - ссылки из узлов карты на другие карты с состоянием цели: ok,
+// node_missing (узел удален из карты) или unavailable (карта удалена или недоступна).
+func (h *MindMapHandler) GetOutlinks(w http.ResponseWriter, r *http.Request, id int) {
+	_, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+
+	links, err := h.nodeLinkRepo.Outgoing(r.Context(), user.UserID, user.Role == "admin", id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"links": links})
+}
+
+// GetMapGraph - граф ссылок между картами, доступными пользователю.
+// ?mindmap_id= - только связи этой карты.
+func (h *MindMapHandler) GetMapGraph(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var mindMapID *int
+	if v := r.URL.Query().Get("mindmap_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			h.respondError(w, http.StatusBadRequest, "invalid mindmap_id")
+			return
+		}
+		mindMapID = &id
+	}
+
+	graph, err := h.nodeLinkRepo.Graph(r.Context(), user.UserID, user.Role == "admin", mindMapID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, graph)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+// maxOpsBatchesPerPage - сколько пакетов операций отдается за один запрос
+const maxOpsBatchesPerPage = 500
+
+// handleOps -> /api/mindmaps/{id}/ops
+func (h *MindMapHandler) handleOps(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.GetOps(w, r, id)
+	case http.MethodPatch:
+		h.PatchOps(w, r, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PatchOps - атомарно применяет пакет операций над узлами к карте. В ответе -
+// примененные операции: по ним клиент узнает uid, назначенные новым узлам.
+func (h *MindMapHandler) PatchOps(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		Version int          `json:"version"` // Версия, от которой клиент строил операции (необязательно)
+		Ops     []mindmap.Op `json:"ops"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if len(req.Ops) == 0 {
+		h.respondError(w, http.StatusBadRequest, "ops required")
+		return
+	}
+
+	current, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleEditor)
+	if !ok {
+		return
+	}
+	if !ifMatch(r, current.Version) || req.Version != 0 && req.Version != current.Version {
+		h.respondVersionConflict(w, current.Version)
+		return
+	}
+
+	doc, err := mindmap.ParseString(current.Data, mindmap.Limits{})
+	if err != nil {
+		h.respondError(w, http.StatusConflict, "stored mindmap data is invalid: "+err.Error())
+		return
+	}
+	mindmap.EnsureUIDs(doc)
+
+	updated, applied, err := mindmap.ApplyOps(doc, req.Ops)
+	if err != nil {
+		var opErr *mindmap.OpError
+		if errors.As(err, &opErr) {
+			h.respondJSON(w, http.StatusUnprocessableEntity, map[string]any{
+				"error": "op cannot be applied",
+				"op":    opErr,
+			})
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := updated.CheckLimits(mindmap.DefaultLimits); err != nil {
+		h.respondDataError(w, err)
+		return
+	}
+
+	// Картинки выносятся в файлы только после проверки пакета, чтобы отклоненный
+	// пакет не оставлял файлов, на которые ничего не ссылается
+	replaced, ok := h.extractOpAttachments(w, r, applied)
+	if !ok {
+		return
+	}
+	if replaced > 0 {
+		if updated, applied, err = mindmap.ApplyOps(doc, applied); err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	encoded, err := updated.Encode()
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	data, ok := h.normalizeData(w, r, encoded)
+	if !ok {
+		return
+	}
+
+	// В журнал пишутся примененные операции: с назначенными uid и ссылками на файлы
+	ops, err := json.Marshal(applied)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	current.Data = data
+	batch, err := h.mindMapRepo.UpdateWithOps(r.Context(), current, user.UserID, ops)
+	if err != nil {
+		h.respondSaveError(w, r, id, err)
+		return
+	}
+
+	setETag(w, current.Version)
+	h.respondJSON(w, http.StatusOK, map[string]any{
+		"id":         current.ID,
+		"version":    current.Version,
+		"batch_id":   batch.ID,
+		"ops":        applied,
+		"updated_at": current.UpdatedAt,
+	})
+}
+
+// GetOps - пакеты операций после версии ?since=N для воспроизведения на клиенте
+func (h *MindMapHandler) GetOps(w http.ResponseWriter, r *http.Request, id int) {
+	since := 0
+	if v := r.URL.Query().Get("since"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			h.respondError(w, http.StatusBadRequest, "invalid since")
+			return
+		}
+		since = n
+	}
+
+	current, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+
+	batches, err := h.opsRepo.ListSince(r.Context(), id, since, maxOpsBatchesPerPage)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Цепочка пакетов может прерываться: журнал хранится ограниченное время,
+	// а PUT меняет карту без операций. Тогда клиент должен загрузить карту целиком.
+	reached := since
+	for _, batch := range batches {
+		if batch.BaseVersion != reached {
+			break
+		}
+		reached = batch.Version
+	}
+	hasMore := len(batches) == maxOpsBatchesPerPage
+
+	setETag(w, current.Version)
+	h.respondJSON(w, http.StatusOK, map[string]any{
+		"version":  current.Version,
+		"complete": reached == current.Version || hasMore && reached == batches[len(batches)-1].Version,
+		"has_more": hasMore,
+		"batches":  batches,
+	})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/dbtest"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+func TestPatchOps(t *testing.T) {
+	h, db := newTestMindMapHandler(t)
+	ctx := context.Background()
+	alice := dbtest.CreateUser(t, db, "alice")
+
+	m := &models.MindMap{Title: "Plan", Data: mergeDoc("A", "B"), UserID: alice}
+	require.NoError(t, h.mindMapRepo.CreateMindMap(ctx, m))
+	patch := func(ops ...map[string]any) *httptest.ResponseRecorder {
+		r := userRequest(http.MethodPatch, fmt.Sprintf("/api/mindmaps/%d/ops", m.ID), map[string]any{"ops": ops}, alice)
+		w := httptest.NewRecorder()
+		h.PatchOps(w, r, m.ID)
+		return w
+	}
+
+	// Отклоненный пакет не меняет карту
+	w := patch(
+		map[string]any{"op": "set", "uid": "a", "field": "text", "value": "A2"},
+		map[string]any{"op": "delete", "uid": "missing"},
+	)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	current, err := h.mindMapRepo.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, current.Version)
+
+	// В ответе - примененные операции с uid, назначенным новому узлу
+	w = patch(map[string]any{"op": "insert", "parent": "r", "node": map[string]any{"data": map[string]any{"text": "C"}}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Version int          `json:"version"`
+		Ops     []mindmap.Op `json:"ops"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Version)
+	require.Len(t, resp.Ops, 1)
+	require.NotNil(t, resp.Ops[0].Node)
+	uid := resp.Ops[0].Node.Data.UID
+	require.NotEmpty(t, uid)
+
+	current, err = h.mindMapRepo.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	doc, err := mindmap.ParseString(current.Data, mindmap.Limits{})
+	require.NoError(t, err)
+	inserted, _ := doc.Find(uid)
+	require.NotNil(t, inserted)
+	assert.Equal(t, "C", inserted.Data.Text)
+}
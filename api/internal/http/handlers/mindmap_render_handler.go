@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/mymindmap/api/internal/render"
+	"github.com/mymindmap/api/models"
+)
+
+// handleRender -> /api/mindmaps/{id}/render?format=svg|png|pdf&scale=
+func (h *MindMapHandler) handleRender(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.RenderMindMap(w, r, id)
+}
+
+// handleThumbnail -> /api/mindmaps/{id}/thumbnail
+func (h *MindMapHandler) handleThumbnail(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.GetThumbnail(w, r, id)
+}
+
+// RenderMindMap - изображение карты. По умолчанию SVG; scale увеличивает разрешение PNG.
+func (h *MindMapHandler) RenderMindMap(w http.ResponseWriter, r *http.Request, id int) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = render.FormatSVG
+	}
+	if render.ContentType(format) == "" {
+		h.respondError(w, http.StatusBadRequest, "format must be svg, png or pdf")
+		return
+	}
+	opts := render.Options{Scale: 1}
+	if v := query.Get("scale"); v != "" {
+		scale, err := strconv.ParseFloat(v, 64)
+		if err != nil || scale <= 0 || scale > render.MaxScale {
+			h.respondError(w, http.StatusBadRequest, "scale must be a number from 0 to 4")
+			return
+		}
+		opts.Scale = scale
+	}
+
+	mindmap, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+
+	doc, err := parseStored(mindmap.Data)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	image, err := render.Render(doc, format, opts)
+	if err != nil {
+		if errors.Is(err, render.ErrUnknownFormat) {
+			h.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", render.ContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{
+		"filename": exportFileName(mindmap.Title) + "." + format,
+	}))
+	if format == render.FormatSVG {
+		// SVG открывается как документ: запрещаем скрипты на случай ошибки экранирования
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(image); err != nil {
+		h.logger.Printf("render mindmap %d: %v", id, err)
+	}
+}
+
+// GetThumbnail - превью карты для списка. Ссылка на превью с версией приходит
+// в поле thumbnail списка карт, поэтому ответ можно кешировать.
+func (h *MindMapHandler) GetThumbnail(w http.ResponseWriter, r *http.Request, id int) {
+	if _, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer); !ok {
+		return
+	}
+
+	thumbnail, err := h.thumbRepo.GetThumbnail(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if thumbnail == nil {
+		h.respondError(w, http.StatusNotFound, "thumbnail not ready")
+		return
+	}
+
+	if notModified(w, r, thumbnail.Version) {
+		return
+	}
+	setETag(w, thumbnail.Version)
+	w.Header().Set("Content-Type", thumbnail.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(thumbnail.Content); err != nil {
+		h.logger.Printf("thumbnail %d: %v", id, err)
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mymindmap/api/models"
+)
+
+// handleRevisions -> /api/mindmaps/{id}/revisions[/{rev}[/restore]]
+func (h *MindMapHandler) handleRevisions(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.GetRevisions(w, r, id)
+		return
+	}
+
+	rev, err := strconv.Atoi(parts[0])
+	if err != nil || rev <= 0 {
+		h.respondError(w, http.StatusBadRequest, "invalid revision")
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		h.GetRevision(w, r, id, rev)
+	case len(parts) == 2 && parts[1] == "restore" && r.Method == http.MethodPost:
+		h.RestoreRevision(w, r, id, rev)
+	case len(parts) <= 2:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		h.respondError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// GetRevisions - история изменений карты
+func (h *MindMapHandler) GetRevisions(w http.ResponseWriter, r *http.Request, id int) {
+	if _, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer); !ok {
+		return
+	}
+
+	revisions, err := h.revisionRepo.List(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, revisions)
+}
+
+// GetRevision - одна ревизия вместе с документом
+func (h *MindMapHandler) GetRevision(w http.ResponseWriter, r *http.Request, id, rev int) {
+	if _, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer); !ok {
+		return
+	}
+
+	revision, err := h.revisionRepo.Get(r.Context(), id, rev)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if revision == nil {
+		h.respondError(w, http.StatusNotFound, "revision not found")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, revision)
+}
+
+// RestoreRevision - возвращает карту к состоянию ревизии. Восстановление
+// записывается новой ревизией, так что его тоже можно откатить.
+func (h *MindMapHandler) RestoreRevision(w http.ResponseWriter, r *http.Request, id, rev int) {
+	mindmap, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleEditor)
+	if !ok {
+		return
+	}
+	if !ifMatch(r, mindmap.Version) {
+		h.respondVersionConflict(w, mindmap.Version)
+		return
+	}
+
+	revision, err := h.revisionRepo.Get(r.Context(), id, rev)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if revision == nil {
+		h.respondError(w, http.StatusNotFound, "revision not found")
+		return
+	}
+
+	mindmap.Title, mindmap.Data = revision.Title, revision.Data
+	if !h.saveMindMap(w, r, mindmap, user.UserID) {
+		return
+	}
+
+	setETag(w, mindmap.Version)
+	h.respondJSON(w, http.StatusOK, mindmap)
+}
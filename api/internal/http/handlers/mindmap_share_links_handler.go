@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/mymindmap/api/internal/attachments"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// Ограничения анонимного доступа по ссылкам
+const (
+	sharedRequestsPerMinute = 120              // Запросов по ссылкам с одного IP в минуту
+	sharedPasswordAttempts  = 5                // Неверных паролей к одной ссылке с одного IP
+	sharedPasswordBlockTime = 15 * time.Minute // Блокировка после неверных паролей
+)
+
+// sharePasswordHeader - заголовок с паролем ссылки (не в URL, чтобы пароль не попадал в логи)
+const sharePasswordHeader = "X-Share-Password"
+
+// handleLinks -> /api/mindmaps/{id}/links[/{linkId}]
+func (h *MindMapHandler) handleLinks(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetShareLinks(w, r, id)
+		case http.MethodPost:
+			h.CreateShareLink(w, r, id)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+	if len(parts) > 1 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	linkID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid link id")
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.RevokeShareLink(w, r, id, linkID)
+}
+
+// GetShareLinks - ссылки доступа к карте
+func (h *MindMapHandler) GetShareLinks(w http.ResponseWriter, r *http.Request, id int) {
+	if _, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleOwner); !ok {
+		return
+	}
+
+	links, err := h.linkRepo.List(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, links)
+}
+
+// CreateShareLink - создает ссылку доступа. Токен возвращается только в этом ответе.
+func (h *MindMapHandler) CreateShareLink(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		Mode      string     `json:"mode"`
+		Password  string     `json:"password"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.ShareLinkModeView
+	}
+	if req.Mode != models.ShareLinkModeView && req.Mode != models.ShareLinkModeEdit {
+		h.respondError(w, http.StatusBadRequest, "mode must be view or edit")
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		h.respondError(w, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	_, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleOwner)
+	if !ok {
+		return
+	}
+
+	link := &models.ShareLink{
+		MindMapID: id,
+		Mode:      req.Mode,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: &user.UserID,
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	token, err := h.linkRepo.Create(r.Context(), link)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, map[string]any{
+		"link":  link,
+		"token": token,
+		"url":   "/api/shared/" + token,
+	})
+}
+
+// RevokeShareLink - отзывает ссылку доступа
+func (h *MindMapHandler) RevokeShareLink(w http.ResponseWriter, r *http.Request, id, linkID int) {
+	if _, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleOwner); !ok {
+		return
+	}
+
+	if err := h.linkRepo.Revoke(r.Context(), id, linkID); err != nil {
+		if errors.Is(err, repository.ErrShareLinkNotFound) {
+			h.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// handleShared -> /api/shared/{token}[/attachments/{hash}]
+// Анонимный доступ к одной карте по ссылке, со своим ограничением частоты запросов.
+func (h *MindMapHandler) handleShared(w http.ResponseWriter, r *http.Request) {
+	ip := clientIP(r)
+	if !h.shareLimiter.IsAllowed(ip) {
+		w.Header().Set("Retry-After", "60")
+		h.respondError(w, http.StatusTooManyRequests, "too many requests")
+		return
+	}
+	h.shareLimiter.RecordAttempt(ip)
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/shared/"), "/"), "/")
+	token := parts[0]
+	if token == "" {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if len(parts) == 3 && parts[1] == "attachments" {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.GetSharedAttachment(w, r, token, parts[2])
+		return
+	}
+	if len(parts) != 1 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.GetSharedMindMap(w, r, token)
+	case http.MethodPut:
+		h.UpdateSharedMindMap(w, r, token)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetSharedMindMap - карта по ссылке
+func (h *MindMapHandler) GetSharedMindMap(w http.ResponseWriter, r *http.Request, token string) {
+	link, mindmap, ok := h.resolveShareLink(w, r, token)
+	if !ok {
+		return
+	}
+
+	if notModified(w, r, mindmap.Version) {
+		return
+	}
+	setETag(w, mindmap.Version)
+	h.respondJSON(w, http.StatusOK, sharedView(link, mindmap))
+}
+
+// GetSharedAttachment - файл узла карты, открытой по ссылке. Ссылка дает
+// доступ только к файлам своей карты; клиент подставляет этот адрес вместо
+// /api/attachments/{hash}.
+func (h *MindMapHandler) GetSharedAttachment(w http.ResponseWriter, r *http.Request, token, hash string) {
+	if !attachments.ValidHash(hash) {
+		h.respondError(w, http.StatusNotFound, attachments.ErrNotFound.Error())
+		return
+	}
+	_, mindmap, ok := h.resolveShareLink(w, r, token)
+	if !ok {
+		return
+	}
+
+	linked, err := h.attachRepo.LinkedTo(r.Context(), hash, mindmap.ID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !linked {
+		h.respondError(w, http.StatusNotFound, attachments.ErrNotFound.Error())
+		return
+	}
+	attachment, err := h.attachRepo.Get(r.Context(), hash)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if attachment == nil {
+		h.respondError(w, http.StatusNotFound, attachments.ErrNotFound.Error())
+		return
+	}
+
+	writeAttachment(w, r, h.attachments, attachment, h.logger, h.respondError)
+}
+
+// UpdateSharedMindMap - сохранение карты по ссылке в режиме редактирования
+func (h *MindMapHandler) UpdateSharedMindMap(w http.ResponseWriter, r *http.Request, token string) {
+	var req struct {
+		Title string `json:"title"`
+		Data  string `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	link, mindmap, ok := h.resolveShareLink(w, r, token)
+	if !ok {
+		return
+	}
+	if link.Mode != models.ShareLinkModeEdit {
+		h.respondError(w, http.StatusForbidden, "link is view-only")
+		return
+	}
+	if !ifMatch(r, mindmap.Version) {
+		h.respondVersionConflict(w, mindmap.Version)
+		return
+	}
+
+	data, ok := h.normalizeData(w, r, req.Data)
+	if !ok {
+		return
+	}
+
+	if req.Title != "" {
+		mindmap.Title = req.Title
+	}
+	mindmap.Data = data
+	// Анонимная правка: автор ревизии не указывается
+	if !h.saveMindMap(w, r, mindmap, 0) {
+		return
+	}
+
+	setETag(w, mindmap.Version)
+	h.respondJSON(w, http.StatusOK, sharedView(link, mindmap))
+}
+
+// resolveShareLink находит действующую ссылку, проверяет пароль и загружает карту.
+// Неизвестная, отозванная и истекшая ссылки неотличимы для клиента.
+// При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) resolveShareLink(w http.ResponseWriter, r *http.Request, token string) (*models.ShareLink, *models.MindMap, bool) {
+	link, err := h.linkRepo.GetByToken(r.Context(), token)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, nil, false
+	}
+	if link == nil || !link.Active(time.Now()) {
+		h.respondError(w, http.StatusNotFound, "link not found or expired")
+		return nil, nil, false
+	}
+
+	if link.HasPassword {
+		key := strconv.Itoa(link.ID) + ":" + clientIP(r)
+		if !h.sharePasswordLimiter.IsAllowed(key) {
+			h.respondError(w, http.StatusTooManyRequests, "too many password attempts")
+			return nil, nil, false
+		}
+		password := r.Header.Get(sharePasswordHeader)
+		if password == "" {
+			h.respondError(w, http.StatusUnauthorized, "password required")
+			return nil, nil, false
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			h.sharePasswordLimiter.RecordAttempt(key)
+			h.respondError(w, http.StatusUnauthorized, "invalid password")
+			return nil, nil, false
+		}
+		h.sharePasswordLimiter.Reset(key)
+	}
+
+	mindmap, err := h.mindMapRepo.GetByID(r.Context(), link.MindMapID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, nil, false
+	}
+	if mindmap == nil {
+		h.respondError(w, http.StatusNotFound, "link not found or expired")
+		return nil, nil, false
+	}
+
+	if err := h.linkRepo.Touch(r.Context(), link.ID); err != nil {
+		h.logger.Printf("share link %d: %v", link.ID, err)
+	}
+	return link, mindmap, true
+}
+
+// sharedView - карта для анонимного пользователя: без данных владельца
+func sharedView(link *models.ShareLink, mindmap *models.MindMap) map[string]any {
+	return map[string]any{
+		"id":         mindmap.ID,
+		"title":      mindmap.Title,
+		"data":       mindmap.Data,
+		"version":    mindmap.Version,
+		"mode":       link.Mode,
+		"updated_at": mindmap.UpdatedAt,
+	}
+}
+
+// clientIP - адрес клиента для ограничения частоты запросов
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
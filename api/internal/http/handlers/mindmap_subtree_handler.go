@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// maxMindMapTitle - длина названия карты в БД
+const maxMindMapTitle = 255
+
+// handleNodes -> /api/mindmaps/{id}/nodes/{uid}/move|copy|extract
+func (h *MindMapHandler) handleNodes(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 2 || parts[0] == "" {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch uid := parts[0]; parts[1] {
+	case "move":
+		h.TransplantSubtree(w, r, id, uid, false)
+	case "copy":
+		h.TransplantSubtree(w, r, id, uid, true)
+	case "extract":
+		h.ExtractSubtree(w, r, id, uid)
+	default:
+		h.respondError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// TransplantSubtree - переносит (asCopy = false) или копирует поддерево узла uid
+// в узел target_parent_uid другой карты. Перенос требует прав редактора в обеих
+// картах и сохраняет их в одной транзакции; для копирования в исходной карте
+// достаточно чтения. If-Match проверяет версию исходной карты, target_version -
+// версию целевой.
+func (h *MindMapHandler) TransplantSubtree(w http.ResponseWriter, r *http.Request, id int, uid string, asCopy bool) {
+	var req struct {
+		TargetMindMapID int    `json:"target_mindmap_id"`
+		TargetParentUID string `json:"target_parent_uid"`
+		Index           *int   `json:"index"` // nil - в конец списка детей
+		TargetVersion   *int   `json:"target_version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.TargetMindMapID == 0 || req.TargetParentUID == "" {
+		h.respondError(w, http.StatusBadRequest, "target_mindmap_id and target_parent_uid required")
+		return
+	}
+	if req.TargetMindMapID == id {
+		h.respondError(w, http.StatusBadRequest, "target must be another mindmap, use ops to move nodes within a map")
+		return
+	}
+
+	required := models.MindMapRoleEditor
+	if asCopy {
+		required = models.MindMapRoleViewer
+	}
+	source, user, ok := h.loadMindMap(w, r, id, required)
+	if !ok {
+		return
+	}
+	if !asCopy && !ifMatch(r, source.Version) {
+		h.respondVersionConflict(w, source.Version)
+		return
+	}
+	target, _, ok := h.loadMindMap(w, r, req.TargetMindMapID, models.MindMapRoleEditor)
+	if !ok {
+		return
+	}
+	if req.TargetVersion != nil && *req.TargetVersion != target.Version {
+		h.respondVersionConflict(w, target.Version)
+		return
+	}
+
+	srcDoc, dstDoc, ok := h.parseDocs(w, source, target)
+	if !ok {
+		return
+	}
+	if node, _ := srcDoc.Find(uid); node == nil {
+		h.respondError(w, http.StatusNotFound, "node not found")
+		return
+	}
+	if node, _ := dstDoc.Find(req.TargetParentUID); node == nil {
+		h.respondError(w, http.StatusNotFound, "target node not found")
+		return
+	}
+
+	newSrc, newDst, node, err := mindmap.Transplant(srcDoc, dstDoc, uid, req.TargetParentUID, req.Index, asCopy)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, err := newDst.Encode()
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// Целевая карта проверяется по тем же ограничениям, что и при обычном сохранении
+	if target.Data, ok = h.normalizeData(w, r, data); !ok {
+		return
+	}
+
+	if asCopy {
+		if !h.saveMindMap(w, r, target, user.UserID) {
+			return
+		}
+	} else {
+		if source.Data, err = newSrc.Encode(); err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := h.mindMapRepo.UpdateMany(r.Context(), []*models.MindMap{source, target}, user.UserID); err != nil {
+			h.respondTransplantError(w, err)
+			return
+		}
+	}
+
+	resp := map[string]any{
+		"node_uid": node.Data.UID,
+		"target":   map[string]any{"id": target.ID, "version": target.Version},
+	}
+	if !asCopy {
+		resp["source"] = map[string]any{"id": source.ID, "version": source.Version}
+	}
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+// ExtractSubtree - выносит поддерево узла uid в новую карту пользователя. В исходной
+// карте на месте поддерева остается узел с тем же текстом и ссылкой на новую карту.
+// Название по умолчанию - текст узла.
+func (h *MindMapHandler) ExtractSubtree(w http.ResponseWriter, r *http.Request, id int, uid string) {
+	var req struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	source, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleEditor)
+	if !ok {
+		return
+	}
+	if !ifMatch(r, source.Version) {
+		h.respondVersionConflict(w, source.Version)
+		return
+	}
+
+	srcDoc, err := mindmap.ParseString(source.Data, mindmap.Limits{})
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	node, _ := srcDoc.Find(uid)
+	if node == nil {
+		h.respondError(w, http.StatusNotFound, "node not found")
+		return
+	}
+	rest, branchDoc, err := mindmap.ExtractSubtree(srcDoc, uid)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = strings.Join(strings.Fields(node.Data.PlainText()), " ")
+	}
+	if title == "" {
+		title = source.Title
+	}
+	title = truncateRunes(title, maxMindMapTitle)
+
+	branchData, err := branchDoc.Encode()
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if source.Data, err = rest.Encode(); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	branch := &models.MindMap{
+		Title:  title,
+		Data:   branchData,
+		UserID: user.UserID,
+		Role:   models.MindMapRoleOwner,
+	}
+	if err := h.mindMapRepo.ExtractSubtree(r.Context(), source, branch, uid, user.UserID); err != nil {
+		h.respondTransplantError(w, err)
+		return
+	}
+
+	setETag(w, branch.Version)
+	h.respondJSON(w, http.StatusCreated, map[string]any{
+		"mindmap": branch,
+		"source":  map[string]any{"id": source.ID, "version": source.Version},
+	})
+}
+
+// parseDocs разбирает документы исходной и целевой карт. При ошибке сам пишет
+// ответ и возвращает false.
+func (h *MindMapHandler) parseDocs(w http.ResponseWriter, source, target *models.MindMap) (*mindmap.Document, *mindmap.Document, bool) {
+	srcDoc, err := mindmap.ParseString(source.Data, mindmap.Limits{})
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, nil, false
+	}
+	dstDoc, err := mindmap.ParseString(target.Data, mindmap.Limits{})
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, nil, false
+	}
+	return srcDoc, dstDoc, true
+}
+
+// respondTransplantError отвечает на ошибку сохранения нескольких карт: 412, если
+// одну из них успели изменить, 404, если удалить, иначе 500
+func (h *MindMapHandler) respondTransplantError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		h.respondError(w, http.StatusPreconditionFailed, "mindmap was modified by someone else")
+		return
+	}
+	if errors.Is(err, repository.ErrMindMapNotFound) {
+		h.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.respondError(w, http.StatusInternalServerError, err.Error())
+}
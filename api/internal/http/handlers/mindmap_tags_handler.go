@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/mymindmap/api/models"
+)
+
+// handleMindMapTags -> /api/mindmaps/{id}/tags
+func (h *MindMapHandler) handleMindMapTags(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.SetMindMapTags(w, r, id)
+}
+
+// handleStar -> /api/mindmaps/{id}/star
+func (h *MindMapHandler) handleStar(w http.ResponseWriter, r *http.Request, id int, parts []string) {
+	if len(parts) != 0 {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		h.StarMindMap(w, r, id, true)
+	case http.MethodDelete:
+		h.StarMindMap(w, r, id, false)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SetMindMapTags - заменяет теги пользователя на карте списком названий.
+// Недостающие теги создаются. Теги личные: другие участники карты их не видят.
+func (h *MindMapHandler) SetMindMapTags(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	names := make([]string, 0, len(req.Tags))
+	seen := map[string]bool{}
+	for _, raw := range req.Tags {
+		name, msg := normalizeTagName(raw)
+		if msg != "" {
+			h.respondError(w, http.StatusBadRequest, msg)
+			return
+		}
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) > maxMindMapTags {
+		h.respondError(w, http.StatusBadRequest, "too many tags")
+		return
+	}
+
+	_, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+
+	tags, err := h.tagRepo.SetMindMapTags(r.Context(), user.UserID, id, names)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, tags)
+}
+
+// StarMindMap - добавляет карту в избранное пользователя или убирает из него
+func (h *MindMapHandler) StarMindMap(w http.ResponseWriter, r *http.Request, id int, starred bool) {
+	_, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
+		return
+	}
+
+	var err error
+	if starred {
+		err = h.activityRepo.Star(r.Context(), user.UserID, id)
+	} else {
+		err = h.activityRepo.Unstar(r.Context(), user.UserID, id)
+	}
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"id": id, "starred": starred})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/internal/templates"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// Ограничения переменных при создании карты из шаблона
+const (
+	maxTemplateVariables   = 50
+	maxTemplateVariableLen = 1000 // Символов в значении
+)
+
+// CreateMindMapFromTemplate - новая карта из шаблона (POST /api/mindmaps?template={id}).
+// В названии и узлах подставляются встроенные переменные (date, year, month, week, user)
+// и переданные в variables; переданные значения важнее встроенных. Название по
+// умолчанию - название шаблона. Узлы получают новые uid.
+func (h *MindMapHandler) CreateMindMapFromTemplate(w http.ResponseWriter, r *http.Request, templateID string, req *models.CreateMindMapRequest) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if req.Data != "" {
+		h.respondError(w, http.StatusBadRequest, "data must be empty when creating from a template")
+		return
+	}
+	if len(req.Variables) > maxTemplateVariables {
+		h.respondError(w, http.StatusBadRequest, "too many variables")
+		return
+	}
+
+	vars := templates.Builtin(time.Now(), user.Name)
+	for name, value := range req.Variables {
+		if !templates.ValidName(name) {
+			h.respondError(w, http.StatusBadRequest, "invalid variable name: "+name)
+			return
+		}
+		if utf8.RuneCountInString(value) > maxTemplateVariableLen {
+			h.respondError(w, http.StatusBadRequest, "variable is too long: "+name)
+			return
+		}
+		vars[strings.ToLower(name)] = value
+	}
+
+	tmpl, err := findTemplate(r.Context(), h.templateRepo, templateID, user.UserID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if tmpl == nil {
+		h.respondError(w, http.StatusNotFound, repository.ErrTemplateNotFound.Error())
+		return
+	}
+
+	doc, err := mindmap.ParseString(tmpl.Data, mindmap.Limits{})
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	templates.Apply(doc, vars)
+	// Карты из одного шаблона не должны делить uid узлов
+	mindmap.RegenerateUIDs(doc.Root)
+
+	encoded, err := doc.Encode()
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// Подставленные значения могли превысить ограничения документа
+	data, ok := h.normalizeData(w, r, encoded)
+	if !ok {
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = templates.Substitute(tmpl.Title, vars)
+	}
+
+	mindmap := &models.MindMap{
+		Title:    title,
+		Data:     data,
+		UserID:   user.UserID,
+		IsPublic: req.IsPublic,
+		Role:     models.MindMapRoleOwner,
+	}
+	if err := h.mindMapRepo.CreateMindMap(r.Context(), mindmap); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setETag(w, mindmap.Version)
+	h.respondJSON(w, http.StatusCreated, mindmap)
+}
@@ -2,34 +2,99 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/mymindmap/api/internal/attachments"
 	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/collab"
 	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/internal/mindmap"
 	"github.com/mymindmap/api/models"
 	"github.com/mymindmap/api/repository"
 )
 
 type MindMapHandler struct {
-	mindMapRepo *repository.MindMapRepository
-	authService *auth.AuthService
-	logger      *log.Logger
+	mindMapRepo  *repository.MindMapRepository
+	revisionRepo *repository.MindMapRevisionRepository
+	opsRepo      *repository.MindMapOpsRepository
+	memberRepo   *repository.MindMapMemberRepository
+	linkRepo     *repository.MindMapShareLinkRepository
+	userRepo     *repository.UserRepository
+	thumbRepo    *repository.MindMapThumbnailRepository
+	templateRepo *repository.MindMapTemplateRepository
+	folderRepo   *repository.FolderRepository
+	tagRepo      *repository.TagRepository
+	activityRepo *repository.MindMapActivityRepository
+	attachRepo   *repository.AttachmentRepository
+	attachments  *attachments.Service
+	commentRepo  *repository.CommentRepository
+	nodeLinkRepo *repository.NodeLinkRepository
+	collab       *collab.Hub
+	authService  *auth.AuthService
+	logger       *log.Logger
+
+	shareLimiter         *auth.RateLimiter // Запросы по ссылкам доступа с одного IP
+	sharePasswordLimiter *auth.RateLimiter // Неверные пароли ссылок
+}
+
+// MindMapDeps - зависимости MindMapHandler
+type MindMapDeps struct {
+	MindMaps    *repository.MindMapRepository
+	Revisions   *repository.MindMapRevisionRepository
+	Ops         *repository.MindMapOpsRepository
+	Members     *repository.MindMapMemberRepository
+	ShareLinks  *repository.MindMapShareLinkRepository
+	Users       *repository.UserRepository
+	Thumbnails  *repository.MindMapThumbnailRepository
+	Templates   *repository.MindMapTemplateRepository
+	Folders     *repository.FolderRepository
+	Tags        *repository.TagRepository
+	Activity    *repository.MindMapActivityRepository
+	Attachments *repository.AttachmentRepository
+	Files       *attachments.Service
+	Comments    *repository.CommentRepository
+	NodeLinks   *repository.NodeLinkRepository
+	Collab      *collab.Hub
+	Auth        *auth.AuthService
+	Logger      *log.Logger
 }
 
-func NewMindMapHandler(mindMapRepo *repository.MindMapRepository, authService *auth.AuthService, logger *log.Logger) *MindMapHandler {
+func NewMindMapHandler(deps MindMapDeps) *MindMapHandler {
 	return &MindMapHandler{
-		mindMapRepo: mindMapRepo,
-		authService: authService,
-		logger:      logger,
+		mindMapRepo:  deps.MindMaps,
+		revisionRepo: deps.Revisions,
+		opsRepo:      deps.Ops,
+		memberRepo:   deps.Members,
+		linkRepo:     deps.ShareLinks,
+		userRepo:     deps.Users,
+		thumbRepo:    deps.Thumbnails,
+		templateRepo: deps.Templates,
+		folderRepo:   deps.Folders,
+		tagRepo:      deps.Tags,
+		activityRepo: deps.Activity,
+		attachRepo:   deps.Attachments,
+		attachments:  deps.Files,
+		commentRepo:  deps.Comments,
+		nodeLinkRepo: deps.NodeLinks,
+		collab:       deps.Collab,
+		authService:  deps.Auth,
+		logger:       deps.Logger,
+
+		shareLimiter:         auth.NewRateLimiter(sharedRequestsPerMinute, time.Minute, time.Minute),
+		sharePasswordLimiter: auth.NewRateLimiter(sharedPasswordAttempts, sharedPasswordBlockTime, sharedPasswordBlockTime),
 	}
 }
 
 func (h *MindMapHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/mindmaps", middleware.AuthMiddleware(h.authService, h.handleMindMaps))       // GET list, POST create
-	mux.HandleFunc("/api/mindmaps/", middleware.AuthMiddleware(h.authService, h.handleSingleMindMap)) // GET, PUT, DELETE by id
+	mux.HandleFunc("/api/mindmaps/", middleware.AuthMiddleware(h.authService, h.handleSingleMindMap)) // GET, PUT, DELETE by id, вложенные ресурсы
+	mux.HandleFunc("/api/shared/", h.handleShared)                                                   // GET, PUT по ссылке доступа, без авторизации
 }
 
 // --- Handlers ---
@@ -46,15 +111,69 @@ func (h *MindMapHandler) handleMindMaps(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// handleSingleMindMap -> /api/mindmaps/{id}
+// handleSingleMindMap -> /api/mindmaps/{id}, /api/mindmaps/{id}/..., /api/mindmaps/import, /api/mindmaps/graph
 func (h *MindMapHandler) handleSingleMindMap(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
-	id, err := strconv.Atoi(idStr)
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/mindmaps/"), "/"), "/")
+	if parts[0] == "import" {
+		h.handleImport(w, r, parts[1:])
+		return
+	}
+	if parts[0] == "graph" {
+		h.handleGraph(w, r, parts[1:])
+		return
+	}
+	id, err := strconv.Atoi(parts[0])
 	if err != nil {
 		h.respondError(w, http.StatusBadRequest, "invalid mindmap id")
 		return
 	}
 
+	if len(parts) > 1 {
+		switch parts[1] {
+		case "revisions":
+			h.handleRevisions(w, r, id, parts[2:])
+		case "ops":
+			h.handleOps(w, r, id, parts[2:])
+		case "live":
+			h.handleLive(w, r, id, parts[2:])
+		case "members":
+			h.handleMembers(w, r, id, parts[2:])
+		case "links":
+			h.handleLinks(w, r, id, parts[2:])
+		case "transfer":
+			h.handleTransfer(w, r, id, parts[2:])
+		case "export":
+			h.handleExport(w, r, id, parts[2:])
+		case "render":
+			h.handleRender(w, r, id, parts[2:])
+		case "thumbnail":
+			h.handleThumbnail(w, r, id, parts[2:])
+		case "fork":
+			h.handleFork(w, r, id, parts[2:])
+		case "folder":
+			h.handleMindMapFolder(w, r, id, parts[2:])
+		case "comments":
+			h.handleComments(w, r, id, parts[2:])
+		case "attachments":
+			h.handleMindMapAttachments(w, r, id, parts[2:])
+		case "tags":
+			h.handleMindMapTags(w, r, id, parts[2:])
+		case "star":
+			h.handleStar(w, r, id, parts[2:])
+		case "backlinks":
+			h.handleBacklinks(w, r, id, parts[2:])
+		case "outlinks":
+			h.handleOutlinks(w, r, id, parts[2:])
+		case "nodes":
+			h.handleNodes(w, r, id, parts[2:])
+		case "diff":
+			h.handleDiff(w, r, id, parts[2:])
+		default:
+			h.respondError(w, http.StatusNotFound, "not found")
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		h.GetMindMap(w, r, id)
@@ -67,7 +186,10 @@ func (h *MindMapHandler) handleSingleMindMap(w http.ResponseWriter, r *http.Requ
 	}
 }
 
-// GetMindMaps - список
+// GetMindMaps - список своих карт и карт, к которым дали доступ, без документов.
+// Параметры: limit, cursor, sort=updated_at|created_at|title, order=asc|desc,
+// visibility=public|private, updated_since=RFC3339, folder_id={id}|root, tag_id,
+// starred=true, recent=true (открытые пользователем карты по времени открытия).
 func (h *MindMapHandler) GetMindMaps(w http.ResponseWriter, r *http.Request) {
 		user := middleware.GetUserFromContext(r.Context())
 	if user == nil {
@@ -75,46 +197,61 @@ func (h *MindMapHandler) GetMindMaps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	maps, err := h.mindMapRepo.GetMindMapsByUser(r.Context(), user.UserID)
+	opts, ok := h.listOptions(w, r)
+	if !ok {
+		return
+	}
+
+	items, next, err := h.mindMapRepo.ListAccessible(r.Context(), user.UserID, opts)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			h.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, maps)
+	var nextCursor *string
+	if next != nil {
+		c := repository.EncodeCursor(*next)
+		nextCursor = &c
+	}
+	h.respondJSON(w, http.StatusOK, map[string]any{
+		"items":       items,
+		"next_cursor": nextCursor,
+	})
 }
 
-// GetMindMap - один mindmap
+// GetMindMap - один mindmap. Открытие записывается для списка недавних карт.
 func (h *MindMapHandler) GetMindMap(w http.ResponseWriter, r *http.Request, id int) {
-	mindmap, err := h.mindMapRepo.GetMindMapByID(r.Context(), id)
-	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+	mindmap, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleViewer)
+	if !ok {
 		return
 	}
-	if mindmap == nil {
-		h.respondError(w, http.StatusNotFound, "mindmap not found")
-		return
+	if err := h.activityRepo.RecordOpen(r.Context(), user.UserID, id); err != nil {
+		h.logger.Printf("mindmap %d: %v", id, err)
 	}
 
-		user := middleware.GetUserFromContext(r.Context())
-	if user == nil || mindmap.UserID != user.UserID && user.Role != "admin" {
-		h.respondError(w, http.StatusForbidden, "forbidden")
+	if notModified(w, r, mindmap.Version) {
 		return
 	}
-
+	setETag(w, mindmap.Version)
 	h.respondJSON(w, http.StatusOK, mindmap)
 }
 
-// CreateMindMap
+// CreateMindMap. С параметром ?template= карта создается из шаблона.
 func (h *MindMapHandler) CreateMindMap(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Title string `json:"title"`
-		Data  string `json:"data"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	var req models.CreateMindMapRequest
+	templateID := r.URL.Query().Get("template")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !(templateID != "" && errors.Is(err, io.EOF)) {
 		h.respondError(w, http.StatusBadRequest, "invalid json")
 		return
 	}
+	if templateID != "" {
+		h.CreateMindMapFromTemplate(w, r, templateID, &req)
+		return
+	}
 	if req.Title == "" {
 		h.respondError(w, http.StatusBadRequest, "title required")
 		return
@@ -126,90 +263,331 @@ func (h *MindMapHandler) CreateMindMap(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Data == "" {
+		data, err := mindmap.New(req.Title).Encode()
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		req.Data = data
+	}
+	data, ok := h.normalizeData(w, r, req.Data)
+	if !ok {
+		return
+	}
+
 	mindmap := &models.MindMap{
-		Title:  req.Title,
-		Data:   req.Data,
-		UserID: user.UserID,
+		Title:    req.Title,
+		Data:     data,
+		UserID:   user.UserID,
+		IsPublic: req.IsPublic,
+		Role:     models.MindMapRoleOwner,
 	}
 	if err := h.mindMapRepo.CreateMindMap(r.Context(), mindmap); err != nil {
 		h.respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	setETag(w, mindmap.Version)
 	h.respondJSON(w, http.StatusCreated, mindmap)
 }
 
-// UpdateMindMap
+// UpdateMindMap - сохранение карты. Версия проверяется по If-Match; если клиент
+// передал base_version - версию, с которой начал редактирование, - и карту с тех
+// пор изменили, его правки сливаются с текущей версией, а при конфликте
+// возвращается 409 со списком конфликтов.
 func (h *MindMapHandler) UpdateMindMap(w http.ResponseWriter, r *http.Request, id int) {
 	var req struct {
-		Title string `json:"title"`
-		Data  string `json:"data"`
+		Title       string `json:"title"`
+		Data        string `json:"data"`
+		IsPublic    *bool  `json:"is_public"` // Не передано - видимость не меняется
+		BaseVersion *int   `json:"base_version"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.respondError(w, http.StatusBadRequest, "invalid json")
 		return
 	}
 
-		user := middleware.GetUserFromContext(r.Context())
-	if user == nil {
-		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+	mindmap, user, ok := h.loadMindMap(w, r, id, models.MindMapRoleEditor)
+	if !ok {
 		return
 	}
-
-	mindmap, err := h.mindMapRepo.GetMindMapByID(r.Context(), id)
-	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+	if req.BaseVersion == nil && !ifMatch(r, mindmap.Version) {
+		h.respondVersionConflict(w, mindmap.Version)
 		return
 	}
-	if mindmap == nil {
-		h.respondError(w, http.StatusNotFound, "mindmap not found")
+	// Публиковать карту может только владелец
+	if req.IsPublic != nil && *req.IsPublic != mindmap.IsPublic && mindmap.Role != models.MindMapRoleOwner {
+		h.respondError(w, http.StatusForbidden, "only the owner can change visibility")
 		return
 	}
-	if mindmap.UserID != user.UserID && user.Role != "admin" {
-		h.respondError(w, http.StatusForbidden, "forbidden")
+
+	data, ok := h.normalizeData(w, r, req.Data)
+	if !ok {
 		return
 	}
+	title := req.Title
+	if req.BaseVersion != nil && *req.BaseVersion != mindmap.Version {
+		if title, data, ok = h.mergeWithBase(w, r, mindmap, *req.BaseVersion, title, data); !ok {
+			return
+		}
+	}
 
-	mindmap.Title, mindmap.Data = req.Title, req.Data
-	if err := h.mindMapRepo.UpdateMindMap(r.Context(), mindmap); err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+	mindmap.Title, mindmap.Data = title, data
+	if req.IsPublic != nil {
+		mindmap.IsPublic = *req.IsPublic
+	}
+	if !h.saveMindMap(w, r, mindmap, user.UserID) {
 		return
 	}
 
+	setETag(w, mindmap.Version)
 	h.respondJSON(w, http.StatusOK, mindmap)
 }
 
-// DeleteMindMap
+// DeleteMindMap - удаление в корзину, восстановить можно через /api/trash
 func (h *MindMapHandler) DeleteMindMap(w http.ResponseWriter, r *http.Request, id int) {
-		user := middleware.GetUserFromContext(r.Context())
+	if _, _, ok := h.loadMindMap(w, r, id, models.MindMapRoleOwner); !ok {
+		return
+	}
+
+	if err := h.mindMapRepo.DeleteMindMap(r.Context(), id); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.collab.Close(id)
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// --- Helpers ---
+
+// Размер страницы списка карт
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// listOptions разбирает параметры списка карт. При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) listOptions(w http.ResponseWriter, r *http.Request) (repository.ListOptions, bool) {
+	query := r.URL.Query()
+	opts := repository.ListOptions{Limit: defaultListLimit}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			h.respondError(w, http.StatusBadRequest, "invalid limit")
+			return opts, false
+		}
+		opts.Limit = min(n, maxListLimit)
+	}
+
+	switch opts.Sort = query.Get("sort"); opts.Sort {
+	case "", repository.ListSortUpdatedAt, repository.ListSortCreatedAt, repository.ListSortTitle:
+	default:
+		h.respondError(w, http.StatusBadRequest, "sort must be updated_at, created_at or title")
+		return opts, false
+	}
+
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		opts.Asc = true
+	default:
+		h.respondError(w, http.StatusBadRequest, "order must be asc or desc")
+		return opts, false
+	}
+
+	switch opts.Visibility = query.Get("visibility"); opts.Visibility {
+	case "", repository.VisibilityPublic, repository.VisibilityPrivate:
+	default:
+		h.respondError(w, http.StatusBadRequest, "visibility must be public or private")
+		return opts, false
+	}
+
+	if v := query.Get("updated_since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "updated_since must be an RFC 3339 timestamp")
+			return opts, false
+		}
+		// Даты в БД хранятся без часового пояса, по местному времени сервера
+		t = t.Local()
+		opts.UpdatedSince = &t
+	}
+
+	switch v := query.Get("folder_id"); v {
+	case "":
+	case "root":
+		opts.RootFolder = true
+	default:
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			h.respondError(w, http.StatusBadRequest, "folder_id must be a folder id or root")
+			return opts, false
+		}
+		opts.FolderID = &n
+	}
+
+	if v := query.Get("tag_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			h.respondError(w, http.StatusBadRequest, "invalid tag_id")
+			return opts, false
+		}
+		opts.TagID = &n
+	}
+
+	switch query.Get("starred") {
+	case "", "false":
+	case "true":
+		opts.Starred = true
+	default:
+		h.respondError(w, http.StatusBadRequest, "starred must be true or false")
+		return opts, false
+	}
+
+	// Недавние - открытые пользователем карты, последние открытые первыми
+	switch query.Get("recent") {
+	case "", "false":
+	case "true":
+		if opts.Sort != "" {
+			h.respondError(w, http.StatusBadRequest, "sort cannot be combined with recent")
+			return opts, false
+		}
+		opts.Sort = repository.ListSortOpenedAt
+	default:
+		h.respondError(w, http.StatusBadRequest, "recent must be true or false")
+		return opts, false
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := repository.DecodeCursor(v)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, err.Error())
+			return opts, false
+		}
+		opts.After = cursor
+	}
+
+	return opts, true
+}
+
+// saveMindMap сохраняет карту через репозиторий. При конфликте версий отвечает
+// 412 с текущей версией на сервере. При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) saveMindMap(w http.ResponseWriter, r *http.Request, mindmap *models.MindMap, authorID int) bool {
+	if err := h.mindMapRepo.Update(r.Context(), mindmap, authorID); err != nil {
+		h.respondSaveError(w, r, mindmap.ID, err)
+		return false
+	}
+	return true
+}
+
+// respondSaveError отвечает на ошибку сохранения карты: 412 при конфликте версий,
+// 404, если карту успели удалить, иначе 500
+func (h *MindMapHandler) respondSaveError(w http.ResponseWriter, r *http.Request, id int, err error) {
+	if errors.Is(err, repository.ErrMindMapNotFound) {
+		h.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	current, err := h.mindMapRepo.GetByID(r.Context(), id)
+	if err != nil || current == nil {
+		h.respondError(w, http.StatusConflict, repository.ErrVersionConflict.Error())
+		return
+	}
+	h.respondVersionConflict(w, current.Version)
+}
+
+// respondVersionConflict - ответ 412 с текущей версией карты
+func (h *MindMapHandler) respondVersionConflict(w http.ResponseWriter, version int) {
+	setETag(w, version)
+	h.respondJSON(w, http.StatusPreconditionFailed, map[string]any{
+		"error":   "mindmap was modified by someone else",
+		"version": version,
+	})
+}
+
+// loadMindMap загружает карту и проверяет, что роль пользователя в ней не ниже required.
+// Роль записывается в mindmap.Role. При ошибке сам пишет ответ и возвращает false.
+func (h *MindMapHandler) loadMindMap(w http.ResponseWriter, r *http.Request, id int, required string) (*models.MindMap, *auth.Claims, bool) {
+	user := middleware.GetUserFromContext(r.Context())
 	if user == nil {
 		h.respondError(w, http.StatusUnauthorized, "unauthorized")
-		return
+		return nil, nil, false
 	}
 
 	mindmap, err := h.mindMapRepo.GetMindMapByID(r.Context(), id)
 	if err != nil {
 		h.respondError(w, http.StatusInternalServerError, err.Error())
-		return
+		return nil, nil, false
 	}
 	if mindmap == nil {
 		h.respondError(w, http.StatusNotFound, "mindmap not found")
-		return
+		return nil, nil, false
+	}
+
+	role, err := h.mindMapRole(r, mindmap, user)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, nil, false
 	}
-	if mindmap.UserID != user.UserID && user.Role != "admin" {
+	if !models.MindMapRoleAllows(role, required) {
 		h.respondError(w, http.StatusForbidden, "forbidden")
-		return
+		return nil, nil, false
 	}
 
-	if err := h.mindMapRepo.DeleteMindMap(r.Context(), id); err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error())
-		return
+	mindmap.Role = role
+	return mindmap, user, true
+}
+
+// mindMapRole возвращает роль пользователя в карте. Администратор имеет права владельца.
+// Пустая строка - у пользователя нет доступа.
+func (h *MindMapHandler) mindMapRole(r *http.Request, mindmap *models.MindMap, user *auth.Claims) (string, error) {
+	if mindmap.UserID == user.UserID || user.Role == "admin" {
+		return models.MindMapRoleOwner, nil
 	}
+	return h.memberRepo.GetRole(r.Context(), mindmap.ID, user.UserID)
+}
 
-	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+// normalizeData проверяет документ карты и возвращает его в нормализованном виде
+// (с uid у всех узлов, картинки из data URL вынесены в файлы). При ошибке сам
+// пишет ответ и возвращает false.
+func (h *MindMapHandler) normalizeData(w http.ResponseWriter, r *http.Request, data string) (string, bool) {
+	doc, err := mindmap.ParseString(data, mindmap.DefaultLimits)
+	if err != nil {
+		h.respondDataError(w, err)
+		return "", false
+	}
+	mindmap.EnsureUIDs(doc)
+	if !h.extractAttachments(w, r, doc) {
+		return "", false
+	}
+
+	normalized, err := doc.Encode()
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return "", false
+	}
+	return normalized, true
 }
 
-// --- Helpers ---
+// respondDataError - 422 с ошибками полей для документа, не прошедшего проверку, иначе 400
+func (h *MindMapHandler) respondDataError(w http.ResponseWriter, err error) {
+	var verr *mindmap.ValidationError
+	if errors.As(err, &verr) {
+		h.respondJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"error":  "invalid mindmap data",
+			"fields": verr.Errors,
+		})
+		return
+	}
+	h.respondError(w, http.StatusBadRequest, err.Error())
+}
 
 func (h *MindMapHandler) respondJSON(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
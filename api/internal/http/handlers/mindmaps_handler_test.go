@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/internal/attachments"
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/collab"
+	"github.com/mymindmap/api/internal/dbtest"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/repository"
+)
+
+// newTestMindMapHandler - обработчик карт поверх тестовой базы
+func newTestMindMapHandler(t *testing.T) (*MindMapHandler, *pgxpool.Pool) {
+	t.Helper()
+	db := dbtest.New(t)
+	logger := log.New(io.Discard, "", 0)
+	mindMaps := repository.NewMindMapRepository(db)
+	attachRepo := repository.NewAttachmentRepository(db)
+	return NewMindMapHandler(MindMapDeps{
+		MindMaps:    mindMaps,
+		Revisions:   repository.NewMindMapRevisionRepository(db),
+		Ops:         repository.NewMindMapOpsRepository(db),
+		Members:     repository.NewMindMapMemberRepository(db),
+		ShareLinks:  repository.NewMindMapShareLinkRepository(db),
+		Users:       repository.NewUserRepository(db),
+		Thumbnails:  repository.NewMindMapThumbnailRepository(db),
+		Templates:   repository.NewMindMapTemplateRepository(db),
+		Folders:     repository.NewFolderRepository(db),
+		Tags:        repository.NewTagRepository(db),
+		Activity:    repository.NewMindMapActivityRepository(db),
+		Attachments: attachRepo,
+		Files:       attachments.NewService(nil, attachRepo),
+		Comments:    repository.NewCommentRepository(db),
+		NodeLinks:   repository.NewNodeLinkRepository(db),
+		Collab:      collab.NewHub(mindMaps, nil, nil, logger),
+		Logger:      logger,
+	}), db
+}
+
+// userRequest - запрос от имени пользователя, как после AuthMiddleware
+func userRequest(method, target string, body any, userID int) *http.Request {
+	raw, _ := json.Marshal(body)
+	r := httptest.NewRequest(method, target, strings.NewReader(string(raw)))
+	ctx := context.WithValue(r.Context(), middleware.UserContextKey, &auth.Claims{UserID: userID})
+	return r.WithContext(ctx)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -88,6 +89,10 @@ func (h *PostHandler) GetPost(w http.ResponseWriter, r *http.Request, id int) {
 		h.respondError(w, http.StatusNotFound, "post not found")
 		return
 	}
+	if notModified(w, r, post.Version) {
+		return
+	}
+	setETag(w, post.Version)
 	h.respondJSON(w, http.StatusOK, post)
 }
 
@@ -118,6 +123,7 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, post.Version)
 	h.respondJSON(w, http.StatusCreated, post)
 }
 
@@ -151,17 +157,31 @@ func (h *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request, id int)
 		h.respondError(w, http.StatusForbidden, "forbidden")
 		return
 	}
+	if !ifMatch(r, post.Version) {
+		h.respondVersionConflict(w, post.Version)
+		return
+	}
 
 	post.Title, post.Content = req.Title, req.Content
 	if err := h.postRepo.UpdatePost(r.Context(), post); err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		if !errors.Is(err, repository.ErrVersionConflict) {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		current, err := h.postRepo.GetPostByID(r.Context(), id)
+		if err != nil || current == nil {
+			h.respondError(w, http.StatusNotFound, "post not found")
+			return
+		}
+		h.respondVersionConflict(w, current.Version)
 		return
 	}
 
+	setETag(w, post.Version)
 	h.respondJSON(w, http.StatusOK, post)
 }
 
-// DeletePost - удаление
+// DeletePost - удаление в корзину
 func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request, id int) {
 		user := middleware.GetUserFromContext(r.Context())
 	if user == nil {
@@ -201,6 +221,15 @@ func (h *PostHandler) respondJSON(w http.ResponseWriter, status int, data any) {
 	}
 }
 
+// respondVersionConflict - ответ 412 с текущей версией поста
+func (h *PostHandler) respondVersionConflict(w http.ResponseWriter, version int) {
+	setETag(w, version)
+	h.respondJSON(w, http.StatusPreconditionFailed, map[string]any{
+		"error":   "post was modified by someone else",
+		"version": version,
+	})
+}
+
 func (h *PostHandler) respondError(w http.ResponseWriter, status int, msg string) {
 	h.respondJSON(w, status, map[string]string{"error": msg})
 }
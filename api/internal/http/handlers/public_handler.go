@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// Пагинация галереи
+const (
+	defaultPublicPerPage = 20
+	maxPublicPerPage     = 100
+	maxPublicPage        = 1000 // Дальше смещение не имеет смысла и может переполниться
+)
+
+// publicViewWindow - повторные просмотры карты с одного IP в течение окна не считаются
+const publicViewWindow = 30 * time.Minute
+
+// PublicHandler - галерея публичных карт, доступна без авторизации
+type PublicHandler struct {
+	mindMapRepo *repository.MindMapRepository
+	logger      *log.Logger
+	viewLimiter *auth.RateLimiter // Уже засчитанные просмотры: ключ - карта и IP
+}
+
+func NewPublicHandler(mindMapRepo *repository.MindMapRepository, logger *log.Logger) *PublicHandler {
+	return &PublicHandler{
+		mindMapRepo: mindMapRepo,
+		logger:      logger,
+		viewLimiter: auth.NewRateLimiter(1, publicViewWindow, publicViewWindow),
+	}
+}
+
+func (h *PublicHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/public/mindmaps", h.handlePublicMindMaps)       // GET list
+	mux.HandleFunc("/api/public/mindmaps/", h.handleSinglePublicMindMap) // GET by id
+}
+
+// --- Handlers ---
+
+// handlePublicMindMaps -> /api/public/mindmaps
+func (h *PublicHandler) handlePublicMindMaps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.GetPublicMindMaps(w, r)
+}
+
+// handleSinglePublicMindMap -> /api/public/mindmaps/{id}
+func (h *PublicHandler) handleSinglePublicMindMap(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/public/mindmaps/"), "/"))
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid mindmap id")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.GetPublicMindMap(w, r, id)
+}
+
+// GetPublicMindMaps - страница галереи: ?page=1&per_page=20&sort=recent|popular
+func (h *PublicHandler) GetPublicMindMaps(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, ok := h.intParam(w, query.Get("page"), 1, "page")
+	if !ok {
+		return
+	}
+	if page > maxPublicPage {
+		h.respondError(w, http.StatusBadRequest, "invalid page")
+		return
+	}
+	perPage, ok := h.intParam(w, query.Get("per_page"), defaultPublicPerPage, "per_page")
+	if !ok {
+		return
+	}
+	if perPage > maxPublicPerPage {
+		perPage = maxPublicPerPage
+	}
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = models.PublicSortRecent
+	}
+	if sort != models.PublicSortRecent && sort != models.PublicSortPopular {
+		h.respondError(w, http.StatusBadRequest, "sort must be recent or popular")
+		return
+	}
+
+	items, total, err := h.mindMapRepo.GetPublicPage(r.Context(), sort, perPage, (page-1)*perPage)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{
+		"items":    items,
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+		"sort":     sort,
+	})
+}
+
+// GetPublicMindMap - публичная карта только для чтения
+func (h *PublicHandler) GetPublicMindMap(w http.ResponseWriter, r *http.Request, id int) {
+	mindmap, err := h.mindMapRepo.GetPublicByID(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if mindmap == nil {
+		h.respondError(w, http.StatusNotFound, "mindmap not found")
+		return
+	}
+
+	if notModified(w, r, mindmap.Version) {
+		return
+	}
+	h.countView(r, id)
+
+	setETag(w, mindmap.Version)
+	h.respondJSON(w, http.StatusOK, mindmap)
+}
+
+// --- Helpers ---
+
+// countView засчитывает просмотр карты не чаще раза в publicViewWindow с одного IP
+func (h *PublicHandler) countView(r *http.Request, id int) {
+	key := strconv.Itoa(id) + ":" + clientIP(r)
+	if !h.viewLimiter.IsAllowed(key) {
+		return
+	}
+	h.viewLimiter.RecordAttempt(key)
+	if err := h.mindMapRepo.IncrementViews(r.Context(), id); err != nil {
+		h.logger.Printf("public mindmap %d: %v", id, err)
+	}
+}
+
+// intParam разбирает положительный числовой параметр запроса.
+// При ошибке сам пишет ответ и возвращает false.
+func (h *PublicHandler) intParam(w http.ResponseWriter, value string, def int, name string) (int, bool) {
+	if value == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		h.respondError(w, http.StatusBadRequest, "invalid "+name)
+		return 0, false
+	}
+	return n, true
+}
+
+func (h *PublicHandler) respondJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Printf("json encode error: %v", err)
+	}
+}
+
+func (h *PublicHandler) respondError(w http.ResponseWriter, status int, msg string) {
+	h.respondJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/repository"
+)
+
+// Ограничения поиска
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	maxSearchQueryLen  = 200 // Символов в запросе
+)
+
+// SearchHandler - полнотекстовый поиск по картам пользователя
+type SearchHandler struct {
+	searchRepo  *repository.MindMapSearchRepository
+	authService *auth.AuthService
+	logger      *log.Logger
+}
+
+func NewSearchHandler(searchRepo *repository.MindMapSearchRepository, authService *auth.AuthService, logger *log.Logger) *SearchHandler {
+	return &SearchHandler{
+		searchRepo:  searchRepo,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+func (h *SearchHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/search", middleware.AuthMiddleware(h.authService, h.handleSearch)) // GET ?q=
+}
+
+// handleSearch -> /api/search
+func (h *SearchHandler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.Search(w, r)
+}
+
+// Search - карты, доступные пользователю, по запросу ?q= в тексте узлов, заметках и тегах.
+// Поддерживается синтаксис websearch: "точная фраза", -исключить, or.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		h.respondError(w, http.StatusBadRequest, "q required")
+		return
+	}
+	if utf8.RuneCountInString(q) > maxSearchQueryLen {
+		h.respondError(w, http.StatusBadRequest, "query is too long")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			h.respondError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			h.respondError(w, http.StatusBadRequest, "invalid offset")
+			return
+		}
+		offset = n
+	}
+
+	results, err := h.searchRepo.Search(r.Context(), user.UserID, user.Role == "admin", q, limit, offset)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{
+		"query":   q,
+		"results": results,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+func (h *SearchHandler) respondJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Printf("json encode error: %v", err)
+	}
+}
+
+func (h *SearchHandler) respondError(w http.ResponseWriter, status int, msg string) {
+	h.respondJSON(w, status, map[string]string{"error": msg})
+}
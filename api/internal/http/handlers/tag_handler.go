@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// Ограничения тегов
+const (
+	maxTagNameLen      = 50 // Символов в названии
+	maxMindMapTags     = 20 // Тегов пользователя на одной карте
+	defaultTagSuggests = 10 // Подсказок автодополнения
+	maxTagSuggests     = 50
+)
+
+var tagColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// TagHandler - личные теги пользователя для карт
+type TagHandler struct {
+	tagRepo     *repository.TagRepository
+	authService *auth.AuthService
+	logger      *log.Logger
+}
+
+func NewTagHandler(tagRepo *repository.TagRepository, authService *auth.AuthService, logger *log.Logger) *TagHandler {
+	return &TagHandler{
+		tagRepo:     tagRepo,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+func (h *TagHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/tags", middleware.AuthMiddleware(h.authService, h.handleTags))       // GET list (?q= - автодополнение), POST create
+	mux.HandleFunc("/api/tags/", middleware.AuthMiddleware(h.authService, h.handleSingleTag)) // PUT, DELETE by id
+}
+
+// handleTags -> /api/tags
+func (h *TagHandler) handleTags(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetTags(w, r)
+	case http.MethodPost:
+		h.CreateTag(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSingleTag -> /api/tags/{id}
+func (h *TagHandler) handleSingleTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tags/"), "/"))
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid tag id")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		h.UpdateTag(w, r, id)
+	case http.MethodDelete:
+		h.DeleteTag(w, r, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetTags - теги пользователя с числом карт. С ?q= - автодополнение: теги,
+// начинающиеся с q, самые используемые первыми, не больше limit.
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	prefix := strings.TrimSpace(query.Get("q"))
+	limit := 0
+	if prefix != "" {
+		limit = defaultTagSuggests
+		if v := query.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				h.respondError(w, http.StatusBadRequest, "invalid limit")
+				return
+			}
+			limit = min(n, maxTagSuggests)
+		}
+	}
+
+	tags, err := h.tagRepo.List(r.Context(), user.UserID, prefix, limit)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, tags)
+}
+
+// CreateTag - новый тег
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	tag, ok := h.decodeTag(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.tagRepo.Create(r.Context(), tag); err != nil {
+		h.respondTagError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, tag)
+}
+
+// UpdateTag - меняет название и цвет тега
+func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request, id int) {
+	tag, ok := h.decodeTag(w, r)
+	if !ok {
+		return
+	}
+
+	tag.ID = id
+	if err := h.tagRepo.Update(r.Context(), tag); err != nil {
+		h.respondTagError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, tag)
+}
+
+// DeleteTag - удаляет тег и снимает его со всех карт
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request, id int) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.tagRepo.Delete(r.Context(), id, user.UserID); err != nil {
+		h.respondTagError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// decodeTag читает и проверяет тег из тела запроса. При ошибке сам пишет ответ и возвращает false.
+func (h *TagHandler) decodeTag(w http.ResponseWriter, r *http.Request) (*models.Tag, bool) {
+	var req struct {
+		Name  string  `json:"name"`
+		Color *string `json:"color"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return nil, false
+	}
+	name, msg := normalizeTagName(req.Name)
+	if msg != "" {
+		h.respondError(w, http.StatusBadRequest, msg)
+		return nil, false
+	}
+	if req.Color != nil && !tagColor.MatchString(*req.Color) {
+		h.respondError(w, http.StatusBadRequest, "color must be #RRGGBB")
+		return nil, false
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+
+	return &models.Tag{UserID: user.UserID, Name: name, Color: req.Color}, true
+}
+
+func (h *TagHandler) respondTagError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrTagNotFound):
+		h.respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, repository.ErrTagExists):
+		h.respondError(w, http.StatusConflict, err.Error())
+	default:
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (h *TagHandler) respondJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Printf("json encode error: %v", err)
+	}
+}
+
+func (h *TagHandler) respondError(w http.ResponseWriter, status int, msg string) {
+	h.respondJSON(w, status, map[string]string{"error": msg})
+}
+
+// normalizeTagName убирает лишние пробелы в названии тега и проверяет его.
+// Вторым значением возвращает текст ошибки или пустую строку.
+func normalizeTagName(name string) (string, string) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", "tag name required"
+	}
+	if utf8.RuneCountInString(name) > maxTagNameLen {
+		return "", "tag name is too long"
+	}
+	return name, ""
+}
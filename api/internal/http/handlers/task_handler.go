@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/internal/tasks"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// Ограничения списка задач
+const (
+	defaultTaskLimit = 100
+	maxTaskLimit     = 500
+)
+
+// calendarPath - адрес подписки на календарь задач: /api/tasks/calendar/{token}.ics
+const calendarPath = "/api/tasks/calendar/"
+
+// TaskHandler - задачи из узлов карт пользователя и подписка на их календарь
+type TaskHandler struct {
+	taskRepo    *repository.TaskRepository
+	authService *auth.AuthService
+	logger      *log.Logger
+}
+
+func NewTaskHandler(taskRepo *repository.TaskRepository, authService *auth.AuthService, logger *log.Logger) *TaskHandler {
+	return &TaskHandler{
+		taskRepo:    taskRepo,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+func (h *TaskHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/tasks", middleware.AuthMiddleware(h.authService, h.handleTasks))                        // GET list
+	mux.HandleFunc("/api/tasks/calendar-token", middleware.AuthMiddleware(h.authService, h.handleCalendarToken)) // POST create/rotate, DELETE revoke
+	mux.HandleFunc(calendarPath, h.handleCalendar)                                                               // GET {token}.ics, без авторизации
+}
+
+// handleTasks -> /api/tasks
+func (h *TaskHandler) handleTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.ListTasks(w, r)
+}
+
+// handleCalendarToken -> /api/tasks/calendar-token
+func (h *TaskHandler) handleCalendarToken(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.CreateCalendarToken(w, r)
+	case http.MethodDelete:
+		h.DeleteCalendarToken(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCalendar -> /api/tasks/calendar/{token}.ics
+func (h *TaskHandler) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, calendarPath), ".ics")
+	if !ok || token == "" || strings.Contains(token, "/") {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	h.GetCalendar(w, r, token)
+}
+
+// ListTasks - задачи карт, доступных пользователю. Параметры:
+// assignee=me|none|{id}, status=open|done|all (по умолчанию open), mindmap_id,
+// due_from и due_to - дата "2006-01-02" или время RFC 3339 (дата в due_to включительно), limit.
+func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.TaskFilter{UserID: user.UserID, Status: models.TaskStatusOpen, Limit: defaultTaskLimit}
+
+	switch v := query.Get("assignee"); v {
+	case "":
+	case "me":
+		filter.AssigneeID = &user.UserID
+	case "none":
+		filter.Unassigned = true
+	default:
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			h.respondError(w, http.StatusBadRequest, "invalid assignee")
+			return
+		}
+		filter.AssigneeID = &id
+	}
+
+	if v := query.Get("status"); v != "" {
+		if v != models.TaskStatusOpen && v != models.TaskStatusDone && v != models.TaskStatusAll {
+			h.respondError(w, http.StatusBadRequest, "invalid status")
+			return
+		}
+		filter.Status = v
+	}
+
+	if v := query.Get("mindmap_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			h.respondError(w, http.StatusBadRequest, "invalid mindmap_id")
+			return
+		}
+		filter.MindMapID = &id
+	}
+
+	if v := query.Get("due_from"); v != "" {
+		from, _, ok := tasks.ParseDue(v)
+		if !ok {
+			h.respondError(w, http.StatusBadRequest, "invalid due_from")
+			return
+		}
+		filter.DueFrom = &from
+	}
+	if v := query.Get("due_to"); v != "" {
+		to, allDay, ok := tasks.ParseDue(v)
+		if !ok {
+			h.respondError(w, http.StatusBadRequest, "invalid due_to")
+			return
+		}
+		if allDay {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.DueTo = &to
+	}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			h.respondError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		filter.Limit = min(n, maxTaskLimit)
+	}
+
+	list, err := h.taskRepo.List(r.Context(), filter)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{
+		"tasks": list,
+		"limit": filter.Limit,
+	})
+}
+
+// CreateCalendarToken - выпускает адрес подписки на календарь задач. Токен
+// показывается один раз; повторный вызов заменяет прежний адрес новым.
+func (h *TaskHandler) CreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	token, err := h.taskRepo.CreateCalendarToken(r.Context(), user.UserID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, map[string]any{
+		"token": token,
+		"url":   calendarPath + token + ".ics",
+	})
+}
+
+// DeleteCalendarToken - отключает подписку на календарь задач
+func (h *TaskHandler) DeleteCalendarToken(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	deleted, err := h.taskRepo.DeleteCalendarToken(r.Context(), user.UserID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		h.respondError(w, http.StatusNotFound, "calendar token not found")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// GetCalendar - календарь iCalendar с открытыми задачами со сроком: назначенными
+// владельцу токена и задачами без ответственного в его картах. Доступ по токену
+// из адреса, календарные приложения не передают авторизацию.
+func (h *TaskHandler) GetCalendar(w http.ResponseWriter, r *http.Request, token string) {
+	userID, err := h.taskRepo.CalendarUser(r.Context(), token)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if userID == 0 {
+		h.respondError(w, http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	list, err := h.taskRepo.CalendarTasks(r.Context(), userID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	events := make([]tasks.Event, 0, len(list))
+	for _, t := range list {
+		events = append(events, tasks.Event{
+			UID:         "task-" + strconv.Itoa(t.MindMapID) + "-" + t.NodeUID + "@mymindmap",
+			Summary:     t.Title,
+			Description: "Карта: " + t.MindMapTitle,
+			Start:       *t.DueAt,
+			AllDay:      t.DueAllDay,
+			Updated:     t.UpdatedAt,
+		})
+	}
+
+	body := tasks.ICS("Задачи", events)
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(body); err != nil {
+		h.logger.Printf("calendar write error: %v", err)
+	}
+}
+
+func (h *TaskHandler) respondJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Printf("json encode error: %v", err)
+	}
+}
+
+func (h *TaskHandler) respondError(w http.ResponseWriter, status int, msg string) {
+	h.respondJSON(w, status, map[string]string{"error": msg})
+}
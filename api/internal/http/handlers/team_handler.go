@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+const maxTeamNameLen = 255 // Символов в названии команды
+
+// TeamHandler - команды пользователей с общими шаблонами карт
+type TeamHandler struct {
+	teamRepo    *repository.TeamRepository
+	userRepo    *repository.UserRepository
+	authService *auth.AuthService
+	logger      *log.Logger
+}
+
+func NewTeamHandler(teamRepo *repository.TeamRepository, userRepo *repository.UserRepository, authService *auth.AuthService, logger *log.Logger) *TeamHandler {
+	return &TeamHandler{
+		teamRepo:    teamRepo,
+		userRepo:    userRepo,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+func (h *TeamHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/teams", middleware.AuthMiddleware(h.authService, h.handleTeams))       // GET list, POST create
+	mux.HandleFunc("/api/teams/", middleware.AuthMiddleware(h.authService, h.handleSingleTeam)) // DELETE by id, участники
+}
+
+// handleTeams -> /api/teams
+func (h *TeamHandler) handleTeams(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetTeams(w, r)
+	case http.MethodPost:
+		h.CreateTeam(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSingleTeam -> /api/teams/{id}, /api/teams/{id}/members[/{userId}]
+func (h *TeamHandler) handleSingleTeam(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/teams/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid team id")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.DeleteTeam(w, r, id)
+	case parts[1] == "members" && len(parts) == 2:
+		switch r.Method {
+		case http.MethodGet:
+			h.GetTeamMembers(w, r, id)
+		case http.MethodPost:
+			h.AddTeamMember(w, r, id)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	case parts[1] == "members" && len(parts) == 3:
+		userID, err := strconv.Atoi(parts[2])
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid user id")
+			return
+		}
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.RemoveTeamMember(w, r, id, userID)
+	default:
+		h.respondError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// GetTeams - команды, в которых состоит пользователь
+func (h *TeamHandler) GetTeams(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	teams, err := h.teamRepo.ListForUser(r.Context(), user.UserID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, teams)
+}
+
+// CreateTeam - новая команда, создатель становится ее владельцем
+func (h *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		h.respondError(w, http.StatusBadRequest, "name required")
+		return
+	}
+	if utf8.RuneCountInString(req.Name) > maxTeamNameLen {
+		h.respondError(w, http.StatusBadRequest, "name is too long")
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	team := &models.Team{Name: req.Name}
+	if err := h.teamRepo.Create(r.Context(), team, user.UserID); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, team)
+}
+
+// DeleteTeam - удаляет команду вместе с ее шаблонами. Только владелец.
+func (h *TeamHandler) DeleteTeam(w http.ResponseWriter, r *http.Request, id int) {
+	if _, ok := h.checkTeamRole(w, r, id, models.TeamRoleOwner); !ok {
+		return
+	}
+
+	if err := h.teamRepo.Delete(r.Context(), id); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// GetTeamMembers - участники команды, видны всем участникам
+func (h *TeamHandler) GetTeamMembers(w http.ResponseWriter, r *http.Request, id int) {
+	if _, ok := h.checkTeamRole(w, r, id, models.TeamRoleMember); !ok {
+		return
+	}
+
+	members, err := h.teamRepo.ListMembers(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, members)
+}
+
+// AddTeamMember - добавляет в команду зарегистрированного пользователя по email
+func (h *TeamHandler) AddTeamMember(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" {
+		h.respondError(w, http.StatusBadRequest, "email required")
+		return
+	}
+
+	if _, ok := h.checkTeamRole(w, r, id, models.TeamRoleOwner); !ok {
+		return
+	}
+
+	invitee, err := h.userRepo.GetUserByEmail(r.Context(), email)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if invitee == nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	member := &models.TeamMember{
+		TeamID: id,
+		UserID: invitee.ID,
+		Name:   invitee.Name,
+		Email:  invitee.Email,
+		Role:   models.TeamRoleMember,
+	}
+	if err := h.teamRepo.AddMember(r.Context(), member); err != nil {
+		if errors.Is(err, repository.ErrMemberExists) {
+			h.respondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, member)
+}
+
+// RemoveTeamMember - исключает участника. Владелец может исключить любого,
+// участник - только выйти сам.
+func (h *TeamHandler) RemoveTeamMember(w http.ResponseWriter, r *http.Request, id, userID int) {
+	user, ok := h.checkTeamRole(w, r, id, models.TeamRoleMember)
+	if !ok {
+		return
+	}
+	if userID != user.UserID {
+		if _, ok := h.checkTeamRole(w, r, id, models.TeamRoleOwner); !ok {
+			return
+		}
+	}
+
+	if err := h.teamRepo.RemoveMember(r.Context(), id, userID); err != nil {
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			h.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// checkTeamRole проверяет, что пользователь состоит в команде, а для required = owner -
+// что он ее владелец. Администратор имеет права владельца. Не участнику команда не
+// видна: 404. При ошибке сам пишет ответ и возвращает false.
+func (h *TeamHandler) checkTeamRole(w http.ResponseWriter, r *http.Request, id int, required string) (*auth.Claims, bool) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	if user.Role == "admin" {
+		return user, true
+	}
+
+	role, err := h.teamRepo.GetRole(r.Context(), id, user.UserID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if role == "" {
+		h.respondError(w, http.StatusNotFound, "team not found")
+		return nil, false
+	}
+	if required == models.TeamRoleOwner && role != models.TeamRoleOwner {
+		h.respondError(w, http.StatusForbidden, "forbidden")
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *TeamHandler) respondJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Printf("json encode error: %v", err)
+	}
+}
+
+func (h *TeamHandler) respondError(w http.ResponseWriter, status int, msg string) {
+	h.respondJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/internal/templates"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// Ограничения шаблонов
+const (
+	maxTemplateTitleLen       = 255  // Символов в названии
+	maxTemplateDescriptionLen = 1000 // Символов в описании
+)
+
+// TemplateHandler - библиотека шаблонов карт: системные, личные и командные
+type TemplateHandler struct {
+	templateRepo *repository.MindMapTemplateRepository
+	teamRepo     *repository.TeamRepository
+	mindMapRepo  *repository.MindMapRepository
+	memberRepo   *repository.MindMapMemberRepository
+	authService  *auth.AuthService
+	logger       *log.Logger
+}
+
+func NewTemplateHandler(templateRepo *repository.MindMapTemplateRepository, teamRepo *repository.TeamRepository, mindMapRepo *repository.MindMapRepository, memberRepo *repository.MindMapMemberRepository, authService *auth.AuthService, logger *log.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		templateRepo: templateRepo,
+		teamRepo:     teamRepo,
+		mindMapRepo:  mindMapRepo,
+		memberRepo:   memberRepo,
+		authService:  authService,
+		logger:       logger,
+	}
+}
+
+func (h *TemplateHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/templates", middleware.AuthMiddleware(h.authService, h.handleTemplates))       // GET list, POST save map as template
+	mux.HandleFunc("/api/templates/", middleware.AuthMiddleware(h.authService, h.handleSingleTemplate)) // GET, DELETE by id
+}
+
+// handleTemplates -> /api/templates
+func (h *TemplateHandler) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetTemplates(w, r)
+	case http.MethodPost:
+		h.CreateTemplate(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSingleTemplate -> /api/templates/{id}
+func (h *TemplateHandler) handleSingleTemplate(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/templates/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.GetTemplate(w, r, id)
+	case http.MethodDelete:
+		h.DeleteTemplate(w, r, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetTemplates - системные шаблоны, личные шаблоны пользователя и шаблоны его команд, без документов
+func (h *TemplateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	own, err := h.templateRepo.ListAccessible(r.Context(), user.UserID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	system := templates.System()
+	result := make([]*models.MindMapTemplate, 0, len(system)+len(own))
+	for _, t := range system {
+		tmpl := systemTemplate(t)
+		tmpl.Data = ""
+		result = append(result, tmpl)
+	}
+	result = append(result, own...)
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+// GetTemplate - шаблон с документом
+func (h *TemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request, id string) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	tmpl, err := findTemplate(r.Context(), h.templateRepo, id, user.UserID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if tmpl == nil {
+		h.respondError(w, http.StatusNotFound, repository.ErrTemplateNotFound.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, tmpl)
+}
+
+// CreateTemplate - сохраняет карту как личный шаблон или, если передан team_id, как шаблон команды
+func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MindMapID   int    `json:"mindmap_id"`
+		Title       string `json:"title"` // Не передано - название карты
+		Description string `json:"description"`
+		TeamID      *int   `json:"team_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.MindMapID == 0 {
+		h.respondError(w, http.StatusBadRequest, "mindmap_id required")
+		return
+	}
+	req.Title, req.Description = strings.TrimSpace(req.Title), strings.TrimSpace(req.Description)
+	if utf8.RuneCountInString(req.Title) > maxTemplateTitleLen {
+		h.respondError(w, http.StatusBadRequest, "title is too long")
+		return
+	}
+	if utf8.RuneCountInString(req.Description) > maxTemplateDescriptionLen {
+		h.respondError(w, http.StatusBadRequest, "description is too long")
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	source, ok := h.loadSourceMindMap(w, r, req.MindMapID, user)
+	if !ok {
+		return
+	}
+	if req.TeamID != nil {
+		role, err := h.teamRepo.GetRole(r.Context(), *req.TeamID, user.UserID)
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if role == "" {
+			h.respondError(w, http.StatusForbidden, "not a member of the team")
+			return
+		}
+	}
+	if req.Title == "" {
+		req.Title = source.Title
+	}
+
+	doc, err := mindmap.ParseString(source.Data, mindmap.DefaultLimits)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tmpl := &models.MindMapTemplate{
+		Title:       req.Title,
+		Description: req.Description,
+		Variables:   templates.Variables(req.Title, doc),
+		UserID:      &user.UserID,
+		TeamID:      req.TeamID,
+		Data:        source.Data,
+	}
+	if err := h.templateRepo.Create(r.Context(), tmpl); err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, tmpl)
+}
+
+// DeleteTemplate - удаляет шаблон. Удалить может автор, а шаблон команды - и ее владелец.
+// Системные шаблоны не удаляются.
+func (h *TemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request, id string) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	tmpl, err := findTemplate(r.Context(), h.templateRepo, id, user.UserID)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if tmpl == nil {
+		h.respondError(w, http.StatusNotFound, repository.ErrTemplateNotFound.Error())
+		return
+	}
+	if tmpl.Scope == models.TemplateScopeSystem {
+		h.respondError(w, http.StatusForbidden, "system templates cannot be deleted")
+		return
+	}
+
+	allowed := *tmpl.UserID == user.UserID || user.Role == "admin"
+	if !allowed && tmpl.TeamID != nil {
+		role, err := h.teamRepo.GetRole(r.Context(), *tmpl.TeamID, user.UserID)
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		allowed = role == models.TeamRoleOwner
+	}
+	if !allowed {
+		h.respondError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	templateID, _ := strconv.Atoi(tmpl.ID)
+	if err := h.templateRepo.Delete(r.Context(), templateID); err != nil {
+		if errors.Is(err, repository.ErrTemplateNotFound) {
+			h.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// loadSourceMindMap загружает карту, из которой сохраняется шаблон: пользователь должен
+// иметь к ней доступ хотя бы на чтение. При ошибке сам пишет ответ и возвращает false.
+func (h *TemplateHandler) loadSourceMindMap(w http.ResponseWriter, r *http.Request, id int, user *auth.Claims) (*models.MindMap, bool) {
+	source, err := h.mindMapRepo.GetMindMapByID(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if source == nil {
+		h.respondError(w, http.StatusNotFound, "mindmap not found")
+		return nil, false
+	}
+
+	if source.UserID != user.UserID && user.Role != "admin" {
+		role, err := h.memberRepo.GetRole(r.Context(), id, user.UserID)
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return nil, false
+		}
+		if !models.MindMapRoleAllows(role, models.MindMapRoleViewer) {
+			h.respondError(w, http.StatusForbidden, "forbidden")
+			return nil, false
+		}
+	}
+	return source, true
+}
+
+func (h *TemplateHandler) respondJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Printf("json encode error: %v", err)
+	}
+}
+
+func (h *TemplateHandler) respondError(w http.ResponseWriter, status int, msg string) {
+	h.respondJSON(w, status, map[string]string{"error": msg})
+}
+
+// findTemplate ищет шаблон с документом: числовой id - в БД среди доступных
+// пользователю, иначе среди системных. nil - шаблон не найден.
+func findTemplate(ctx context.Context, repo *repository.MindMapTemplateRepository, id string, userID int) (*models.MindMapTemplate, error) {
+	if n, err := strconv.Atoi(id); err == nil {
+		return repo.GetAccessible(ctx, n, userID)
+	}
+	t, ok := templates.SystemByID(id)
+	if !ok {
+		return nil, nil
+	}
+	return systemTemplate(t), nil
+}
+
+func systemTemplate(t templates.Template) *models.MindMapTemplate {
+	return &models.MindMapTemplate{
+		ID:          t.ID,
+		Scope:       models.TemplateScopeSystem,
+		Title:       t.Title,
+		Description: t.Description,
+		Variables:   t.Variables,
+		Data:        t.Data,
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mymindmap/api/internal/auth"
+	"github.com/mymindmap/api/internal/http/middleware"
+	"github.com/mymindmap/api/models"
+	"github.com/mymindmap/api/repository"
+)
+
+// trashStore - операции корзины, общие для карт и постов
+type trashStore interface {
+	ListTrashed(ctx context.Context, userID int) ([]*models.TrashItem, error)
+	GetTrashed(ctx context.Context, id int) (*models.TrashItem, error)
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int) error
+}
+
+// TrashHandler - корзина удаленных карт и постов пользователя
+type TrashHandler struct {
+	stores      map[string]trashStore // Часть пути (mindmaps, posts) -> хранилище
+	retention   time.Duration
+	authService *auth.AuthService
+	logger      *log.Logger
+}
+
+func NewTrashHandler(mindMapRepo *repository.MindMapRepository, postRepo *repository.PostRepository, retention time.Duration, authService *auth.AuthService, logger *log.Logger) *TrashHandler {
+	return &TrashHandler{
+		stores: map[string]trashStore{
+			"mindmaps": mindMapRepo,
+			"posts":    postRepo,
+		},
+		retention:   retention,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+func (h *TrashHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/trash", middleware.AuthMiddleware(h.authService, h.handleTrash))      // GET list
+	mux.HandleFunc("/api/trash/", middleware.AuthMiddleware(h.authService, h.handleTrashItem)) // DELETE purge, POST .../restore
+}
+
+// handleTrash -> /api/trash
+func (h *TrashHandler) handleTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.GetTrash(w, r)
+}
+
+// handleTrashItem -> /api/trash/{mindmaps|posts}/{id}, /api/trash/{mindmaps|posts}/{id}/restore
+func (h *TrashHandler) handleTrashItem(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/trash/"), "/"), "/")
+	store, ok := h.stores[parts[0]]
+	if !ok || len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "restore") {
+		h.respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	switch {
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		h.RestoreItem(w, r, store, id)
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		h.PurgeItem(w, r, store, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetTrash - карты и посты пользователя в корзине, недавно удаленные первыми,
+// с датой окончательного удаления
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	items := []*models.TrashItem{}
+	for _, name := range []string{"mindmaps", "posts"} {
+		list, err := h.stores[name].ListTrashed(r.Context(), user.UserID)
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		items = append(items, list...)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+	for _, item := range items {
+		item.PurgeAt = item.DeletedAt.Add(h.retention)
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{
+		"items":          items,
+		"retention_days": int(h.retention / (24 * time.Hour)),
+	})
+}
+
+// RestoreItem - возвращает элемент из корзины
+func (h *TrashHandler) RestoreItem(w http.ResponseWriter, r *http.Request, store trashStore, id int) {
+	item, ok := h.loadItem(w, r, store, id)
+	if !ok {
+		return
+	}
+
+	if err := store.Restore(r.Context(), id); err != nil {
+		h.respondTrashError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"type": item.Type, "id": id, "restored": true})
+}
+
+// PurgeItem - окончательно удаляет элемент из корзины
+func (h *TrashHandler) PurgeItem(w http.ResponseWriter, r *http.Request, store trashStore, id int) {
+	if _, ok := h.loadItem(w, r, store, id); !ok {
+		return
+	}
+
+	if err := store.Purge(r.Context(), id); err != nil {
+		h.respondTrashError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// loadItem загружает элемент корзины и проверяет, что он принадлежит пользователю.
+// Администратор может восстановить и удалить любой. При ошибке сам пишет ответ и возвращает false.
+func (h *TrashHandler) loadItem(w http.ResponseWriter, r *http.Request, store trashStore, id int) (*models.TrashItem, bool) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.respondError(w, http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+
+	item, err := store.GetTrashed(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if item == nil || (item.UserID != user.UserID && user.Role != "admin") {
+		h.respondError(w, http.StatusNotFound, repository.ErrNotInTrash.Error())
+		return nil, false
+	}
+	return item, true
+}
+
+func (h *TrashHandler) respondTrashError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrNotInTrash) {
+		h.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.respondError(w, http.StatusInternalServerError, err.Error())
+}
+
+func (h *TrashHandler) respondJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Printf("json encode error: %v", err)
+	}
+}
+
+func (h *TrashHandler) respondError(w http.ResponseWriter, status int, msg string) {
+	h.respondJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,247 @@
+// Package mapdiff сравнивает версии документа карты по uid узлов и сливает
+// две версии, разошедшиеся от общего предка.
+package mapdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// Типы изменений узла
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeMoved   = "moved" // Другой родитель или другое место среди прежних соседей
+	ChangeText    = "text"  // Текст узла
+	ChangeStyle   = "style" // Остальные поля данных: оформление, заметка, ссылка, иконки, теги
+)
+
+// textFields - поля данных, изменение которых считается изменением текста
+var textFields = map[string]bool{"text": true, "richText": true}
+
+// Change - изменение узла. Один узел может быть и перемещен, и изменен.
+type Change struct {
+	Type      string   `json:"type"`
+	UID       string   `json:"uid"`
+	Text      string   `json:"text"`                 // Текст без разметки; для removed - в старой версии
+	OldText   string   `json:"old_text,omitempty"`   // Для text
+	Parent    string   `json:"parent,omitempty"`     // Для added и moved
+	OldParent string   `json:"old_parent,omitempty"` // Для moved
+	Fields    []string `json:"fields,omitempty"`     // Для style: измененные поля данных
+}
+
+// Diff - разница между двумя версиями документа
+type Diff struct {
+	Changes       []Change `json:"changes"`
+	LayoutChanged bool     `json:"layout_changed"`
+	ThemeChanged  bool     `json:"theme_changed"`
+}
+
+// Compute сравнивает документы from и to. Изменения идут в порядке обхода to,
+// удаленные узлы - в конце в порядке обхода from.
+func Compute(from, to *mindmap.Document) *Diff {
+	a, b := flatten(from), flatten(to)
+	diff := &Diff{
+		Changes:       []Change{},
+		LayoutChanged: from.Layout != to.Layout,
+		ThemeChanged:  !jsonEqual(from.Theme, to.Theme),
+	}
+
+	reordered := make(map[string]bool)
+	for parent, children := range b.children {
+		for _, uid := range reorderedChildren(a, b, parent, children) {
+			reordered[uid] = true
+		}
+	}
+
+	for _, uid := range b.order {
+		node := b.nodes[uid]
+		text := plainText(node.Data)
+		old, existed := a.nodes[uid]
+		if !existed {
+			diff.Changes = append(diff.Changes, Change{Type: ChangeAdded, UID: uid, Text: text, Parent: node.Parent})
+			continue
+		}
+		if old.Parent != node.Parent || reordered[uid] {
+			diff.Changes = append(diff.Changes, Change{Type: ChangeMoved, UID: uid, Text: text, Parent: node.Parent, OldParent: old.Parent})
+		}
+
+		var textChanged bool
+		var style []string
+		for _, field := range changedFields(old.Data, node.Data) {
+			if textFields[field] {
+				textChanged = true
+			} else {
+				style = append(style, field)
+			}
+		}
+		if textChanged {
+			diff.Changes = append(diff.Changes, Change{Type: ChangeText, UID: uid, Text: text, OldText: plainText(old.Data)})
+		}
+		if len(style) > 0 {
+			diff.Changes = append(diff.Changes, Change{Type: ChangeStyle, UID: uid, Text: text, Fields: style})
+		}
+	}
+
+	for _, uid := range a.order {
+		if _, ok := b.nodes[uid]; !ok {
+			diff.Changes = append(diff.Changes, Change{Type: ChangeRemoved, UID: uid, Text: plainText(a.nodes[uid].Data)})
+		}
+	}
+	return diff
+}
+
+// flatDoc - плоское представление документа с быстрым доступом по uid
+type flatDoc struct {
+	nodes    map[string]mindmap.FlatNode
+	children map[string][]string // uid родителя -> uid детей по порядку
+	order    []string            // Прямой порядок обхода
+}
+
+func flatten(doc *mindmap.Document) flatDoc {
+	flat := mindmap.Flatten(doc)
+	f := flatDoc{
+		nodes:    make(map[string]mindmap.FlatNode, len(flat)),
+		children: make(map[string][]string),
+		order:    make([]string, 0, len(flat)),
+	}
+	for _, n := range flat {
+		f.nodes[n.UID] = n
+		f.order = append(f.order, n.UID)
+		if n.Parent != "" {
+			f.children[n.Parent] = append(f.children[n.Parent], n.UID)
+		}
+	}
+	return f
+}
+
+// commonChildren возвращает детей parent в x, которые в y тоже дети parent
+func commonChildren(x, y flatDoc, parent string) []string {
+	var common []string
+	for _, uid := range x.children[parent] {
+		if n, ok := y.nodes[uid]; ok && n.Parent == parent {
+			common = append(common, uid)
+		}
+	}
+	return common
+}
+
+// reorderedChildren возвращает детей parent, которые остались у него, но поменяли
+// порядок относительно остальных. Неподвижными считается наибольшая часть детей,
+// сохранившая взаимный порядок, перемещенными - остальные.
+func reorderedChildren(a, b flatDoc, parent string, children []string) []string {
+	before := commonChildren(a, b, parent)
+	if len(before) < 2 {
+		return nil
+	}
+	pos := make(map[string]int, len(before))
+	for i, uid := range before {
+		pos[uid] = i
+	}
+	var after []string
+	for _, uid := range children {
+		if _, ok := pos[uid]; ok {
+			after = append(after, uid)
+		}
+	}
+
+	stay := longestIncreasing(after, pos)
+	var moved []string
+	for _, uid := range after {
+		if !stay[uid] {
+			moved = append(moved, uid)
+		}
+	}
+	return moved
+}
+
+// longestIncreasing возвращает наибольшую подпоследовательность seq, идущую
+// в порядке pos (O(n log n))
+func longestIncreasing(seq []string, pos map[string]int) map[string]bool {
+	tails := []int{}              // Индексы в seq концов подпоследовательностей каждой длины
+	prev := make([]int, len(seq)) // Предыдущий элемент подпоследовательности
+	for i, uid := range seq {
+		p := pos[uid]
+		k := sort.Search(len(tails), func(j int) bool { return pos[seq[tails[j]]] >= p })
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	result := make(map[string]bool, len(tails))
+	if len(tails) == 0 {
+		return result
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+		result[seq[i]] = true
+	}
+	return result
+}
+
+// dataFields возвращает поля данных узла в JSON-представлении без uid
+func dataFields(d mindmap.NodeData) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if b, err := json.Marshal(d); err == nil {
+		_ = json.Unmarshal(b, &fields)
+	}
+	delete(fields, "uid")
+	return fields
+}
+
+// changedFields возвращает отсортированные имена полей, которые различаются в a и b
+func changedFields(a, b mindmap.NodeData) []string {
+	if mindmap.DataEqual(a, b) {
+		return nil
+	}
+	fa, fb := dataFields(a), dataFields(b)
+	var changed []string
+	for _, k := range unionKeys(fa, fb) {
+		if !rawEqual(fa[k], fb[k]) {
+			changed = append(changed, k)
+		}
+	}
+	return changed
+}
+
+func unionKeys(maps ...map[string]json.RawMessage) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// rawEqual сравнивает значения полей; отсутствующее поле равно только отсутствующему
+func rawEqual(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return bytes.Equal(a, b)
+}
+
+func jsonEqual(a, b any) bool {
+	ab, errA := json.Marshal(a)
+	bb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ab, bb)
+}
+
+func plainText(d mindmap.NodeData) string {
+	return strings.Join(strings.Fields(d.PlainText()), " ")
+}
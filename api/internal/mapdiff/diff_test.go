@@ -0,0 +1,90 @@
+package mapdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+const baseDoc = `{"root":{"data":{"text":"Root","uid":"r"},"children":[
+	{"data":{"text":"A","uid":"a"},"children":[{"data":{"text":"A1","uid":"a1"},"children":[]}]},
+	{"data":{"text":"B","uid":"b"},"children":[]},
+	{"data":{"text":"C","uid":"c"},"children":[]},
+	{"data":{"text":"D","uid":"d"},"children":[]}
+]}}`
+
+func parse(t *testing.T, s string) *mindmap.Document {
+	t.Helper()
+	doc, err := mindmap.ParseString(s, mindmap.DefaultLimits)
+	require.NoError(t, err)
+	return doc
+}
+
+func changeTypes(diff *Diff) map[string][]string {
+	types := map[string][]string{}
+	for _, c := range diff.Changes {
+		types[c.UID] = append(types[c.UID], c.Type)
+	}
+	return types
+}
+
+func TestCompute(t *testing.T) {
+	from := parse(t, baseDoc)
+	to := parse(t, `{"layout":"mindMap","root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"A","uid":"a"},"children":[]},
+		{"data":{"text":"D","uid":"d"},"children":[]},
+		{"data":{"text":"B2","uid":"b","color":"#f00"},"children":[{"data":{"text":"A1","uid":"a1"},"children":[]}]},
+		{"data":{"text":"C","uid":"c","note":"n"},"children":[]},
+		{"data":{"text":"E","uid":"e"},"children":[]}
+	]}}`)
+
+	diff := Compute(from, to)
+
+	assert.Equal(t, map[string][]string{
+		"d":  {ChangeMoved},
+		"b":  {ChangeText, ChangeStyle},
+		"a1": {ChangeMoved},
+		"c":  {ChangeStyle},
+		"e":  {ChangeAdded},
+	}, changeTypes(diff))
+	assert.True(t, diff.LayoutChanged)
+	assert.False(t, diff.ThemeChanged)
+
+	for _, c := range diff.Changes {
+		switch {
+		case c.UID == "a1":
+			assert.Equal(t, "a", c.OldParent)
+			assert.Equal(t, "b", c.Parent)
+		case c.UID == "b" && c.Type == ChangeText:
+			assert.Equal(t, "B", c.OldText)
+			assert.Equal(t, "B2", c.Text)
+		case c.UID == "b" && c.Type == ChangeStyle:
+			assert.Equal(t, []string{"color"}, c.Fields)
+		case c.UID == "c":
+			assert.Equal(t, []string{"note"}, c.Fields)
+		}
+	}
+}
+
+func TestComputeRemoved(t *testing.T) {
+	from := parse(t, baseDoc)
+	to := parse(t, `{"root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"B","uid":"b"},"children":[]},
+		{"data":{"text":"C","uid":"c"},"children":[]},
+		{"data":{"text":"D","uid":"d"},"children":[]}
+	]}}`)
+
+	diff := Compute(from, to)
+	require.Len(t, diff.Changes, 2)
+	assert.Equal(t, Change{Type: ChangeRemoved, UID: "a", Text: "A"}, diff.Changes[0])
+	assert.Equal(t, Change{Type: ChangeRemoved, UID: "a1", Text: "A1"}, diff.Changes[1])
+}
+
+func TestComputeSame(t *testing.T) {
+	diff := Compute(parse(t, baseDoc), parse(t, baseDoc))
+	assert.Empty(t, diff.Changes)
+	assert.False(t, diff.LayoutChanged)
+}
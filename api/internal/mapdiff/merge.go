@@ -0,0 +1,379 @@
+package mapdiff
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// Типы конфликтов слияния
+const (
+	ConflictData     = "data"     // Обе стороны по-разному изменили одно поле узла
+	ConflictDelete   = "delete"   // Одна сторона удалила узел, другая его изменила или добавила в него детей
+	ConflictAdd      = "add"      // Обе стороны добавили узел с одним uid, но по-разному
+	ConflictMove     = "move"     // Обе стороны по-разному переместили узел
+	ConflictOrder    = "order"    // Обе стороны по-разному упорядочили детей узла
+	ConflictDocument = "document" // Обе стороны по-разному изменили layout или theme
+)
+
+// Conflict - конфликт слияния. В результате слияния конфликт разрешен в пользу
+// ours, кроме удаления: измененный одной стороной узел сохраняется.
+type Conflict struct {
+	Type   string          `json:"type"`
+	UID    string          `json:"uid,omitempty"`   // Узел; пусто - документ целиком
+	Field  string          `json:"field,omitempty"` // Поле данных узла или документа
+	Ours   json.RawMessage `json:"ours,omitempty"`
+	Theirs json.RawMessage `json:"theirs,omitempty"`
+}
+
+// mergedNode - узел результата слияния
+type mergedNode struct {
+	parent string
+	data   mindmap.NodeData
+}
+
+// Merge сливает версии ours и theirs, разошедшиеся от base. Изменения, которые
+// сделала только одна сторона, переносятся в результат; поля данных узлов
+// сливаются по отдельности. Исходные документы не меняются. Ошибка возвращается,
+// если из версий нельзя собрать дерево (например, у них разные корни).
+func Merge(base, ours, theirs *mindmap.Document) (*mindmap.Document, []Conflict, error) {
+	if ours.Root.Data.UID != base.Root.Data.UID || theirs.Root.Data.UID != base.Root.Data.UID {
+		return nil, nil, fmt.Errorf("merge: versions have different root nodes")
+	}
+	b, o, t := flatten(base), flatten(ours), flatten(theirs)
+	m := &merger{base: b, ours: o, theirs: t, nodes: make(map[string]*mergedNode)}
+
+	for _, uid := range m.union() {
+		if err := m.mergeNode(uid); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := m.restoreParents(); err != nil {
+		return nil, nil, err
+	}
+	if err := m.breakCycles(); err != nil {
+		return nil, nil, err
+	}
+
+	flat := m.ordered()
+	root, err := mindmap.Build(flat)
+	if err != nil {
+		return nil, nil, fmt.Errorf("merge: %w", err)
+	}
+
+	doc := &mindmap.Document{Root: root}
+	doc.Layout = mergeValue(m, "layout", base.Layout, ours.Layout, theirs.Layout)
+	doc.Theme = mergeValue(m, "theme", base.Theme, ours.Theme, theirs.Theme)
+	// Положение и масштаб не конфликтуют: берется последнее изменение присланной стороны
+	doc.View = ours.View
+	if !jsonEqual(base.View, theirs.View) {
+		doc.View = theirs.View
+	}
+	return doc.Clone(), m.conflicts, nil
+}
+
+// mergeValue сливает значение уровня документа
+func mergeValue[T any](m *merger, field string, base, ours, theirs T) T {
+	switch {
+	case jsonEqual(ours, base):
+		return theirs
+	case jsonEqual(theirs, base), jsonEqual(ours, theirs):
+		return ours
+	}
+	m.conflict(Conflict{Type: ConflictDocument, Field: field, Ours: marshal(ours), Theirs: marshal(theirs)})
+	return ours
+}
+
+type merger struct {
+	base, ours, theirs flatDoc
+	nodes              map[string]*mergedNode
+	conflicts          []Conflict
+}
+
+func (m *merger) conflict(c Conflict) {
+	m.conflicts = append(m.conflicts, c)
+}
+
+// union возвращает uid всех версий: сначала в порядке ours, затем новые из theirs и base
+func (m *merger) union() []string {
+	seen := make(map[string]bool)
+	var uids []string
+	for _, doc := range []flatDoc{m.ours, m.theirs, m.base} {
+		for _, uid := range doc.order {
+			if !seen[uid] {
+				seen[uid] = true
+				uids = append(uids, uid)
+			}
+		}
+	}
+	return uids
+}
+
+// mergeNode решает, остается ли узел, и сливает его данные и родителя
+func (m *merger) mergeNode(uid string) error {
+	bn, inB := m.base.nodes[uid]
+	on, inO := m.ours.nodes[uid]
+	tn, inT := m.theirs.nodes[uid]
+
+	switch {
+	case inB && inO && inT:
+		data, err := m.mergeData(uid, bn.Data, on.Data, tn.Data)
+		if err != nil {
+			return err
+		}
+		parent := on.Parent
+		switch {
+		case on.Parent == bn.Parent:
+			parent = tn.Parent
+		case tn.Parent != bn.Parent && tn.Parent != on.Parent:
+			m.conflict(Conflict{Type: ConflictMove, UID: uid, Ours: marshal(on.Parent), Theirs: marshal(tn.Parent)})
+		}
+		m.nodes[uid] = &mergedNode{parent: parent, data: data}
+
+	case inB && inO: // theirs удалили
+		if changed(bn, on) {
+			m.conflict(Conflict{Type: ConflictDelete, UID: uid})
+			m.nodes[uid] = &mergedNode{parent: on.Parent, data: on.Data}
+		}
+
+	case inB && inT: // ours удалили
+		if changed(bn, tn) {
+			m.conflict(Conflict{Type: ConflictDelete, UID: uid})
+			m.nodes[uid] = &mergedNode{parent: tn.Parent, data: tn.Data}
+		}
+
+	case inO && inT: // Добавлен обеими сторонами
+		if changed(on, tn) {
+			m.conflict(Conflict{Type: ConflictAdd, UID: uid})
+		}
+		m.nodes[uid] = &mergedNode{parent: on.Parent, data: on.Data}
+
+	case inO:
+		m.nodes[uid] = &mergedNode{parent: on.Parent, data: on.Data}
+
+	case inT:
+		m.nodes[uid] = &mergedNode{parent: tn.Parent, data: tn.Data}
+	}
+	return nil
+}
+
+// mergeData сливает данные узла по полям
+func (m *merger) mergeData(uid string, base, ours, theirs mindmap.NodeData) (mindmap.NodeData, error) {
+	if mindmap.DataEqual(ours, base) {
+		return theirs, nil
+	}
+	if mindmap.DataEqual(theirs, base) || mindmap.DataEqual(ours, theirs) {
+		return ours, nil
+	}
+
+	fb, fo, ft := dataFields(base), dataFields(ours), dataFields(theirs)
+	merged := make(map[string]json.RawMessage)
+	for _, k := range unionKeys(fb, fo, ft) {
+		v := fo[k]
+		switch {
+		case rawEqual(fo[k], fb[k]):
+			v = ft[k]
+		case rawEqual(ft[k], fb[k]), rawEqual(fo[k], ft[k]):
+		default:
+			m.conflict(Conflict{Type: ConflictData, UID: uid, Field: k, Ours: fo[k], Theirs: ft[k]})
+		}
+		if v != nil {
+			merged[k] = v
+		}
+	}
+
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return ours, fmt.Errorf("merge node %q: %w", uid, err)
+	}
+	var data mindmap.NodeData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return ours, fmt.Errorf("merge node %q: %w", uid, err)
+	}
+	data.UID = uid
+	return data, nil
+}
+
+// restoreParents возвращает удаленных одной из сторон родителей оставшихся узлов
+func (m *merger) restoreParents() error {
+	for restored := true; restored; {
+		restored = false
+		for _, uid := range m.union() {
+			n, ok := m.nodes[uid]
+			if !ok || n.parent == "" || m.nodes[n.parent] != nil {
+				continue
+			}
+			parent, ok := m.find(n.parent)
+			if !ok {
+				return fmt.Errorf("merge: node %q references missing parent %q", uid, n.parent)
+			}
+			m.conflict(Conflict{Type: ConflictDelete, UID: n.parent})
+			m.nodes[n.parent] = &mergedNode{parent: parent.Parent, data: parent.Data}
+			restored = true
+		}
+	}
+	return nil
+}
+
+// find возвращает узел из версии, где он есть: theirs, ours или base
+func (m *merger) find(uid string) (mindmap.FlatNode, bool) {
+	for _, doc := range []flatDoc{m.theirs, m.ours, m.base} {
+		if n, ok := doc.nodes[uid]; ok {
+			return n, true
+		}
+	}
+	return mindmap.FlatNode{}, false
+}
+
+// breakCycles возвращает узлам, попавшим в цикл после встречных перемещений,
+// родителя из ours
+func (m *merger) breakCycles() error {
+	for _, uid := range m.union() {
+		if _, ok := m.nodes[uid]; !ok || !m.inCycle(uid) {
+			continue
+		}
+		on, ok := m.ours.nodes[uid]
+		if !ok || (on.Parent != "" && m.nodes[on.Parent] == nil) {
+			return fmt.Errorf("merge: node %q is moved into its own subtree", uid)
+		}
+		m.conflict(Conflict{Type: ConflictMove, UID: uid, Ours: marshal(on.Parent), Theirs: marshal(m.nodes[uid].parent)})
+		m.nodes[uid].parent = on.Parent
+	}
+	for uid := range m.nodes {
+		if m.inCycle(uid) {
+			return fmt.Errorf("merge: node %q is moved into its own subtree", uid)
+		}
+	}
+	return nil
+}
+
+func (m *merger) inCycle(uid string) bool {
+	p := m.nodes[uid].parent
+	for steps := 0; p != "" && steps <= len(m.nodes); steps++ {
+		if p == uid {
+			return true
+		}
+		n, ok := m.nodes[p]
+		if !ok {
+			return false
+		}
+		p = n.parent
+	}
+	return p != ""
+}
+
+// ordered возвращает узлы результата с позициями среди детей. За основу берется
+// порядок ours; порядок theirs - если детей переупорядочили только они.
+func (m *merger) ordered() []mindmap.FlatNode {
+	children := make(map[string][]string)
+	var parents []string // В порядке обхода, чтобы конфликты шли в одном порядке
+	var flat []mindmap.FlatNode
+	for _, uid := range m.union() {
+		n, ok := m.nodes[uid]
+		if !ok {
+			continue
+		}
+		if n.parent == "" {
+			flat = append(flat, mindmap.FlatNode{UID: uid, Data: n.data})
+			continue
+		}
+		if _, ok := children[n.parent]; !ok {
+			parents = append(parents, n.parent)
+		}
+		children[n.parent] = append(children[n.parent], uid)
+	}
+
+	for _, parent := range parents {
+		for i, uid := range m.orderChildren(parent, children[parent]) {
+			flat = append(flat, mindmap.FlatNode{UID: uid, Parent: parent, Index: i, Data: m.nodes[uid].data})
+		}
+	}
+	return flat
+}
+
+// orderChildren упорядочивает детей parent
+func (m *merger) orderChildren(parent string, members []string) []string {
+	oursReordered := len(reorderedChildren(m.base, m.ours, parent, m.ours.children[parent])) > 0
+	theirsReordered := len(reorderedChildren(m.base, m.theirs, parent, m.theirs.children[parent])) > 0
+
+	primary, secondary := m.ours, m.theirs
+	if theirsReordered && !oursReordered {
+		primary, secondary = m.theirs, m.ours
+	}
+	if oursReordered && theirsReordered && !sameOrder(commonChildren(m.ours, m.theirs, parent), commonChildren(m.theirs, m.ours, parent)) {
+		m.conflict(Conflict{Type: ConflictOrder, UID: parent})
+	}
+
+	isMember := make(map[string]bool, len(members))
+	for _, uid := range members {
+		isMember[uid] = true
+	}
+	var result []string
+	placed := make(map[string]bool, len(members))
+	for _, uid := range primary.children[parent] {
+		if isMember[uid] {
+			result = append(result, uid)
+			placed[uid] = true
+		}
+	}
+	// Остальных ставим после ближайшего соседа, предшествующего им во второй версии или в base
+	for _, doc := range []flatDoc{secondary, m.base} {
+		var prev string
+		for _, uid := range doc.children[parent] {
+			if isMember[uid] && !placed[uid] {
+				result = insertAfter(result, prev, uid)
+				placed[uid] = true
+			}
+			if placed[uid] {
+				prev = uid
+			}
+		}
+	}
+	for _, uid := range members {
+		if !placed[uid] {
+			result = append(result, uid)
+		}
+	}
+	return result
+}
+
+// insertAfter вставляет uid после after; пустой after - в начало
+func insertAfter(list []string, after, uid string) []string {
+	pos := 0
+	for i, v := range list {
+		if v == after {
+			pos = i + 1
+			break
+		}
+	}
+	list = append(list, "")
+	copy(list[pos+1:], list[pos:])
+	list[pos] = uid
+	return list
+}
+
+func sameOrder(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// changed - узел отличается данными или родителем
+func changed(a, b mindmap.FlatNode) bool {
+	return a.Parent != b.Parent || !mindmap.DataEqual(a.Data, b.Data)
+}
+
+func marshal(v any) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,162 @@
+package mapdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func childUIDs(n *mindmap.Node) []string {
+	uids := []string{}
+	for _, c := range n.Children {
+		uids = append(uids, c.Data.UID)
+	}
+	return uids
+}
+
+func find(t *testing.T, doc *mindmap.Document, uid string) *mindmap.Node {
+	t.Helper()
+	n, _ := doc.Find(uid)
+	require.NotNil(t, n, "node %s", uid)
+	return n
+}
+
+func TestMergeIndependentChanges(t *testing.T) {
+	base := parse(t, baseDoc)
+	// Сохраненная версия: текст A, новый узел под B, цвет C
+	ours := parse(t, `{"root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"A!","uid":"a"},"children":[{"data":{"text":"A1","uid":"a1"},"children":[]}]},
+		{"data":{"text":"B","uid":"b"},"children":[{"data":{"text":"B1","uid":"b1"},"children":[]}]},
+		{"data":{"text":"C","uid":"c","color":"#f00"},"children":[]},
+		{"data":{"text":"D","uid":"d"},"children":[]}
+	]}}`)
+	// Версия клиента: заметка C, A1 перенесен в D, B удален, новый узел в конце
+	theirs := parse(t, `{"layout":"mindMap","root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"A","uid":"a"},"children":[]},
+		{"data":{"text":"C","uid":"c","note":"n"},"children":[]},
+		{"data":{"text":"D","uid":"d"},"children":[{"data":{"text":"A1","uid":"a1"},"children":[]}]},
+		{"data":{"text":"E","uid":"e"},"children":[]}
+	]}}`)
+
+	merged, conflicts, err := Merge(base, ours, theirs)
+	require.NoError(t, err)
+
+	// B изменен сохраненной версией (новый ребенок), поэтому удаление конфликтует и B остается
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, Conflict{Type: ConflictDelete, UID: "b"}, conflicts[0])
+
+	assert.Equal(t, "mindMap", merged.Layout)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, childUIDs(merged.Root))
+	assert.Equal(t, "A!", find(t, merged, "a").Data.Text)
+	assert.Empty(t, find(t, merged, "a").Children)
+	assert.Equal(t, []string{"a1"}, childUIDs(find(t, merged, "d")))
+	assert.Equal(t, []string{"b1"}, childUIDs(find(t, merged, "b")))
+
+	c := find(t, merged, "c")
+	assert.Equal(t, "n", c.Data.Note)
+	assert.Equal(t, `"#f00"`, string(c.Data.Fields["color"]))
+}
+
+func TestMergeDeleteUnchanged(t *testing.T) {
+	base := parse(t, baseDoc)
+	ours := parse(t, baseDoc)
+	theirs := parse(t, `{"root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"B","uid":"b"},"children":[]},
+		{"data":{"text":"C","uid":"c"},"children":[]},
+		{"data":{"text":"D","uid":"d"},"children":[]}
+	]}}`)
+
+	merged, conflicts, err := Merge(base, ours, theirs)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.Equal(t, []string{"b", "c", "d"}, childUIDs(merged.Root))
+}
+
+func TestMergeConflicts(t *testing.T) {
+	base := parse(t, baseDoc)
+	ours := parse(t, `{"layout":"mindMap","root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"A ours","uid":"a"},"children":[]},
+		{"data":{"text":"B","uid":"b"},"children":[{"data":{"text":"A1","uid":"a1"},"children":[]}]},
+		{"data":{"text":"C","uid":"c"},"children":[]},
+		{"data":{"text":"D","uid":"d"},"children":[]}
+	]}}`)
+	theirs := parse(t, `{"layout":"organizationStructure","root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"A theirs","uid":"a"},"children":[]},
+		{"data":{"text":"B","uid":"b"},"children":[]},
+		{"data":{"text":"C","uid":"c"},"children":[{"data":{"text":"A1","uid":"a1"},"children":[]}]},
+		{"data":{"text":"D","uid":"d"},"children":[]}
+	]}}`)
+
+	merged, conflicts, err := Merge(base, ours, theirs)
+	require.NoError(t, err)
+
+	types := map[string]string{}
+	for _, c := range conflicts {
+		types[c.UID+"/"+c.Field] = c.Type
+	}
+	assert.Equal(t, map[string]string{
+		"a/text":  ConflictData,
+		"a1/":     ConflictMove,
+		"/layout": ConflictDocument,
+	}, types)
+
+	// Конфликты разрешаются в пользу сохраненной версии
+	assert.Equal(t, "A ours", find(t, merged, "a").Data.Text)
+	assert.Equal(t, []string{"a1"}, childUIDs(find(t, merged, "b")))
+	assert.Equal(t, "mindMap", merged.Layout)
+}
+
+func TestMergeReorder(t *testing.T) {
+	base := parse(t, baseDoc)
+	ours := parse(t, `{"root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"A","uid":"a"},"children":[{"data":{"text":"A1","uid":"a1"},"children":[]}]},
+		{"data":{"text":"B","uid":"b"},"children":[]},
+		{"data":{"text":"X","uid":"x"},"children":[]},
+		{"data":{"text":"C","uid":"c"},"children":[]},
+		{"data":{"text":"D","uid":"d"},"children":[]}
+	]}}`)
+	theirs := parse(t, `{"root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"D","uid":"d"},"children":[]},
+		{"data":{"text":"A","uid":"a"},"children":[{"data":{"text":"A1","uid":"a1"},"children":[]}]},
+		{"data":{"text":"B","uid":"b"},"children":[]},
+		{"data":{"text":"C","uid":"c"},"children":[]}
+	]}}`)
+
+	merged, conflicts, err := Merge(base, ours, theirs)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	// Порядок клиента, узел из сохраненной версии - после своего соседа
+	assert.Equal(t, []string{"d", "a", "b", "x", "c"}, childUIDs(merged.Root))
+}
+
+func TestMergeCrossMoveCycle(t *testing.T) {
+	base := parse(t, baseDoc)
+	ours := parse(t, `{"root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"A","uid":"a"},"children":[{"data":{"text":"A1","uid":"a1"},"children":[]},{"data":{"text":"B","uid":"b"},"children":[]}]},
+		{"data":{"text":"C","uid":"c"},"children":[]},
+		{"data":{"text":"D","uid":"d"},"children":[]}
+	]}}`)
+	theirs := parse(t, `{"root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"B","uid":"b"},"children":[{"data":{"text":"A","uid":"a"},"children":[{"data":{"text":"A1","uid":"a1"},"children":[]}]}]},
+		{"data":{"text":"C","uid":"c"},"children":[]},
+		{"data":{"text":"D","uid":"d"},"children":[]}
+	]}}`)
+
+	merged, conflicts, err := Merge(base, ours, theirs)
+	require.NoError(t, err)
+	require.NotEmpty(t, conflicts)
+	assert.Equal(t, ConflictMove, conflicts[0].Type)
+	assert.Equal(t, 6, merged.NodeCount())
+	assert.Equal(t, []string{"a", "c", "d"}, childUIDs(merged.Root))
+}
+
+func TestMergeDifferentRoots(t *testing.T) {
+	base := parse(t, baseDoc)
+	other := parse(t, `{"root":{"data":{"text":"Other","uid":"o"},"children":[]}}`)
+
+	_, _, err := Merge(base, base, other)
+	assert.Error(t, err)
+}
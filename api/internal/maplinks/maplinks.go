@@ -0,0 +1,84 @@
+// Package maplinks - ссылки из узла одной карты на узел другой (или той же) карты.
+//
+// Ссылка хранится в поле данных узла:
+//
+//	"mapLink": {"mindmapId": 12, "nodeUid": "k3f9a0c2d1e4"}
+//
+// nodeUid можно не указывать - тогда ссылка ведет на карту целиком.
+package maplinks
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// Field - поле данных узла со ссылкой
+const Field = "mapLink"
+
+// maxUIDLen - длина uid узла, как в таблицах обсуждений и задач
+const maxUIDLen = 64
+
+// maxTextLen - символов текста узла-источника, сохраняемых вместе со ссылкой
+const maxTextLen = 200
+
+// Link - цель ссылки
+type Link struct {
+	MindMapID int    `json:"mindmapId"`
+	NodeUID   string `json:"nodeUid,omitempty"` // Пусто - карта целиком
+}
+
+// Ref - ссылка из узла документа
+type Ref struct {
+	SourceUID  string
+	SourceText string // Текст узла без разметки, для списка «что ссылается сюда»
+	Link
+}
+
+// Get возвращает ссылку узла; ok = false, если ссылки нет или она некорректна
+func Get(d mindmap.NodeData) (Link, bool) {
+	raw, ok := d.Fields[Field]
+	if !ok {
+		return Link{}, false
+	}
+	var link Link
+	if err := json.Unmarshal(raw, &link); err != nil {
+		return Link{}, false
+	}
+	if link.MindMapID < 1 || link.MindMapID > math.MaxInt32 || len(link.NodeUID) > maxUIDLen {
+		return Link{}, false
+	}
+	return link, true
+}
+
+// Set записывает ссылку в данные узла
+func Set(d *mindmap.NodeData, link Link) {
+	raw, _ := json.Marshal(link) // Структура из числа и строки всегда кодируется
+	if d.Fields == nil {
+		d.Fields = make(map[string]json.RawMessage)
+	}
+	d.Fields[Field] = raw
+}
+
+// Refs возвращает ссылки документа в порядке обхода дерева. Узлы без uid
+// пропускаются: на них нельзя сослаться при обновлении индекса.
+func Refs(doc *mindmap.Document) []Ref {
+	var refs []Ref
+	doc.Walk(func(n, _ *mindmap.Node, _ int) bool {
+		if n.Data.UID == "" {
+			return true
+		}
+		if link, ok := Get(n.Data); ok {
+			text := strings.Join(strings.Fields(n.Data.PlainText()), " ")
+			if utf8.RuneCountInString(text) > maxTextLen {
+				text = string([]rune(text)[:maxTextLen])
+			}
+			refs = append(refs, Ref{SourceUID: n.Data.UID, SourceText: text, Link: link})
+		}
+		return true
+	})
+	return refs
+}
@@ -0,0 +1,42 @@
+package maplinks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func TestRefs(t *testing.T) {
+	doc, err := mindmap.ParseString(`{"root":{"data":{"text":"Root","uid":"r"},"children":[
+		{"data":{"text":"See <b>design</b>","richText":true,"uid":"a","mapLink":{"mindmapId":12,"nodeUid":"x1"}},"children":[]},
+		{"data":{"text":"Whole map","uid":"b","mapLink":{"mindmapId":7}},"children":[]},
+		{"data":{"text":"Bad id","uid":"c","mapLink":{"mindmapId":0}},"children":[]},
+		{"data":{"text":"Huge id","uid":"e","mapLink":{"mindmapId":9999999999}},"children":[]},
+		{"data":{"text":"Bad type","uid":"d","mapLink":"12/x1"},"children":[]},
+		{"data":{"text":"No uid","mapLink":{"mindmapId":3}},"children":[]}
+	]}}`, mindmap.DefaultLimits)
+	require.NoError(t, err)
+
+	refs := Refs(doc)
+	require.Len(t, refs, 2)
+	assert.Equal(t, Ref{SourceUID: "a", SourceText: "See design", Link: Link{MindMapID: 12, NodeUID: "x1"}}, refs[0])
+	assert.Equal(t, Ref{SourceUID: "b", SourceText: "Whole map", Link: Link{MindMapID: 7}}, refs[1])
+}
+
+func TestSet(t *testing.T) {
+	doc := mindmap.New("Root")
+	Set(&doc.Root.Data, Link{MindMapID: 5, NodeUID: "n1"})
+
+	data, err := doc.Encode()
+	require.NoError(t, err)
+	assert.Contains(t, data, `"mapLink":{"mindmapId":5,"nodeUid":"n1"}`)
+
+	parsed, err := mindmap.ParseString(data, mindmap.DefaultLimits)
+	require.NoError(t, err)
+	link, ok := Get(parsed.Root.Data)
+	require.True(t, ok)
+	assert.Equal(t, Link{MindMapID: 5, NodeUID: "n1"}, link)
+}
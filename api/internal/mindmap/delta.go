@@ -0,0 +1,182 @@
+package mindmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// FlatNode - узел в плоском представлении дерева
+type FlatNode struct {
+	UID    string   `json:"uid"`
+	Parent string   `json:"parent,omitempty"` // Пусто у корня
+	Index  int      `json:"index"`            // Позиция среди детей родителя
+	Data   NodeData `json:"data"`
+}
+
+// Flatten возвращает узлы документа в прямом порядке обхода
+func Flatten(doc *Document) []FlatNode {
+	var nodes []FlatNode
+	if doc.Root == nil {
+		return nodes
+	}
+	var visit func(n *Node, parent string, index int)
+	visit = func(n *Node, parent string, index int) {
+		nodes = append(nodes, FlatNode{UID: n.Data.UID, Parent: parent, Index: index, Data: n.Data})
+		for i, child := range n.Children {
+			visit(child, n.Data.UID, i)
+		}
+	}
+	visit(doc.Root, "", 0)
+	return nodes
+}
+
+// Build собирает дерево из плоского списка узлов
+func Build(nodes []FlatNode) (*Node, error) {
+	byUID := make(map[string]*Node, len(nodes))
+	var root *Node
+	for _, fn := range nodes {
+		if _, dup := byUID[fn.UID]; dup {
+			return nil, fmt.Errorf("duplicate node uid %q", fn.UID)
+		}
+		n := &Node{Data: fn.Data, Children: []*Node{}}
+		n.Data.UID = fn.UID
+		byUID[fn.UID] = n
+		if fn.Parent == "" {
+			if root != nil {
+				return nil, fmt.Errorf("document has more than one root")
+			}
+			root = n
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("document has no root")
+	}
+
+	ordered := make([]FlatNode, len(nodes))
+	copy(ordered, nodes)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Index < ordered[j].Index })
+	for _, fn := range ordered {
+		if fn.Parent == "" {
+			continue
+		}
+		parent, ok := byUID[fn.Parent]
+		if !ok {
+			return nil, fmt.Errorf("node %q references missing parent %q", fn.UID, fn.Parent)
+		}
+		parent.Children = append(parent.Children, byUID[fn.UID])
+	}
+
+	// Узлы, не достижимые из корня, означают цикл
+	reached := 0
+	walk(root, nil, 1, func(*Node, *Node, int) bool {
+		reached++
+		return reached <= len(nodes)
+	})
+	if reached != len(nodes) {
+		return nil, fmt.Errorf("document tree contains a cycle")
+	}
+	return root, nil
+}
+
+// Delta - разница между двумя версиями документа на уровне узлов.
+// Оформление (layout, theme, view) хранится целиком.
+type Delta struct {
+	Layout  string          `json:"layout,omitempty"`
+	Theme   *Theme          `json:"theme,omitempty"`
+	View    json.RawMessage `json:"view,omitempty"`
+	Nodes   []DeltaNode     `json:"nodes,omitempty"`
+	Removed []string        `json:"removed,omitempty"`
+}
+
+// DeltaNode - новый, перемещенный или измененный узел.
+// Data заполняется только для новых узлов и узлов с измененными данными.
+type DeltaNode struct {
+	UID    string    `json:"uid"`
+	Parent string    `json:"parent,omitempty"`
+	Index  int       `json:"index"`
+	Data   *NodeData `json:"data,omitempty"`
+}
+
+// ComputeDelta вычисляет разницу, превращающую base в target
+func ComputeDelta(base, target *Document) *Delta {
+	delta := &Delta{Layout: target.Layout, Theme: target.Theme, View: target.View}
+
+	baseNodes := make(map[string]FlatNode)
+	for _, fn := range Flatten(base) {
+		baseNodes[fn.UID] = fn
+	}
+
+	for _, fn := range Flatten(target) {
+		old, existed := baseNodes[fn.UID]
+		delete(baseNodes, fn.UID)
+
+		dataChanged := !existed || !DataEqual(old.Data, fn.Data)
+		if existed && !dataChanged && old.Parent == fn.Parent && old.Index == fn.Index {
+			continue
+		}
+		dn := DeltaNode{UID: fn.UID, Parent: fn.Parent, Index: fn.Index}
+		if dataChanged {
+			data := fn.Data
+			dn.Data = &data
+		}
+		delta.Nodes = append(delta.Nodes, dn)
+	}
+
+	for uid := range baseNodes {
+		delta.Removed = append(delta.Removed, uid)
+	}
+	sort.Strings(delta.Removed)
+	return delta
+}
+
+// ApplyDelta применяет разницу к base и возвращает новый документ. base не меняется.
+func ApplyDelta(base *Document, delta *Delta) (*Document, error) {
+	flat := Flatten(base)
+	index := make(map[string]int, len(flat))
+	for i, fn := range flat {
+		index[fn.UID] = i
+	}
+
+	removed := make(map[string]bool, len(delta.Removed))
+	for _, uid := range delta.Removed {
+		removed[uid] = true
+	}
+
+	for _, dn := range delta.Nodes {
+		if i, ok := index[dn.UID]; ok {
+			flat[i].Parent, flat[i].Index = dn.Parent, dn.Index
+			if dn.Data != nil {
+				flat[i].Data = *dn.Data
+			}
+			continue
+		}
+		if dn.Data == nil {
+			return nil, fmt.Errorf("delta adds node %q without data", dn.UID)
+		}
+		index[dn.UID] = len(flat)
+		flat = append(flat, FlatNode{UID: dn.UID, Parent: dn.Parent, Index: dn.Index, Data: *dn.Data})
+	}
+
+	kept := flat[:0]
+	for _, fn := range flat {
+		if !removed[fn.UID] {
+			kept = append(kept, fn)
+		}
+	}
+
+	root, err := Build(kept)
+	if err != nil {
+		return nil, fmt.Errorf("apply delta: %w", err)
+	}
+	doc := &Document{Root: root, Layout: delta.Layout, Theme: delta.Theme, View: delta.View}
+	return doc.Clone(), nil
+}
+
+// DataEqual сравнивает данные двух узлов по их JSON-представлению
+func DataEqual(a, b NodeData) bool {
+	ab, errA := json.Marshal(a)
+	bb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ab, bb)
+}
@@ -0,0 +1,94 @@
+package mindmap
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParse(t *testing.T, s string) *Document {
+	t.Helper()
+	doc, err := Parse([]byte(s), DefaultLimits)
+	require.NoError(t, err)
+	EnsureUIDs(doc)
+	return doc
+}
+
+func mustEncode(t *testing.T, doc *Document) string {
+	t.Helper()
+	s, err := doc.Encode()
+	require.NoError(t, err)
+	return s
+}
+
+func TestFlattenBuild_RoundTrip(t *testing.T) {
+	doc := mustParse(t, sampleDoc)
+
+	root, err := Build(Flatten(doc))
+	require.NoError(t, err)
+
+	rebuilt := &Document{Root: root, Layout: doc.Layout, Theme: doc.Theme, View: doc.View}
+	assert.Equal(t, mustEncode(t, doc), mustEncode(t, rebuilt))
+}
+
+func TestBuild_Errors(t *testing.T) {
+	_, err := Build([]FlatNode{{UID: "a", Parent: "missing"}})
+	assert.Error(t, err)
+
+	_, err = Build([]FlatNode{{UID: "r"}, {UID: "r2"}})
+	assert.Error(t, err)
+
+	_, err = Build([]FlatNode{{UID: "r"}, {UID: "a", Parent: "b"}, {UID: "b", Parent: "a"}})
+	assert.Error(t, err)
+}
+
+func TestDelta_ApplyReproducesTarget(t *testing.T) {
+	base := mustParse(t, `{"root":{"data":{"text":"r","uid":"r"},"children":[
+		{"data":{"text":"a","uid":"a"},"children":[{"data":{"text":"a1","uid":"a1"}}]},
+		{"data":{"text":"b","uid":"b"}},
+		{"data":{"text":"c","uid":"c"}}
+	]},"layout":"mindMap"}`)
+	target := mustParse(t, `{"root":{"data":{"text":"r","uid":"r"},"children":[
+		{"data":{"text":"c","uid":"c"},"children":[{"data":{"text":"a1","uid":"a1"}}]},
+		{"data":{"text":"b changed","uid":"b","color":"#f00"}},
+		{"data":{"text":"new","uid":"n"}}
+	]},"layout":"logicalStructure","view":{"x":1}}`)
+
+	delta := ComputeDelta(base, target)
+	assert.Equal(t, []string{"a"}, delta.Removed)
+
+	byUID := map[string]DeltaNode{}
+	for _, dn := range delta.Nodes {
+		byUID[dn.UID] = dn
+	}
+	assert.NotContains(t, byUID, "r", "unchanged nodes are not stored")
+	assert.Nil(t, byUID["c"].Data, "moved node without data changes carries no data")
+	assert.NotNil(t, byUID["b"].Data)
+	assert.NotNil(t, byUID["n"].Data)
+
+	// Delta survives storage as JSON
+	raw, err := json.Marshal(delta)
+	require.NoError(t, err)
+	var stored Delta
+	require.NoError(t, json.Unmarshal(raw, &stored))
+
+	result, err := ApplyDelta(base, &stored)
+	require.NoError(t, err)
+	assert.Equal(t, mustEncode(t, target), mustEncode(t, result))
+	assert.Equal(t, "a", base.Root.Children[0].Data.Text, "base must not be modified")
+}
+
+func TestDelta_Empty(t *testing.T) {
+	doc := mustParse(t, sampleDoc)
+	delta := ComputeDelta(doc, doc.Clone())
+	assert.Empty(t, delta.Nodes)
+	assert.Empty(t, delta.Removed)
+}
+
+func TestApplyDelta_NewNodeWithoutData(t *testing.T) {
+	base := mustParse(t, `{"root":{"data":{"text":"r","uid":"r"}}}`)
+	_, err := ApplyDelta(base, &Delta{Nodes: []DeltaNode{{UID: "x", Parent: "r"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,293 @@
+// Package mindmap содержит серверную модель документа simple-mind-map:
+// разбор, валидацию и обход дерева узлов, которое хранится в models.MindMap.Data.
+package mindmap
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Document - документ simple-mind-map целиком
+type Document struct {
+	Root   *Node           `json:"root"`
+	Layout string          `json:"layout,omitempty"`
+	Theme  *Theme          `json:"theme,omitempty"`
+	View   json.RawMessage `json:"view,omitempty"`
+}
+
+// Theme - тема оформления карты
+type Theme struct {
+	Template string                     `json:"template,omitempty"`
+	Config   map[string]json.RawMessage `json:"config,omitempty"`
+}
+
+// Node - узел дерева
+type Node struct {
+	Data     NodeData `json:"data"`
+	Children []*Node  `json:"children"`
+}
+
+// NodeData - данные узла. Известные поля типизированы, остальные
+// (стили, expand, generalization и т.п.) сохраняются как есть в Fields.
+type NodeData struct {
+	UID            string
+	Text           string
+	RichText       bool
+	Note           string
+	Hyperlink      string
+	HyperlinkTitle string
+	Image          string
+	ImageTitle     string
+	Icon           []string
+	Tag            []Tag
+	Fields         map[string]json.RawMessage
+}
+
+// Tag - тег узла. simple-mind-map хранит теги либо строками, либо объектами {text, style}.
+type Tag struct {
+	Text  string
+	Style json.RawMessage
+}
+
+// New создает пустой документ с корневым узлом
+func New(rootText string) *Document {
+	root := &Node{Data: NodeData{Text: rootText}, Children: []*Node{}}
+	root.Data.UID = NewUID()
+	return &Document{Root: root, Layout: DefaultLayout}
+}
+
+// DefaultLayout - структура по умолчанию для новых карт
+const DefaultLayout = "logicalStructure"
+
+// Encode сериализует документ в строку для models.MindMap.Data
+func (d *Document) Encode() (string, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("encode mindmap: %w", err)
+	}
+	return string(b), nil
+}
+
+// Clone возвращает глубокую копию документа
+func (d *Document) Clone() *Document {
+	c := &Document{Layout: d.Layout}
+	if d.Root != nil {
+		c.Root = d.Root.Clone()
+	}
+	if d.Theme != nil {
+		c.Theme = &Theme{Template: d.Theme.Template, Config: cloneFields(d.Theme.Config)}
+	}
+	if d.View != nil {
+		c.View = append(json.RawMessage(nil), d.View...)
+	}
+	return c
+}
+
+// Clone возвращает глубокую копию поддерева
+func (n *Node) Clone() *Node {
+	c := &Node{Data: n.Data.Clone(), Children: make([]*Node, 0, len(n.Children))}
+	for _, child := range n.Children {
+		c.Children = append(c.Children, child.Clone())
+	}
+	return c
+}
+
+// Clone возвращает копию данных узла
+func (d NodeData) Clone() NodeData {
+	c := d
+	c.Icon = append([]string(nil), d.Icon...)
+	if d.Tag != nil {
+		c.Tag = make([]Tag, len(d.Tag))
+		for i, t := range d.Tag {
+			c.Tag[i] = t
+			if t.Style != nil {
+				c.Tag[i].Style = append(json.RawMessage(nil), t.Style...)
+			}
+		}
+	}
+	c.Fields = cloneFields(d.Fields)
+	return c
+}
+
+func cloneFields(fields map[string]json.RawMessage) map[string]json.RawMessage {
+	if fields == nil {
+		return nil
+	}
+	c := make(map[string]json.RawMessage, len(fields))
+	for k, v := range fields {
+		c[k] = append(json.RawMessage(nil), v...)
+	}
+	return c
+}
+
+// Walk обходит дерево в прямом порядке. Если fn возвращает false,
+// потомки узла пропускаются.
+func (d *Document) Walk(fn func(n, parent *Node, depth int) bool) {
+	if d.Root == nil {
+		return
+	}
+	walk(d.Root, nil, 1, fn)
+}
+
+func walk(n, parent *Node, depth int, fn func(n, parent *Node, depth int) bool) {
+	if !fn(n, parent, depth) {
+		return
+	}
+	for _, child := range n.Children {
+		walk(child, n, depth+1, fn)
+	}
+}
+
+// Find ищет узел по uid и возвращает его вместе с родителем
+func (d *Document) Find(uid string) (node, parent *Node) {
+	d.Walk(func(n, p *Node, _ int) bool {
+		if node != nil {
+			return false
+		}
+		if n.Data.UID == uid {
+			node, parent = n, p
+			return false
+		}
+		return true
+	})
+	return node, parent
+}
+
+// NodeCount возвращает количество узлов в документе
+func (d *Document) NodeCount() int {
+	count := 0
+	d.Walk(func(*Node, *Node, int) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+// PlainText возвращает текст узла без HTML-разметки
+func (d NodeData) PlainText() string {
+	if !d.RichText {
+		return d.Text
+	}
+	return StripHTML(d.Text)
+}
+
+// TagTexts возвращает тексты тегов узла
+func (d NodeData) TagTexts() []string {
+	texts := make([]string, 0, len(d.Tag))
+	for _, t := range d.Tag {
+		texts = append(texts, t.Text)
+	}
+	return texts
+}
+
+// --- JSON ---
+
+// MarshalJSON всегда пишет children массивом: simple-mind-map не принимает null
+func (n *Node) MarshalJSON() ([]byte, error) {
+	children := n.Children
+	if children == nil {
+		children = []*Node{}
+	}
+	return json.Marshal(struct {
+		Data     NodeData `json:"data"`
+		Children []*Node  `json:"children"`
+	}{n.Data, children})
+}
+
+var knownDataKeys = map[string]bool{
+	"uid": true, "text": true, "richText": true, "note": true,
+	"hyperlink": true, "hyperlinkTitle": true, "image": true, "imageTitle": true,
+	"icon": true, "tag": true,
+}
+
+// MarshalJSON собирает известные поля и Fields в один объект
+func (d NodeData) MarshalJSON() ([]byte, error) {
+	out := make(map[string]any, len(d.Fields)+4)
+	for k, v := range d.Fields {
+		if !knownDataKeys[k] {
+			out[k] = v
+		}
+	}
+	out["text"] = d.Text
+	if d.UID != "" {
+		out["uid"] = d.UID
+	}
+	if d.RichText {
+		out["richText"] = true
+	}
+	if d.Note != "" {
+		out["note"] = d.Note
+	}
+	if d.Hyperlink != "" {
+		out["hyperlink"] = d.Hyperlink
+	}
+	if d.HyperlinkTitle != "" {
+		out["hyperlinkTitle"] = d.HyperlinkTitle
+	}
+	if d.Image != "" {
+		out["image"] = d.Image
+	}
+	if d.ImageTitle != "" {
+		out["imageTitle"] = d.ImageTitle
+	}
+	if len(d.Icon) > 0 {
+		out["icon"] = d.Icon
+	}
+	if len(d.Tag) > 0 {
+		out["tag"] = d.Tag
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON разбирает данные узла, сохраняя неизвестные поля
+func (d *NodeData) UnmarshalJSON(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	var errs []FieldError
+	*d = decodeNodeData(raw, "data", &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// MarshalJSON пишет тег строкой, если у него нет стиля
+func (t Tag) MarshalJSON() ([]byte, error) {
+	if len(t.Style) == 0 {
+		return json.Marshal(t.Text)
+	}
+	return json.Marshal(struct {
+		Text  string          `json:"text"`
+		Style json.RawMessage `json:"style"`
+	}{t.Text, t.Style})
+}
+
+// UnmarshalJSON принимает тег в виде строки или объекта
+func (t *Tag) UnmarshalJSON(b []byte) error {
+	tag, ok := decodeTag(b)
+	if !ok {
+		return fmt.Errorf("tag must be a string or an object with text")
+	}
+	*t = tag
+	return nil
+}
+
+func decodeTag(b json.RawMessage) (Tag, bool) {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return Tag{Text: s}, true
+	}
+	var obj struct {
+		Text  *string         `json:"text"`
+		Style json.RawMessage `json:"style"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil || obj.Text == nil {
+		return Tag{}, false
+	}
+	if string(obj.Style) == "null" {
+		obj.Style = nil
+	}
+	return Tag{Text: *obj.Text, Style: obj.Style}, true
+}
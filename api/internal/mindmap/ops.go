@@ -0,0 +1,280 @@
+package mindmap
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Типы операций над деревом
+const (
+	OpInsert = "insert" // Вставить поддерево Node в Parent на позицию Index
+	OpDelete = "delete" // Удалить поддерево UID
+	OpMove   = "move"   // Переместить поддерево UID в Parent на позицию Index
+	OpSet    = "set"    // Установить поле Field данных узла UID в Value (null удаляет поле)
+)
+
+// Op - операция над деревом, адресованная uid узлов
+type Op struct {
+	Op     string          `json:"op"`
+	UID    string          `json:"uid,omitempty"`
+	Parent string          `json:"parent,omitempty"`
+	Index  *int            `json:"index,omitempty"` // nil - в конец списка детей
+	Node   *Node           `json:"node,omitempty"`
+	Field  string          `json:"field,omitempty"`
+	Value  json.RawMessage `json:"value,omitempty"`
+}
+
+// OpError - ошибка применения операции из пакета
+type OpError struct {
+	Index   int    `json:"index"` // Номер операции в пакете
+	Op      string `json:"op"`
+	Message string `json:"message"`
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("op %d (%s): %s", e.Index, e.Op, e.Message)
+}
+
+// ApplyOps применяет пакет операций к копии документа. Пакет применяется
+// целиком или не применяется совсем: исходный документ не меняется.
+// Возвращает новый документ и операции в нормализованном виде (вставленные
+// узлы с назначенными uid): именно их нужно воспроизводить на других клиентах.
+func ApplyOps(doc *Document, ops []Op) (*Document, []Op, error) {
+	result := doc.Clone()
+	if result.Root == nil {
+		return nil, nil, &OpError{Index: 0, Message: "document has no root"}
+	}
+
+	idx := newNodeIndex(result)
+	applied := make([]Op, 0, len(ops))
+	for i, op := range ops {
+		normalized, err := idx.apply(op)
+		if err != nil {
+			return nil, nil, &OpError{Index: i, Op: op.Op, Message: err.Error()}
+		}
+		applied = append(applied, normalized)
+	}
+	return result, applied, nil
+}
+
+// ApplyOpsLenient применяет операции по одной к копии документа и пропускает
+// те, что неприменимы к текущему состоянию: например, правку узла, который
+// уже удалил другой участник. Возвращает новый документ, примененные операции
+// в нормализованном виде (вставленные узлы с назначенными uid) и ошибки
+// пропущенных операций. Используется при совместном редактировании, где
+// порядок операций задает сервер.
+func ApplyOpsLenient(doc *Document, ops []Op) (*Document, []Op, []OpError) {
+	result := doc.Clone()
+	if result.Root == nil {
+		return result, nil, []OpError{{Index: 0, Message: "document has no root"}}
+	}
+
+	idx := newNodeIndex(result)
+	applied := make([]Op, 0, len(ops))
+	var rejected []OpError
+	for i, op := range ops {
+		normalized, err := idx.apply(op)
+		if err != nil {
+			rejected = append(rejected, OpError{Index: i, Op: op.Op, Message: err.Error()})
+			continue
+		}
+		applied = append(applied, normalized)
+	}
+	return result, applied, rejected
+}
+
+// nodeIndex - uid -> узел и его родитель для документа, который меняется операциями
+type nodeIndex struct {
+	nodes  map[string]*Node
+	parent map[string]*Node
+}
+
+func newNodeIndex(doc *Document) *nodeIndex {
+	idx := &nodeIndex{nodes: map[string]*Node{}, parent: map[string]*Node{}}
+	idx.add(doc.Root, nil)
+	return idx
+}
+
+func (idx *nodeIndex) add(n, parent *Node) {
+	walk(n, parent, 1, func(n, p *Node, _ int) bool {
+		idx.nodes[n.Data.UID] = n
+		idx.parent[n.Data.UID] = p
+		return true
+	})
+}
+
+func (idx *nodeIndex) remove(n *Node) {
+	walk(n, nil, 1, func(n, _ *Node, _ int) bool {
+		delete(idx.nodes, n.Data.UID)
+		delete(idx.parent, n.Data.UID)
+		return true
+	})
+}
+
+// apply применяет операцию и возвращает ее нормализованный вид.
+// При ошибке документ не меняется.
+func (idx *nodeIndex) apply(op Op) (Op, error) {
+	switch op.Op {
+	case OpInsert:
+		node, err := idx.insert(op)
+		if err != nil {
+			return op, err
+		}
+		op.UID, op.Node = node.Data.UID, node.Clone()
+		return op, nil
+	case OpDelete:
+		return op, idx.delete(op)
+	case OpMove:
+		return op, idx.move(op)
+	case OpSet:
+		return op, idx.set(op)
+	default:
+		return op, fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+func (idx *nodeIndex) insert(op Op) (*Node, error) {
+	if op.Node == nil {
+		return nil, fmt.Errorf("node is required")
+	}
+	parent, ok := idx.nodes[op.Parent]
+	if !ok {
+		return nil, fmt.Errorf("parent %q not found", op.Parent)
+	}
+
+	node := op.Node.Clone()
+	if node.Data.UID == "" && op.UID != "" {
+		node.Data.UID = op.UID
+	}
+	seen := map[string]bool{}
+	var conflict string
+	walk(node, nil, 1, func(n, _ *Node, _ int) bool {
+		if n.Data.UID == "" {
+			n.Data.UID = NewUID()
+		}
+		if _, exists := idx.nodes[n.Data.UID]; exists || seen[n.Data.UID] {
+			conflict = n.Data.UID
+			return false
+		}
+		seen[n.Data.UID] = true
+		return true
+	})
+	if conflict != "" {
+		return nil, fmt.Errorf("node %q already exists", conflict)
+	}
+
+	parent.Children = insertAt(parent.Children, node, op.Index)
+	idx.add(node, parent)
+	return node, nil
+}
+
+func (idx *nodeIndex) delete(op Op) error {
+	node, parent, err := idx.lookup(op.UID)
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		return fmt.Errorf("root node cannot be deleted")
+	}
+	parent.Children = removeChild(parent.Children, node)
+	idx.remove(node)
+	return nil
+}
+
+func (idx *nodeIndex) move(op Op) error {
+	node, parent, err := idx.lookup(op.UID)
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		return fmt.Errorf("root node cannot be moved")
+	}
+	target, ok := idx.nodes[op.Parent]
+	if !ok {
+		return fmt.Errorf("parent %q not found", op.Parent)
+	}
+	for p := target; p != nil; p = idx.parent[p.Data.UID] {
+		if p == node {
+			return fmt.Errorf("node cannot be moved into its own subtree")
+		}
+	}
+
+	parent.Children = removeChild(parent.Children, node)
+	target.Children = insertAt(target.Children, node, op.Index)
+	idx.parent[node.Data.UID] = target
+	return nil
+}
+
+func (idx *nodeIndex) set(op Op) error {
+	node, _, err := idx.lookup(op.UID)
+	if err != nil {
+		return err
+	}
+	if op.Field == "" {
+		return fmt.Errorf("field is required")
+	}
+	if op.Field == "uid" {
+		return fmt.Errorf("uid cannot be changed")
+	}
+
+	data, err := SetField(node.Data, op.Field, op.Value)
+	if err != nil {
+		return err
+	}
+	node.Data = data
+	return nil
+}
+
+func (idx *nodeIndex) lookup(uid string) (node, parent *Node, err error) {
+	node, ok := idx.nodes[uid]
+	if !ok {
+		return nil, nil, fmt.Errorf("node %q not found", uid)
+	}
+	return node, idx.parent[uid], nil
+}
+
+// SetField возвращает копию данных узла с измененным полем.
+// Пустое значение или null удаляет поле.
+func SetField(d NodeData, field string, value json.RawMessage) (NodeData, error) {
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		return d, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &raw); err != nil {
+		return d, err
+	}
+
+	if isNull(value) {
+		delete(raw, field)
+	} else {
+		raw[field] = value
+	}
+
+	var errs []FieldError
+	updated := decodeNodeData(raw, "data", &errs)
+	if len(errs) > 0 {
+		return d, fmt.Errorf("%s: %s", errs[0].Field, errs[0].Message)
+	}
+	return updated, nil
+}
+
+func insertAt(children []*Node, n *Node, index *int) []*Node {
+	pos := len(children)
+	if index != nil && *index >= 0 && *index < pos {
+		pos = *index
+	}
+	children = append(children, nil)
+	copy(children[pos+1:], children[pos:])
+	children[pos] = n
+	return children
+}
+
+func removeChild(children []*Node, n *Node) []*Node {
+	for i, c := range children {
+		if c == n {
+			return append(children[:i], children[i+1:]...)
+		}
+	}
+	return children
+}
@@ -0,0 +1,147 @@
+package mindmap
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const opsDoc = `{"root":{"data":{"text":"r","uid":"r"},"children":[
+	{"data":{"text":"a","uid":"a"},"children":[{"data":{"text":"a1","uid":"a1"}}]},
+	{"data":{"text":"b","uid":"b"}}
+]}}`
+
+func decodeOps(t *testing.T, s string) []Op {
+	t.Helper()
+	var ops []Op
+	require.NoError(t, json.Unmarshal([]byte(s), &ops))
+	return ops
+}
+
+func childTexts(n *Node) []string {
+	texts := []string{}
+	for _, c := range n.Children {
+		texts = append(texts, c.Data.Text)
+	}
+	return texts
+}
+
+func TestApplyOps(t *testing.T) {
+	doc := mustParse(t, opsDoc)
+	ops := decodeOps(t, `[
+		{"op":"insert","parent":"r","index":0,"node":{"data":{"text":"new","uid":"n"},"children":[{"data":{"text":"n1"}}]}},
+		{"op":"move","uid":"a1","parent":"b"},
+		{"op":"set","uid":"b","field":"text","value":"B"},
+		{"op":"set","uid":"b","field":"color","value":"#ff0000"},
+		{"op":"set","uid":"r","field":"note","value":"root note"},
+		{"op":"delete","uid":"a"}
+	]`)
+
+	result, applied, err := ApplyOps(doc, ops)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"new", "B"}, childTexts(result.Root))
+	assert.Equal(t, "root note", result.Root.Data.Note)
+
+	b, parent := result.Find("b")
+	require.NotNil(t, b)
+	assert.Same(t, result.Root, parent)
+	assert.Equal(t, []string{"a1"}, childTexts(b))
+	assert.JSONEq(t, `"#ff0000"`, string(b.Data.Fields["color"]))
+
+	n1 := result.Root.Children[0].Children[0]
+	assert.NotEmpty(t, n1.Data.UID, "inserted nodes get uids")
+
+	// Returned ops carry the assigned uids, so replaying them reproduces the document
+	require.Len(t, applied, len(ops))
+	assert.Equal(t, n1.Data.UID, applied[0].Node.Children[0].Data.UID)
+	assert.Empty(t, ops[0].Node.Children[0].Data.UID, "input ops are untouched")
+	replayed, _, err := ApplyOps(doc, applied)
+	require.NoError(t, err)
+	assert.Equal(t, mustEncode(t, result), mustEncode(t, replayed))
+
+	assert.Equal(t, []string{"a", "b"}, childTexts(doc.Root), "source document is untouched")
+}
+
+func TestApplyOps_SetNullRemovesField(t *testing.T) {
+	doc := mustParse(t, `{"root":{"data":{"text":"r","uid":"r","color":"#000","note":"x"}}}`)
+	result, _, err := ApplyOps(doc, decodeOps(t, `[
+		{"op":"set","uid":"r","field":"color","value":null},
+		{"op":"set","uid":"r","field":"note"}
+	]`))
+	require.NoError(t, err)
+	assert.NotContains(t, result.Root.Data.Fields, "color")
+	assert.Empty(t, result.Root.Data.Note)
+}
+
+func TestApplyOps_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		ops   string
+		index int
+	}{
+		{"unknown op", `[{"op":"rename","uid":"a"}]`, 0},
+		{"missing node", `[{"op":"delete","uid":"x"}]`, 0},
+		{"delete root", `[{"op":"delete","uid":"r"}]`, 0},
+		{"move root", `[{"op":"move","uid":"r","parent":"a"}]`, 0},
+		{"move into own subtree", `[{"op":"move","uid":"a","parent":"a1"}]`, 0},
+		{"insert without node", `[{"op":"insert","parent":"r"}]`, 0},
+		{"insert duplicate uid", `[{"op":"insert","parent":"r","node":{"data":{"text":"x","uid":"b"}}}]`, 0},
+		{"insert into missing parent", `[{"op":"insert","parent":"x","node":{"data":{"text":"x"}}}]`, 0},
+		{"set uid", `[{"op":"set","uid":"a","field":"uid","value":"z"}]`, 0},
+		{"set wrong type", `[{"op":"set","uid":"a","field":"text","value":{"x":1}}]`, 0},
+		{"second op fails", `[{"op":"delete","uid":"a"},{"op":"set","uid":"a1","field":"text","value":"x"}]`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParse(t, opsDoc)
+			before := mustEncode(t, doc)
+
+			_, _, err := ApplyOps(doc, decodeOps(t, tt.ops))
+			var opErr *OpError
+			require.ErrorAs(t, err, &opErr)
+			assert.Equal(t, tt.index, opErr.Index)
+			assert.Equal(t, before, mustEncode(t, doc))
+		})
+	}
+}
+
+func TestApplyOps_MoveIndexWithinSameParent(t *testing.T) {
+	doc := mustParse(t, opsDoc)
+	result, _, err := ApplyOps(doc, decodeOps(t, `[{"op":"move","uid":"b","parent":"r","index":0}]`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "a"}, childTexts(result.Root))
+}
+
+func TestApplyOpsLenient_SkipsStaleOps(t *testing.T) {
+	doc := mustParse(t, opsDoc)
+
+	// Another participant already deleted "a": edits inside it are dropped,
+	// everything else still applies
+	result, applied, rejected := ApplyOpsLenient(doc, decodeOps(t, `[
+		{"op":"delete","uid":"a"},
+		{"op":"set","uid":"a1","field":"text","value":"lost"},
+		{"op":"insert","parent":"b","node":{"data":{"text":"kept"}}}
+	]`))
+
+	require.Len(t, rejected, 1)
+	assert.Equal(t, 1, rejected[0].Index)
+	require.Len(t, applied, 2)
+
+	insert := applied[1]
+	require.NotNil(t, insert.Node)
+	assert.NotEmpty(t, insert.UID, "assigned uid is returned so every client inserts the same node")
+	assert.Equal(t, insert.UID, insert.Node.Data.UID)
+
+	b, _ := result.Find("b")
+	require.Len(t, b.Children, 1)
+	assert.Equal(t, insert.UID, b.Children[0].Data.UID)
+
+	// Replaying the normalized ops reproduces the same document
+	replayed, _, err := ApplyOps(doc, applied)
+	require.NoError(t, err)
+	assert.Equal(t, mustEncode(t, result), mustEncode(t, replayed))
+}
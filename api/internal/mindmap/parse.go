@@ -0,0 +1,362 @@
+package mindmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits - ограничения на размер документа
+type Limits struct {
+	MaxBytes      int // Максимальный размер JSON в байтах
+	MaxNodes      int // Максимальное количество узлов
+	MaxDepth      int // Максимальная глубина дерева (корень - уровень 1)
+	MaxTextLength int // Максимальная длина текста и заметки узла в символах
+	MaxUIDLength  int // Максимальная длина uid узла в символах (столбцы node_uid в БД)
+}
+
+// DefaultLimits - ограничения, которые применяют обработчики API
+var DefaultLimits = Limits{
+	MaxBytes:      10 << 20,
+	MaxNodes:      10000,
+	MaxDepth:      100,
+	MaxTextLength: 20000,
+	MaxUIDLength:  64,
+}
+
+// maxReportedErrors ограничивает количество ошибок в ответе
+const maxReportedErrors = 50
+
+// ErrEmptyDocument - пустые данные вместо документа
+var ErrEmptyDocument = errors.New("mindmap data is empty")
+
+// FieldError - ошибка в конкретном поле документа
+type FieldError struct {
+	Field   string `json:"field"`   // Путь до поля, например root.children[0].data.text
+	Message string `json:"message"` // Описание ошибки
+}
+
+// ValidationError - набор ошибок валидации документа
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 0 {
+		return "invalid mindmap"
+	}
+	first := e.Errors[0]
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("invalid mindmap: %s: %s", first.Field, first.Message)
+	}
+	return fmt.Sprintf("invalid mindmap: %s: %s (and %d more)", first.Field, first.Message, len(e.Errors)-1)
+}
+
+// Parse разбирает и проверяет документ. Принимается как полный документ
+// ({root, layout, theme, view}), так и один корневой узел ({data, children}).
+// uid узлов не меняются: для их назначения нужно вызвать EnsureUIDs.
+func Parse(data []byte, limits Limits) (*Document, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, ErrEmptyDocument
+	}
+	if limits.MaxBytes > 0 && len(data) > limits.MaxBytes {
+		return nil, &ValidationError{Errors: []FieldError{{
+			Field:   "data",
+			Message: fmt.Sprintf("document is larger than %d bytes", limits.MaxBytes),
+		}}}
+	}
+
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, &ValidationError{Errors: []FieldError{{Field: "data", Message: "must be a JSON object: " + err.Error()}}}
+	}
+
+	p := &parser{limits: limits}
+	doc := &Document{}
+
+	rootRaw, hasRoot := top["root"]
+	if !hasRoot {
+		if _, bare := top["data"]; bare {
+			rootRaw = data
+			hasRoot = true
+		}
+	}
+	if !hasRoot || isNull(rootRaw) {
+		p.fail("root", "is required")
+	} else {
+		doc.Root = p.node(json.NewDecoder(bytes.NewReader(rootRaw)), "root", 1)
+	}
+
+	if raw, ok := top["layout"]; ok && !isNull(raw) {
+		if err := json.Unmarshal(raw, &doc.Layout); err != nil {
+			p.fail("layout", "must be a string")
+		}
+	}
+	if raw, ok := top["theme"]; ok && !isNull(raw) {
+		doc.Theme = &Theme{}
+		if err := json.Unmarshal(raw, doc.Theme); err != nil {
+			p.fail("theme", "must be an object with template and config")
+		}
+	}
+	if raw, ok := top["view"]; ok && !isNull(raw) {
+		if raw[0] != '{' {
+			p.fail("view", "must be an object")
+		} else {
+			doc.View = append(json.RawMessage(nil), raw...)
+		}
+	}
+
+	if len(p.errs) > 0 {
+		return nil, &ValidationError{Errors: p.errs}
+	}
+	return doc, nil
+}
+
+// ParseString - Parse для строки из models.MindMap.Data
+func ParseString(data string, limits Limits) (*Document, error) {
+	return Parse([]byte(data), limits)
+}
+
+// CheckLimits проверяет ограничения на количество узлов, глубину, длину
+// текста и uid для документа, измененного после разбора (например, операциями)
+func (d *Document) CheckLimits(limits Limits) error {
+	p := &parser{limits: limits}
+	if d.Root != nil {
+		p.check(d.Root, "root", 1)
+	}
+	if len(p.errs) > 0 {
+		return &ValidationError{Errors: p.errs}
+	}
+	return nil
+}
+
+func (p *parser) check(n *Node, path string, depth int) {
+	p.nodes++
+	if p.limits.MaxNodes > 0 && p.nodes == p.limits.MaxNodes+1 {
+		p.fail(path, fmt.Sprintf("document has more than %d nodes", p.limits.MaxNodes))
+	}
+	if p.limits.MaxDepth > 0 && depth > p.limits.MaxDepth {
+		p.fail(path, fmt.Sprintf("tree is deeper than %d levels", p.limits.MaxDepth))
+		return
+	}
+	p.checkLength(n.Data.Text, path+".data.text")
+	p.checkLength(n.Data.Note, path+".data.note")
+	p.checkUID(n.Data.UID, path+".data.uid")
+	for i, child := range n.Children {
+		p.check(child, fmt.Sprintf("%s.children[%d]", path, i), depth+1)
+	}
+}
+
+type parser struct {
+	limits Limits
+	nodes  int
+	errs   []FieldError
+}
+
+func (p *parser) fail(field, msg string) {
+	if len(p.errs) < maxReportedErrors {
+		p.errs = append(p.errs, FieldError{Field: field, Message: msg})
+	}
+}
+
+// node разбирает узел из потока dec. Дерево читается за один проход, поэтому
+// время разбора не зависит от глубины дерева.
+func (p *parser) node(dec *json.Decoder, path string, depth int) *Node {
+	p.nodes++
+	if p.limits.MaxNodes > 0 && p.nodes == p.limits.MaxNodes+1 {
+		p.fail(path, fmt.Sprintf("document has more than %d nodes", p.limits.MaxNodes))
+	}
+	if p.limits.MaxDepth > 0 && depth > p.limits.MaxDepth {
+		p.fail(path, fmt.Sprintf("tree is deeper than %d levels", p.limits.MaxDepth))
+		skipValue(dec)
+		return nil
+	}
+
+	tok, err := dec.Token()
+	if err != nil || tok != json.Delim('{') {
+		skipRest(dec, tok)
+		p.fail(path, "must be an object")
+		return nil
+	}
+
+	n := &Node{Children: []*Node{}}
+	hasData := false
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch key {
+		case "data":
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				break
+			}
+			if hasData = !isNull(raw); hasData {
+				n.Data = p.nodeData(raw, path+".data")
+			}
+		case "children":
+			n.Children = p.children(dec, path, depth)
+		default:
+			skipValue(dec)
+		}
+	}
+	_, _ = dec.Token() // '}'
+
+	if !hasData {
+		p.fail(path+".data", "is required")
+	}
+	return n
+}
+
+// children разбирает массив дочерних узлов из потока dec
+func (p *parser) children(dec *json.Decoder, path string, depth int) []*Node {
+	children := []*Node{}
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return children
+	}
+	if tok != json.Delim('[') {
+		skipRest(dec, tok)
+		p.fail(path+".children", "must be an array")
+		return children
+	}
+	for i := 0; dec.More(); i++ {
+		if child := p.node(dec, fmt.Sprintf("%s.children[%d]", path, i), depth+1); child != nil {
+			children = append(children, child)
+		}
+	}
+	_, _ = dec.Token() // ']'
+	return children
+}
+
+// nodeData разбирает и проверяет поле data узла
+func (p *parser) nodeData(raw json.RawMessage, path string) NodeData {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		p.fail(path, "must be an object")
+		return NodeData{}
+	}
+	d := decodeNodeData(fields, path, &p.errs)
+	p.checkLength(d.Text, path+".text")
+	p.checkLength(d.Note, path+".note")
+	p.checkUID(d.UID, path+".uid")
+	return d
+}
+
+// skipValue пропускает в потоке очередное значение целиком
+func skipValue(dec *json.Decoder) {
+	var raw json.RawMessage
+	_ = dec.Decode(&raw)
+}
+
+// skipRest пропускает остаток значения, первый токен которого tok уже прочитан
+func skipRest(dec *json.Decoder, tok json.Token) {
+	if tok != json.Delim('{') && tok != json.Delim('[') {
+		return
+	}
+	for level := 1; level > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			level++
+		case json.Delim('}'), json.Delim(']'):
+			level--
+		}
+	}
+}
+
+func (p *parser) checkLength(s, field string) {
+	if p.limits.MaxTextLength > 0 && utf8.RuneCountInString(s) > p.limits.MaxTextLength {
+		p.fail(field, fmt.Sprintf("is longer than %d characters", p.limits.MaxTextLength))
+	}
+}
+
+func (p *parser) checkUID(uid, field string) {
+	if p.limits.MaxUIDLength > 0 && utf8.RuneCountInString(uid) > p.limits.MaxUIDLength {
+		p.fail(field, fmt.Sprintf("is longer than %d characters", p.limits.MaxUIDLength))
+	}
+}
+
+// decodeNodeData разбирает известные поля узла, ошибки пишет в errs
+func decodeNodeData(raw map[string]json.RawMessage, path string, errs *[]FieldError) NodeData {
+	var d NodeData
+	fail := func(key, msg string) {
+		if len(*errs) < maxReportedErrors {
+			*errs = append(*errs, FieldError{Field: path + "." + key, Message: msg})
+		}
+	}
+	str := func(key string, dst *string) {
+		v, ok := raw[key]
+		if !ok || isNull(v) {
+			return
+		}
+		if err := json.Unmarshal(v, dst); err != nil {
+			// simple-mind-map иногда сохраняет числовой текст числом
+			if key == "text" && isNumber(v) {
+				*dst = string(v)
+				return
+			}
+			fail(key, "must be a string")
+		}
+	}
+
+	str("uid", &d.UID)
+	str("text", &d.Text)
+	str("note", &d.Note)
+	str("hyperlink", &d.Hyperlink)
+	str("hyperlinkTitle", &d.HyperlinkTitle)
+	str("image", &d.Image)
+	str("imageTitle", &d.ImageTitle)
+
+	if v, ok := raw["richText"]; ok && !isNull(v) {
+		if err := json.Unmarshal(v, &d.RichText); err != nil {
+			fail("richText", "must be a boolean")
+		}
+	}
+	if v, ok := raw["icon"]; ok && !isNull(v) {
+		if err := json.Unmarshal(v, &d.Icon); err != nil {
+			fail("icon", "must be an array of strings")
+		}
+	}
+	if v, ok := raw["tag"]; ok && !isNull(v) {
+		var items []json.RawMessage
+		if err := json.Unmarshal(v, &items); err != nil {
+			fail("tag", "must be an array")
+		} else {
+			for i, item := range items {
+				tag, ok := decodeTag(item)
+				if !ok {
+					fail(fmt.Sprintf("tag[%d]", i), "must be a string or an object with text")
+					continue
+				}
+				d.Tag = append(d.Tag, tag)
+			}
+		}
+	}
+
+	for k, v := range raw {
+		if knownDataKeys[k] {
+			continue
+		}
+		if d.Fields == nil {
+			d.Fields = make(map[string]json.RawMessage)
+		}
+		d.Fields[k] = append(json.RawMessage(nil), v...)
+	}
+	return d
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+func isNumber(raw json.RawMessage) bool {
+	return len(raw) > 0 && (raw[0] == '-' || raw[0] >= '0' && raw[0] <= '9')
+}
@@ -0,0 +1,217 @@
+package mindmap
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleDoc = `{
+	"root": {
+		"data": {"text": "Root", "uid": "root-uid", "expand": true, "fillColor": "#fff"},
+		"children": [
+			{"data": {"text": "<p>Rich <b>child</b></p>", "richText": true, "uid": "a", "note": "note a", "tag": ["x", {"text": "y", "style": {"fill": "red"}}]}, "children": []},
+			{"data": {"text": 42, "icon": ["priority_1"]}}
+		]
+	},
+	"layout": "mindMap",
+	"theme": {"template": "classic", "config": {"lineWidth": 2}},
+	"view": {"transform": {"scaleX": 1}}
+}`
+
+func TestParse_FullDocument(t *testing.T) {
+	doc, err := Parse([]byte(sampleDoc), DefaultLimits)
+	require.NoError(t, err)
+
+	assert.Equal(t, "mindMap", doc.Layout)
+	assert.Equal(t, "classic", doc.Theme.Template)
+	assert.Equal(t, 3, doc.NodeCount())
+
+	assert.Equal(t, "root-uid", doc.Root.Data.UID)
+	assert.JSONEq(t, `"#fff"`, string(doc.Root.Data.Fields["fillColor"]))
+
+	a := doc.Root.Children[0]
+	assert.Equal(t, "Rich child", a.Data.PlainText())
+	assert.Equal(t, []string{"x", "y"}, a.Data.TagTexts())
+	assert.JSONEq(t, `{"fill":"red"}`, string(a.Data.Tag[1].Style))
+
+	second := doc.Root.Children[1]
+	assert.Equal(t, "42", second.Data.Text)
+	assert.Empty(t, second.Data.UID, "parse keeps uids as is")
+	assert.NotNil(t, second.Children)
+
+	assert.Equal(t, 1, EnsureUIDs(doc))
+	assert.NotEmpty(t, second.Data.UID)
+}
+
+func TestParse_BareRoot(t *testing.T) {
+	doc, err := Parse([]byte(`{"data": {"text": "only root"}, "children": []}`), DefaultLimits)
+	require.NoError(t, err)
+	assert.Equal(t, "only root", doc.Root.Data.Text)
+	assert.Empty(t, doc.Layout)
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	doc, err := Parse([]byte(sampleDoc), DefaultLimits)
+	require.NoError(t, err)
+
+	encoded, err := doc.Encode()
+	require.NoError(t, err)
+
+	again, err := ParseString(encoded, DefaultLimits)
+	require.NoError(t, err)
+	reencoded, err := again.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, encoded, reencoded)
+	assert.Equal(t, doc.NodeCount(), again.NodeCount())
+
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal([]byte(encoded), &raw))
+	tags := raw["root"].(map[string]any)["children"].([]any)[0].(map[string]any)["data"].(map[string]any)["tag"].([]any)
+	assert.Equal(t, "x", tags[0], "tags without style stay plain strings")
+}
+
+func TestParse_FieldErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		field string
+	}{
+		{"not an object", `[1,2]`, "data"},
+		{"missing root", `{"layout": "mindMap"}`, "root"},
+		{"missing node data", `{"root": {"children": []}}`, "root.data"},
+		{"text not a string", `{"root": {"data": {"text": {"a": 1}}}}`, "root.data.text"},
+		{"children not an array", `{"root": {"data": {"text": "r"}, "children": {}}}`, "root.children"},
+		{"child not an object", `{"root": {"data": {"text": "r"}, "children": [{"data": {"text": "a"}}, 5]}}`, "root.children[1]"},
+		{"root not an object", `{"root": [{"data": {}}]}`, "root"},
+		{"null node data", `{"root": {"data": null, "children": []}}`, "root.data"},
+		{"nested children not an array", `{"root": {"data": {"text": "r"}, "children": [{"data": {"text": "a"}, "children": {"x": [1, {"y": 2}]}}, 5]}}`, "root.children[0].children"},
+		{"bad tag", `{"root": {"data": {"text": "r", "tag": [true]}}}`, "root.data.tag[0]"},
+		{"bad icon", `{"root": {"data": {"text": "r", "icon": "x"}}}`, "root.data.icon"},
+		{"bad layout", `{"root": {"data": {"text": "r"}}, "layout": 1}`, "layout"},
+		{"bad view", `{"root": {"data": {"text": "r"}}, "view": "x"}`, "view"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input), DefaultLimits)
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			require.NotEmpty(t, verr.Errors)
+			assert.Equal(t, tt.field, verr.Errors[0].Field)
+		})
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	_, err := Parse([]byte("  "), DefaultLimits)
+	assert.ErrorIs(t, err, ErrEmptyDocument)
+}
+
+func TestParse_Limits(t *testing.T) {
+	deep := `{"data":{"text":"leaf"}}`
+	for i := 0; i < 5; i++ {
+		deep = `{"data":{"text":"n"},"children":[` + deep + `]}`
+	}
+
+	_, err := Parse([]byte(deep), Limits{MaxDepth: 3})
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "root.children[0].children[0].children[0]", verr.Errors[0].Field)
+
+	_, err = Parse([]byte(deep), Limits{MaxNodes: 4})
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Errors[0].Message, "more than 4 nodes")
+
+	_, err = Parse([]byte(deep), Limits{MaxBytes: 10})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "data", verr.Errors[0].Field)
+
+	long := `{"root":{"data":{"text":"` + strings.Repeat("я", 11) + `"}}}`
+	_, err = Parse([]byte(long), Limits{MaxTextLength: 10})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "root.data.text", verr.Errors[0].Field)
+
+	longUID := `{"root":{"data":{"text":"r","uid":"r"},"children":[{"data":{"text":"a","uid":"` + strings.Repeat("u", 65) + `"}}]}}`
+	_, err = Parse([]byte(longUID), DefaultLimits)
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "root.children[0].data.uid", verr.Errors[0].Field)
+
+	doc, err := Parse([]byte(longUID), Limits{})
+	require.NoError(t, err)
+	err = doc.CheckLimits(DefaultLimits)
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "root.children[0].data.uid", verr.Errors[0].Field)
+}
+
+// Глубокий документ разбирается за то же время, что и плоский того же размера:
+// каждый уровень не должен заново разбирать свое поддерево
+func TestParse_DeepDocumentTime(t *testing.T) {
+	if testing.Short() {
+		t.Skip("parses two 9.5 MB documents")
+	}
+	text := strings.Repeat("x", 19000)
+	leaves := strings.Repeat(`{"data":{"text":"`+text+`"}},`, 499) + `{"data":{"text":"` + text + `"}}`
+	deep := leaves
+	for i := 0; i < 98; i++ {
+		deep = `{"data":{"text":"n"},"children":[` + deep + `]}`
+	}
+	flat := `{"data":{"text":"n"},"children":[` + leaves + strings.Repeat(`,{"data":{"text":"n"}}`, 97) + `]}`
+
+	elapsed := func(data string) time.Duration {
+		start := time.Now()
+		_, err := ParseString(data, DefaultLimits)
+		require.NoError(t, err)
+		return time.Since(start)
+	}
+	flatTime := elapsed(flat)
+	deepTime := elapsed(deep)
+	assert.Less(t, deepTime, 3*flatTime+200*time.Millisecond, "flat %v, deep %v", flatTime, deepTime)
+}
+
+func TestEnsureUIDs_Duplicates(t *testing.T) {
+	doc, err := Parse([]byte(`{"root":{"data":{"text":"r","uid":"same"},"children":[{"data":{"text":"a","uid":"same"}}]}}`), DefaultLimits)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, EnsureUIDs(doc))
+	assert.Equal(t, "same", doc.Root.Data.UID)
+	assert.NotEqual(t, "same", doc.Root.Children[0].Data.UID)
+	assert.Zero(t, EnsureUIDs(doc), "uids are stable once assigned")
+}
+
+func TestFind(t *testing.T) {
+	doc, err := Parse([]byte(sampleDoc), DefaultLimits)
+	require.NoError(t, err)
+
+	node, parent := doc.Find("a")
+	require.NotNil(t, node)
+	assert.Same(t, doc.Root, parent)
+
+	node, _ = doc.Find("missing")
+	assert.Nil(t, node)
+}
+
+func TestStripHTML(t *testing.T) {
+	assert.Equal(t, "a & b", StripHTML("a &amp; b"))
+	assert.Equal(t, "line one\nline two", StripHTML("<p>line one</p><p>line <span style=\"x\">two</span></p>"))
+	assert.Equal(t, "x\ny", StripHTML("x<br/>y"))
+}
+
+func TestCheckLimits(t *testing.T) {
+	doc, err := Parse([]byte(sampleDoc), DefaultLimits)
+	require.NoError(t, err)
+	assert.NoError(t, doc.CheckLimits(DefaultLimits))
+
+	err = doc.CheckLimits(Limits{MaxNodes: 2})
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "root.children[1]", verr.Errors[0].Field)
+
+	err = doc.CheckLimits(Limits{MaxDepth: 1})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "root.children[0]", verr.Errors[0].Field)
+}
@@ -0,0 +1,35 @@
+package mindmap
+
+import "strings"
+
+// SearchText возвращает текст узла для полнотекстового поиска: текст, заметку и теги
+func (d NodeData) SearchText() string {
+	parts := make([]string, 0, 2+len(d.Tag))
+	if text := strings.TrimSpace(d.PlainText()); text != "" {
+		parts = append(parts, text)
+	}
+	if note := strings.TrimSpace(d.Note); note != "" {
+		parts = append(parts, note)
+	}
+	for _, tag := range d.TagTexts() {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			parts = append(parts, tag)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// SearchText возвращает текст документа для полнотекстового поиска, по строке на узел
+func (d *Document) SearchText() string {
+	var b strings.Builder
+	d.Walk(func(n, _ *Node, _ int) bool {
+		if text := n.Data.SearchText(); text != "" {
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(text)
+		}
+		return true
+	})
+	return b.String()
+}
@@ -0,0 +1,18 @@
+package mindmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchText(t *testing.T) {
+	doc, err := ParseString(`{"root":{"data":{"text":"<p>Проект <b>Альфа</b></p>","richText":true,"note":"срок - март"},"children":[
+		{"data":{"text":"Risks","tag":["urgent",{"text":"legal","style":{}}]},"children":[]},
+		{"data":{"text":"  "},"children":[]}
+	]}}`, Limits{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Проект Альфа срок - март\nRisks urgent legal", doc.SearchText())
+}
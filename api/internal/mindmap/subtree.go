@@ -0,0 +1,76 @@
+package mindmap
+
+import (
+	"fmt"
+)
+
+// Transplant переносит (asCopy = false) или копирует поддерево uid из документа src
+// в узел parent документа dst на позицию index (nil - в конец). Исходные документы
+// не меняются, возвращаются измененные копии и вставленное поддерево; при
+// копировании newSrc совпадает с src.
+//
+// При переносе uid узлов сохраняются, чтобы ссылки на них можно было поправить;
+// uid, уже занятые в dst, заменяются новыми. При копировании все uid новые.
+func Transplant(src, dst *Document, uid, parent string, index *int, asCopy bool) (newSrc, newDst *Document, node *Node, err error) {
+	found, _ := src.Find(uid)
+	if found == nil {
+		return nil, nil, nil, fmt.Errorf("node %q not found", uid)
+	}
+	if !asCopy && found == src.Root {
+		return nil, nil, nil, fmt.Errorf("root node cannot be moved")
+	}
+
+	node = found.Clone()
+	if asCopy {
+		RegenerateUIDs(node)
+	} else {
+		taken := make(map[string]bool)
+		dst.Walk(func(n, _ *Node, _ int) bool {
+			taken[n.Data.UID] = true
+			return true
+		})
+		walk(node, nil, 1, func(n, _ *Node, _ int) bool {
+			if n.Data.UID == "" || taken[n.Data.UID] {
+				n.Data.UID = NewUID()
+			}
+			taken[n.Data.UID] = true
+			return true
+		})
+	}
+
+	newDst, _, err = ApplyOps(dst, []Op{{Op: OpInsert, Parent: parent, Index: index, Node: node}})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	newSrc = src
+	if !asCopy {
+		if newSrc, _, err = ApplyOps(src, []Op{{Op: OpDelete, UID: uid}}); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return newSrc, newDst, node, nil
+}
+
+// ExtractSubtree выносит поддерево uid в отдельный документ с оформлением src.
+// В rest на месте поддерева остается узел-заглушка с тем же uid и текстом,
+// в который вызывающий записывает ссылку на новую карту.
+func ExtractSubtree(src *Document, uid string) (rest, branch *Document, err error) {
+	found, parent := src.Find(uid)
+	if found == nil {
+		return nil, nil, fmt.Errorf("node %q not found", uid)
+	}
+	if parent == nil {
+		return nil, nil, fmt.Errorf("root node cannot be extracted")
+	}
+
+	branch = src.Clone()
+	branch.Root = found.Clone()
+	branch.View = nil // Положение и масштаб относятся к исходной карте
+
+	rest = src.Clone()
+	stub, _ := rest.Find(uid)
+	stub.Data = NodeData{UID: uid, Text: found.Data.Text, RichText: found.Data.RichText}
+	stub.Children = []*Node{}
+	return rest, branch, nil
+}
@@ -0,0 +1,81 @@
+package mindmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const subtreeDst = `{"root":{"data":{"text":"dst","uid":"d"},"children":[
+	{"data":{"text":"x","uid":"x"}},
+	{"data":{"text":"taken","uid":"a1"}}
+]}}`
+
+func TestTransplantMove(t *testing.T) {
+	src, dst := mustParse(t, opsDoc), mustParse(t, subtreeDst)
+	index := 0
+
+	newSrc, newDst, node, err := Transplant(src, dst, "a", "d", &index, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"b"}, childTexts(newSrc.Root))
+	assert.Equal(t, []string{"a", "x", "taken"}, childTexts(newDst.Root))
+	assert.Equal(t, "a", node.Data.UID, "free uid is kept")
+	require.Len(t, node.Children, 1)
+	assert.NotEqual(t, "a1", node.Children[0].Data.UID, "uid taken in dst is replaced")
+
+	// Исходные документы не меняются
+	assert.Equal(t, []string{"a", "b"}, childTexts(src.Root))
+	assert.Equal(t, []string{"x", "taken"}, childTexts(dst.Root))
+}
+
+func TestTransplantCopy(t *testing.T) {
+	src, dst := mustParse(t, opsDoc), mustParse(t, subtreeDst)
+
+	newSrc, newDst, node, err := Transplant(src, dst, "a", "x", nil, true)
+	require.NoError(t, err)
+
+	assert.Same(t, src, newSrc)
+	x, _ := newDst.Find("x")
+	require.NotNil(t, x)
+	assert.Equal(t, []string{"a"}, childTexts(x))
+	assert.NotEqual(t, "a", node.Data.UID)
+	assert.NotEqual(t, "a1", node.Children[0].Data.UID)
+	assert.Equal(t, "a1", node.Children[0].Data.Text)
+}
+
+func TestTransplantErrors(t *testing.T) {
+	src, dst := mustParse(t, opsDoc), mustParse(t, subtreeDst)
+
+	_, _, _, err := Transplant(src, dst, "missing", "d", nil, false)
+	assert.Error(t, err)
+	_, _, _, err = Transplant(src, dst, "r", "d", nil, false)
+	assert.Error(t, err, "root cannot be moved")
+	_, _, _, err = Transplant(src, dst, "a", "missing", nil, false)
+	assert.Error(t, err)
+
+	_, _, _, err = Transplant(src, dst, "r", "d", nil, true)
+	assert.NoError(t, err, "root can be copied")
+}
+
+func TestExtractSubtree(t *testing.T) {
+	src := mustParse(t, opsDoc)
+	src.Layout = "mindMap"
+
+	rest, branch, err := ExtractSubtree(src, "a")
+	require.NoError(t, err)
+
+	assert.Equal(t, "a", branch.Root.Data.UID)
+	assert.Equal(t, []string{"a1"}, childTexts(branch.Root))
+	assert.Equal(t, "mindMap", branch.Layout)
+
+	stub, _ := rest.Find("a")
+	require.NotNil(t, stub)
+	assert.Equal(t, "a", stub.Data.Text)
+	assert.Empty(t, stub.Children)
+	assert.Equal(t, []string{"a", "b"}, childTexts(rest.Root))
+
+	_, _, err = ExtractSubtree(src, "r")
+	assert.Error(t, err)
+}
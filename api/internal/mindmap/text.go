@@ -0,0 +1,38 @@
+package mindmap
+
+import (
+	"html"
+	"strings"
+)
+
+// blockTags - теги, после которых в простом тексте начинается новая строка
+var blockTags = map[string]bool{"p": true, "div": true, "br": true, "li": true}
+
+// StripHTML превращает HTML из узлов с richText в простой текст
+func StripHTML(s string) string {
+	if !strings.ContainsRune(s, '<') {
+		return html.UnescapeString(s)
+	}
+	var b strings.Builder
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			b.WriteString(html.UnescapeString(s))
+			break
+		}
+		b.WriteString(html.UnescapeString(s[:i]))
+		j := strings.IndexByte(s[i:], '>')
+		if j < 0 {
+			break
+		}
+		tag := strings.TrimLeft(s[i+1:i+j], "/")
+		if k := strings.IndexAny(tag, " \t\n/"); k >= 0 {
+			tag = tag[:k]
+		}
+		if blockTags[strings.ToLower(tag)] && b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+		s = s[i+j+1:]
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,42 @@
+package mindmap
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// NewUID генерирует uid узла в том же формате, что и simple-mind-map (UUID v4)
+func NewUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("mindmap: generate uid: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// EnsureUIDs назначает uid узлам без него и узлам, чей uid уже встречался
+// раньше в дереве. Существующие уникальные uid не меняются.
+// Возвращает количество назначенных uid.
+func EnsureUIDs(doc *Document) int {
+	seen := make(map[string]bool)
+	assigned := 0
+	doc.Walk(func(n, _ *Node, _ int) bool {
+		if n.Data.UID == "" || seen[n.Data.UID] {
+			n.Data.UID = NewUID()
+			assigned++
+		}
+		seen[n.Data.UID] = true
+		return true
+	})
+	return assigned
+}
+
+// RegenerateUIDs назначает новые uid всем узлам поддерева
+func RegenerateUIDs(n *Node) {
+	walk(n, nil, 1, func(n, _ *Node, _ int) bool {
+		n.Data.UID = NewUID()
+		return true
+	})
+}
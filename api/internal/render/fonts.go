@@ -0,0 +1,87 @@
+package render
+
+import (
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Шрифты Go: свободная лицензия, есть кириллица. Одни и те же шрифты
+// используются для измерения текста и для PNG и PDF, поэтому текст
+// помещается в узлы одинаково во всех форматах.
+var (
+	fontsOnce   sync.Once
+	regularFont *opentype.Font
+	boldFont    *opentype.Font
+	fontsErr    error
+)
+
+func loadFonts() error {
+	fontsOnce.Do(func() {
+		if regularFont, fontsErr = opentype.Parse(goregular.TTF); fontsErr != nil {
+			return
+		}
+		boldFont, fontsErr = opentype.Parse(gobold.TTF)
+	})
+	return fontsErr
+}
+
+type faceKey struct {
+	size float64
+	bold bool
+}
+
+// faceCache - начертания одной отрисовки. font.Face нельзя использовать
+// из нескольких горутин, поэтому кеш создается на каждый вызов.
+type faceCache struct {
+	faces map[faceKey]font.Face
+}
+
+func newFaceCache() *faceCache {
+	return &faceCache{faces: make(map[faceKey]font.Face)}
+}
+
+// face возвращает начертание размера size. nil - шрифты не загрузились,
+// тогда ширина текста оценивается приблизительно.
+func (c *faceCache) face(size float64, bold bool) font.Face {
+	key := faceKey{size: size, bold: bold}
+	if f, ok := c.faces[key]; ok {
+		return f
+	}
+	if loadFonts() != nil {
+		return nil
+	}
+	src := regularFont
+	if bold {
+		src = boldFont
+	}
+	f, err := opentype.NewFace(src, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil
+	}
+	c.faces[key] = f
+	return f
+}
+
+// measure - ширина строки в единицах раскладки
+func (c *faceCache) measure(s string, size float64, bold bool) float64 {
+	f := c.face(size, bold)
+	if f == nil {
+		return float64(len([]rune(s))) * size * 0.6
+	}
+	return fixedToFloat(font.MeasureString(f, s))
+}
+
+func (c *faceCache) Close() {
+	for _, f := range c.faces {
+		f.Close()
+	}
+}
+
+func fixedToFloat(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
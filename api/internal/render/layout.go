@@ -0,0 +1,279 @@
+package render
+
+import (
+	"math"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// Параметры раскладки в единицах SVG (px)
+const (
+	margin        = 30  // Поле вокруг карты
+	maxTextWidth  = 260 // Ширина, после которой текст узла переносится
+	lineHeight    = 1.3 // Межстрочный интервал относительно размера шрифта
+	horizontalGap = 50  // Между уровнями в горизонтальных структурах
+	verticalGap   = 14  // Между соседними ветками в горизонтальных структурах
+	orgLevelGap   = 40  // Между уровнями в организационной структуре
+	orgSiblingGap = 20  // Между соседними ветками в организационной структуре
+)
+
+// Структуры карты simple-mind-map, кроме logicalStructure - она по умолчанию
+const (
+	layoutMindMap      = "mindMap"
+	layoutOrganization = "organizationStructure"
+)
+
+// box - узел с размерами и положением (левый верхний угол)
+type box struct {
+	style     levelStyle
+	lines     []string
+	lineWidth []float64
+	x, y      float64
+	w, h      float64
+	extent    float64 // Размер поддерева вдоль оси, по которой идут соседи
+	children  []*box
+}
+
+// edge - линия между узлами: кривая Безье из четырех точек или ломаная
+type edge struct {
+	points []point
+	curve  bool
+}
+
+type point struct {
+	x, y float64
+}
+
+// scene - разложенная карта, которую рисуют все форматы
+type scene struct {
+	width, height float64
+	theme         theme
+	boxes         []*box
+	edges         []edge
+}
+
+// layout раскладывает документ. Свернутые ветки (expand: false) не рисуются.
+func layout(doc *mindmap.Document, faces *faceCache) *scene {
+	t := themeOf(doc)
+	root := buildBox(doc.Root, 1, t, faces)
+
+	sc := &scene{theme: t}
+	switch doc.Layout {
+	case layoutOrganization:
+		measureVertical(root)
+		placeVertical(root, 0, 0)
+		connectVertical(sc, root)
+	case layoutMindMap:
+		right, left := splitSides(root.children)
+		placeBothSides(root, right, left)
+		connectHorizontal(sc, root, right, 1)
+		connectHorizontal(sc, root, left, -1)
+	default:
+		placeBothSides(root, root.children, nil)
+		connectHorizontal(sc, root, root.children, 1)
+	}
+
+	collect(sc, root)
+	normalize(sc)
+	return sc
+}
+
+func buildBox(n *mindmap.Node, depth int, t theme, faces *faceCache) *box {
+	b := &box{style: t.level(n.Data, depth)}
+	b.measure(n.Data.PlainText(), faces)
+	if collapsedNode(n.Data) {
+		return b
+	}
+	for _, child := range n.Children {
+		b.children = append(b.children, buildBox(child, depth+1, t, faces))
+	}
+	return b
+}
+
+func collapsedNode(d mindmap.NodeData) bool {
+	return string(d.Fields["expand"]) == "false"
+}
+
+// measure переносит текст по словам и вычисляет размер узла
+func (b *box) measure(text string, faces *faceCache) {
+	size, bold := b.style.FontSize, b.style.bold()
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && faces.measure(candidate, size, bold) > maxTextWidth {
+				b.addLine(line, faces)
+				candidate = word
+			}
+			line = candidate
+		}
+		b.addLine(line, faces)
+	}
+	// Пустые строки по краям не занимают места
+	for len(b.lines) > 1 && b.lines[len(b.lines)-1] == "" {
+		b.lines, b.lineWidth = b.lines[:len(b.lines)-1], b.lineWidth[:len(b.lineWidth)-1]
+	}
+
+	textWidth := 0.0
+	for _, w := range b.lineWidth {
+		textWidth = max(textWidth, w)
+	}
+	b.w = max(textWidth, size) + 2*b.style.PaddingX
+	b.h = float64(len(b.lines))*size*lineHeight + 2*b.style.PaddingY
+}
+
+func (b *box) addLine(line string, faces *faceCache) {
+	b.lines = append(b.lines, line)
+	b.lineWidth = append(b.lineWidth, faces.measure(line, b.style.FontSize, b.style.bold()))
+}
+
+// --- Горизонтальные структуры: logicalStructure и mindMap ---
+
+// splitSides делит ветки корня: первая половина справа, остальные слева
+func splitSides(children []*box) (right, left []*box) {
+	half := (len(children) + 1) / 2
+	return children[:half], children[half:]
+}
+
+// measureHorizontal - высота поддерева
+func measureHorizontal(b *box) float64 {
+	b.extent = max(b.h, stackExtent(b.children, verticalGap, measureHorizontal))
+	return b.extent
+}
+
+// stackExtent - размер ряда соседних поддеревьев с зазорами gap
+func stackExtent(children []*box, gap float64, measure func(*box) float64) float64 {
+	total := 0.0
+	for i, child := range children {
+		if i > 0 {
+			total += gap
+		}
+		total += measure(child)
+	}
+	return total
+}
+
+// placeBothSides ставит корень в начало координат и раскладывает ветки справа и слева
+func placeBothSides(root *box, right, left []*box) {
+	measureHorizontal(root)
+	root.x, root.y = 0, 0
+	center := root.h / 2
+	placeStack(right, root.x+root.w+horizontalGap, center, 1)
+	placeStack(left, root.x-horizontalGap, center, -1)
+}
+
+// placeStack раскладывает соседние ветки столбиком, центрируя столбик на center.
+// side=1 - ветки растут вправо от x, side=-1 - влево.
+func placeStack(children []*box, x, center float64, side int) {
+	top := center - stackExtent(children, verticalGap, func(c *box) float64 { return c.extent })/2
+	for _, child := range children {
+		placeHorizontal(child, x, top, side)
+		top += child.extent + verticalGap
+	}
+}
+
+func placeHorizontal(b *box, x, top float64, side int) {
+	if side > 0 {
+		b.x = x
+	} else {
+		b.x = x - b.w
+	}
+	b.y = top + (b.extent-b.h)/2
+
+	next := b.x + b.w + horizontalGap
+	if side < 0 {
+		next = b.x - horizontalGap
+	}
+	placeStack(b.children, next, b.y+b.h/2, side)
+}
+
+// connectHorizontal соединяет узлы кривыми от боковой стороны родителя к ребенку
+func connectHorizontal(sc *scene, parent *box, children []*box, side int) {
+	for _, child := range children {
+		from := point{parent.x + parent.w, parent.y + parent.h/2}
+		to := point{child.x, child.y + child.h/2}
+		if side < 0 {
+			from.x = parent.x
+			to.x = child.x + child.w
+		}
+		mid := (from.x + to.x) / 2
+		sc.edges = append(sc.edges, edge{
+			points: []point{from, {mid, from.y}, {mid, to.y}, to},
+			curve:  true,
+		})
+		connectHorizontal(sc, child, child.children, side)
+	}
+}
+
+// --- Вертикальная структура: organizationStructure ---
+
+// measureVertical - ширина поддерева
+func measureVertical(b *box) float64 {
+	b.extent = max(b.w, stackExtent(b.children, orgSiblingGap, measureVertical))
+	return b.extent
+}
+
+func placeVertical(b *box, left, top float64) {
+	b.x = left + (b.extent-b.w)/2
+	b.y = top
+
+	total := stackExtent(b.children, orgSiblingGap, func(c *box) float64 { return c.extent })
+	childLeft := left + (b.extent-total)/2
+	for _, child := range b.children {
+		placeVertical(child, childLeft, top+b.h+orgLevelGap)
+		childLeft += child.extent + orgSiblingGap
+	}
+}
+
+// connectVertical соединяет узлы ломаными: вниз от родителя, вдоль уровня, вниз к ребенку
+func connectVertical(sc *scene, parent *box) {
+	for _, child := range parent.children {
+		from := point{parent.x + parent.w/2, parent.y + parent.h}
+		to := point{child.x + child.w/2, child.y}
+		mid := from.y + orgLevelGap/2
+		sc.edges = append(sc.edges, edge{points: []point{from, {from.x, mid}, {to.x, mid}, to}})
+		connectVertical(sc, child)
+	}
+}
+
+// --- Общее ---
+
+func collect(sc *scene, b *box) {
+	sc.boxes = append(sc.boxes, b)
+	for _, child := range b.children {
+		collect(sc, child)
+	}
+}
+
+// normalize сдвигает карту в положительные координаты с полями и считает размер сцены
+func normalize(sc *scene) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, b := range sc.boxes {
+		minX, minY = min(minX, b.x), min(minY, b.y)
+		maxX, maxY = max(maxX, b.x+b.w), max(maxY, b.y+b.h)
+	}
+	dx, dy := margin-minX, margin-minY
+	for _, b := range sc.boxes {
+		b.x += dx
+		b.y += dy
+	}
+	for i := range sc.edges {
+		for j := range sc.edges[i].points {
+			sc.edges[i].points[j].x += dx
+			sc.edges[i].points[j].y += dy
+		}
+	}
+	sc.width = maxX - minX + 2*margin
+	sc.height = maxY - minY + 2*margin
+}
+
+// baseline - координата базовой линии строки i узла
+func (b *box) baseline(i int) float64 {
+	size := b.style.FontSize
+	return b.y + b.style.PaddingY + float64(i)*size*lineHeight + size*(lineHeight-1)/2 + size*0.8
+}
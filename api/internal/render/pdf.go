@@ -0,0 +1,87 @@
+package render
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// writePDF рисует карту на одной странице размером со сцену (1 px = 1 pt)
+func writePDF(w io.Writer, sc *scene) error {
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: "pt",
+		Size:    gofpdf.SizeType{Wd: sc.width, Ht: sc.height},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddUTF8FontFromBytes("go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("go", "B", gobold.TTF)
+	orientation := "P"
+	if sc.width > sc.height {
+		orientation = "L"
+	}
+	pdf.AddPageFormat(orientation, gofpdf.SizeType{Wd: sc.width, Ht: sc.height})
+	t := sc.theme
+
+	if c, ok := parseColor(t.BackgroundColor); ok {
+		pdf.SetFillColor(int(c.R), int(c.G), int(c.B))
+		pdf.Rect(0, 0, sc.width, sc.height, "F")
+	}
+
+	if c, ok := parseColor(t.LineColor); ok && t.LineWidth > 0 {
+		pdf.SetDrawColor(int(c.R), int(c.G), int(c.B))
+		pdf.SetLineWidth(t.LineWidth)
+		for _, e := range sc.edges {
+			p := e.points
+			if e.curve {
+				pdf.CurveBezierCubic(p[0].x, p[0].y, p[1].x, p[1].y, p[2].x, p[2].y, p[3].x, p[3].y, "D")
+				continue
+			}
+			for i := 1; i < len(p); i++ {
+				pdf.Line(p[i-1].x, p[i-1].y, p[i].x, p[i].y)
+			}
+		}
+	}
+
+	for _, b := range sc.boxes {
+		s := b.style
+		fill, hasFill := parseColor(s.FillColor)
+		border, hasBorder := parseColor(s.BorderColor)
+		hasBorder = hasBorder && s.BorderWidth > 0
+		style := ""
+		if hasFill {
+			pdf.SetFillColor(int(fill.R), int(fill.G), int(fill.B))
+			style += "F"
+		}
+		if hasBorder {
+			pdf.SetDrawColor(int(border.R), int(border.G), int(border.B))
+			pdf.SetLineWidth(s.BorderWidth)
+			style += "D"
+		}
+		if style != "" {
+			pdf.RoundedRect(b.x, b.y, b.w, b.h, min(s.BorderRadius, b.h/2), "1234", style)
+		}
+
+		c, ok := parseColor(s.Color)
+		if !ok {
+			continue
+		}
+		fontStyle := ""
+		if s.bold() {
+			fontStyle = "B"
+		}
+		pdf.SetFont("go", fontStyle, s.FontSize)
+		pdf.SetTextColor(int(c.R), int(c.G), int(c.B))
+		for i, line := range b.lines {
+			pdf.Text(b.x+(b.w-b.lineWidth[i])/2, b.baseline(i), line)
+		}
+	}
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("write pdf: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package render
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fogleman/gg"
+)
+
+// canvas - размер растра и преобразование координат сцены в пиксели
+type canvas struct {
+	width, height int
+	scale         float64
+	dx, dy        float64
+}
+
+func (c canvas) pt(p point) (float64, float64) {
+	return p.x*c.scale + c.dx, p.y*c.scale + c.dy
+}
+
+func writePNG(w io.Writer, sc *scene, faces *faceCache, cv canvas) error {
+	if cv.width < 1 || cv.height < 1 {
+		return fmt.Errorf("invalid image size %dx%d", cv.width, cv.height)
+	}
+	dc := gg.NewContext(cv.width, cv.height)
+	t := sc.theme
+
+	if c, ok := parseColor(t.BackgroundColor); ok {
+		dc.SetColor(c)
+		dc.Clear()
+	}
+
+	if c, ok := parseColor(t.LineColor); ok && t.LineWidth > 0 {
+		dc.SetColor(c)
+		dc.SetLineWidth(t.LineWidth * cv.scale)
+		for _, e := range sc.edges {
+			dc.MoveTo(cv.pt(e.points[0]))
+			if e.curve {
+				x1, y1 := cv.pt(e.points[1])
+				x2, y2 := cv.pt(e.points[2])
+				x3, y3 := cv.pt(e.points[3])
+				dc.CubicTo(x1, y1, x2, y2, x3, y3)
+			} else {
+				for _, p := range e.points[1:] {
+					dc.LineTo(cv.pt(p))
+				}
+			}
+			dc.Stroke()
+		}
+	}
+
+	for _, b := range sc.boxes {
+		s := b.style
+		x, y := cv.pt(point{b.x, b.y})
+		bw, bh, r := b.w*cv.scale, b.h*cv.scale, s.BorderRadius*cv.scale
+		if c, ok := parseColor(s.FillColor); ok {
+			dc.SetColor(c)
+			dc.DrawRoundedRectangle(x, y, bw, bh, r)
+			dc.Fill()
+		}
+		if c, ok := parseColor(s.BorderColor); ok && s.BorderWidth > 0 {
+			dc.SetColor(c)
+			dc.SetLineWidth(s.BorderWidth * cv.scale)
+			dc.DrawRoundedRectangle(x, y, bw, bh, r)
+			dc.Stroke()
+		}
+
+		c, ok := parseColor(s.Color)
+		face := faces.face(s.FontSize*cv.scale, s.bold())
+		if !ok || face == nil {
+			continue
+		}
+		dc.SetColor(c)
+		dc.SetFontFace(face)
+		for i, line := range b.lines {
+			lx, ly := cv.pt(point{b.x + (b.w-b.lineWidth[i])/2, b.baseline(i)})
+			dc.DrawString(line, lx, ly)
+		}
+	}
+
+	return dc.EncodePNG(w)
+}
@@ -0,0 +1,121 @@
+// Package render раскладывает документ карты (mindmap.Document) на плоскости
+// и рисует его без браузера: SVG, PNG и PDF. Поддерживаются структуры
+// logicalStructure, mindMap и organizationStructure; прочие рисуются как
+// logicalStructure. Цвета берутся из темы карты и стилей узлов.
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// Форматы вывода
+const (
+	FormatSVG = "svg"
+	FormatPNG = "png"
+	FormatPDF = "pdf"
+)
+
+// Ограничения растра
+const (
+	MaxScale     = 4
+	maxPNGPixels = 40_000_000 // Ширина * высота итогового PNG
+)
+
+// ErrUnknownFormat - формат вывода не поддерживается
+var ErrUnknownFormat = errors.New("unknown render format")
+
+// Options - параметры отрисовки
+type Options struct {
+	Scale float64 // Множитель разрешения PNG, 1 - пиксель на единицу раскладки
+}
+
+// ContentType возвращает MIME-тип формата
+func ContentType(format string) string {
+	switch format {
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatPNG:
+		return "image/png"
+	case FormatPDF:
+		return "application/pdf"
+	}
+	return ""
+}
+
+// Render рисует документ в выбранном формате
+func Render(doc *mindmap.Document, format string, opts Options) ([]byte, error) {
+	if ContentType(format) == "" {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+	if doc.Root == nil {
+		return nil, errors.New("document has no root")
+	}
+
+	faces := newFaceCache()
+	defer faces.Close()
+	sc := layout(doc, faces)
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case FormatSVG:
+		err = writeSVG(&buf, sc)
+	case FormatPNG:
+		scale := opts.Scale
+		if scale <= 0 {
+			scale = 1
+		}
+		scale = min(scale, MaxScale)
+		// Большие карты уменьшаются, чтобы не выделять гигабайты под растр
+		if pixels := sc.width * sc.height * scale * scale; pixels > maxPNGPixels {
+			scale *= math.Sqrt(maxPNGPixels / pixels)
+		}
+		err = writePNG(&buf, sc, faces, canvas{
+			width:  int(sc.width*scale + 0.5),
+			height: int(sc.height*scale + 0.5),
+			scale:  scale,
+		})
+	case FormatPDF:
+		err = writePDF(&buf, sc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Размер превью для списка карт
+const (
+	ThumbnailWidth  = 320
+	ThumbnailHeight = 200
+)
+
+// Thumbnail рисует превью: PNG постоянного размера, карта уменьшена и вписана по центру
+func Thumbnail(doc *mindmap.Document) ([]byte, error) {
+	if doc.Root == nil {
+		return nil, errors.New("document has no root")
+	}
+
+	faces := newFaceCache()
+	defer faces.Close()
+	sc := layout(doc, faces)
+
+	scale := min(ThumbnailWidth/sc.width, ThumbnailHeight/sc.height, 1)
+	var buf bytes.Buffer
+	err := writePNG(&buf, sc, faces, canvas{
+		width:  ThumbnailWidth,
+		height: ThumbnailHeight,
+		scale:  scale,
+		dx:     (ThumbnailWidth - sc.width*scale) / 2,
+		dy:     (ThumbnailHeight - sc.height*scale) / 2,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
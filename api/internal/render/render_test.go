@@ -0,0 +1,193 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func parseDoc(t *testing.T, data string) *mindmap.Document {
+	t.Helper()
+	doc, err := mindmap.ParseString(data, mindmap.Limits{})
+	require.NoError(t, err)
+	return doc
+}
+
+// sampleDoc - карта из пяти веток, одна свернута, у одной длинный текст
+func sampleDoc(t *testing.T, layout string) *mindmap.Document {
+	return parseDoc(t, `{"layout":"`+layout+`","root":{"data":{"text":"Корень <&> \"кавычки\""},"children":[
+		{"data":{"text":"Первая ветка с очень длинным текстом, который не помещается в одну строку и переносится"},"children":[
+			{"data":{"text":"1.1"},"children":[]},
+			{"data":{"text":"1.2"},"children":[]}
+		]},
+		{"data":{"text":"Вторая","fillColor":"#ff0000","color":"red\"/><script>"},"children":[]},
+		{"data":{"text":"Третья","expand":false},"children":[
+			{"data":{"text":"скрыта"},"children":[]}
+		]},
+		{"data":{"text":"Четвертая"},"children":[]},
+		{"data":{"text":"Пятая"},"children":[]}
+	]}}`)
+}
+
+func layoutOf(t *testing.T, doc *mindmap.Document) *scene {
+	faces := newFaceCache()
+	t.Cleanup(faces.Close)
+	return layout(doc, faces)
+}
+
+func overlaps(a, b *box) bool {
+	return a.x < b.x+b.w && b.x < a.x+a.w && a.y < b.y+b.h && b.y < a.y+a.h
+}
+
+func TestLayoutNoOverlap(t *testing.T) {
+	for _, name := range []string{"logicalStructure", layoutMindMap, layoutOrganization} {
+		t.Run(name, func(t *testing.T) {
+			sc := layoutOf(t, sampleDoc(t, name))
+			// Свернутая ветка рисуется без детей: 1 + 5 + 2
+			require.Len(t, sc.boxes, 8)
+			assert.Len(t, sc.edges, 7)
+
+			for i, a := range sc.boxes {
+				assert.GreaterOrEqual(t, a.x, float64(margin)-0.001)
+				assert.GreaterOrEqual(t, a.y, float64(margin)-0.001)
+				assert.LessOrEqual(t, a.x+a.w, sc.width-margin+0.001)
+				assert.LessOrEqual(t, a.y+a.h, sc.height-margin+0.001)
+				for _, b := range sc.boxes[i+1:] {
+					assert.False(t, overlaps(a, b), "%v and %v overlap", a.lines, b.lines)
+				}
+			}
+		})
+	}
+}
+
+func TestLayoutDirections(t *testing.T) {
+	sc := layoutOf(t, sampleDoc(t, "logicalStructure"))
+	root := sc.boxes[0]
+	for _, child := range root.children {
+		assert.Greater(t, child.x, root.x+root.w)
+	}
+
+	sc = layoutOf(t, sampleDoc(t, layoutMindMap))
+	root = sc.boxes[0]
+	right, left := splitSides(root.children)
+	require.Len(t, right, 3)
+	require.Len(t, left, 2)
+	for _, child := range right {
+		assert.Greater(t, child.x, root.x+root.w)
+	}
+	for _, child := range left {
+		assert.Less(t, child.x+child.w, root.x)
+	}
+
+	sc = layoutOf(t, sampleDoc(t, layoutOrganization))
+	root = sc.boxes[0]
+	for i, child := range root.children {
+		assert.Greater(t, child.y, root.y+root.h)
+		if i > 0 {
+			assert.Greater(t, child.x, root.children[i-1].x+root.children[i-1].w)
+		}
+	}
+}
+
+func TestLayoutWrapsLongText(t *testing.T) {
+	sc := layoutOf(t, sampleDoc(t, "logicalStructure"))
+	long := sc.boxes[1]
+	assert.Greater(t, len(long.lines), 1)
+	for _, w := range long.lineWidth {
+		assert.LessOrEqual(t, w, float64(maxTextWidth))
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	data, err := Render(sampleDoc(t, layoutMindMap), FormatSVG, Options{})
+	require.NoError(t, err)
+
+	// Документ должен быть корректным XML: текст экранирован, цвета не вставляются как есть
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var texts []string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if el, ok := tok.(xml.StartElement); ok {
+			assert.NotEqual(t, "script", el.Name.Local)
+		}
+		if text, ok := tok.(xml.CharData); ok && strings.TrimSpace(string(text)) != "" {
+			texts = append(texts, string(text))
+		}
+	}
+	assert.Contains(t, texts, `Корень <&> "кавычки"`)
+	assert.NotContains(t, texts, "скрыта")
+	assert.Contains(t, string(data), "#ff0000")
+}
+
+func TestRenderPNG(t *testing.T) {
+	doc := sampleDoc(t, "logicalStructure")
+	sc := layoutOf(t, doc)
+
+	data, err := Render(doc, FormatPNG, Options{Scale: 2})
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.InDelta(t, sc.width*2, img.Bounds().Dx(), 1)
+	assert.InDelta(t, sc.height*2, img.Bounds().Dy(), 1)
+
+	// Масштаб выше предела ограничивается
+	data, err = Render(doc, FormatPNG, Options{Scale: 100})
+	require.NoError(t, err)
+	img, err = png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.InDelta(t, sc.width*MaxScale, img.Bounds().Dx(), 1)
+}
+
+func TestRenderPDF(t *testing.T) {
+	data, err := Render(sampleDoc(t, layoutOrganization), FormatPDF, Options{})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-")))
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	_, err := Render(sampleDoc(t, "logicalStructure"), "gif", Options{})
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestThumbnailSize(t *testing.T) {
+	for _, doc := range []*mindmap.Document{
+		mindmap.New("Одна тема"),
+		sampleDoc(t, layoutMindMap),
+	} {
+		data, err := Thumbnail(doc)
+		require.NoError(t, err)
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, ThumbnailWidth, img.Bounds().Dx())
+		assert.Equal(t, ThumbnailHeight, img.Bounds().Dy())
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	for input, ok := range map[string]bool{
+		"#fff":               true,
+		"#336699":            true,
+		"#33669980":          true,
+		"rgb(1, 2, 3)":       true,
+		"rgba(1, 2, 3, 0.5)": true,
+		"transparent":        false,
+		"red\"/><script>":    false,
+		"url(javascript:x)":  false,
+		"#12345":             false,
+	} {
+		_, got := parseColor(input)
+		assert.Equal(t, ok, got, input)
+	}
+}
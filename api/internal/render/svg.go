@@ -0,0 +1,88 @@
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// svgFontFamily - шрифты Go первыми, чтобы текст совпадал с раскладкой
+const svgFontFamily = "Go, 'Helvetica Neue', Arial, sans-serif"
+
+func writeSVG(w io.Writer, sc *scene) error {
+	bw := bufio.NewWriter(w)
+	t := sc.theme
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %[1]s %[2]s">`+"\n", num(sc.width), num(sc.height))
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%"%s/>`+"\n", svgPaint("fill", t.BackgroundColor))
+
+	fmt.Fprintf(bw, `<g fill="none"%s stroke-width="%s">`+"\n", svgPaint("stroke", t.LineColor), num(t.LineWidth))
+	for _, e := range sc.edges {
+		fmt.Fprintf(bw, `<path d="%s"/>`+"\n", svgPath(e))
+	}
+	bw.WriteString("</g>\n")
+
+	for _, b := range sc.boxes {
+		s := b.style
+		stroke := ""
+		if s.BorderWidth > 0 {
+			stroke = svgPaint("stroke", s.BorderColor) + fmt.Sprintf(` stroke-width="%s"`, num(s.BorderWidth))
+		}
+		fmt.Fprintf(bw, `<rect x="%s" y="%s" width="%s" height="%s" rx="%s"%s%s/>`+"\n",
+			num(b.x), num(b.y), num(b.w), num(b.h), num(s.BorderRadius), svgPaint("fill", s.FillColor), stroke)
+
+		weight := "normal"
+		if s.bold() {
+			weight = "bold"
+		}
+		for i, line := range b.lines {
+			if line == "" {
+				continue
+			}
+			fmt.Fprintf(bw, `<text x="%s" y="%s" text-anchor="middle" font-family="%s" font-size="%s" font-weight="%s"%s>`,
+				num(b.x+b.w/2), num(b.baseline(i)), svgFontFamily, num(s.FontSize), weight, svgPaint("fill", s.Color))
+			if err := xml.EscapeText(bw, []byte(line)); err != nil {
+				return err
+			}
+			bw.WriteString("</text>\n")
+		}
+	}
+
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+func svgPath(e edge) string {
+	p := e.points
+	var b strings.Builder
+	fmt.Fprintf(&b, "M%s %s", num(p[0].x), num(p[0].y))
+	if e.curve {
+		fmt.Fprintf(&b, " C%s %s %s %s %s %s", num(p[1].x), num(p[1].y), num(p[2].x), num(p[2].y), num(p[3].x), num(p[3].y))
+		return b.String()
+	}
+	for _, pt := range p[1:] {
+		fmt.Fprintf(&b, " L%s %s", num(pt.x), num(pt.y))
+	}
+	return b.String()
+}
+
+// svgPaint - атрибут цвета. Цвет из документа не попадает в SVG как есть:
+// он разбирается и пишется заново, что исключает внедрение разметки.
+func svgPaint(attr, value string) string {
+	c, ok := parseColor(value)
+	if !ok {
+		return fmt.Sprintf(` %s="none"`, attr)
+	}
+	out := fmt.Sprintf(` %s="#%02x%02x%02x"`, attr, c.R, c.G, c.B)
+	if c.A < 255 {
+		out += fmt.Sprintf(` %s-opacity="%s"`, attr, strconv.FormatFloat(float64(c.A)/255, 'f', 2, 64))
+	}
+	return out
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,212 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// levelStyle - оформление узлов одного уровня в терминах темы simple-mind-map
+type levelStyle struct {
+	FillColor    string  `json:"fillColor"`
+	Color        string  `json:"color"`
+	BorderColor  string  `json:"borderColor"`
+	BorderWidth  float64 `json:"borderWidth"`
+	BorderRadius float64 `json:"borderRadius"`
+	FontSize     float64 `json:"fontSize"`
+	FontWeight   string  `json:"fontWeight"`
+	PaddingX     float64 `json:"paddingX"`
+	PaddingY     float64 `json:"paddingY"`
+}
+
+// theme - тема карты: фон, линии и стили корня, второго уровня и остальных узлов
+type theme struct {
+	BackgroundColor string     `json:"backgroundColor"`
+	LineColor       string     `json:"lineColor"`
+	LineWidth       float64    `json:"lineWidth"`
+	Root            levelStyle `json:"root"`
+	Second          levelStyle `json:"second"`
+	Node            levelStyle `json:"node"`
+}
+
+// themes - встроенные темы; у неизвестной темы берется default
+var themes = map[string]theme{
+	"default": {
+		BackgroundColor: "#fafafa",
+		LineColor:       "#549688",
+		LineWidth:       1,
+		Root: levelStyle{FillColor: "#549688", Color: "#ffffff", BorderColor: "transparent",
+			BorderRadius: 5, FontSize: 16, FontWeight: "bold", PaddingX: 15, PaddingY: 8},
+		Second: levelStyle{FillColor: "#ffffff", Color: "#565656", BorderColor: "#549688", BorderWidth: 1,
+			BorderRadius: 5, FontSize: 16, FontWeight: "normal", PaddingX: 15, PaddingY: 5},
+		Node: levelStyle{FillColor: "transparent", Color: "#6a6d6c", BorderColor: "transparent",
+			BorderRadius: 5, FontSize: 14, FontWeight: "normal", PaddingX: 10, PaddingY: 5},
+	},
+	"dark": {
+		BackgroundColor: "#262a2e",
+		LineColor:       "#ffffff",
+		LineWidth:       1,
+		Root: levelStyle{FillColor: "#ffffff", Color: "#222222", BorderColor: "transparent",
+			BorderRadius: 5, FontSize: 16, FontWeight: "bold", PaddingX: 15, PaddingY: 8},
+		Second: levelStyle{FillColor: "#3a3f44", Color: "#ffffff", BorderColor: "#ffffff", BorderWidth: 1,
+			BorderRadius: 5, FontSize: 16, FontWeight: "normal", PaddingX: 15, PaddingY: 5},
+		Node: levelStyle{FillColor: "transparent", Color: "#dddddd", BorderColor: "transparent",
+			BorderRadius: 5, FontSize: 14, FontWeight: "normal", PaddingX: 10, PaddingY: 5},
+	},
+}
+
+// themeOf - встроенная тема документа с переопределениями из theme.config
+func themeOf(doc *mindmap.Document) theme {
+	t := themes["default"]
+	if doc.Theme == nil {
+		return t
+	}
+	if named, ok := themes[doc.Theme.Template]; ok {
+		t = named
+	}
+	if len(doc.Theme.Config) > 0 {
+		// Неверные значения в конфиге не мешают отрисовке: остаются значения темы
+		if raw, err := json.Marshal(doc.Theme.Config); err == nil {
+			override := t
+			if json.Unmarshal(raw, &override) == nil {
+				t = override.sanitized(t)
+			}
+		}
+	}
+	return t
+}
+
+// sanitized заменяет значения, с которыми нельзя рисовать, значениями темы base
+func (t theme) sanitized(base theme) theme {
+	if t.LineWidth < 0 || t.LineWidth > 20 {
+		t.LineWidth = base.LineWidth
+	}
+	t.Root = t.Root.sanitized(base.Root)
+	t.Second = t.Second.sanitized(base.Second)
+	t.Node = t.Node.sanitized(base.Node)
+	return t
+}
+
+func (s levelStyle) sanitized(base levelStyle) levelStyle {
+	if s.FontSize <= 0 || s.FontSize > 200 {
+		s.FontSize = base.FontSize
+	}
+	if s.BorderWidth < 0 || s.BorderWidth > 20 {
+		s.BorderWidth = base.BorderWidth
+	}
+	if s.BorderRadius < 0 {
+		s.BorderRadius = base.BorderRadius
+	}
+	if s.PaddingX < 0 || s.PaddingX > 200 {
+		s.PaddingX = base.PaddingX
+	}
+	if s.PaddingY < 0 || s.PaddingY > 200 {
+		s.PaddingY = base.PaddingY
+	}
+	return s
+}
+
+// level - стиль узла на глубине depth (1 - корень) с учетом стилей самого узла
+func (t theme) level(d mindmap.NodeData, depth int) levelStyle {
+	s := t.Node
+	switch depth {
+	case 1:
+		s = t.Root
+	case 2:
+		s = t.Second
+	}
+	if d.Fields == nil {
+		return s
+	}
+	own := struct {
+		Color       *string  `json:"color"`
+		FillColor   *string  `json:"fillColor"`
+		BorderColor *string  `json:"borderColor"`
+		BorderWidth *float64 `json:"borderWidth"`
+		FontSize    *float64 `json:"fontSize"`
+		FontWeight  *string  `json:"fontWeight"`
+	}{}
+	for key, dst := range map[string]any{
+		"color": &own.Color, "fillColor": &own.FillColor, "borderColor": &own.BorderColor,
+		"borderWidth": &own.BorderWidth, "fontSize": &own.FontSize, "fontWeight": &own.FontWeight,
+	} {
+		if raw, ok := d.Fields[key]; ok {
+			_ = json.Unmarshal(raw, dst)
+		}
+	}
+	if own.Color != nil {
+		s.Color = *own.Color
+	}
+	if own.FillColor != nil {
+		s.FillColor = *own.FillColor
+	}
+	if own.BorderColor != nil {
+		s.BorderColor = *own.BorderColor
+	}
+	if own.BorderWidth != nil && *own.BorderWidth >= 0 && *own.BorderWidth <= 20 {
+		s.BorderWidth = *own.BorderWidth
+	}
+	if own.FontSize != nil && *own.FontSize > 0 && *own.FontSize <= 200 {
+		s.FontSize = *own.FontSize
+	}
+	if own.FontWeight != nil {
+		s.FontWeight = *own.FontWeight
+	}
+	return s
+}
+
+func (s levelStyle) bold() bool {
+	return s.FontWeight == "bold" || s.FontWeight == "bolder" || s.FontWeight == "700" || s.FontWeight == "800" || s.FontWeight == "900"
+}
+
+// parseColor понимает #rgb, #rrggbb, #rrggbbaa, rgb(), rgba() и transparent.
+// ok=false - цвет не задан или не распознан, рисовать нечего.
+func parseColor(s string) (c color.NRGBA, ok bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "transparent", "none":
+		return c, false
+	case "white":
+		return color.NRGBA{255, 255, 255, 255}, true
+	case "black":
+		return color.NRGBA{0, 0, 0, 255}, true
+	}
+
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) == 6 {
+			hex += "ff"
+		}
+		if len(hex) != 8 {
+			return c, false
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return c, false
+		}
+		c = color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}
+		return c, c.A > 0
+	}
+
+	var r, g, b int
+	a := 1.0
+	s = strings.ReplaceAll(s, " ", "")
+	if _, err := fmt.Sscanf(s, "rgba(%d,%d,%d,%g)", &r, &g, &b, &a); err != nil {
+		if _, err := fmt.Sscanf(s, "rgb(%d,%d,%d)", &r, &g, &b); err != nil {
+			return c, false
+		}
+	}
+	c = color.NRGBA{clampByte(r), clampByte(g), clampByte(b), clampByte(int(a * 255))}
+	return c, c.A > 0
+}
+
+func clampByte(v int) uint8 {
+	return uint8(max(0, min(255, v)))
+}
@@ -0,0 +1,92 @@
+package render
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+// Параметры фоновой отрисовки превью
+const (
+	thumbnailInterval = 5 * time.Second // Как часто искать карты с устаревшим превью
+	thumbnailBatch    = 10              // Карт за один запрос к БД
+)
+
+// ThumbnailStore - хранилище карт и их превью
+type ThumbnailStore interface {
+	PendingThumbnails(ctx context.Context, limit int) ([]*models.MindMap, error)
+	SaveThumbnail(ctx context.Context, mindMapID, version int, image []byte) error
+}
+
+// Thumbnailer рисует превью в фоне. Карты с устаревшим превью берутся из БД,
+// а не из очереди в памяти: так превью обновляется после любого сохранения
+// (PUT, операции, совместное редактирование) и после перезапуска сервера.
+// Частые сохранения одной карты дают не больше одной отрисовки за интервал.
+type Thumbnailer struct {
+	store    ThumbnailStore
+	logger   *log.Logger
+	interval time.Duration
+}
+
+func NewThumbnailer(store ThumbnailStore, logger *log.Logger) *Thumbnailer {
+	return &Thumbnailer{
+		store:    store,
+		logger:   logger,
+		interval: thumbnailInterval,
+	}
+}
+
+// Run рисует превью, пока не отменен ctx
+func (t *Thumbnailer) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		t.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce рисует все устаревшие превью и возвращает число обработанных карт
+func (t *Thumbnailer) RunOnce(ctx context.Context) int {
+	processed := 0
+	for ctx.Err() == nil {
+		pending, err := t.store.PendingThumbnails(ctx, thumbnailBatch)
+		if err != nil {
+			t.logger.Printf("thumbnails: %v", err)
+			return processed
+		}
+		for _, m := range pending {
+			if err := t.store.SaveThumbnail(ctx, m.ID, m.Version, t.draw(m)); err != nil {
+				// Ошибка БД: следующая попытка через интервал, без повторов в цикле
+				t.logger.Printf("thumbnail for mindmap %d: %v", m.ID, err)
+				return processed
+			}
+			processed++
+		}
+		if len(pending) < thumbnailBatch {
+			return processed
+		}
+	}
+	return processed
+}
+
+// draw рисует превью карты. nil - документ не удалось разобрать или нарисовать.
+func (t *Thumbnailer) draw(m *models.MindMap) []byte {
+	doc, err := mindmap.ParseString(m.Data, mindmap.Limits{})
+	if err == nil {
+		var image []byte
+		if image, err = Thumbnail(doc); err == nil {
+			return image
+		}
+	}
+	t.logger.Printf("thumbnail for mindmap %d: %v", m.ID, err)
+	return nil
+}
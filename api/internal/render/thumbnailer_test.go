@@ -0,0 +1,84 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+// fakeStore - хранилище в памяти: превью устарело, пока версия превью меньше версии карты
+type fakeStore struct {
+	maps     []*models.MindMap
+	saved    map[int]int
+	images   map[int][]byte
+	failSave bool
+}
+
+func newFakeStore(maps ...*models.MindMap) *fakeStore {
+	return &fakeStore{maps: maps, saved: map[int]int{}, images: map[int][]byte{}}
+}
+
+func (s *fakeStore) PendingThumbnails(_ context.Context, limit int) ([]*models.MindMap, error) {
+	var result []*models.MindMap
+	for _, m := range s.maps {
+		if s.saved[m.ID] < m.Version && len(result) < limit {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+func (s *fakeStore) SaveThumbnail(_ context.Context, id, version int, image []byte) error {
+	if s.failSave {
+		return errors.New("db is down")
+	}
+	s.saved[id] = version
+	s.images[id] = image
+	return nil
+}
+
+func storedMap(t *testing.T, id, version int, text string) *models.MindMap {
+	data, err := mindmap.New(text).Encode()
+	require.NoError(t, err)
+	return &models.MindMap{ID: id, Version: version, Data: data}
+}
+
+func TestThumbnailerRunOnce(t *testing.T) {
+	var maps []*models.MindMap
+	for id := 1; id <= thumbnailBatch+2; id++ {
+		maps = append(maps, storedMap(t, id, 1, "Карта"))
+	}
+	broken := &models.MindMap{ID: 100, Version: 3, Data: "{not json"}
+	store := newFakeStore(append(maps, broken)...)
+	th := NewThumbnailer(store, log.New(io.Discard, "", 0))
+
+	assert.Equal(t, len(maps)+1, th.RunOnce(context.Background()))
+	for _, m := range maps {
+		assert.NotEmpty(t, store.images[m.ID])
+	}
+	// Нерисуемая карта помечается обработанной без картинки, чтобы не повторять ее бесконечно
+	assert.Equal(t, 3, store.saved[broken.ID])
+	assert.Nil(t, store.images[broken.ID])
+
+	assert.Zero(t, th.RunOnce(context.Background()))
+
+	maps[0].Version = 2
+	assert.Equal(t, 1, th.RunOnce(context.Background()))
+	assert.Equal(t, 2, store.saved[maps[0].ID])
+}
+
+func TestThumbnailerStopsOnStoreError(t *testing.T) {
+	store := newFakeStore(storedMap(t, 1, 1, "Карта"))
+	store.failSave = true
+	th := NewThumbnailer(store, log.New(io.Discard, "", 0))
+
+	assert.Zero(t, th.RunOnce(context.Background()))
+}
@@ -1,13 +1,20 @@
 package server
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mymindmap/api/auth"
+	"github.com/mymindmap/api/internal/attachments"
 	"github.com/mymindmap/api/internal/handlers"
 	"github.com/mymindmap/api/internal/config"
+	"github.com/mymindmap/api/internal/collab"
+	"github.com/mymindmap/api/internal/render"
+	"github.com/mymindmap/api/internal/trash"
+	"github.com/mymindmap/api/models"
 	"github.com/mymindmap/api/repository"
 )
 
@@ -19,6 +26,40 @@ func New(cfg *config.Config, dbpool *pgxpool.Pool, log *log.Logger) *Server {
 	postRepo := repository.NewPostRepository(dbpool)
 	userRepo := repository.NewUserRepository(dbpool)
 	mindMapRepo := repository.NewMindMapRepository(dbpool)
+	revisionRepo := repository.NewMindMapRevisionRepository(dbpool)
+	opsRepo := repository.NewMindMapOpsRepository(dbpool)
+	memberRepo := repository.NewMindMapMemberRepository(dbpool)
+	linkRepo := repository.NewMindMapShareLinkRepository(dbpool)
+	searchRepo := repository.NewMindMapSearchRepository(dbpool)
+	thumbRepo := repository.NewMindMapThumbnailRepository(dbpool)
+	teamRepo := repository.NewTeamRepository(dbpool)
+	templateRepo := repository.NewMindMapTemplateRepository(dbpool)
+	folderRepo := repository.NewFolderRepository(dbpool)
+	tagRepo := repository.NewTagRepository(dbpool)
+	activityRepo := repository.NewMindMapActivityRepository(dbpool)
+	attachmentRepo := repository.NewAttachmentRepository(dbpool)
+	commentRepo := repository.NewCommentRepository(dbpool)
+	taskRepo := repository.NewTaskRepository(dbpool)
+	nodeLinkRepo := repository.NewNodeLinkRepository(dbpool)
+
+	// Файлы узлов
+	attachmentStorage, err := attachments.NewStorageFromEnv()
+	if err != nil {
+		log.Fatal("unable to init attachments storage:", err)
+	}
+	attachmentService := attachments.NewService(attachmentStorage, attachmentRepo)
+
+	// Совместное редактирование
+	collabHub := collab.NewHub(mindMapRepo, attachmentService, attachmentRepo, log)
+
+	// Превью карт для списка
+	go render.NewThumbnailer(thumbRepo, log).Run(context.Background())
+
+	// Очистка корзины, 0 - срок по умолчанию
+	trashRetention := time.Duration(cfg.TrashRetentionDays) * 24 * time.Hour
+	trashStores := map[string]trash.Store{models.TrashTypeMindMap: mindMapRepo, models.TrashTypePost: postRepo}
+	trashPurger := trash.NewPurger(trashStores, trashRetention, log)
+	go trashPurger.Run(context.Background())
 
 	// Create auth config (for now with defaults, later from env)
 	authConfig := &auth.Config{
@@ -41,9 +82,62 @@ func New(cfg *config.Config, dbpool *pgxpool.Pool, log *log.Logger) *Server {
 	postHandler.RegisterRoutes(mux)
 
 	// mindmap routes
-	mindMapHandler := handlers.NewMindMapHandler(mindMapRepo, authService, log)
+	mindMapHandler := handlers.NewMindMapHandler(handlers.MindMapDeps{
+		MindMaps:    mindMapRepo,
+		Revisions:   revisionRepo,
+		Ops:         opsRepo,
+		Members:     memberRepo,
+		ShareLinks:  linkRepo,
+		Users:       userRepo,
+		Thumbnails:  thumbRepo,
+		Templates:   templateRepo,
+		Folders:     folderRepo,
+		Tags:        tagRepo,
+		Activity:    activityRepo,
+		Attachments: attachmentRepo,
+		Files:       attachmentService,
+		Comments:    commentRepo,
+		NodeLinks:   nodeLinkRepo,
+		Collab:      collabHub,
+		Auth:        authService,
+		Logger:      log,
+	})
 	mindMapHandler.RegisterRoutes(mux)
 
+	// public gallery routes
+	publicHandler := handlers.NewPublicHandler(mindMapRepo, log)
+	publicHandler.RegisterRoutes(mux)
+
+	// search routes
+	searchHandler := handlers.NewSearchHandler(searchRepo, authService, log)
+	searchHandler.RegisterRoutes(mux)
+
+	// team and template routes
+	teamHandler := handlers.NewTeamHandler(teamRepo, userRepo, authService, log)
+	teamHandler.RegisterRoutes(mux)
+	templateHandler := handlers.NewTemplateHandler(templateRepo, teamRepo, mindMapRepo, memberRepo, authService, log)
+	templateHandler.RegisterRoutes(mux)
+
+	// folder routes
+	folderHandler := handlers.NewFolderHandler(folderRepo, collabHub, authService, log)
+	folderHandler.RegisterRoutes(mux)
+
+	// tag routes
+	tagHandler := handlers.NewTagHandler(tagRepo, authService, log)
+	tagHandler.RegisterRoutes(mux)
+
+	// trash routes
+	trashHandler := handlers.NewTrashHandler(mindMapRepo, postRepo, trashPurger.Retention(), authService, log)
+	trashHandler.RegisterRoutes(mux)
+
+	// attachment routes
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentRepo, attachmentService, authService, log)
+	attachmentHandler.RegisterRoutes(mux)
+
+	// task routes
+	taskHandler := handlers.NewTaskHandler(taskRepo, authService, log)
+	taskHandler.RegisterRoutes(mux)
+
 	return &Server{
 		Server: http.Server{
 			Addr:    ":" + cfg.Port,
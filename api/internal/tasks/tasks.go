@@ -0,0 +1,137 @@
+// Package tasks находит в документе карты узлы-задачи и формирует календарь
+// их сроков в формате iCalendar.
+//
+// Узел считается задачей, если у него есть хотя бы одно из полей данных:
+//
+//	checkbox  - флажок: false - не выполнена, true - выполнена
+//	assignee  - id ответственного пользователя
+//	dueDate   - срок: дата "2006-01-02" или время RFC 3339
+//
+// или иконка приоритета (priority_1 … priority_9) или прогресса
+// (progress_1 … progress_8, progress_8 - выполнена).
+package tasks
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+// Поля данных узла
+const (
+	FieldCheckbox = "checkbox"
+	FieldAssignee = "assignee"
+	FieldDueDate  = "dueDate"
+)
+
+// maxTitleLen - символов текста узла в названии задачи
+const maxTitleLen = 500
+
+// doneIcon - иконка прогресса «выполнено»
+const doneIcon = "progress_8"
+
+// Task - задача, найденная в узле карты
+type Task struct {
+	NodeUID    string
+	Title      string
+	Done       bool
+	Priority   *int // 1 - наивысший, nil - без приоритета
+	AssigneeID *int
+	Due        *time.Time // Для сроков-дат - полночь UTC
+	DueAllDay  bool       // Срок - дата без времени
+}
+
+// Extract возвращает задачи документа в порядке обхода дерева
+func Extract(doc *mindmap.Document) []Task {
+	var tasks []Task
+	doc.Walk(func(n, _ *mindmap.Node, _ int) bool {
+		if task, ok := fromNode(n.Data); ok {
+			tasks = append(tasks, task)
+		}
+		return true
+	})
+	return tasks
+}
+
+// fromNode строит задачу по данным узла; ok = false, если узел не задача
+func fromNode(d mindmap.NodeData) (Task, bool) {
+	task := Task{NodeUID: d.UID}
+	isTask := false
+
+	if raw, ok := d.Fields[FieldCheckbox]; ok {
+		var done bool
+		if json.Unmarshal(raw, &done) == nil {
+			task.Done = done
+			isTask = true
+		}
+	}
+	if raw, ok := d.Fields[FieldAssignee]; ok {
+		var id int
+		// id хранится в столбце integer: большие значения сломали бы сохранение карты
+		if json.Unmarshal(raw, &id) == nil && id > 0 && id <= math.MaxInt32 {
+			task.AssigneeID = &id
+			isTask = true
+		}
+	}
+	if raw, ok := d.Fields[FieldDueDate]; ok {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			if due, allDay, ok := ParseDue(s); ok {
+				task.Due, task.DueAllDay = &due, allDay
+				isTask = true
+			}
+		}
+	}
+	for _, icon := range d.Icon {
+		if n, ok := iconLevel(icon, "priority_", 9); ok {
+			if task.Priority == nil || n < *task.Priority {
+				task.Priority = &n
+			}
+			isTask = true
+		}
+		if _, ok := iconLevel(icon, "progress_", 8); ok {
+			task.Done = task.Done || icon == doneIcon
+			isTask = true
+		}
+	}
+	if !isTask {
+		return Task{}, false
+	}
+
+	task.Title = strings.Join(strings.Fields(d.PlainText()), " ")
+	if utf8.RuneCountInString(task.Title) > maxTitleLen {
+		task.Title = string([]rune(task.Title)[:maxTitleLen])
+	}
+	return task, true
+}
+
+// ParseDue разбирает срок: дату "2006-01-02" (allDay = true) или время RFC 3339.
+// Время приводится к UTC.
+func ParseDue(s string) (due time.Time, allDay bool, ok bool) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true, true
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), false, true
+	}
+	return time.Time{}, false, false
+}
+
+// iconLevel разбирает иконку вида prefix+N, 1 <= N <= max
+func iconLevel(icon, prefix string, max int) (int, bool) {
+	rest, ok := strings.CutPrefix(icon, prefix)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil || n < 1 || n > max {
+		return 0, false
+	}
+	return n, true
+}
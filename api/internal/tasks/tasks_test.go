@@ -0,0 +1,95 @@
+package tasks
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func TestExtract(t *testing.T) {
+	doc, err := mindmap.ParseString(`{"root":{"data":{"text":"Project","uid":"root"},"children":[
+		{"data":{"text":"Write spec","uid":"a","checkbox":false,"assignee":7,"dueDate":"2026-10-20"},"children":[]},
+		{"data":{"text":"<p>Ship  <b>it</b></p>","richText":true,"uid":"b","icon":["priority_3","priority_1","progress_8"]},"children":[]},
+		{"data":{"text":"Call","uid":"c","checkbox":true,"dueDate":"2026-10-21T09:30:00+03:00"},"children":[]},
+		{"data":{"text":"Idea","uid":"d","icon":["smiley_1"]},"children":[]},
+		{"data":{"text":"Broken","uid":"e","dueDate":"next week","assignee":"bob"},"children":[]},
+		{"data":{"text":"Huge id","uid":"f","assignee":9999999999},"children":[]}
+	]}}`, mindmap.DefaultLimits)
+	require.NoError(t, err)
+
+	got := Extract(doc)
+	require.Len(t, got, 3)
+
+	a := got[0]
+	assert.Equal(t, "a", a.NodeUID)
+	assert.Equal(t, "Write spec", a.Title)
+	assert.False(t, a.Done)
+	assert.Nil(t, a.Priority)
+	require.NotNil(t, a.AssigneeID)
+	assert.Equal(t, 7, *a.AssigneeID)
+	require.NotNil(t, a.Due)
+	assert.Equal(t, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), *a.Due)
+	assert.True(t, a.DueAllDay)
+
+	b := got[1]
+	assert.Equal(t, "Ship it", b.Title)
+	assert.True(t, b.Done)
+	require.NotNil(t, b.Priority)
+	assert.Equal(t, 1, *b.Priority)
+
+	c := got[2]
+	assert.True(t, c.Done)
+	require.NotNil(t, c.Due)
+	assert.Equal(t, time.Date(2026, 10, 21, 6, 30, 0, 0, time.UTC), *c.Due)
+	assert.False(t, c.DueAllDay)
+}
+
+func TestParseDue(t *testing.T) {
+	_, _, ok := ParseDue("2026-13-01")
+	assert.False(t, ok)
+
+	due, allDay, ok := ParseDue(" 2026-02-28 ")
+	assert.True(t, ok)
+	assert.True(t, allDay)
+	assert.Equal(t, "2026-02-28", due.Format(time.DateOnly))
+}
+
+func TestICS(t *testing.T) {
+	updated := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	ics := string(ICS("My tasks", []Event{
+		{UID: "task-1-a@mymindmap", Summary: "Write spec; draft, v2", Start: time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), AllDay: true, Updated: updated},
+		{UID: "task-1-c@mymindmap", Summary: "Call", Description: "Map: Project\nline 2", Start: time.Date(2026, 10, 21, 6, 30, 0, 0, time.UTC), Updated: updated},
+	}))
+
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+	assert.Contains(t, ics, "X-WR-CALNAME:My tasks\r\n")
+	assert.Contains(t, ics, "SUMMARY:Write spec\\; draft\\, v2\r\n")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:20261020\r\nDTEND;VALUE=DATE:20261021\r\n")
+	assert.Contains(t, ics, "DTSTART:20261021T063000Z\r\n")
+	assert.Contains(t, ics, "DTSTAMP:20261016T120000Z\r\n")
+	assert.Contains(t, ics, "DESCRIPTION:Map: Project\\nline 2\r\n")
+	assert.Equal(t, 2, strings.Count(ics, "BEGIN:VEVENT"))
+}
+
+func TestFoldLine(t *testing.T) {
+	long := "SUMMARY:" + strings.Repeat("ж", 60)
+	folded := foldLine(long)
+
+	lines := strings.Split(folded, "\r\n")
+	require.Greater(t, len(lines), 1)
+	for i, l := range lines {
+		assert.LessOrEqual(t, len(l), 75)
+		assert.True(t, strings.ToValidUTF8(l, "?") == l, "line %d splits a rune", i)
+		if i > 0 {
+			assert.True(t, strings.HasPrefix(l, " "))
+		}
+	}
+	assert.Equal(t, long, strings.ReplaceAll(folded, "\r\n ", ""))
+	assert.Equal(t, "SHORT", foldLine("SHORT"))
+}
@@ -0,0 +1,203 @@
+// Package templates содержит системные шаблоны карт, встроенные в сервер, и
+// подстановку переменных {{name}} в текст узлов при создании карты из шаблона.
+package templates
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+//go:embed system/*.json
+var systemFiles embed.FS
+
+// Template - системный шаблон
+type Template struct {
+	ID          string
+	Title       string
+	Description string
+	Data        string   // Документ карты с uid у всех узлов
+	Variables   []string // Переменные в названии и узлах
+}
+
+var system = loadSystem()
+
+// System возвращает системные шаблоны, отсортированные по id
+func System() []Template {
+	return append([]Template(nil), system...)
+}
+
+// SystemByID возвращает системный шаблон по id
+func SystemByID(id string) (Template, bool) {
+	for _, t := range system {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// loadSystem читает шаблоны из system/*.json. Ошибка в шаблоне - ошибка сборки,
+// поэтому сервер с ней не запускается.
+func loadSystem() []Template {
+	entries, err := systemFiles.ReadDir("system")
+	if err != nil {
+		panic(fmt.Sprintf("templates: %v", err))
+	}
+
+	var result []Template
+	for _, entry := range entries {
+		raw, err := systemFiles.ReadFile(path.Join("system", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("templates: %v", err))
+		}
+		t, err := parseSystem(raw)
+		if err != nil {
+			panic(fmt.Sprintf("templates: %s: %v", entry.Name(), err))
+		}
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+func parseSystem(raw []byte) (Template, error) {
+	var file struct {
+		ID          string          `json:"id"`
+		Title       string          `json:"title"`
+		Description string          `json:"description"`
+		Data        json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return Template{}, err
+	}
+	if file.ID == "" || file.Title == "" {
+		return Template{}, fmt.Errorf("id and title required")
+	}
+	// Числовые id у шаблонов из БД
+	if _, err := strconv.Atoi(file.ID); err == nil {
+		return Template{}, fmt.Errorf("id %q must not be a number", file.ID)
+	}
+
+	doc, err := mindmap.Parse(file.Data, mindmap.DefaultLimits)
+	if err != nil {
+		return Template{}, err
+	}
+	mindmap.EnsureUIDs(doc)
+	data, err := doc.Encode()
+	if err != nil {
+		return Template{}, err
+	}
+
+	return Template{
+		ID:          file.ID,
+		Title:       file.Title,
+		Description: file.Description,
+		Data:        data,
+		Variables:   Variables(file.Title, doc),
+	}, nil
+}
+
+// placeholder - переменная шаблона: {{name}}, пробелы внутри скобок допускаются
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z][A-Za-z0-9_]*)\s*\}\}`)
+
+var variableName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// ValidName - допустимо ли имя переменной: латинская буква, затем буквы, цифры и "_"
+func ValidName(name string) bool {
+	return variableName.MatchString(name)
+}
+
+// Встроенные переменные, которые заполняет сервер
+const (
+	VarDate  = "date"  // Дата создания карты, ГГГГ-ММ-ДД
+	VarYear  = "year"  // Год
+	VarMonth = "month" // Месяц, две цифры
+	VarWeek  = "week"  // Номер недели по ISO 8601
+	VarUser  = "user"  // Имя пользователя, создающего карту
+)
+
+// Builtin возвращает значения встроенных переменных
+func Builtin(now time.Time, userName string) map[string]string {
+	_, week := now.ISOWeek()
+	return map[string]string{
+		VarDate:  now.Format("2006-01-02"),
+		VarYear:  now.Format("2006"),
+		VarMonth: now.Format("01"),
+		VarWeek:  strconv.Itoa(week),
+		VarUser:  userName,
+	}
+}
+
+// Variables возвращает имена переменных в названии шаблона, тексте, заметках
+// и тегах узлов, в нижнем регистре и по алфавиту
+func Variables(title string, doc *mindmap.Document) []string {
+	seen := map[string]bool{}
+	collect := func(s string) {
+		for _, m := range placeholder.FindAllStringSubmatch(s, -1) {
+			seen[strings.ToLower(m[1])] = true
+		}
+	}
+
+	collect(title)
+	doc.Walk(func(n, _ *mindmap.Node, _ int) bool {
+		collect(n.Data.Text)
+		collect(n.Data.Note)
+		for _, tag := range n.Data.Tag {
+			collect(tag.Text)
+		}
+		return true
+	})
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Substitute подставляет значения переменных. Ключи vars - в нижнем регистре.
+// Переменные без значения остаются в тексте как есть, чтобы их было видно в карте.
+func Substitute(s string, vars map[string]string) string {
+	return substitute(s, vars, false)
+}
+
+func substitute(s string, vars map[string]string, escape bool) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		name := strings.ToLower(placeholder.FindStringSubmatch(match)[1])
+		value, ok := vars[name]
+		if !ok {
+			return match
+		}
+		if escape {
+			return html.EscapeString(value)
+		}
+		return value
+	})
+}
+
+// Apply подставляет переменные в текст, заметки и теги всех узлов документа.
+// В форматированном тексте (richText) значения экранируются как HTML.
+func Apply(doc *mindmap.Document, vars map[string]string) {
+	doc.Walk(func(n, _ *mindmap.Node, _ int) bool {
+		n.Data.Text = substitute(n.Data.Text, vars, n.Data.RichText)
+		n.Data.Note = Substitute(n.Data.Note, vars)
+		for i := range n.Data.Tag {
+			n.Data.Tag[i].Text = Substitute(n.Data.Tag[i].Text, vars)
+		}
+		return true
+	})
+}
@@ -0,0 +1,86 @@
+package templates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/mindmap"
+)
+
+func TestSystemTemplates(t *testing.T) {
+	list := System()
+	require.NotEmpty(t, list)
+
+	for i, tmpl := range list {
+		if i > 0 {
+			assert.Less(t, list[i-1].ID, tmpl.ID)
+		}
+		assert.NotEmpty(t, tmpl.Title, tmpl.ID)
+		assert.NotEmpty(t, tmpl.Description, tmpl.ID)
+
+		doc, err := mindmap.ParseString(tmpl.Data, mindmap.DefaultLimits)
+		require.NoError(t, err, tmpl.ID)
+		assert.Zero(t, mindmap.EnsureUIDs(doc), "%s: all nodes must have uids", tmpl.ID)
+	}
+
+	retro, ok := SystemByID("retro")
+	require.True(t, ok)
+	assert.Equal(t, []string{"date", "project", "sprint"}, retro.Variables)
+
+	_, ok = SystemByID("missing")
+	assert.False(t, ok)
+
+	// Вызывающий не может испортить общий список
+	list[0].Title = "changed"
+	assert.NotEqual(t, "changed", System()[0].Title)
+}
+
+func TestSubstitute(t *testing.T) {
+	vars := map[string]string{"project": "Atlas", "date": "2026-10-16", "empty": ""}
+
+	assert.Equal(t, "Atlas 2026-10-16", Substitute("{{project}} {{ DATE }}", vars))
+	assert.Equal(t, "[]", Substitute("[{{empty}}]", vars))
+	assert.Equal(t, "{{unknown}} {project} {{1x}}", Substitute("{{unknown}} {project} {{1x}}", vars))
+	// Значение с похожим на переменную текстом не раскрывается повторно
+	assert.Equal(t, "{{date}}", Substitute("{{p}}", map[string]string{"p": "{{date}}", "date": "x"}))
+}
+
+func TestValidName(t *testing.T) {
+	assert.True(t, ValidName("project"))
+	assert.True(t, ValidName("Sprint_2"))
+	assert.False(t, ValidName(""))
+	assert.False(t, ValidName("2sprint"))
+	assert.False(t, ValidName("дата"))
+	assert.False(t, ValidName("a-b"))
+}
+
+func TestApply(t *testing.T) {
+	doc, err := mindmap.ParseString(`{"root":{"data":{"text":"Проект {{project}}","note":"до {{date}}","tag":["{{project}}"]},"children":[
+		{"data":{"text":"<p>{{project}}</p>","richText":true},"children":[]},
+		{"data":{"text":"{{missing}}"},"children":[]}
+	]}}`, mindmap.Limits{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"date", "missing", "project"}, Variables("", doc))
+
+	Apply(doc, map[string]string{"project": "<A&B>", "date": "2026-10-16"})
+	assert.Equal(t, "Проект <A&B>", doc.Root.Data.Text)
+	assert.Equal(t, "до 2026-10-16", doc.Root.Data.Note)
+	assert.Equal(t, []string{"<A&B>"}, doc.Root.Data.TagTexts())
+	assert.Equal(t, "<p>&lt;A&amp;B&gt;</p>", doc.Root.Children[0].Data.Text)
+	assert.Equal(t, "{{missing}}", doc.Root.Children[1].Data.Text)
+}
+
+func TestBuiltin(t *testing.T) {
+	vars := Builtin(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC), "Анна")
+	assert.Equal(t, map[string]string{
+		"date":  "2026-01-01",
+		"year":  "2026",
+		"month": "01",
+		"week":  "1",
+		"user":  "Анна",
+	}, vars)
+}
@@ -0,0 +1,90 @@
+// Package trash окончательно удаляет элементы, пролежавшие в корзине дольше срока хранения.
+package trash
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+)
+
+// Параметры очистки корзины по умолчанию
+const (
+	DefaultRetention = 30 * 24 * time.Hour // Сколько элементы хранятся в корзине
+	purgeInterval    = time.Hour           // Как часто запускается очистка
+)
+
+// Store - хранилище элементов одного типа с корзиной
+type Store interface {
+	// PurgeTrashed окончательно удаляет элементы, попавшие в корзину раньше before,
+	// и возвращает их число
+	PurgeTrashed(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Purger периодически очищает корзину. Ошибка одного хранилища не мешает очистке
+// остальных; неудаленные элементы удалятся при следующем запуске.
+type Purger struct {
+	stores    map[string]Store // Тип элемента -> хранилище
+	retention time.Duration
+	interval  time.Duration
+	logger    *log.Logger
+}
+
+func NewPurger(stores map[string]Store, retention time.Duration, logger *log.Logger) *Purger {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Purger{
+		stores:    stores,
+		retention: retention,
+		interval:  purgeInterval,
+		logger:    logger,
+	}
+}
+
+// Retention - срок хранения элементов в корзине
+func (p *Purger) Retention() time.Duration {
+	return p.retention
+}
+
+// Run очищает корзину, пока не отменен ctx
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.RunOnce(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce удаляет элементы, попавшие в корзину раньше now - срок хранения,
+// и возвращает их число по типам
+func (p *Purger) RunOnce(ctx context.Context, now time.Time) map[string]int64 {
+	before := now.Add(-p.retention)
+
+	// Порядок обхода постоянный, чтобы логи разных запусков было удобно сравнивать
+	types := make([]string, 0, len(p.stores))
+	for t := range p.stores {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	purged := make(map[string]int64, len(types))
+	for _, t := range types {
+		n, err := p.stores[t].PurgeTrashed(ctx, before)
+		if err != nil {
+			p.logger.Printf("trash: purge %s: %v", t, err)
+			continue
+		}
+		purged[t] = n
+		if n > 0 {
+			p.logger.Printf("trash: purged %d %s", n, t)
+		}
+	}
+	return purged
+}
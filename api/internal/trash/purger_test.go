@@ -0,0 +1,74 @@
+package trash
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStore struct {
+	deletedAt []time.Time
+	err       error
+	calls     int
+}
+
+func (s *fakeStore) PurgeTrashed(_ context.Context, before time.Time) (int64, error) {
+	s.calls++
+	if s.err != nil {
+		return 0, s.err
+	}
+	var kept []time.Time
+	var purged int64
+	for _, t := range s.deletedAt {
+		if t.Before(before) {
+			purged++
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	s.deletedAt = kept
+	return purged, nil
+}
+
+func TestRunOnce(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	maps := &fakeStore{deletedAt: []time.Time{
+		now.Add(-40 * 24 * time.Hour),
+		now.Add(-31 * 24 * time.Hour),
+		now.Add(-29 * 24 * time.Hour),
+	}}
+	posts := &fakeStore{deletedAt: []time.Time{now.Add(-time.Hour)}}
+
+	var logs bytes.Buffer
+	p := NewPurger(map[string]Store{"mindmap": maps, "post": posts}, 0, log.New(&logs, "", 0))
+	assert.Equal(t, DefaultRetention, p.Retention())
+
+	purged := p.RunOnce(context.Background(), now)
+	assert.Equal(t, map[string]int64{"mindmap": 2, "post": 0}, purged)
+	assert.Len(t, maps.deletedAt, 1)
+	assert.Len(t, posts.deletedAt, 1)
+	assert.Equal(t, "trash: purged 2 mindmap\n", logs.String())
+
+	// Повторный запуск ничего не удаляет
+	purged = p.RunOnce(context.Background(), now)
+	assert.Equal(t, map[string]int64{"mindmap": 0, "post": 0}, purged)
+}
+
+func TestRunOnceStoreError(t *testing.T) {
+	now := time.Now()
+	broken := &fakeStore{err: errors.New("db is down")}
+	posts := &fakeStore{deletedAt: []time.Time{now.Add(-2 * time.Hour)}}
+
+	var logs bytes.Buffer
+	p := NewPurger(map[string]Store{"mindmap": broken, "post": posts}, time.Hour, log.New(&logs, "", 0))
+
+	purged := p.RunOnce(context.Background(), now)
+	assert.Equal(t, map[string]int64{"post": 1}, purged)
+	assert.Equal(t, 1, broken.calls)
+	assert.Contains(t, logs.String(), "trash: purge mindmap: db is down")
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Attachment - файл узла карты (картинка или вложение). Файлы адресуются
+// SHA-256 содержимого: одинаковые файлы хранятся один раз.
+type Attachment struct {
+	Hash        string    `json:"hash" db:"hash"` // SHA-256 содержимого, hex
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	CreatedBy   *int      `json:"created_by" db:"created_by"` // nil - загружен без авторизации (по ссылке доступа)
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	URL         string    `json:"url" db:"-"` // Адрес для скачивания, им ссылается документ карты
+}
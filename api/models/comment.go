@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Фильтр обсуждений по состоянию
+const (
+	CommentStatusOpen     = "open"     // Не решенные, узел есть в карте
+	CommentStatusResolved = "resolved" // Решенные
+	CommentStatusOrphaned = "orphaned" // Узел удален из карты
+	CommentStatusAll      = "all"
+)
+
+// CommentThread - обсуждение узла карты
+type CommentThread struct {
+	ID         int        `json:"id" db:"id"`
+	MindMapID  int        `json:"mindmap_id" db:"mindmap_id"`
+	NodeUID    string     `json:"node_uid" db:"node_uid"`
+	NodeText   string     `json:"node_text" db:"node_text"` // Текст узла на момент создания обсуждения
+	CreatedBy  *int       `json:"created_by" db:"created_by"`
+	ResolvedAt *time.Time `json:"resolved_at" db:"resolved_at"`
+	ResolvedBy *int       `json:"resolved_by" db:"resolved_by"`
+	OrphanedAt *time.Time `json:"orphaned_at" db:"orphaned_at"` // Когда узел пропал из карты, nil - узел есть
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	Comments   []*Comment `json:"comments" db:"-"` // Первый - начало обсуждения, дальше ответы
+}
+
+// Comment - сообщение в обсуждении
+type Comment struct {
+	ID         int              `json:"id" db:"id"`
+	ThreadID   int              `json:"thread_id" db:"thread_id"`
+	UserID     *int             `json:"user_id" db:"user_id"` // nil - автор удален
+	AuthorName string           `json:"author_name" db:"-"`
+	Body       string           `json:"body" db:"body"`
+	Mentions   []CommentMention `json:"mentions" db:"-"`
+	CreatedAt  time.Time        `json:"created_at" db:"created_at"`
+	EditedAt   *time.Time       `json:"edited_at" db:"edited_at"`
+}
+
+// CommentMention - упомянутый в комментарии участник карты
+type CommentMention struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Folder - папка пользователя для карт
+type Folder struct {
+	ID         int       `json:"id" db:"id"`
+	UserID     int       `json:"user_id" db:"user_id"`
+	ParentID   *int      `json:"parent_id" db:"parent_id"` // nil - папка в корне
+	Name       string    `json:"name" db:"name"`
+	MapCount   int       `json:"map_count" db:"-"`   // Карт непосредственно в папке
+	TotalCount int       `json:"total_count" db:"-"` // Карт в папке и во всех вложенных
+	Children   []*Folder `json:"children,omitempty" db:"-"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// FolderTree - дерево папок пользователя
+type FolderTree struct {
+	Folders  []*Folder `json:"folders"`   // Папки верхнего уровня с вложенными
+	MapCount int       `json:"map_count"` // Своих карт вне папок
+}
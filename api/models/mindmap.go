@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -10,18 +11,199 @@ type MindMap struct {
 	Data      string    `json:"data" db:"data"`
 	UserID    int       `json:"user_id" db:"user_id"`
 	IsPublic  bool      `json:"is_public" db:"is_public"`
+	Version   int       `json:"version" db:"version"`
+	Role      string    `json:"role,omitempty" db:"-"` // Роль текущего пользователя, заполняется обработчиками
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	FolderID *int `json:"folder_id" db:"folder_id"` // Папка владельца, nil - корень
+
+	// Карта, с которой сделана копия, и ее ревизия. Ссылка обнуляется при удалении исходной карты.
+	ForkedFromID       *int `json:"forked_from_id,omitempty" db:"forked_from_id"`
+	ForkedFromRevision *int `json:"forked_from_revision,omitempty" db:"forked_from_revision"`
+	ForkCount          int  `json:"fork_count" db:"-"` // Сколько копий сделано с карты
 }
 
 type CreateMindMapRequest struct {
 	Title    string `json:"title" validate:"required"`
 	Data     string `json:"data" validate:"required"`
 	IsPublic bool   `json:"is_public"`
+	// Значения переменных шаблона при создании из шаблона (?template=)
+	Variables map[string]string `json:"variables,omitempty"`
 }
 
 type UpdateMindMapRequest struct {
 	Title    string `json:"title" validate:"required"`
 	Data     string `json:"data" validate:"required"`
 	IsPublic bool   `json:"is_public"`
-} 
\ No newline at end of file
+} 
+type MindMapRevision struct {
+	ID        int       `json:"id" db:"id"`
+	MindMapID int       `json:"mindmap_id" db:"mindmap_id"`
+	Revision  int       `json:"revision" db:"revision"`
+	Title     string    `json:"title" db:"title"`
+	Data      string    `json:"data,omitempty" db:"data"`
+	Size      int       `json:"size" db:"size"`
+	AuthorID  *int      `json:"author_id" db:"author_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// MindMapOpsBatch - пакет операций над узлами карты из журнала операций
+type MindMapOpsBatch struct {
+	ID          int             `json:"id" db:"id"`
+	MindMapID   int             `json:"mindmap_id" db:"mindmap_id"`
+	BaseVersion int             `json:"base_version" db:"base_version"`
+	Version     int             `json:"version" db:"version"`
+	AuthorID    *int            `json:"author_id" db:"author_id"`
+	Ops         json.RawMessage `json:"ops" db:"ops"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Роли участников карты, по возрастанию прав
+const (
+	MindMapRoleViewer    = "viewer"    // Просмотр
+	MindMapRoleCommenter = "commenter" // Просмотр и комментарии
+	MindMapRoleEditor    = "editor"    // Редактирование
+	MindMapRoleOwner     = "owner"     // Все права, включая доступ и удаление
+)
+
+var mindMapRoleRank = map[string]int{
+	MindMapRoleViewer:    1,
+	MindMapRoleCommenter: 2,
+	MindMapRoleEditor:    3,
+	MindMapRoleOwner:     4,
+}
+
+// ValidMindMapRole проверяет, что роль известна
+func ValidMindMapRole(role string) bool {
+	_, ok := mindMapRoleRank[role]
+	return ok
+}
+
+// MindMapRoleAllows проверяет, что роль role дает не меньше прав, чем required
+func MindMapRoleAllows(role, required string) bool {
+	return ValidMindMapRole(role) && mindMapRoleRank[role] >= mindMapRoleRank[required]
+}
+
+// MindMapMember - участник карты
+type MindMapMember struct {
+	MindMapID int       `json:"mindmap_id" db:"mindmap_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Email     string    `json:"email" db:"email"`
+	Role      string    `json:"role" db:"role"`
+	InvitedBy *int      `json:"invited_by" db:"invited_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Режимы ссылки доступа
+const (
+	ShareLinkModeView = "view" // Только просмотр
+	ShareLinkModeEdit = "edit" // Анонимное редактирование
+)
+
+// ShareLink - ссылка для доступа к карте без аккаунта
+type ShareLink struct {
+	ID           int        `json:"id" db:"id"`
+	MindMapID    int        `json:"mindmap_id" db:"mindmap_id"`
+	Mode         string     `json:"mode" db:"mode"`
+	PasswordHash string     `json:"-" db:"password_hash"`
+	HasPassword  bool       `json:"has_password" db:"-"`
+	ExpiresAt    *time.Time `json:"expires_at" db:"expires_at"`
+	CreatedBy    *int       `json:"created_by" db:"created_by"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt    *time.Time `json:"revoked_at" db:"revoked_at"`
+	LastUsedAt   *time.Time `json:"last_used_at" db:"last_used_at"`
+}
+
+// Active проверяет, что ссылка не отозвана и не истекла
+func (l *ShareLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil && (l.ExpiresAt == nil || now.Before(*l.ExpiresAt))
+}
+
+// Сортировка галереи публичных карт
+const (
+	PublicSortRecent  = "recent"  // Недавно измененные
+	PublicSortPopular = "popular" // По числу просмотров
+)
+
+// PublicMindMap - карта в публичной галерее
+type PublicMindMap struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Data      string    `json:"data,omitempty"` // Только в просмотре одной карты
+	Author    Author    `json:"author"`
+	ViewCount int       `json:"view_count"`
+	ForkCount int       `json:"fork_count"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Author - публичные сведения об авторе
+type Author struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// SearchResult - карта, найденная полнотекстовым поиском
+type SearchResult struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Role      string    `json:"role"`      // Роль пользователя в карте
+	Snippet   string    `json:"snippet"`   // HTML: текст экранирован, совпадения в <mark>
+	NodeUIDs  []string  `json:"node_uids"` // Узлы, в которых найден запрос
+	Rank      float32   `json:"rank"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MindMapSummary - карта в списке, без документа
+type MindMapSummary struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	UserID    int        `json:"user_id"`
+	IsPublic  bool       `json:"is_public"`
+	Role      string     `json:"role"`      // Роль текущего пользователя
+	FolderID  *int       `json:"folder_id"` // Папка владельца, nil - корень
+	NodeCount int        `json:"node_count"`
+	ForkCount int        `json:"fork_count"`
+	Thumbnail *string    `json:"thumbnail"` // Ссылка на превью, если оно есть
+	Starred   bool       `json:"starred"`   // В избранном у текущего пользователя
+	OpenedAt  *time.Time `json:"opened_at"` // Когда текущий пользователь открывал карту последний раз
+	Tags      []string   `json:"tags"`      // Теги текущего пользователя
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// MindMapThumbnail - превью карты для списка
+type MindMapThumbnail struct {
+	MindMapID   int       `json:"mindmap_id"`
+	Version     int       `json:"version"` // Версия карты, с которой нарисовано превью
+	ContentType string    `json:"content_type"`
+	Content     []byte    `json:"-"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Области видимости шаблонов карт
+const (
+	TemplateScopeSystem = "system" // Встроен в сервер, виден всем
+	TemplateScopeUser   = "user"   // Личный шаблон автора
+	TemplateScopeTeam   = "team"   // Виден участникам команды
+)
+
+// MindMapTemplate - шаблон карты. У системных шаблонов строковые id ("retro"),
+// у шаблонов из БД - числовые, тоже строкой.
+type MindMapTemplate struct {
+	ID          string     `json:"id"`
+	Scope       string     `json:"scope"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Variables   []string   `json:"variables"` // Переменные {{name}} в названии и узлах
+	UserID      *int       `json:"user_id,omitempty"`
+	TeamID      *int       `json:"team_id,omitempty"`
+	Data        string     `json:"data,omitempty"` // Только в просмотре одного шаблона
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+// Состояние цели ссылки на узел другой карты
+const (
+	NodeLinkStatusOK          = "ok"
+	NodeLinkStatusNodeMissing = "node_missing" // Карта доступна, узла в ней нет
+	NodeLinkStatusUnavailable = "unavailable"  // Карта удалена, не существует или недоступна пользователю
+)
+
+// NodeLink - ссылка из узла одной карты на узел другой. Название и состояние
+// недоступной карты не раскрываются.
+type NodeLink struct {
+	SourceMindMapID int    `json:"source_mindmap_id" db:"source_mindmap_id"`
+	SourceTitle     string `json:"source_title,omitempty" db:"-"`
+	SourceNodeUID   string `json:"source_node_uid" db:"source_node_uid"`
+	SourceText      string `json:"source_text" db:"source_text"`
+	TargetMindMapID int    `json:"target_mindmap_id" db:"target_mindmap_id"`
+	TargetTitle     string `json:"target_title,omitempty" db:"-"`
+	TargetNodeUID   string `json:"target_node_uid" db:"target_node_uid"` // Пусто - карта целиком
+	Status          string `json:"status,omitempty" db:"-"`              // Для исходящих ссылок, одно из NodeLinkStatus*
+}
+
+// MapGraph - граф ссылок между картами, доступными пользователю
+type MapGraph struct {
+	Nodes []MapGraphNode `json:"nodes"`
+	Edges []MapGraphEdge `json:"edges"`
+}
+
+// MapGraphNode - карта в графе
+type MapGraphNode struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+// MapGraphEdge - ссылки из узлов одной карты на другую
+type MapGraphEdge struct {
+	Source int `json:"source"`
+	Target int `json:"target"`
+	Links  int `json:"links"` // Число узлов-ссылок
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag - личный тег пользователя для карт
+type Tag struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Color     *string   `json:"color" db:"color"` // #RRGGBB или nil
+	MapCount  int       `json:"map_count" db:"-"` // Доступных пользователю карт с тегом
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Фильтр задач по состоянию
+const (
+	TaskStatusOpen = "open"
+	TaskStatusDone = "done"
+	TaskStatusAll  = "all"
+)
+
+// Task - задача из узла карты. Поля берутся из данных узла при сохранении
+// документа, менять задачу нужно в самой карте.
+type Task struct {
+	MindMapID    int        `json:"mindmap_id" db:"mindmap_id"`
+	MindMapTitle string     `json:"mindmap_title" db:"-"`
+	NodeUID      string     `json:"node_uid" db:"node_uid"`
+	Title        string     `json:"title" db:"title"` // Текст узла без разметки
+	Done         bool       `json:"done" db:"done"`
+	Priority     *int       `json:"priority" db:"priority"` // 1 - наивысший
+	AssigneeID   *int       `json:"assignee_id" db:"assignee_id"`
+	DueAt        *time.Time `json:"due_at" db:"due_at"`
+	DueAllDay    bool       `json:"due_all_day" db:"due_all_day"` // Срок - дата без времени
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// TaskFilter - параметры списка задач
+type TaskFilter struct {
+	UserID     int  // Задачи карт, доступных пользователю
+	AssigneeID *int // Ответственный; nil - любой
+	Unassigned bool // Только задачи без ответственного
+	MindMapID  *int
+	Status     string // Одно из TaskStatus*
+	DueFrom    *time.Time
+	DueTo      *time.Time // Не включительно
+	Limit      int
+}
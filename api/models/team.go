@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// Роли участников команды
+const (
+	TeamRoleMember = "member" // Видит шаблоны команды и может добавлять свои
+	TeamRoleOwner  = "owner"  // Управляет составом команды и любыми ее шаблонами
+)
+
+// Team - команда пользователей с общими шаблонами карт
+type Team struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	CreatedBy   *int      `json:"created_by" db:"created_by"`
+	Role        string    `json:"role" db:"-"` // Роль текущего пользователя
+	MemberCount int       `json:"member_count" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TeamMember - участник команды
+type TeamMember struct {
+	TeamID    int       `json:"team_id" db:"team_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Email     string    `json:"email" db:"email"`
+	Role      string    `json:"role" db:"role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
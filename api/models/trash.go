@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Типы элементов корзины
+const (
+	TrashTypeMindMap = "mindmap"
+	TrashTypePost    = "post"
+)
+
+// TrashItem - удаленная карта или пост в корзине
+type TrashItem struct {
+	Type      string    `json:"type"`
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	UserID    int       `json:"user_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"` // Когда элемент будет удален окончательно
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/internal/attachments"
+	"github.com/mymindmap/api/models"
+)
+
+// AttachmentRepository - метаданные файлов узлов и их связи с картами
+type AttachmentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAttachmentRepository(db *pgxpool.Pool) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+// Get возвращает метаданные файла или nil, если файла нет
+func (r *AttachmentRepository) Get(ctx context.Context, hash string) (*models.Attachment, error) {
+	a := &models.Attachment{Hash: hash}
+	err := r.db.QueryRow(ctx,
+		`SELECT content_type, size, created_by, created_at FROM attachments WHERE hash = $1`,
+		hash,
+	).Scan(&a.ContentType, &a.Size, &a.CreatedBy, &a.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get attachment: %w", err)
+	}
+	a.URL = attachments.URL(hash)
+	return a, nil
+}
+
+// Create сохраняет метаданные файла. Если файл с таким хэшем уже загружен
+// (параллельно), в a записываются сохраненные метаданные.
+func (r *AttachmentRepository) Create(ctx context.Context, a *models.Attachment) error {
+	uploadedBy := a.CreatedBy
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO attachments (hash, content_type, size, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (hash) DO UPDATE SET hash = EXCLUDED.hash
+		RETURNING content_type, size, created_by, created_at`,
+		a.Hash, a.ContentType, a.Size, a.CreatedBy, time.Now(),
+	).Scan(&a.ContentType, &a.Size, &a.CreatedBy, &a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create attachment: %w", err)
+	}
+	if uploadedBy != nil {
+		return r.AddUploader(ctx, a.Hash, *uploadedBy)
+	}
+	return nil
+}
+
+// AddUploader записывает, что пользователь загрузил файл: после этого он может
+// скачать файл и сослаться на него в своих картах
+func (r *AttachmentRepository) AddUploader(ctx context.Context, hash string, userID int) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO attachment_uploads (hash, user_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (hash, user_id) DO NOTHING`,
+		hash, userID, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("add attachment uploader: %w", err)
+	}
+	return nil
+}
+
+// Link связывает с картой файлы, загруженные в нее напрямую. Неизвестные хэши
+// пропускаются. Права на файлы проверяет вызывающий.
+func (r *AttachmentRepository) Link(ctx context.Context, mindMapID int, hashes []string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO mindmap_attachments (mindmap_id, hash, created_at)
+		SELECT $1, a.hash, $3 FROM attachments a WHERE a.hash = ANY($2)
+		ON CONFLICT (mindmap_id, hash) DO NOTHING`,
+		mindMapID, hashes, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("link attachments: %w", err)
+	}
+	return nil
+}
+
+// CanView проверяет, что пользователь может скачать файл: файл связан с
+// публичной картой или с картой, в которой пользователь участник, либо
+// пользователь сам его загрузил. userID = 0 - без авторизации.
+func (r *AttachmentRepository) CanView(ctx context.Context, hash string, userID int) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM attachment_uploads u WHERE u.hash = $1 AND u.user_id = $2
+		) OR EXISTS (
+			SELECT 1
+			FROM mindmap_attachments ma
+			JOIN mindmaps m ON m.id = ma.mindmap_id AND m.deleted_at IS NULL
+			WHERE ma.hash = $1 AND (
+				m.is_public OR m.user_id = $2
+				OR EXISTS (SELECT 1 FROM mindmap_members mm WHERE mm.mindmap_id = m.id AND mm.user_id = $2)
+			)
+		)`,
+		hash, userID,
+	).Scan(&ok)
+	if err != nil {
+		return false, fmt.Errorf("check attachment access: %w", err)
+	}
+	return ok, nil
+}
+
+// LinkedTo проверяет, что файл связан с картой mindMapID. Используется для
+// доступа по ссылке на карту: такая ссылка открывает только файлы своей карты.
+func (r *AttachmentRepository) LinkedTo(ctx context.Context, hash string, mindMapID int) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM mindmap_attachments WHERE hash = $1 AND mindmap_id = $2)`,
+		hash, mindMapID,
+	).Scan(&ok)
+	if err != nil {
+		return false, fmt.Errorf("check attachment link: %w", err)
+	}
+	return ok, nil
+}
+
+// linkAttachments связывает с картой файлы из hashes, на которые ссылается ее
+// документ. Связь дает доступ к файлу всем, кто может читать карту, поэтому
+// связываются только файлы, которые автор сохранения мог скачать и так:
+// загруженные им самим или уже связанные с картой, которую он может читать.
+// Файлы, загруженные без авторизации при правке по ссылке (authorID = 0),
+// связываются, пока на них не ссылается ни одна карта. Остальные хэши
+// пропускаются: картинка в документе остается, но не открывается.
+//
+// Связи не удаляются, когда ссылка пропадает из документа: на файл могут
+// ссылаться старые ревизии карты.
+func linkAttachments(ctx context.Context, q querier, mindMapID, authorID int, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	_, err := q.Exec(ctx, `
+		INSERT INTO mindmap_attachments (mindmap_id, hash, created_at)
+		SELECT $1, a.hash, $4
+		FROM attachments a
+		WHERE a.hash = ANY($2) AND (
+			EXISTS (SELECT 1 FROM attachment_uploads u WHERE u.hash = a.hash AND u.user_id = $3)
+			OR ($3 = 0 AND a.created_by IS NULL
+				AND NOT EXISTS (SELECT 1 FROM mindmap_attachments x WHERE x.hash = a.hash))
+			OR EXISTS (
+				SELECT 1
+				FROM mindmap_attachments ma
+				JOIN mindmaps m ON m.id = ma.mindmap_id AND m.deleted_at IS NULL
+				WHERE ma.hash = a.hash AND (
+					m.id = $1 OR m.is_public OR m.user_id = $3
+					OR EXISTS (SELECT 1 FROM mindmap_members mm WHERE mm.mindmap_id = m.id AND mm.user_id = $3)
+					OR EXISTS (SELECT 1 FROM users adm WHERE adm.id = $3 AND adm.role = 'admin')
+				)
+			)
+		)
+		ON CONFLICT (mindmap_id, hash) DO NOTHING`,
+		mindMapID, hashes, authorID, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("link attachments: %w", err)
+	}
+	return nil
+}
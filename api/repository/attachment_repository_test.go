@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/attachments"
+	"github.com/mymindmap/api/internal/dbtest"
+	"github.com/mymindmap/api/models"
+)
+
+func imageDoc(hash string) string {
+	return fmt.Sprintf(`{"root":{"data":{"text":"Root","uid":"r","image":%q},"children":[]}}`, attachments.URL(hash))
+}
+
+func TestLinkAttachmentsRequiresAccess(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	mindMaps := NewMindMapRepository(db)
+	attachRepo := NewAttachmentRepository(db)
+	service := attachments.NewService(nil, attachRepo)
+
+	alice := dbtest.CreateUser(t, db, "alice")
+	bob := dbtest.CreateUser(t, db, "bob")
+
+	hash := attachments.Hash([]byte("secret"))
+	require.NoError(t, attachRepo.Create(ctx, &models.Attachment{Hash: hash, ContentType: "image/png", Size: 6, CreatedBy: &alice}))
+	private := &models.MindMap{Title: "private", Data: imageDoc(hash), UserID: alice}
+	require.NoError(t, mindMaps.CreateMindMap(ctx, private))
+
+	// Боб знает хэш, но не может скачать файл: публичная карта со ссылкой на него доступа не дает
+	stolen := &models.MindMap{Title: "stolen", Data: imageDoc(hash), UserID: bob, IsPublic: true}
+	require.NoError(t, mindMaps.CreateMindMap(ctx, stolen))
+	require.NoError(t, mindMaps.Update(ctx, stolen, bob))
+
+	for _, userID := range []int{bob, 0} {
+		ok, err := attachRepo.CanView(ctx, hash, userID)
+		require.NoError(t, err)
+		assert.False(t, ok, "user %d", userID)
+	}
+
+	// Участник карты Алисы файл видит и может сослаться на него в своей карте
+	_, err := db.Exec(ctx,
+		`INSERT INTO mindmap_members (mindmap_id, user_id, role) VALUES ($1, $2, 'viewer')`,
+		private.ID, bob,
+	)
+	require.NoError(t, err)
+	shared := &models.MindMap{Title: "shared", Data: imageDoc(hash), UserID: bob, IsPublic: true}
+	require.NoError(t, mindMaps.CreateMindMap(ctx, shared))
+	_, err = db.Exec(ctx, `DELETE FROM mindmap_members WHERE mindmap_id = $1 AND user_id = $2`, private.ID, bob)
+	require.NoError(t, err)
+
+	ok, err := attachRepo.CanView(ctx, hash, 0)
+	require.NoError(t, err)
+	assert.True(t, ok, "file is linked to a public map by a user who could view it")
+
+	// Загрузивший тот же файл повторно получает к нему доступ
+	carol := dbtest.CreateUser(t, db, "carol")
+	_, err = service.Save(ctx, "image/png", []byte("secret"), &carol)
+	require.NoError(t, err)
+	ok, err = attachRepo.CanView(ctx, hash, carol)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,433 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/models"
+)
+
+// CommentRepository - обсуждения узлов карт
+type CommentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCommentRepository(db *pgxpool.Pool) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+const threadColumns = `id, mindmap_id, node_uid, node_text, created_by, resolved_at, resolved_by, orphaned_at, created_at, updated_at`
+
+// ListThreads возвращает обсуждения карты с сообщениями, старые первыми.
+// nodeUID - только обсуждения узла, пустая строка - все. status - одно из
+// models.CommentStatus*.
+func (r *CommentRepository) ListThreads(ctx context.Context, mindMapID int, nodeUID, status string) ([]*models.CommentThread, error) {
+	query := `SELECT ` + threadColumns + ` FROM comment_threads WHERE mindmap_id = $1 AND ($2 = '' OR node_uid = $2)`
+	switch status {
+	case models.CommentStatusOpen:
+		query += ` AND resolved_at IS NULL AND orphaned_at IS NULL`
+	case models.CommentStatusResolved:
+		query += ` AND resolved_at IS NOT NULL`
+	case models.CommentStatusOrphaned:
+		query += ` AND orphaned_at IS NOT NULL`
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := r.db.Query(ctx, query, mindMapID, nodeUID)
+	if err != nil {
+		return nil, fmt.Errorf("list comment threads: %w", err)
+	}
+	defer rows.Close()
+
+	threads := []*models.CommentThread{}
+	for rows.Next() {
+		thread, err := scanThread(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan comment thread: %w", err)
+		}
+		threads = append(threads, thread)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list comment threads: %w", err)
+	}
+
+	if err := r.loadComments(ctx, threads); err != nil {
+		return nil, err
+	}
+	return threads, nil
+}
+
+// GetThread возвращает обсуждение карты с сообщениями или nil, если его нет
+func (r *CommentRepository) GetThread(ctx context.Context, mindMapID, threadID int) (*models.CommentThread, error) {
+	thread, err := scanThread(r.db.QueryRow(ctx,
+		`SELECT `+threadColumns+` FROM comment_threads WHERE id = $1 AND mindmap_id = $2`,
+		threadID, mindMapID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get comment thread: %w", err)
+	}
+
+	if err := r.loadComments(ctx, []*models.CommentThread{thread}); err != nil {
+		return nil, err
+	}
+	return thread, nil
+}
+
+// CreateThread создает обсуждение узла с первым сообщением. mentions - адреса
+// почты упомянутых; упоминания пользователей без доступа к карте не сохраняются.
+func (r *CommentRepository) CreateThread(ctx context.Context, thread *models.CommentThread, comment *models.Comment, mentions []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("create comment thread: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	err = tx.QueryRow(ctx, `
+		INSERT INTO comment_threads (mindmap_id, node_uid, node_text, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING id`,
+		thread.MindMapID, thread.NodeUID, thread.NodeText, thread.CreatedBy, now,
+	).Scan(&thread.ID)
+	if err != nil {
+		return fmt.Errorf("create comment thread: %w", err)
+	}
+	thread.CreatedAt = now
+	thread.UpdatedAt = now
+
+	comment.ThreadID = thread.ID
+	if err := insertComment(ctx, tx, thread.MindMapID, comment, mentions, now); err != nil {
+		return err
+	}
+	thread.Comments = []*models.Comment{comment}
+
+	return tx.Commit(ctx)
+}
+
+// AddComment добавляет ответ в обсуждение карты. Обсуждения нет - ErrCommentThreadNotFound.
+func (r *CommentRepository) AddComment(ctx context.Context, mindMapID int, comment *models.Comment, mentions []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("add comment: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	result, err := tx.Exec(ctx,
+		`UPDATE comment_threads SET updated_at = $3 WHERE id = $1 AND mindmap_id = $2`,
+		comment.ThreadID, mindMapID, now,
+	)
+	if err != nil {
+		return fmt.Errorf("add comment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrCommentThreadNotFound
+	}
+
+	if err := insertComment(ctx, tx, mindMapID, comment, mentions, now); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetComment возвращает сообщение обсуждения или nil, если его нет
+func (r *CommentRepository) GetComment(ctx context.Context, threadID, commentID int) (*models.Comment, error) {
+	comment := new(models.Comment)
+	err := r.db.QueryRow(ctx, `
+		SELECT c.id, c.thread_id, c.user_id, COALESCE(u.name, ''), c.body, c.created_at, c.edited_at
+		FROM comments c
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE c.id = $1 AND c.thread_id = $2`,
+		commentID, threadID,
+	).Scan(
+		&comment.ID,
+		&comment.ThreadID,
+		&comment.UserID,
+		&comment.AuthorName,
+		&comment.Body,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get comment: %w", err)
+	}
+	return comment, nil
+}
+
+// UpdateComment меняет текст сообщения и заново сохраняет упоминания
+func (r *CommentRepository) UpdateComment(ctx context.Context, mindMapID int, comment *models.Comment, mentions []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("update comment: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	result, err := tx.Exec(ctx,
+		`UPDATE comments SET body = $1, edited_at = $2 WHERE id = $3 AND thread_id = $4`,
+		comment.Body, now, comment.ID, comment.ThreadID,
+	)
+	if err != nil {
+		return fmt.Errorf("update comment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrCommentNotFound
+	}
+	comment.EditedAt = &now
+
+	if err := setMentions(ctx, tx, mindMapID, comment, mentions); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteComment удаляет сообщение. Обсуждение без сообщений удаляется
+// целиком, тогда threadDeleted = true.
+func (r *CommentRepository) DeleteComment(ctx context.Context, threadID, commentID int) (threadDeleted bool, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("delete comment: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `DELETE FROM comments WHERE id = $1 AND thread_id = $2`, commentID, threadID)
+	if err != nil {
+		return false, fmt.Errorf("delete comment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, ErrCommentNotFound
+	}
+
+	result, err = tx.Exec(ctx, `
+		DELETE FROM comment_threads t
+		WHERE t.id = $1 AND NOT EXISTS (SELECT 1 FROM comments c WHERE c.thread_id = t.id)`,
+		threadID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("delete comment: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("delete comment: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// DeleteThread удаляет обсуждение со всеми сообщениями
+func (r *CommentRepository) DeleteThread(ctx context.Context, mindMapID, threadID int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM comment_threads WHERE id = $1 AND mindmap_id = $2`, threadID, mindMapID)
+	if err != nil {
+		return fmt.Errorf("delete comment thread: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrCommentThreadNotFound
+	}
+	return nil
+}
+
+// Resolve помечает обсуждение решенным
+func (r *CommentRepository) Resolve(ctx context.Context, thread *models.CommentThread, userID int) error {
+	now := time.Now()
+	result, err := r.db.Exec(ctx,
+		`UPDATE comment_threads SET resolved_at = $1, resolved_by = $2, updated_at = $1 WHERE id = $3 AND mindmap_id = $4`,
+		now, userID, thread.ID, thread.MindMapID,
+	)
+	if err != nil {
+		return fmt.Errorf("resolve comment thread: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrCommentThreadNotFound
+	}
+	thread.ResolvedAt, thread.ResolvedBy, thread.UpdatedAt = &now, &userID, now
+	return nil
+}
+
+// Reopen снимает с обсуждения пометку решенного
+func (r *CommentRepository) Reopen(ctx context.Context, thread *models.CommentThread) error {
+	now := time.Now()
+	result, err := r.db.Exec(ctx,
+		`UPDATE comment_threads SET resolved_at = NULL, resolved_by = NULL, updated_at = $1 WHERE id = $2 AND mindmap_id = $3`,
+		now, thread.ID, thread.MindMapID,
+	)
+	if err != nil {
+		return fmt.Errorf("reopen comment thread: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrCommentThreadNotFound
+	}
+	thread.ResolvedAt, thread.ResolvedBy, thread.UpdatedAt = nil, nil, now
+	return nil
+}
+
+// loadComments загружает сообщения и упоминания обсуждений
+func (r *CommentRepository) loadComments(ctx context.Context, threads []*models.CommentThread) error {
+	if len(threads) == 0 {
+		return nil
+	}
+	ids := make([]int, len(threads))
+	byID := make(map[int]*models.CommentThread, len(threads))
+	for i, thread := range threads {
+		ids[i] = thread.ID
+		thread.Comments = []*models.Comment{}
+		byID[thread.ID] = thread
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT c.id, c.thread_id, c.user_id, COALESCE(u.name, ''), c.body, c.created_at, c.edited_at
+		FROM comments c
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE c.thread_id = ANY($1)
+		ORDER BY c.created_at, c.id`,
+		ids,
+	)
+	if err != nil {
+		return fmt.Errorf("list comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := make(map[int]*models.Comment)
+	for rows.Next() {
+		comment := &models.Comment{Mentions: []models.CommentMention{}}
+		if err := rows.Scan(
+			&comment.ID,
+			&comment.ThreadID,
+			&comment.UserID,
+			&comment.AuthorName,
+			&comment.Body,
+			&comment.CreatedAt,
+			&comment.EditedAt,
+		); err != nil {
+			return fmt.Errorf("scan comment: %w", err)
+		}
+		comments[comment.ID] = comment
+		thread := byID[comment.ThreadID]
+		thread.Comments = append(thread.Comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("list comments: %w", err)
+	}
+	rows.Close()
+
+	mentionRows, err := r.db.Query(ctx, `
+		SELECT cm.comment_id, u.id, u.name
+		FROM comment_mentions cm
+		JOIN comments c ON c.id = cm.comment_id
+		JOIN users u ON u.id = cm.user_id
+		WHERE c.thread_id = ANY($1)
+		ORDER BY u.name, u.id`,
+		ids,
+	)
+	if err != nil {
+		return fmt.Errorf("list comment mentions: %w", err)
+	}
+	defer mentionRows.Close()
+
+	for mentionRows.Next() {
+		var commentID int
+		var mention models.CommentMention
+		if err := mentionRows.Scan(&commentID, &mention.UserID, &mention.Name); err != nil {
+			return fmt.Errorf("scan comment mention: %w", err)
+		}
+		if comment, ok := comments[commentID]; ok {
+			comment.Mentions = append(comment.Mentions, mention)
+		}
+	}
+	if err := mentionRows.Err(); err != nil {
+		return fmt.Errorf("list comment mentions: %w", err)
+	}
+	return nil
+}
+
+// insertComment сохраняет новое сообщение обсуждения с упоминаниями
+func insertComment(ctx context.Context, tx pgx.Tx, mindMapID int, comment *models.Comment, mentions []string, now time.Time) error {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO comments (thread_id, user_id, body, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, COALESCE((SELECT name FROM users WHERE id = $2), '')`,
+		comment.ThreadID, comment.UserID, comment.Body, now,
+	).Scan(&comment.ID, &comment.AuthorName)
+	if err != nil {
+		return fmt.Errorf("insert comment: %w", err)
+	}
+	comment.CreatedAt = now
+	return setMentions(ctx, tx, mindMapID, comment, mentions)
+}
+
+// setMentions заменяет упоминания сообщения. Сохраняются только участники карты,
+// в comment.Mentions записываются сохраненные.
+func setMentions(ctx context.Context, tx pgx.Tx, mindMapID int, comment *models.Comment, emails []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM comment_mentions WHERE comment_id = $1`, comment.ID); err != nil {
+		return fmt.Errorf("set comment mentions: %w", err)
+	}
+
+	comment.Mentions = []models.CommentMention{}
+	if len(emails) == 0 {
+		return nil
+	}
+	rows, err := tx.Query(ctx, `
+		INSERT INTO comment_mentions (comment_id, user_id)
+		SELECT $1, u.id
+		FROM users u
+		JOIN mindmap_members mm ON mm.user_id = u.id AND mm.mindmap_id = $2
+		WHERE lower(u.email) = ANY($3)
+		ON CONFLICT DO NOTHING
+		RETURNING user_id, (SELECT mu.name FROM users mu WHERE mu.id = comment_mentions.user_id)`,
+		comment.ID, mindMapID, emails,
+	)
+	if err != nil {
+		return fmt.Errorf("set comment mentions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mention models.CommentMention
+		if err := rows.Scan(&mention.UserID, &mention.Name); err != nil {
+			return fmt.Errorf("scan comment mention: %w", err)
+		}
+		comment.Mentions = append(comment.Mentions, mention)
+	}
+	return rows.Err()
+}
+
+// syncCommentAnchors отмечает обсуждения узлов, которых больше нет в документе,
+// и снимает отметку с обсуждений вернувшихся узлов (например, после отката к ревизии)
+func syncCommentAnchors(ctx context.Context, q querier, mindMapID int, uids []string) error {
+	_, err := q.Exec(ctx, `
+		UPDATE comment_threads
+		SET orphaned_at = CASE WHEN node_uid = ANY($2) THEN NULL ELSE $3::timestamp END
+		WHERE mindmap_id = $1 AND (orphaned_at IS NULL) <> (node_uid = ANY($2))`,
+		mindMapID, uids, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("sync comment anchors: %w", err)
+	}
+	return nil
+}
+
+func scanThread(row pgx.Row) (*models.CommentThread, error) {
+	thread := new(models.CommentThread)
+	err := row.Scan(
+		&thread.ID,
+		&thread.MindMapID,
+		&thread.NodeUID,
+		&thread.NodeText,
+		&thread.CreatedBy,
+		&thread.ResolvedAt,
+		&thread.ResolvedBy,
+		&thread.OrphanedAt,
+		&thread.CreatedAt,
+		&thread.UpdatedAt,
+	)
+	return thread, err
+}
@@ -0,0 +1,45 @@
+package repository
+
+import "errors"
+
+// ErrVersionConflict - запись изменилась с момента чтения (оптимистичная блокировка)
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrMindMapNotFound - карта не найдена или перенесена в корзину
+var ErrMindMapNotFound = errors.New("mindmap not found")
+
+// ErrMemberExists - пользователь уже участник карты
+var ErrMemberExists = errors.New("user is already a member")
+
+// ErrMemberNotFound - пользователь не участник карты
+var ErrMemberNotFound = errors.New("member not found")
+
+// ErrShareLinkNotFound - ссылка доступа не найдена
+var ErrShareLinkNotFound = errors.New("share link not found")
+
+// ErrTemplateNotFound - шаблон карты не найден
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ErrFolderNotFound - папка не найдена или принадлежит другому пользователю
+var ErrFolderNotFound = errors.New("folder not found")
+
+// ErrFolderNotEmpty - в папке есть карты или вложенные папки
+var ErrFolderNotEmpty = errors.New("folder is not empty")
+
+// ErrFolderCycle - папку нельзя перенести в саму себя или во вложенную папку
+var ErrFolderCycle = errors.New("folder cannot be moved into itself or its subfolder")
+
+// ErrTagNotFound - тег не найден или принадлежит другому пользователю
+var ErrTagNotFound = errors.New("tag not found")
+
+// ErrTagExists - у пользователя уже есть тег с таким названием
+var ErrTagExists = errors.New("tag already exists")
+
+// ErrNotInTrash - элемента нет в корзине
+var ErrNotInTrash = errors.New("item not found in trash")
+
+// ErrCommentThreadNotFound - обсуждение не найдено в карте
+var ErrCommentThreadNotFound = errors.New("comment thread not found")
+
+// ErrCommentNotFound - сообщение не найдено в обсуждении
+var ErrCommentNotFound = errors.New("comment not found")
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/models"
+)
+
+type FolderRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFolderRepository(db *pgxpool.Pool) *FolderRepository {
+	return &FolderRepository{db: db}
+}
+
+// Create создает папку. Родительская папка должна принадлежать тому же пользователю,
+// иначе возвращается ErrFolderNotFound.
+func (r *FolderRepository) Create(ctx context.Context, folder *models.Folder) error {
+	now := time.Now()
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO folders (user_id, parent_id, name, created_at, updated_at)
+		SELECT $1, $2, $3, $4, $4
+		WHERE $2::int IS NULL OR EXISTS (SELECT 1 FROM folders WHERE id = $2 AND user_id = $1)
+		RETURNING id`,
+		folder.UserID, folder.ParentID, folder.Name, now,
+	).Scan(&folder.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrFolderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("create folder: %w", err)
+	}
+
+	folder.CreatedAt = now
+	folder.UpdatedAt = now
+	return nil
+}
+
+// Get возвращает папку пользователя. nil - папки нет или она чужая.
+func (r *FolderRepository) Get(ctx context.Context, id, userID int) (*models.Folder, error) {
+	folder := new(models.Folder)
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, parent_id, name, created_at, updated_at
+		FROM folders
+		WHERE id = $1 AND user_id = $2`,
+		id, userID,
+	).Scan(
+		&folder.ID,
+		&folder.UserID,
+		&folder.ParentID,
+		&folder.Name,
+		&folder.CreatedAt,
+		&folder.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get folder: %w", err)
+	}
+	return folder, nil
+}
+
+// Tree возвращает дерево папок пользователя с числом карт в каждой.
+// Папки одного уровня упорядочены по названию.
+func (r *FolderRepository) Tree(ctx context.Context, userID int) (*models.FolderTree, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT f.id, f.user_id, f.parent_id, f.name,
+		       (SELECT COUNT(*) FROM mindmaps m WHERE m.folder_id = f.id AND m.deleted_at IS NULL),
+		       f.created_at, f.updated_at
+		FROM folders f
+		WHERE f.user_id = $1
+		ORDER BY f.name, f.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list folders: %w", err)
+	}
+	defer rows.Close()
+
+	var folders []*models.Folder
+	for rows.Next() {
+		folder := new(models.Folder)
+		if err := rows.Scan(
+			&folder.ID,
+			&folder.UserID,
+			&folder.ParentID,
+			&folder.Name,
+			&folder.MapCount,
+			&folder.CreatedAt,
+			&folder.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan folder: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	tree := &models.FolderTree{Folders: buildFolderTree(folders)}
+	err = r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM mindmaps WHERE user_id = $1 AND folder_id IS NULL AND deleted_at IS NULL`,
+		userID,
+	).Scan(&tree.MapCount)
+	if err != nil {
+		return nil, fmt.Errorf("count mindmaps outside folders: %w", err)
+	}
+
+	return tree, nil
+}
+
+// buildFolderTree раскладывает папки по родителям и считает карты во вложенных папках.
+// Порядок папок одного уровня сохраняется.
+func buildFolderTree(folders []*models.Folder) []*models.Folder {
+	byID := make(map[int]*models.Folder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+
+	roots := []*models.Folder{}
+	for _, f := range folders {
+		if parent := byID[derefInt(f.ParentID)]; f.ParentID != nil && parent != nil {
+			parent.Children = append(parent.Children, f)
+		} else {
+			roots = append(roots, f)
+		}
+	}
+
+	var total func(f *models.Folder) int
+	total = func(f *models.Folder) int {
+		f.TotalCount = f.MapCount
+		for _, child := range f.Children {
+			f.TotalCount += total(child)
+		}
+		return f.TotalCount
+	}
+	for _, f := range roots {
+		total(f)
+	}
+	return roots
+}
+
+func derefInt(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+// Rename меняет название папки
+func (r *FolderRepository) Rename(ctx context.Context, folder *models.Folder) error {
+	now := time.Now()
+	result, err := r.db.Exec(ctx,
+		`UPDATE folders SET name = $1, updated_at = $2 WHERE id = $3 AND user_id = $4`,
+		folder.Name, now, folder.ID, folder.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("rename folder: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrFolderNotFound
+	}
+
+	folder.UpdatedAt = now
+	return nil
+}
+
+// Move переносит папку в parentID (nil - в корень). Перенос в саму себя или во
+// вложенную папку возвращает ErrFolderCycle.
+func (r *FolderRepository) Move(ctx context.Context, folder *models.Folder, parentID *int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("move folder: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if parentID != nil {
+		// Цепочка предков новой родительской папки не должна проходить через переносимую
+		var cycle, found bool
+		err := tx.QueryRow(ctx, `
+			WITH RECURSIVE ancestors AS (
+				SELECT id, parent_id FROM folders WHERE id = $1 AND user_id = $2
+				UNION ALL
+				SELECT f.id, f.parent_id FROM folders f JOIN ancestors a ON f.id = a.parent_id
+			)
+			SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $3),
+			       EXISTS (SELECT 1 FROM ancestors)`,
+			*parentID, folder.UserID, folder.ID,
+		).Scan(&cycle, &found)
+		if err != nil {
+			return fmt.Errorf("move folder: %w", err)
+		}
+		if !found {
+			return ErrFolderNotFound
+		}
+		if cycle {
+			return ErrFolderCycle
+		}
+	}
+
+	now := time.Now()
+	result, err := tx.Exec(ctx,
+		`UPDATE folders SET parent_id = $1, updated_at = $2 WHERE id = $3 AND user_id = $4`,
+		parentID, now, folder.ID, folder.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("move folder: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrFolderNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("move folder: %w", err)
+	}
+
+	folder.ParentID = parentID
+	folder.UpdatedAt = now
+	return nil
+}
+
+// Delete удаляет пустую папку. Если в ней есть карты или вложенные папки,
+// возвращает ErrFolderNotEmpty и ничего не удаляет. Карты в корзине не считаются.
+func (r *FolderRepository) Delete(ctx context.Context, id, userID int) error {
+	var found, empty bool
+	err := r.db.QueryRow(ctx, `
+		WITH deleted AS (
+			DELETE FROM folders f
+			WHERE f.id = $1 AND f.user_id = $2
+			  AND NOT EXISTS (SELECT 1 FROM folders c WHERE c.parent_id = f.id)
+			  AND NOT EXISTS (SELECT 1 FROM mindmaps m WHERE m.folder_id = f.id AND m.deleted_at IS NULL)
+			RETURNING id
+		)
+		SELECT EXISTS (SELECT 1 FROM folders WHERE id = $1 AND user_id = $2),
+		       EXISTS (SELECT 1 FROM deleted)`,
+		id, userID,
+	).Scan(&found, &empty)
+	if err != nil {
+		return fmt.Errorf("delete folder: %w", err)
+	}
+	if !found {
+		return ErrFolderNotFound
+	}
+	if !empty {
+		return ErrFolderNotEmpty
+	}
+	return nil
+}
+
+// DeleteWithContents удаляет папку со всеми вложенными папками, а их карты переносит
+// в корзину. Возвращает id карт, перенесенных в корзину. Восстановленные из
+// корзины карты окажутся в корне.
+func (r *FolderRepository) DeleteWithContents(ctx context.Context, id, userID int) ([]int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("delete folder: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM folders WHERE id = $1 AND user_id = $2
+			UNION ALL
+			SELECT f.id FROM folders f JOIN subtree s ON f.parent_id = s.id
+		)
+		UPDATE mindmaps SET deleted_at = $3
+		WHERE folder_id IN (SELECT id FROM subtree) AND deleted_at IS NULL
+		RETURNING id`,
+		id, userID, time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("delete folder: %w", err)
+	}
+	var trashed []int
+	for rows.Next() {
+		var mindMapID int
+		if err := rows.Scan(&mindMapID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("delete folder: %w", err)
+		}
+		trashed = append(trashed, mindMapID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("delete folder: %w", err)
+	}
+
+	// Вложенные папки удаляются каскадно, у карт folder_id обнуляется
+	result, err := tx.Exec(ctx, `DELETE FROM folders WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("delete folder: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrFolderNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("delete folder: %w", err)
+	}
+	return trashed, nil
+}
+
+// MoveMindMap переносит карту владельца в папку folderID (nil - в корень).
+// Папка должна принадлежать владельцу карты, иначе возвращается ErrFolderNotFound.
+func (r *FolderRepository) MoveMindMap(ctx context.Context, mindMapID, userID int, folderID *int) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE mindmaps SET folder_id = $1
+		WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL
+		  AND ($1::int IS NULL OR EXISTS (SELECT 1 FROM folders WHERE id = $1 AND user_id = $3))`,
+		folderID, mindMapID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("move mindmap to folder: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrFolderNotFound
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/dbtest"
+	"github.com/mymindmap/api/models"
+)
+
+func TestDeleteWithContentsReturnsTrashedMaps(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	folders := NewFolderRepository(db)
+	mindMaps := NewMindMapRepository(db)
+
+	alice := dbtest.CreateUser(t, db, "alice")
+	parent := &models.Folder{UserID: alice, Name: "parent"}
+	require.NoError(t, folders.Create(ctx, parent))
+	child := &models.Folder{UserID: alice, ParentID: &parent.ID, Name: "child"}
+	require.NoError(t, folders.Create(ctx, child))
+
+	var want []int
+	for _, folderID := range []*int{&parent.ID, &child.ID, nil} {
+		m := &models.MindMap{Title: "map", Data: `{"root":{"data":{"text":"Root","uid":"r"},"children":[]}}`, UserID: alice}
+		require.NoError(t, mindMaps.CreateMindMap(ctx, m))
+		if folderID != nil {
+			require.NoError(t, folders.MoveMindMap(ctx, m.ID, alice, folderID))
+			want = append(want, m.ID)
+		}
+	}
+
+	trashed, err := folders.DeleteWithContents(ctx, parent.ID, alice)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, trashed)
+}
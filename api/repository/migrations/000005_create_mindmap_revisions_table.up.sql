@@ -0,0 +1,24 @@
+-- История изменений карт. Ревизия хранит либо полный документ (kind = 'full'),
+-- либо разницу с ближайшей предыдущей полной ревизией (kind = 'delta', base_revision).
+CREATE TABLE IF NOT EXISTS mindmap_revisions (
+    id SERIAL PRIMARY KEY,
+    mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('full', 'delta')),
+    base_revision INTEGER,
+    data TEXT NOT NULL,
+    size INTEGER NOT NULL DEFAULT 0,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (mindmap_id, revision),
+    CHECK (kind = 'full' OR base_revision IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mindmap_revisions_created_at ON mindmap_revisions(mindmap_id, created_at);
+
+-- Текущее состояние существующих карт становится их первой ревизией
+INSERT INTO mindmap_revisions (mindmap_id, revision, title, kind, data, size, author_id, created_at)
+SELECT id, 1, title, 'full', data, octet_length(data), user_id, updated_at
+FROM mindmaps
+ON CONFLICT (mindmap_id, revision) DO NOTHING;
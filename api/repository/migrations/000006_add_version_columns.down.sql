@@ -0,0 +1,2 @@
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS version;
+ALTER TABLE posts DROP COLUMN IF EXISTS version;
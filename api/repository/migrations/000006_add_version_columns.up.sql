@@ -0,0 +1,3 @@
+-- Версия записи для оптимистичной блокировки (ETag / If-Match)
+ALTER TABLE mindmaps ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+-- Журнал пакетов операций над узлами карты, чтобы клиенты могли их воспроизвести
+CREATE TABLE IF NOT EXISTS mindmap_ops (
+    id SERIAL PRIMARY KEY,
+    mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    base_version INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    ops JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (mindmap_id, version)
+);
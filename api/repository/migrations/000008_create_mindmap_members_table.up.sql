@@ -0,0 +1,19 @@
+-- Участники карты и их роли. Владелец карты (mindmaps.user_id) тоже
+-- записывается сюда с ролью 'owner', чтобы список участников был полным.
+CREATE TABLE IF NOT EXISTS mindmap_members (
+    mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('viewer', 'commenter', 'editor', 'owner')),
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (mindmap_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mindmap_members_user_id ON mindmap_members(user_id);
+
+-- Владельцы существующих карт
+INSERT INTO mindmap_members (mindmap_id, user_id, role, created_at, updated_at)
+SELECT id, user_id, 'owner', created_at, created_at
+FROM mindmaps
+ON CONFLICT (mindmap_id, user_id) DO NOTHING;
@@ -0,0 +1,16 @@
+-- Ссылки для доступа к карте без аккаунта. Хранится только sha256 токена:
+-- сам токен показывается один раз при создании ссылки.
+CREATE TABLE IF NOT EXISTS mindmap_share_links (
+    id SERIAL PRIMARY KEY,
+    mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    mode VARCHAR(10) NOT NULL CHECK (mode IN ('view', 'edit')),
+    password_hash VARCHAR(255),
+    expires_at TIMESTAMP,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mindmap_share_links_mindmap_id ON mindmap_share_links(mindmap_id);
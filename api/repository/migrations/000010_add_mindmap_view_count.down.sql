@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_mindmaps_public_view_count;
+DROP INDEX IF EXISTS idx_mindmaps_public_updated_at;
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS view_count;
@@ -0,0 +1,5 @@
+-- Счетчик просмотров публичной карты для сортировки галереи по популярности
+ALTER TABLE mindmaps ADD COLUMN IF NOT EXISTS view_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_mindmaps_public_updated_at ON mindmaps(updated_at DESC, id DESC) WHERE is_public = true;
+CREATE INDEX IF NOT EXISTS idx_mindmaps_public_view_count ON mindmaps(view_count DESC, id DESC) WHERE is_public = true;
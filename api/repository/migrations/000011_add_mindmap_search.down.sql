@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_mindmaps_search_vector;
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS search_text;
@@ -0,0 +1,35 @@
+-- Полнотекстовый поиск по картам. search_text - текст узлов, заметок и тегов,
+-- его заполняет приложение при каждом сохранении карты.
+ALTER TABLE mindmaps ADD COLUMN IF NOT EXISTS search_text TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE mindmaps ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('russian', search_text), 'B') ||
+        setweight(to_tsvector('english', search_text), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_mindmaps_search_vector ON mindmaps USING GIN (search_vector);
+
+-- Первичное заполнение для существующих карт: текст и заметки всех узлов.
+-- Карты с некорректным JSON пропускаются и проиндексируются при следующем сохранении.
+DO $$
+DECLARE
+    m RECORD;
+BEGIN
+    FOR m IN SELECT id, data FROM mindmaps LOOP
+        BEGIN
+            UPDATE mindmaps SET search_text = coalesce((
+                SELECT string_agg(v #>> '{}', E'\n')
+                FROM jsonb_path_query(m.data::jsonb, 'lax $.**.data.text') AS v
+            ), '') || E'\n' || coalesce((
+                SELECT string_agg(v #>> '{}', E'\n')
+                FROM jsonb_path_query(m.data::jsonb, 'lax $.**.data.note') AS v
+            ), '')
+            WHERE id = m.id;
+        EXCEPTION WHEN others THEN
+            NULL;
+        END;
+    END LOOP;
+END $$;
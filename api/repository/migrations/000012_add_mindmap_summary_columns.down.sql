@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_mindmaps_user_updated_at;
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS thumbnail;
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS node_count;
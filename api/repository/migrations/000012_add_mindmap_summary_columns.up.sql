@@ -0,0 +1,24 @@
+-- Поля для списка карт без загрузки документа: число узлов и ссылка на превью.
+-- node_count заполняет приложение при каждом сохранении карты.
+ALTER TABLE mindmaps ADD COLUMN IF NOT EXISTS node_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mindmaps ADD COLUMN IF NOT EXISTS thumbnail TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_mindmaps_user_updated_at ON mindmaps(user_id, updated_at DESC, id DESC);
+
+-- Первичное заполнение: узел - объект с ключом data
+DO $$
+DECLARE
+    m RECORD;
+BEGIN
+    FOR m IN SELECT id, data FROM mindmaps LOOP
+        BEGIN
+            UPDATE mindmaps SET node_count = (
+                SELECT count(*)
+                FROM jsonb_path_query(m.data::jsonb, 'strict $.** ? (@.type() == "object" && exists(@.data))')
+            )
+            WHERE id = m.id;
+        EXCEPTION WHEN others THEN
+            NULL;
+        END;
+    END LOOP;
+END $$;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_mindmaps_thumbnail_pending;
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS thumbnail_version;
+DROP TABLE IF EXISTS mindmap_thumbnails;
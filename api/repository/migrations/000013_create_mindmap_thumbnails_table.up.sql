@@ -0,0 +1,15 @@
+-- Превью карт для списка. Превью рисуются в фоне: карта ждет нового превью,
+-- пока thumbnail_version не совпадет с version. В mindmaps.thumbnail
+-- записывается ссылка на превью с версией, чтобы клиент не показывал старое из кеша.
+CREATE TABLE IF NOT EXISTS mindmap_thumbnails (
+    mindmap_id INTEGER PRIMARY KEY REFERENCES mindmaps(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    content BYTEA NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE mindmaps ADD COLUMN IF NOT EXISTS thumbnail_version INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_mindmaps_thumbnail_pending ON mindmaps(updated_at)
+    WHERE thumbnail_version IS DISTINCT FROM version;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_mindmaps_forked_from_id;
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS forked_from_revision;
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS forked_from_id;
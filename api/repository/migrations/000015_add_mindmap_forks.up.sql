@@ -0,0 +1,6 @@
+-- Происхождение копии карты: исходная карта и ее ревизия на момент копирования.
+-- При удалении исходной карты ссылка обнуляется, ревизия остается для истории.
+ALTER TABLE mindmaps ADD COLUMN IF NOT EXISTS forked_from_id INTEGER REFERENCES mindmaps(id) ON DELETE SET NULL;
+ALTER TABLE mindmaps ADD COLUMN IF NOT EXISTS forked_from_revision INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_mindmaps_forked_from_id ON mindmaps(forked_from_id) WHERE forked_from_id IS NOT NULL;
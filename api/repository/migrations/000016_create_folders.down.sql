@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_mindmaps_folder_id;
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS folder_id;
+DROP TABLE IF EXISTS folders;
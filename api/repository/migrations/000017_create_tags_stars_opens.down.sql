@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS mindmap_opens;
+DROP TABLE IF EXISTS mindmap_stars;
+DROP TABLE IF EXISTS mindmap_tags;
+DROP TABLE IF EXISTS tags;
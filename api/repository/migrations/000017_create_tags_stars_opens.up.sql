@@ -0,0 +1,40 @@
+-- Личные теги пользователя. Пользователь может пометить тегом любую доступную ему карту,
+-- другие участники карты его тегов не видят.
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    color VARCHAR(16),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags(user_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS mindmap_tags (
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tag_id, mindmap_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mindmap_tags_mindmap_id ON mindmap_tags(mindmap_id);
+
+-- Избранные карты
+CREATE TABLE IF NOT EXISTS mindmap_stars (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, mindmap_id)
+);
+
+-- Последнее открытие карты пользователем, для списка недавних
+CREATE TABLE IF NOT EXISTS mindmap_opens (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    opened_at TIMESTAMP NOT NULL,
+    open_count INTEGER NOT NULL DEFAULT 1,
+    PRIMARY KEY (user_id, mindmap_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mindmap_opens_user_opened_at ON mindmap_opens(user_id, opened_at DESC, mindmap_id DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_posts_deleted_at;
+DROP INDEX IF EXISTS idx_mindmaps_deleted_at;
+ALTER TABLE mindmaps DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,7 @@
+-- Корзина: удаленные карты и посты помечаются deleted_at и окончательно
+-- удаляются фоновой задачей по истечении срока хранения. posts.deleted_at
+-- есть с миграции 000001.
+ALTER TABLE mindmaps ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_mindmaps_deleted_at ON mindmaps(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts(deleted_at) WHERE deleted_at IS NOT NULL;
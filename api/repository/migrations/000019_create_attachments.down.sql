@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mindmap_attachments;
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,20 @@
+-- Файлы узлов карт. Содержимое лежит во внешнем хранилище (диск или S3)
+-- под ключом из SHA-256, здесь - метаданные.
+CREATE TABLE IF NOT EXISTS attachments (
+    hash CHAR(64) PRIMARY KEY,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Карты, в которые файл загружен или на него ссылается документ (в том числе
+-- старые ревизии). Доступ к файлу дает доступ к любой из этих карт.
+CREATE TABLE IF NOT EXISTS mindmap_attachments (
+    mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    hash CHAR(64) NOT NULL REFERENCES attachments(hash) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (mindmap_id, hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mindmap_attachments_hash ON mindmap_attachments(hash);
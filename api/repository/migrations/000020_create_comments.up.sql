@@ -0,0 +1,36 @@
+-- Обсуждения узлов карты. Обсуждение привязано к uid узла; если узел удален
+-- из документа, обсуждение остается с пометкой orphaned_at.
+CREATE TABLE IF NOT EXISTS comment_threads (
+    id SERIAL PRIMARY KEY,
+    mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    node_uid VARCHAR(64) NOT NULL,
+    node_text TEXT NOT NULL DEFAULT '', -- Текст узла на момент создания, для обсуждений удаленных узлов
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    orphaned_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_threads_mindmap_node ON comment_threads(mindmap_id, node_uid);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id SERIAL PRIMARY KEY,
+    thread_id INTEGER NOT NULL REFERENCES comment_threads(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_thread_id ON comments(thread_id, created_at);
+
+-- Упомянутые в комментарии участники карты
+CREATE TABLE IF NOT EXISTS comment_mentions (
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_mentions_user_id ON comment_mentions(user_id);
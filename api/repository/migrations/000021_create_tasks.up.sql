@@ -0,0 +1,23 @@
+-- Задачи карт: узлы с флажком, ответственным, сроком, приоритетом или прогрессом.
+-- Таблица пересобирается из документа при каждом сохранении карты.
+CREATE TABLE IF NOT EXISTS tasks (
+    mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    node_uid VARCHAR(64) NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    done BOOLEAN NOT NULL DEFAULT FALSE,
+    priority SMALLINT,
+    assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL, -- Только участник карты
+    due_at TIMESTAMP,
+    due_all_day BOOLEAN NOT NULL DEFAULT FALSE, -- due_at - дата без времени
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (mindmap_id, node_uid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_assignee_due ON tasks(assignee_id, due_at) WHERE NOT done;
+
+-- Токен подписки на календарь задач пользователя; хранится только хеш
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
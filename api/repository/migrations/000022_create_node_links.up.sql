@@ -0,0 +1,12 @@
+-- Ссылки из узлов на узлы других карт (поле данных mapLink). Таблица
+-- пересобирается из документа карты-источника при каждом сохранении.
+CREATE TABLE IF NOT EXISTS node_links (
+    source_mindmap_id INTEGER NOT NULL REFERENCES mindmaps(id) ON DELETE CASCADE,
+    source_node_uid VARCHAR(64) NOT NULL,
+    source_text TEXT NOT NULL DEFAULT '', -- Текст узла-источника без разметки
+    target_mindmap_id INTEGER NOT NULL, -- Без внешнего ключа: ссылка на удаленную карту остается в документе
+    target_node_uid VARCHAR(64) NOT NULL DEFAULT '', -- Пусто - карта целиком
+    PRIMARY KEY (source_mindmap_id, source_node_uid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_links_target ON node_links(target_mindmap_id, target_node_uid);
@@ -0,0 +1,13 @@
+-- Пользователи, которые загрузили файл (в том числе повторно, уже сохраненный
+-- кем-то другим). Связать файл с картой можно, только если он загружен автором
+-- сохранения или уже связан с картой, которую автор может читать.
+CREATE TABLE IF NOT EXISTS attachment_uploads (
+    hash CHAR(64) NOT NULL REFERENCES attachments(hash) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (hash, user_id)
+);
+
+INSERT INTO attachment_uploads (hash, user_id, created_at)
+SELECT hash, created_by, created_at FROM attachments WHERE created_by IS NOT NULL
+ON CONFLICT DO NOTHING;
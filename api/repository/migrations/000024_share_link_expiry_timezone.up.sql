@@ -0,0 +1,4 @@
+-- Срок действия ссылки хранится с часовым поясом, чтобы смещение из expires_at
+-- клиента не терялось. Прежние значения сравнивались с текущим временем как UTC.
+ALTER TABLE mindmap_share_links
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at AT TIME ZONE 'UTC';
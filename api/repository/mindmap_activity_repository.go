@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MindMapActivityRepository - избранное и открытия карт пользователем
+type MindMapActivityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMindMapActivityRepository(db *pgxpool.Pool) *MindMapActivityRepository {
+	return &MindMapActivityRepository{db: db}
+}
+
+// Star добавляет карту в избранное. Повторное добавление не ошибка.
+func (r *MindMapActivityRepository) Star(ctx context.Context, userID, mindMapID int) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO mindmap_stars (user_id, mindmap_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, mindmap_id) DO NOTHING`,
+		userID, mindMapID, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("star mindmap: %w", err)
+	}
+	return nil
+}
+
+// Unstar убирает карту из избранного
+func (r *MindMapActivityRepository) Unstar(ctx context.Context, userID, mindMapID int) error {
+	_, err := r.db.Exec(ctx,
+		`DELETE FROM mindmap_stars WHERE user_id = $1 AND mindmap_id = $2`,
+		userID, mindMapID,
+	)
+	if err != nil {
+		return fmt.Errorf("unstar mindmap: %w", err)
+	}
+	return nil
+}
+
+// RecordOpen запоминает, что пользователь открыл карту
+func (r *MindMapActivityRepository) RecordOpen(ctx context.Context, userID, mindMapID int) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO mindmap_opens (user_id, mindmap_id, opened_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, mindmap_id)
+		DO UPDATE SET opened_at = EXCLUDED.opened_at, open_count = mindmap_opens.open_count + 1`,
+		userID, mindMapID, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("record mindmap open: %w", err)
+	}
+	return nil
+}
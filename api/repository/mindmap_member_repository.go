@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/models"
+)
+
+type MindMapMemberRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMindMapMemberRepository(db *pgxpool.Pool) *MindMapMemberRepository {
+	return &MindMapMemberRepository{db: db}
+}
+
+// GetRole возвращает роль пользователя в карте или пустую строку, если он не участник
+func (r *MindMapMemberRepository) GetRole(ctx context.Context, mindMapID, userID int) (string, error) {
+	var role string
+	err := r.db.QueryRow(ctx,
+		`SELECT role FROM mindmap_members WHERE mindmap_id = $1 AND user_id = $2`,
+		mindMapID, userID,
+	).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get mindmap role: %w", err)
+	}
+	return role, nil
+}
+
+// List возвращает участников карты: сначала владелец, затем по дате приглашения
+func (r *MindMapMemberRepository) List(ctx context.Context, mindMapID int) ([]*models.MindMapMember, error) {
+	query := `
+		SELECT mm.mindmap_id, mm.user_id, u.name, u.email, mm.role, mm.invited_by, mm.created_at, mm.updated_at
+		FROM mindmap_members mm
+		JOIN users u ON u.id = mm.user_id
+		WHERE mm.mindmap_id = $1
+		ORDER BY mm.role = 'owner' DESC, mm.created_at, mm.user_id`
+
+	rows, err := r.db.Query(ctx, query, mindMapID)
+	if err != nil {
+		return nil, fmt.Errorf("list mindmap members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*models.MindMapMember{}
+	for rows.Next() {
+		member := new(models.MindMapMember)
+		if err := rows.Scan(
+			&member.MindMapID,
+			&member.UserID,
+			&member.Name,
+			&member.Email,
+			&member.Role,
+			&member.InvitedBy,
+			&member.CreatedAt,
+			&member.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan mindmap member: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return members, nil
+}
+
+// Add добавляет участника. Если пользователь уже участник, возвращает ErrMemberExists.
+func (r *MindMapMemberRepository) Add(ctx context.Context, member *models.MindMapMember) error {
+	now := time.Now()
+	result, err := r.db.Exec(ctx, `
+		INSERT INTO mindmap_members (mindmap_id, user_id, role, invited_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (mindmap_id, user_id) DO NOTHING`,
+		member.MindMapID, member.UserID, member.Role, member.InvitedBy, now,
+	)
+	if err != nil {
+		return fmt.Errorf("add mindmap member: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMemberExists
+	}
+
+	member.CreatedAt = now
+	member.UpdatedAt = now
+	return nil
+}
+
+// UpdateRole меняет роль участника. Роль владельца так не меняется - для этого есть TransferOwnership.
+func (r *MindMapMemberRepository) UpdateRole(ctx context.Context, mindMapID, userID int, role string) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE mindmap_members SET role = $1, updated_at = $2
+		WHERE mindmap_id = $3 AND user_id = $4 AND role <> 'owner'`,
+		role, time.Now(), mindMapID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("update mindmap member: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// Remove удаляет участника. Владельца удалить нельзя.
+func (r *MindMapMemberRepository) Remove(ctx context.Context, mindMapID, userID int) error {
+	result, err := r.db.Exec(ctx,
+		`DELETE FROM mindmap_members WHERE mindmap_id = $1 AND user_id = $2 AND role <> 'owner'`,
+		mindMapID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("remove mindmap member: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// TransferOwnership передает карту пользователю toUserID.
+// Прежний владелец остается участником с ролью редактора. Папки принадлежат
+// владельцу, поэтому карта переносится из папки в корень.
+func (r *MindMapMemberRepository) TransferOwnership(ctx context.Context, mindMapID, fromUserID, toUserID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("transfer mindmap: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	result, err := tx.Exec(ctx,
+		`UPDATE mindmaps SET user_id = $1, folder_id = NULL WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL`,
+		toUserID, mindMapID, fromUserID,
+	)
+	if err != nil {
+		return fmt.Errorf("transfer mindmap: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("mindmap not found or access denied")
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO mindmap_members (mindmap_id, user_id, role, invited_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (mindmap_id, user_id) DO UPDATE SET role = EXCLUDED.role, updated_at = EXCLUDED.updated_at`,
+		mindMapID, fromUserID, models.MindMapRoleEditor, nil, now,
+	)
+	if err != nil {
+		return fmt.Errorf("transfer mindmap: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO mindmap_members (mindmap_id, user_id, role, invited_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (mindmap_id, user_id) DO UPDATE SET role = EXCLUDED.role, updated_at = EXCLUDED.updated_at`,
+		mindMapID, toUserID, models.MindMapRoleOwner, fromUserID, now,
+	)
+	if err != nil {
+		return fmt.Errorf("transfer mindmap: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// addOwner записывает создателя карты ее владельцем
+func addOwner(ctx context.Context, q querier, mindMap *models.MindMap) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO mindmap_members (mindmap_id, user_id, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (mindmap_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		mindMap.ID, mindMap.UserID, models.MindMapRoleOwner, mindMap.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("add mindmap owner: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/models"
+)
+
+// opsLogKeepFor - сколько хранится журнал операций. Клиенту, отставшему
+// сильнее, нужно заново загрузить карту целиком.
+const opsLogKeepFor = 7 * 24 * time.Hour
+
+type MindMapOpsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMindMapOpsRepository(db *pgxpool.Pool) *MindMapOpsRepository {
+	return &MindMapOpsRepository{db: db}
+}
+
+// ListSince возвращает пакеты операций, примененные после версии since, по порядку
+func (r *MindMapOpsRepository) ListSince(ctx context.Context, mindMapID, since, limit int) ([]*models.MindMapOpsBatch, error) {
+	query := `
+		SELECT id, mindmap_id, base_version, version, author_id, ops, created_at
+		FROM mindmap_ops
+		WHERE mindmap_id = $1 AND version > $2
+		ORDER BY version
+		LIMIT $3`
+
+	rows, err := r.db.Query(ctx, query, mindMapID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list mindmap ops: %w", err)
+	}
+	defer rows.Close()
+
+	batches := []*models.MindMapOpsBatch{}
+	for rows.Next() {
+		batch := new(models.MindMapOpsBatch)
+		if err := rows.Scan(
+			&batch.ID,
+			&batch.MindMapID,
+			&batch.BaseVersion,
+			&batch.Version,
+			&batch.AuthorID,
+			&batch.Ops,
+			&batch.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan mindmap ops: %w", err)
+		}
+		batches = append(batches, batch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return batches, nil
+}
+
+// insertOpsBatch записывает пакет в журнал и удаляет устаревшие записи журнала
+func insertOpsBatch(ctx context.Context, q querier, batch *models.MindMapOpsBatch) error {
+	err := q.QueryRow(ctx, `
+		INSERT INTO mindmap_ops (mindmap_id, base_version, version, author_id, ops, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		batch.MindMapID, batch.BaseVersion, batch.Version, batch.AuthorID, batch.Ops, batch.CreatedAt,
+	).Scan(&batch.ID)
+	if err != nil {
+		return fmt.Errorf("insert mindmap ops: %w", err)
+	}
+
+	_, err = q.Exec(ctx,
+		`DELETE FROM mindmap_ops WHERE mindmap_id = $1 AND created_at < $2`,
+		batch.MindMapID, time.Now().Add(-opsLogKeepFor),
+	)
+	if err != nil {
+		return fmt.Errorf("prune mindmap ops: %w", err)
+	}
+	return nil
+}
@@ -2,34 +2,65 @@ package repository
 
 import (
 	"context"
-	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/mymindmap/api/internal/attachments"
+	"github.com/mymindmap/api/internal/maplinks"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/internal/tasks"
 	"github.com/mymindmap/api/models"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type MindMapRepository struct {
-	db *pgxpool.Pool
+	db        *pgxpool.Pool
+	revisions RevisionPolicy
 }
 
 func NewMindMapRepository(db *pgxpool.Pool) *MindMapRepository {
-	return &MindMapRepository{db: db}
+	return &MindMapRepository{db: db, revisions: DefaultRevisionPolicy}
 }
 
 func (r *MindMapRepository) Create(ctx context.Context, mindMap *models.MindMap) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating mindmap: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.create(ctx, tx, mindMap); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// create создает карту в транзакции вместе с владельцем и первой ревизией
+func (r *MindMapRepository) create(ctx context.Context, tx pgx.Tx, mindMap *models.MindMap) error {
 	query := `
-		INSERT INTO mindmaps (title, data, user_id, is_public, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO mindmaps (title, data, user_id, is_public, search_text, node_count, forked_from_id, forked_from_revision, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id`
 
 	now := time.Now()
-	err := r.db.QueryRow(ctx, query,
+	index := indexDocument(mindMap.Data)
+	err := tx.QueryRow(ctx, query,
 		mindMap.Title,
 		mindMap.Data,
 		mindMap.UserID,
 		mindMap.IsPublic,
+		index.searchText,
+		index.nodeCount,
+		mindMap.ForkedFromID,
+		mindMap.ForkedFromRevision,
 		now,
 		now,
 	).Scan(&mindMap.ID)
@@ -38,16 +69,38 @@ func (r *MindMapRepository) Create(ctx context.Context, mindMap *models.MindMap)
 		return fmt.Errorf("error creating mindmap: %w", err)
 	}
 
+	mindMap.Version = 1
 	mindMap.CreatedAt = now
 	mindMap.UpdatedAt = now
-	return nil
+
+	if err := addOwner(ctx, tx, mindMap); err != nil {
+		return err
+	}
+	if err := linkAttachments(ctx, tx, mindMap.ID, mindMap.UserID, index.attachments); err != nil {
+		return err
+	}
+	if len(index.tasks) > 0 {
+		if err := syncTasks(ctx, tx, mindMap.ID, index.tasks); err != nil {
+			return err
+		}
+	}
+	if len(index.links) > 0 {
+		if err := syncNodeLinks(ctx, tx, mindMap.ID, index.links); err != nil {
+			return err
+		}
+	}
+	return saveRevision(ctx, tx, mindMap, mindMap.UserID, r.revisions)
 }
 
+// forkCount - число копий карты m
+const forkCount = `(SELECT COUNT(*) FROM mindmaps f WHERE f.forked_from_id = m.id AND f.deleted_at IS NULL)`
+
 func (r *MindMapRepository) GetByID(ctx context.Context, id int) (*models.MindMap, error) {
 	query := `
-		SELECT id, title, data, user_id, is_public, created_at, updated_at
-		FROM mindmaps
-		WHERE id = $1`
+		SELECT id, title, data, user_id, is_public, version, folder_id, forked_from_id, forked_from_revision,
+			` + forkCount + `, created_at, updated_at
+		FROM mindmaps m
+		WHERE id = $1 AND deleted_at IS NULL`
 
 	mindMap := &models.MindMap{}
 	err := r.db.QueryRow(ctx, query, id).Scan(
@@ -56,12 +109,17 @@ func (r *MindMapRepository) GetByID(ctx context.Context, id int) (*models.MindMa
 		&mindMap.Data,
 		&mindMap.UserID,
 		&mindMap.IsPublic,
+		&mindMap.Version,
+		&mindMap.FolderID,
+		&mindMap.ForkedFromID,
+		&mindMap.ForkedFromRevision,
+		&mindMap.ForkCount,
 		&mindMap.CreatedAt,
 		&mindMap.UpdatedAt,
 	)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("error getting mindmap: %w", err)
@@ -72,9 +130,9 @@ func (r *MindMapRepository) GetByID(ctx context.Context, id int) (*models.MindMa
 
 func (r *MindMapRepository) GetByUserID(ctx context.Context, userID int) ([]*models.MindMap, error) {
 	query := `
-		SELECT id, title, data, user_id, is_public, created_at, updated_at
+		SELECT id, title, data, user_id, is_public, version, created_at, updated_at
 		FROM mindmaps
-		WHERE user_id = $1
+		WHERE user_id = $1 AND deleted_at IS NULL
 		ORDER BY updated_at DESC
 	`
 
@@ -94,6 +152,7 @@ func (r *MindMapRepository) GetByUserID(ctx context.Context, userID int) ([]*mod
 			&mindMap.Data,
 			&mindMap.UserID,
 			&mindMap.IsPublic,
+			&mindMap.Version,
 			&mindMap.CreatedAt,
 			&mindMap.UpdatedAt,
 		); err != nil {
@@ -110,12 +169,209 @@ func (r *MindMapRepository) GetByUserID(ctx context.Context, userID int) ([]*mod
 	return mindMaps, nil
 }
 
+// Сортировка списка карт
+const (
+	ListSortUpdatedAt = "updated_at"
+	ListSortCreatedAt = "created_at"
+	ListSortTitle     = "title"
+	ListSortOpenedAt  = "opened_at" // Только открытые пользователем карты
+)
+
+// Фильтр видимости в списке карт
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// ListOptions - параметры списка карт
+type ListOptions struct {
+	Sort         string      // ListSort*, по умолчанию updated_at
+	Asc          bool        // По возрастанию; по умолчанию по убыванию
+	Visibility   string      // Visibility* или пустая строка - все
+	UpdatedSince *time.Time  // Только карты, измененные не раньше
+	FolderID     *int        // Только карты в папке (без вложенных)
+	RootFolder   bool        // Только свои карты вне папок
+	TagID        *int        // Только карты с тегом пользователя
+	Starred      bool        // Только избранные
+	After        *ListCursor // Продолжение после последней карты предыдущей страницы
+	Limit        int
+}
+
+// ListCursor - позиция в списке: значение поля сортировки и id последней карты
+type ListCursor struct {
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// ErrInvalidCursor - курсор поврежден или от другой сортировки
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor упаковывает курсор в непрозрачную для клиента строку
+func EncodeCursor(c ListCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor распаковывает курсор из EncodeCursor
+func DecodeCursor(s string) (*ListCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c ListCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// ListAccessible возвращает страницу карт пользователя и карт, к которым ему дали
+// доступ, без документа. Пагинация по ключу (поле сортировки, id): вставка и удаление
+// карт между запросами не сдвигают страницы. Возвращает курсор следующей страницы
+// или nil, если страница последняя.
+func (r *MindMapRepository) ListAccessible(ctx context.Context, userID int, opts ListOptions) ([]*models.MindMapSummary, *ListCursor, error) {
+	sort := opts.Sort
+	if sort == "" {
+		sort = ListSortUpdatedAt
+	}
+	column := map[string]string{
+		ListSortUpdatedAt: "m.updated_at",
+		ListSortCreatedAt: "m.created_at",
+		ListSortTitle:     "m.title",
+		ListSortOpenedAt:  "mo.opened_at",
+	}[sort]
+	if column == "" {
+		return nil, nil, fmt.Errorf("unknown sort %q", sort)
+	}
+	dir, cmp := "DESC", "<"
+	if opts.Asc {
+		dir, cmp = "ASC", ">"
+	}
+
+	args := []any{userID}
+	where := []string{"(m.user_id = $1 OR mm.user_id IS NOT NULL)", "m.deleted_at IS NULL"}
+	switch opts.Visibility {
+	case "":
+	case VisibilityPublic:
+		where = append(where, "m.is_public = true")
+	case VisibilityPrivate:
+		where = append(where, "m.is_public = false")
+	default:
+		return nil, nil, fmt.Errorf("unknown visibility %q", opts.Visibility)
+	}
+	if opts.FolderID != nil {
+		args = append(args, *opts.FolderID)
+		where = append(where, fmt.Sprintf("m.folder_id = $%d", len(args)))
+	}
+	if opts.RootFolder {
+		where = append(where, "m.user_id = $1 AND m.folder_id IS NULL")
+	}
+	if opts.TagID != nil {
+		args = append(args, *opts.TagID)
+		where = append(where, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM mindmap_tags mt JOIN tags t ON t.id = mt.tag_id
+			WHERE mt.mindmap_id = m.id AND t.id = $%d AND t.user_id = $1)`, len(args)))
+	}
+	if opts.Starred {
+		where = append(where, "ms.user_id IS NOT NULL")
+	}
+	if sort == ListSortOpenedAt {
+		where = append(where, "mo.opened_at IS NOT NULL")
+	}
+	if opts.UpdatedSince != nil {
+		args = append(args, *opts.UpdatedSince)
+		where = append(where, fmt.Sprintf("m.updated_at >= $%d", len(args)))
+	}
+	if opts.After != nil {
+		var value any = opts.After.Value
+		if sort != ListSortTitle {
+			t, err := time.Parse(time.RFC3339Nano, opts.After.Value)
+			if err != nil {
+				return nil, nil, ErrInvalidCursor
+			}
+			value = t
+		}
+		args = append(args, value, opts.After.ID)
+		where = append(where, fmt.Sprintf("(%s, m.id) %s ($%d, $%d)", column, cmp, len(args)-1, len(args)))
+	}
+	// Берем на одну карту больше, чтобы узнать, есть ли следующая страница
+	args = append(args, opts.Limit+1)
+
+	query := `
+		SELECT m.id, m.title, m.user_id, m.is_public,
+			CASE WHEN m.user_id = $1 THEN 'owner' ELSE mm.role END,
+			m.folder_id, m.node_count, ` + forkCount + `, m.thumbnail,
+			ms.user_id IS NOT NULL, mo.opened_at,
+			ARRAY(SELECT t.name FROM mindmap_tags mt JOIN tags t ON t.id = mt.tag_id
+			      WHERE mt.mindmap_id = m.id AND t.user_id = $1 ORDER BY lower(t.name)),
+			m.version, m.created_at, m.updated_at
+		FROM mindmaps m
+		LEFT JOIN mindmap_members mm ON mm.mindmap_id = m.id AND mm.user_id = $1
+		LEFT JOIN mindmap_stars ms ON ms.mindmap_id = m.id AND ms.user_id = $1
+		LEFT JOIN mindmap_opens mo ON mo.mindmap_id = m.id AND mo.user_id = $1
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY ` + column + ` ` + dir + `, m.id ` + dir + `
+		LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list mindmaps: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*models.MindMapSummary{}
+	for rows.Next() {
+		item := new(models.MindMapSummary)
+		if err := rows.Scan(
+			&item.ID,
+			&item.Title,
+			&item.UserID,
+			&item.IsPublic,
+			&item.Role,
+			&item.FolderID,
+			&item.NodeCount,
+			&item.ForkCount,
+			&item.Thumbnail,
+			&item.Starred,
+			&item.OpenedAt,
+			&item.Tags,
+			&item.Version,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		); err != nil {
+			return nil, nil, fmt.Errorf("scan mindmap: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if len(items) <= opts.Limit {
+		return items, nil, nil
+	}
+	items = items[:opts.Limit]
+	last := items[len(items)-1]
+	next := &ListCursor{ID: last.ID}
+	switch sort {
+	case ListSortUpdatedAt:
+		next.Value = last.UpdatedAt.Format(time.RFC3339Nano)
+	case ListSortCreatedAt:
+		next.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	case ListSortTitle:
+		next.Value = last.Title
+	case ListSortOpenedAt:
+		next.Value = last.OpenedAt.Format(time.RFC3339Nano)
+	}
+	return items, next, nil
+}
 
 func (r *MindMapRepository) GetPublic(ctx context.Context) ([]*models.MindMap, error) {
 	query := `
-		SELECT id, title, data, user_id, is_public, created_at, updated_at
+		SELECT id, title, data, user_id, is_public, version, created_at, updated_at
 		FROM mindmaps
-		WHERE is_public = true
+		WHERE is_public = true AND deleted_at IS NULL
 		ORDER BY updated_at DESC`
 
 	rows, err := r.db.Query(ctx, query)
@@ -133,6 +389,7 @@ func (r *MindMapRepository) GetPublic(ctx context.Context) ([]*models.MindMap, e
 			&mindMap.Data,
 			&mindMap.UserID,
 			&mindMap.IsPublic,
+			&mindMap.Version,
 			&mindMap.CreatedAt,
 			&mindMap.UpdatedAt,
 		)
@@ -145,36 +402,278 @@ func (r *MindMapRepository) GetPublic(ctx context.Context) ([]*models.MindMap, e
 	return mindMaps, nil
 }
 
-func (r *MindMapRepository) Update(ctx context.Context, mindMap *models.MindMap) error {
+// publicOrder - порядок галереи для каждой сортировки
+var publicOrder = map[string]string{
+	models.PublicSortRecent:  "m.updated_at DESC, m.id DESC",
+	models.PublicSortPopular: "m.view_count DESC, m.updated_at DESC, m.id DESC",
+}
+
+// GetPublicPage возвращает страницу публичных карт без данных документа
+// и общее количество публичных карт
+func (r *MindMapRepository) GetPublicPage(ctx context.Context, sort string, limit, offset int) ([]*models.PublicMindMap, int, error) {
+	order, ok := publicOrder[sort]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown sort %q", sort)
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM mindmaps WHERE is_public = true AND deleted_at IS NULL`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count public mindmaps: %w", err)
+	}
+
+	query := `
+		SELECT m.id, m.title, m.user_id, u.name, m.view_count, ` + forkCount + `, m.version, m.created_at, m.updated_at
+		FROM mindmaps m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.is_public = true AND m.deleted_at IS NULL
+		ORDER BY ` + order + `
+		LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get public mindmaps: %w", err)
+	}
+	defer rows.Close()
+
+	mindMaps := []*models.PublicMindMap{}
+	for rows.Next() {
+		mindMap := new(models.PublicMindMap)
+		if err := rows.Scan(
+			&mindMap.ID,
+			&mindMap.Title,
+			&mindMap.Author.ID,
+			&mindMap.Author.Name,
+			&mindMap.ViewCount,
+			&mindMap.ForkCount,
+			&mindMap.Version,
+			&mindMap.CreatedAt,
+			&mindMap.UpdatedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("scan public mindmap: %w", err)
+		}
+		mindMaps = append(mindMaps, mindMap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return mindMaps, total, nil
+}
+
+// GetPublicByID возвращает публичную карту с документом. Для непубличной
+// или несуществующей карты возвращает nil.
+func (r *MindMapRepository) GetPublicByID(ctx context.Context, id int) (*models.PublicMindMap, error) {
+	query := `
+		SELECT m.id, m.title, m.data, m.user_id, u.name, m.view_count, ` + forkCount + `, m.version, m.created_at, m.updated_at
+		FROM mindmaps m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.id = $1 AND m.is_public = true AND m.deleted_at IS NULL`
+
+	mindMap := new(models.PublicMindMap)
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&mindMap.ID,
+		&mindMap.Title,
+		&mindMap.Data,
+		&mindMap.Author.ID,
+		&mindMap.Author.Name,
+		&mindMap.ViewCount,
+		&mindMap.ForkCount,
+		&mindMap.Version,
+		&mindMap.CreatedAt,
+		&mindMap.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting public mindmap: %w", err)
+	}
+
+	return mindMap, nil
+}
+
+// IncrementViews увеличивает счетчик просмотров публичной карты.
+// Версия и дата изменения карты не меняются.
+func (r *MindMapRepository) IncrementViews(ctx context.Context, id int) error {
+	_, err := r.db.Exec(ctx, `UPDATE mindmaps SET view_count = view_count + 1 WHERE id = $1 AND is_public = true AND deleted_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("increment mindmap views: %w", err)
+	}
+	return nil
+}
+
+// Update сохраняет карту и записывает новую ревизию в ее историю.
+// authorID - пользователь, выполнивший изменение. mindMap.Version должна
+// совпадать с версией в БД, иначе возвращается ErrVersionConflict;
+// после сохранения в mindMap.Version записывается новая версия.
+func (r *MindMapRepository) Update(ctx context.Context, mindMap *models.MindMap, authorID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error updating mindmap: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.update(ctx, tx, mindMap, authorID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UpdateWithOps сохраняет карту, измененную пакетом операций, и записывает
+// пакет в журнал операций карты в той же транзакции
+func (r *MindMapRepository) UpdateWithOps(ctx context.Context, mindMap *models.MindMap, authorID int, ops json.RawMessage) (*models.MindMapOpsBatch, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error updating mindmap: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	baseVersion := mindMap.Version
+	if err := r.update(ctx, tx, mindMap, authorID); err != nil {
+		return nil, err
+	}
+
+	batch := &models.MindMapOpsBatch{
+		MindMapID:   mindMap.ID,
+		BaseVersion: baseVersion,
+		Version:     mindMap.Version,
+		Ops:         ops,
+		CreatedAt:   mindMap.UpdatedAt,
+	}
+	if authorID > 0 {
+		batch.AuthorID = &authorID
+	}
+	if err := insertOpsBatch(ctx, tx, batch); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error updating mindmap: %w", err)
+	}
+	return batch, nil
+}
+
+// UpdateMany сохраняет несколько карт в одной транзакции: сохраняются все или
+// ни одна. Версии проверяются, как в Update. Карты блокируются по возрастанию id,
+// чтобы встречные переносы между одними и теми же картами не ждали друг друга.
+func (r *MindMapRepository) UpdateMany(ctx context.Context, mindMaps []*models.MindMap, authorID int) error {
+	sorted := append([]*models.MindMap(nil), mindMaps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error updating mindmaps: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, mindMap := range sorted {
+		if err := r.update(ctx, tx, mindMap, authorID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ExtractSubtree создает карту branch и сохраняет source в одной транзакции.
+// Узлу stubUID в документе source записывается ссылка на созданную карту.
+func (r *MindMapRepository) ExtractSubtree(ctx context.Context, source, branch *models.MindMap, stubUID string, authorID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error extracting subtree: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.create(ctx, tx, branch); err != nil {
+		return err
+	}
+
+	doc, err := mindmap.ParseString(source.Data, mindmap.Limits{})
+	if err != nil {
+		return fmt.Errorf("error extracting subtree: %w", err)
+	}
+	stub, _ := doc.Find(stubUID)
+	if stub == nil {
+		return fmt.Errorf("error extracting subtree: node %q not found", stubUID)
+	}
+	maplinks.Set(&stub.Data, maplinks.Link{MindMapID: branch.ID})
+	if source.Data, err = doc.Encode(); err != nil {
+		return err
+	}
+
+	if err := r.update(ctx, tx, source, authorID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// update сохраняет карту в транзакции. Права на изменение проверяет вызывающий:
+// карту могут менять не только владелец, но и редакторы.
+func (r *MindMapRepository) update(ctx context.Context, tx pgx.Tx, mindMap *models.MindMap, authorID int) error {
+	var current int
+	err := tx.QueryRow(ctx,
+		`SELECT version FROM mindmaps WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		mindMap.ID,
+	).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrMindMapNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error updating mindmap: %w", err)
+	}
+	if current != mindMap.Version {
+		return ErrVersionConflict
+	}
+
 	query := `
 		UPDATE mindmaps
-		SET title = $1, data = $2, is_public = $3, updated_at = $4
-		WHERE id = $5 AND user_id = $6`
+		SET title = $1, data = $2, is_public = $3, search_text = $4, node_count = $5, updated_at = $6, version = version + 1
+		WHERE id = $7
+		RETURNING version`
 
-	result, err := r.db.Exec(ctx, query,
+	now := time.Now()
+	index := indexDocument(mindMap.Data)
+	err = tx.QueryRow(ctx, query,
 		mindMap.Title,
 		mindMap.Data,
 		mindMap.IsPublic,
-		time.Now(),
+		index.searchText,
+		index.nodeCount,
+		now,
 		mindMap.ID,
-		mindMap.UserID,
-	)
+	).Scan(&mindMap.Version)
 
 	if err != nil {
 		return fmt.Errorf("error updating mindmap: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("mindmap not found or access denied")
+	mindMap.UpdatedAt = now
+	if err := linkAttachments(ctx, tx, mindMap.ID, authorID, index.attachments); err != nil {
+		return err
 	}
-
-	return nil
+	// Неразобранный документ не должен отрывать обсуждения от всех узлов и удалять задачи и ссылки
+	if index.nodeCount > 0 {
+		if err := syncCommentAnchors(ctx, tx, mindMap.ID, index.uids); err != nil {
+			return err
+		}
+		if err := syncTasks(ctx, tx, mindMap.ID, index.tasks); err != nil {
+			return err
+		}
+		if err := syncNodeLinks(ctx, tx, mindMap.ID, index.links); err != nil {
+			return err
+		}
+	}
+	return saveRevision(ctx, tx, mindMap, authorID, r.revisions)
 }
 
+// Delete переносит карту владельца в корзину
 func (r *MindMapRepository) Delete(ctx context.Context, id, userID int) error {
-	query := `DELETE FROM mindmaps WHERE id = $1 AND user_id = $2`
+	query := `UPDATE mindmaps SET deleted_at = $3 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
 
-	result, err := r.db.Exec(ctx, query, id, userID)
+	result, err := r.db.Exec(ctx, query, id, userID, time.Now())
 	if err != nil {
 		return fmt.Errorf("error deleting mindmap: %w", err)
 	}
@@ -186,10 +685,11 @@ func (r *MindMapRepository) Delete(ctx context.Context, id, userID int) error {
 	return nil
 }
 
+// DeleteByAdmin переносит любую карту в корзину
 func (r *MindMapRepository) DeleteByAdmin(ctx context.Context, id int) error {
-	query := `DELETE FROM mindmaps WHERE id = $1`
+	query := `UPDATE mindmaps SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
 
-	result, err := r.db.Exec(ctx, query, id)
+	result, err := r.db.Exec(ctx, query, id, time.Now())
 	if err != nil {
 		return fmt.Errorf("error deleting mindmap: %w", err)
 	}
@@ -221,11 +721,124 @@ func (r *MindMapRepository) CreateMindMap(ctx context.Context, m *models.MindMap
 
 // UpdateMindMap updates an existing mindmap.
 func (r *MindMapRepository) UpdateMindMap(ctx context.Context, m *models.MindMap) error {
-    return r.Update(ctx, m)
+    return r.Update(ctx, m, m.UserID)
 }
 
 // DeleteMindMap deletes a mindmap by ID (and optionally checks user elsewhere).
 func (r *MindMapRepository) DeleteMindMap(ctx context.Context, id int) error {
     // For generic deletion without user constraint, reuse admin delete
     return r.DeleteByAdmin(ctx, id)
-}
\ No newline at end of file
+}
+
+// documentIndex - поля карты, которые вычисляются из документа при сохранении
+type documentIndex struct {
+	searchText  string
+	nodeCount   int
+	attachments []string // Хэши файлов, на которые ссылается документ
+	uids        []string // uid всех узлов, для привязки обсуждений
+	tasks       []tasks.Task
+	links       []maplinks.Ref // Ссылки на узлы других карт
+}
+
+// indexDocument разбирает документ для поискового индекса, списка карт, связей с файлами,
+// обсуждений, задач и ссылок между картами. Данные, которые не удалось разобрать, не индексируются: карта все равно
+// находится по названию.
+func indexDocument(data string) documentIndex {
+	doc, err := mindmap.ParseString(data, mindmap.Limits{})
+	if err != nil {
+		return documentIndex{}
+	}
+	index := documentIndex{searchText: doc.SearchText(), attachments: attachments.Refs(doc), tasks: tasks.Extract(doc), links: maplinks.Refs(doc)}
+	doc.Walk(func(n, _ *mindmap.Node, _ int) bool {
+		index.uids = append(index.uids, n.Data.UID)
+		return true
+	})
+	index.nodeCount = len(index.uids)
+	return index
+}
+
+// ListTrashed возвращает карты пользователя в корзине, недавно удаленные первыми.
+// PurgeAt заполняет вызывающий.
+func (r *MindMapRepository) ListTrashed(ctx context.Context, userID int) ([]*models.TrashItem, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, title, user_id, deleted_at
+		FROM mindmaps
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list trashed mindmaps: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*models.TrashItem{}
+	for rows.Next() {
+		item := &models.TrashItem{Type: models.TrashTypeMindMap}
+		if err := rows.Scan(&item.ID, &item.Title, &item.UserID, &item.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan trashed mindmap: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetTrashed возвращает карту из корзины. nil - карты нет или она не в корзине.
+func (r *MindMapRepository) GetTrashed(ctx context.Context, id int) (*models.TrashItem, error) {
+	item := &models.TrashItem{Type: models.TrashTypeMindMap}
+	err := r.db.QueryRow(ctx, `
+		SELECT id, title, user_id, deleted_at
+		FROM mindmaps
+		WHERE id = $1 AND deleted_at IS NOT NULL`,
+		id,
+	).Scan(&item.ID, &item.Title, &item.UserID, &item.DeletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get trashed mindmap: %w", err)
+	}
+	return item, nil
+}
+
+// Restore возвращает карту из корзины. Если ее папку за это время удалили, карта
+// окажется в корне.
+func (r *MindMapRepository) Restore(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx,
+		`UPDATE mindmaps SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("restore mindmap: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotInTrash
+	}
+	return nil
+}
+
+// Purge окончательно удаляет карту из корзины вместе с историей и доступами
+func (r *MindMapRepository) Purge(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM mindmaps WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return fmt.Errorf("purge mindmap: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotInTrash
+	}
+	return nil
+}
+
+// PurgeTrashed окончательно удаляет карты, попавшие в корзину раньше before
+func (r *MindMapRepository) PurgeTrashed(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM mindmaps WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge trashed mindmaps: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
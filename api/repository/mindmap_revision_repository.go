@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+const (
+	revisionKindFull  = "full"
+	revisionKindDelta = "delta"
+)
+
+// RevisionPolicy - правила хранения истории карт
+type RevisionPolicy struct {
+	KeepLast      int           // Сколько последних ревизий хранится всегда
+	KeepFor       time.Duration // Ревизии моложе этого срока не удаляются
+	KeyframeEvery int           // Каждая N-я ревизия хранится целиком, остальные - разницей
+}
+
+// DefaultRevisionPolicy - политика хранения по умолчанию
+var DefaultRevisionPolicy = RevisionPolicy{
+	KeepLast:      50,
+	KeepFor:       30 * 24 * time.Hour,
+	KeyframeEvery: 20,
+}
+
+// querier - общее подмножество pgxpool.Pool и pgx.Tx
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type MindMapRevisionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMindMapRevisionRepository(db *pgxpool.Pool) *MindMapRevisionRepository {
+	return &MindMapRevisionRepository{db: db}
+}
+
+// List возвращает ревизии карты без данных, новые первыми
+func (r *MindMapRevisionRepository) List(ctx context.Context, mindMapID int) ([]*models.MindMapRevision, error) {
+	query := `
+		SELECT id, mindmap_id, revision, title, size, author_id, created_at
+		FROM mindmap_revisions
+		WHERE mindmap_id = $1
+		ORDER BY revision DESC`
+
+	rows, err := r.db.Query(ctx, query, mindMapID)
+	if err != nil {
+		return nil, fmt.Errorf("list mindmap revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*models.MindMapRevision{}
+	for rows.Next() {
+		rev := new(models.MindMapRevision)
+		if err := rows.Scan(
+			&rev.ID,
+			&rev.MindMapID,
+			&rev.Revision,
+			&rev.Title,
+			&rev.Size,
+			&rev.AuthorID,
+			&rev.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan mindmap revision: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// Get возвращает ревизию с восстановленным документом или nil, если ее нет
+func (r *MindMapRevisionRepository) Get(ctx context.Context, mindMapID, revision int) (*models.MindMapRevision, error) {
+	return getRevision(ctx, r.db, mindMapID, revision)
+}
+
+func getRevision(ctx context.Context, q querier, mindMapID, revision int) (*models.MindMapRevision, error) {
+	query := `
+		SELECT id, mindmap_id, revision, title, kind, base_revision, data, size, author_id, created_at
+		FROM mindmap_revisions
+		WHERE mindmap_id = $1 AND revision = $2`
+
+	rev := &models.MindMapRevision{}
+	var kind string
+	var baseRevision *int
+	err := q.QueryRow(ctx, query, mindMapID, revision).Scan(
+		&rev.ID,
+		&rev.MindMapID,
+		&rev.Revision,
+		&rev.Title,
+		&kind,
+		&baseRevision,
+		&rev.Data,
+		&rev.Size,
+		&rev.AuthorID,
+		&rev.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get mindmap revision: %w", err)
+	}
+
+	if kind == revisionKindFull {
+		return rev, nil
+	}
+
+	var baseData string
+	err = q.QueryRow(ctx,
+		`SELECT data FROM mindmap_revisions WHERE mindmap_id = $1 AND revision = $2 AND kind = 'full'`,
+		mindMapID, *baseRevision,
+	).Scan(&baseData)
+	if err != nil {
+		return nil, fmt.Errorf("get base of mindmap revision %d: %w", revision, err)
+	}
+
+	base, err := mindmap.ParseString(baseData, mindmap.Limits{})
+	if err != nil {
+		return nil, fmt.Errorf("parse base of mindmap revision %d: %w", revision, err)
+	}
+	var delta mindmap.Delta
+	if err := json.Unmarshal([]byte(rev.Data), &delta); err != nil {
+		return nil, fmt.Errorf("decode mindmap revision %d: %w", revision, err)
+	}
+	doc, err := mindmap.ApplyDelta(base, &delta)
+	if err != nil {
+		return nil, fmt.Errorf("restore mindmap revision %d: %w", revision, err)
+	}
+	if rev.Data, err = doc.Encode(); err != nil {
+		return nil, err
+	}
+
+	return rev, nil
+}
+
+// saveRevision записывает текущее состояние карты новой ревизией и удаляет
+// ревизии, вышедшие за пределы политики хранения. Вызывается в той же
+// транзакции, что и изменение карты.
+func saveRevision(ctx context.Context, q querier, m *models.MindMap, authorID int, policy RevisionPolicy) error {
+	var next int
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(MAX(revision), 0) + 1 FROM mindmap_revisions WHERE mindmap_id = $1`,
+		m.ID,
+	).Scan(&next)
+	if err != nil {
+		return fmt.Errorf("next mindmap revision: %w", err)
+	}
+
+	kind, baseRevision, data, err := revisionPayload(ctx, q, m, next, policy)
+	if err != nil {
+		return err
+	}
+
+	var author *int
+	if authorID > 0 {
+		author = &authorID
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO mindmap_revisions (mindmap_id, revision, title, kind, base_revision, data, size, author_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		m.ID, next, m.Title, kind, baseRevision, data, len(m.Data), author, m.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert mindmap revision: %w", err)
+	}
+
+	return pruneRevisions(ctx, q, m.ID, next, policy)
+}
+
+// revisionPayload решает, хранить ревизию целиком или разницей с последней полной ревизией
+func revisionPayload(ctx context.Context, q querier, m *models.MindMap, next int, policy RevisionPolicy) (string, *int, string, error) {
+	var baseRevision int
+	var baseData string
+	err := q.QueryRow(ctx, `
+		SELECT revision, data FROM mindmap_revisions
+		WHERE mindmap_id = $1 AND kind = 'full'
+		ORDER BY revision DESC
+		LIMIT 1`,
+		m.ID,
+	).Scan(&baseRevision, &baseData)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return revisionKindFull, nil, m.Data, nil
+	}
+	if err != nil {
+		return "", nil, "", fmt.Errorf("get base mindmap revision: %w", err)
+	}
+	if policy.KeyframeEvery <= 1 || next-baseRevision >= policy.KeyframeEvery {
+		return revisionKindFull, nil, m.Data, nil
+	}
+
+	// Разница возможна только между документами с устойчивыми uid,
+	// иначе восстановленный документ не совпадет с сохраненным
+	base, err := mindmap.ParseString(baseData, mindmap.Limits{})
+	if err != nil || mindmap.EnsureUIDs(base) > 0 {
+		return revisionKindFull, nil, m.Data, nil
+	}
+	target, err := mindmap.ParseString(m.Data, mindmap.Limits{})
+	if err != nil || mindmap.EnsureUIDs(target) > 0 {
+		return revisionKindFull, nil, m.Data, nil
+	}
+
+	delta, err := json.Marshal(mindmap.ComputeDelta(base, target))
+	if err != nil || len(delta) > len(m.Data)/2 {
+		return revisionKindFull, nil, m.Data, nil
+	}
+	return revisionKindDelta, &baseRevision, string(delta), nil
+}
+
+// pruneRevisions удаляет старые ревизии, не трогая полные ревизии,
+// на которые ссылаются оставшиеся разницы
+func pruneRevisions(ctx context.Context, q querier, mindMapID, latest int, policy RevisionPolicy) error {
+	if policy.KeepLast <= 0 {
+		return nil
+	}
+	_, err := q.Exec(ctx, `
+		DELETE FROM mindmap_revisions
+		WHERE mindmap_id = $1
+		  AND revision <= $2
+		  AND created_at < $3
+		  AND revision NOT IN (
+			SELECT base_revision FROM mindmap_revisions
+			WHERE mindmap_id = $1 AND base_revision IS NOT NULL
+			  AND (revision > $2 OR created_at >= $3)
+		  )`,
+		mindMapID, latest-policy.KeepLast, time.Now().Add(-policy.KeepFor),
+	)
+	if err != nil {
+		return fmt.Errorf("prune mindmap revisions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+// Маркеры совпадений в ts_headline. Текст сниппета экранируется уже после
+// выделения, поэтому маркеры - управляющие символы, а не теги.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+// searchQuery - запрос в обеих конфигурациях: совпадение в любой из них
+const searchQuery = `(websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1))`
+
+type MindMapSearchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMindMapSearchRepository(db *pgxpool.Pool) *MindMapSearchRepository {
+	return &MindMapSearchRepository{db: db}
+}
+
+// Search ищет карты, доступные пользователю: свои и те, где он участник.
+// all - искать по всем картам (для администратора).
+func (r *MindMapSearchRepository) Search(ctx context.Context, userID int, all bool, query string, limit, offset int) ([]*models.SearchResult, error) {
+	sql := `
+		SELECT m.id, m.title, m.data,
+			CASE WHEN m.user_id = $2 OR $3 THEN 'owner' ELSE mm.role END,
+			ts_rank(m.search_vector, q.query) AS rank,
+			ts_headline('russian', m.search_text, q.query, $4),
+			m.updated_at
+		FROM mindmaps m
+		CROSS JOIN (SELECT ` + searchQuery + ` AS query) q
+		LEFT JOIN mindmap_members mm ON mm.mindmap_id = m.id AND mm.user_id = $2
+		WHERE m.search_vector @@ q.query
+			AND m.deleted_at IS NULL
+			AND ($3 OR m.user_id = $2 OR mm.user_id IS NOT NULL)
+		ORDER BY rank DESC, m.updated_at DESC, m.id DESC
+		LIMIT $5 OFFSET $6`
+
+	options := "StartSel=" + highlightStart + ", StopSel=" + highlightStop +
+		", MaxFragments=3, MaxWords=20, MinWords=5, FragmentDelimiter=\" … \""
+
+	rows, err := r.db.Query(ctx, sql, query, userID, all, options, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("search mindmaps: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*models.SearchResult{}
+	var docs []string
+	for rows.Next() {
+		result := &models.SearchResult{NodeUIDs: []string{}}
+		var data string
+		if err := rows.Scan(
+			&result.ID,
+			&result.Title,
+			&data,
+			&result.Role,
+			&result.Rank,
+			&result.Snippet,
+			&result.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan search result: %w", err)
+		}
+		result.Snippet = highlight(result.Snippet)
+		results = append(results, result)
+		docs = append(docs, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if err := r.matchNodes(ctx, query, results, docs); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// matchNodes находит в каждой карте узлы, текст которых сам по себе подходит под запрос
+func (r *MindMapSearchRepository) matchNodes(ctx context.Context, query string, results []*models.SearchResult, docs []string) error {
+	var (
+		owners []int // Индекс результата для каждого узла
+		uids   []string
+		texts  []string
+	)
+	for i, data := range docs {
+		doc, err := mindmap.ParseString(data, mindmap.Limits{})
+		if err != nil {
+			continue
+		}
+		doc.Walk(func(n, _ *mindmap.Node, _ int) bool {
+			if text := n.Data.SearchText(); text != "" && n.Data.UID != "" {
+				owners = append(owners, i)
+				uids = append(uids, n.Data.UID)
+				texts = append(texts, text)
+			}
+			return true
+		})
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT t.ord
+		FROM unnest($2::text[]) WITH ORDINALITY AS t(txt, ord)
+		WHERE (to_tsvector('russian', t.txt) || to_tsvector('english', t.txt)) @@ `+searchQuery+`
+		ORDER BY t.ord`,
+		query, texts,
+	)
+	if err != nil {
+		return fmt.Errorf("match search nodes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ord int
+		if err := rows.Scan(&ord); err != nil {
+			return fmt.Errorf("scan search node: %w", err)
+		}
+		result := results[owners[ord-1]]
+		result.NodeUIDs = append(result.NodeUIDs, uids[ord-1])
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+	return nil
+}
+
+// highlight экранирует сниппет и заменяет маркеры совпадений на <mark>
+func highlight(snippet string) string {
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").
+		Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/models"
+)
+
+// shareTokenBytes - энтропия токена ссылки (256 бит)
+const shareTokenBytes = 32
+
+type MindMapShareLinkRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMindMapShareLinkRepository(db *pgxpool.Pool) *MindMapShareLinkRepository {
+	return &MindMapShareLinkRepository{db: db}
+}
+
+// Create создает ссылку и возвращает ее токен. В БД сохраняется только хеш токена.
+func (r *MindMapShareLinkRepository) Create(ctx context.Context, link *models.ShareLink) (string, error) {
+	raw := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate share token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	var passwordHash *string
+	if link.PasswordHash != "" {
+		passwordHash = &link.PasswordHash
+	}
+
+	now := time.Now()
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO mindmap_share_links (mindmap_id, token_hash, mode, password_hash, expires_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		link.MindMapID, hashShareToken(token), link.Mode, passwordHash, link.ExpiresAt, link.CreatedBy, now,
+	).Scan(&link.ID)
+	if err != nil {
+		return "", fmt.Errorf("create share link: %w", err)
+	}
+
+	link.CreatedAt = now
+	link.HasPassword = passwordHash != nil
+	return token, nil
+}
+
+// List возвращает ссылки карты, включая отозванные, новые первыми
+func (r *MindMapShareLinkRepository) List(ctx context.Context, mindMapID int) ([]*models.ShareLink, error) {
+	query := `
+		SELECT id, mindmap_id, mode, COALESCE(password_hash, ''), expires_at, created_by, created_at, revoked_at, last_used_at
+		FROM mindmap_share_links
+		WHERE mindmap_id = $1
+		ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.Query(ctx, query, mindMapID)
+	if err != nil {
+		return nil, fmt.Errorf("list share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []*models.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return links, nil
+}
+
+// GetByToken находит ссылку по токену. Возвращает nil, если ссылки нет.
+// Отозванные и истекшие ссылки тоже возвращаются - их проверяет вызывающий.
+func (r *MindMapShareLinkRepository) GetByToken(ctx context.Context, token string) (*models.ShareLink, error) {
+	row := r.db.QueryRow(ctx, `
+		SELECT id, mindmap_id, mode, COALESCE(password_hash, ''), expires_at, created_by, created_at, revoked_at, last_used_at
+		FROM mindmap_share_links
+		WHERE token_hash = $1`,
+		hashShareToken(token),
+	)
+	link, err := scanShareLink(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// Revoke отзывает ссылку карты
+func (r *MindMapShareLinkRepository) Revoke(ctx context.Context, mindMapID, linkID int) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE mindmap_share_links SET revoked_at = $1
+		WHERE id = $2 AND mindmap_id = $3 AND revoked_at IS NULL`,
+		time.Now(), linkID, mindMapID,
+	)
+	if err != nil {
+		return fmt.Errorf("revoke share link: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrShareLinkNotFound
+	}
+	return nil
+}
+
+// Touch запоминает время последнего использования ссылки
+func (r *MindMapShareLinkRepository) Touch(ctx context.Context, linkID int) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE mindmap_share_links SET last_used_at = $1 WHERE id = $2`,
+		time.Now(), linkID,
+	)
+	if err != nil {
+		return fmt.Errorf("touch share link: %w", err)
+	}
+	return nil
+}
+
+func scanShareLink(row pgx.Row) (*models.ShareLink, error) {
+	link := new(models.ShareLink)
+	err := row.Scan(
+		&link.ID,
+		&link.MindMapID,
+		&link.Mode,
+		&link.PasswordHash,
+		&link.ExpiresAt,
+		&link.CreatedBy,
+		&link.CreatedAt,
+		&link.RevokedAt,
+		&link.LastUsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan share link: %w", err)
+	}
+	link.HasPassword = link.PasswordHash != ""
+	return link, nil
+}
+
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
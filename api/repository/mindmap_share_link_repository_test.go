@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/dbtest"
+	"github.com/mymindmap/api/models"
+)
+
+func TestShareLinkExpiryKeepsOffset(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	mindMaps := NewMindMapRepository(db)
+	links := NewMindMapShareLinkRepository(db)
+
+	alice := dbtest.CreateUser(t, db, "alice")
+	m := &models.MindMap{Title: "shared", Data: `{"root":{"data":{"text":"Root","uid":"r"},"children":[]}}`, UserID: alice}
+	require.NoError(t, mindMaps.CreateMindMap(ctx, m))
+
+	now := time.Now()
+	for _, offset := range []int{3, -5} {
+		zone := time.FixedZone("", offset*3600)
+		for _, tt := range []struct {
+			in     time.Duration
+			active bool
+		}{
+			{30 * time.Minute, true},
+			{-30 * time.Minute, false},
+		} {
+			expires := now.Add(tt.in).In(zone)
+			token, err := links.Create(ctx, &models.ShareLink{MindMapID: m.ID, Mode: models.ShareLinkModeView, ExpiresAt: &expires, CreatedBy: &alice})
+			require.NoError(t, err)
+
+			link, err := links.GetByToken(ctx, token)
+			require.NoError(t, err)
+			require.NotNil(t, link.ExpiresAt)
+			assert.WithinDuration(t, expires, *link.ExpiresAt, time.Millisecond, "offset %+d", offset)
+			assert.Equal(t, tt.active, link.Active(now), "offset %+d, expires in %v", offset, tt.in)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/models"
+)
+
+type MindMapTemplateRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMindMapTemplateRepository(db *pgxpool.Pool) *MindMapTemplateRepository {
+	return &MindMapTemplateRepository{db: db}
+}
+
+// templateAccess - условие доступа пользователя (параметр param) к шаблону:
+// личный шаблон автора или шаблон команды, в которой он состоит
+func templateAccess(param string) string {
+	return `
+		(t.team_id IS NULL AND t.user_id = ` + param + `
+		 OR t.team_id IN (SELECT team_id FROM team_members WHERE user_id = ` + param + `))`
+}
+
+// Create сохраняет шаблон. Области видимости и id заполняются по team_id.
+func (r *MindMapTemplateRepository) Create(ctx context.Context, t *models.MindMapTemplate) error {
+	if t.UserID == nil {
+		return fmt.Errorf("create mindmap template: author required")
+	}
+
+	now := time.Now()
+	var id int
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO mindmap_templates (user_id, team_id, title, description, data, variables, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING id`,
+		*t.UserID, t.TeamID, t.Title, t.Description, t.Data, t.Variables, now,
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("create mindmap template: %w", err)
+	}
+
+	t.ID = strconv.Itoa(id)
+	t.Scope = templateScope(t.TeamID)
+	t.CreatedAt = &now
+	t.UpdatedAt = &now
+	return nil
+}
+
+// ListAccessible возвращает шаблоны пользователя и его команд без документов:
+// сначала личные, затем командные, внутри - по названию
+func (r *MindMapTemplateRepository) ListAccessible(ctx context.Context, userID int) ([]*models.MindMapTemplate, error) {
+	query := `
+		SELECT t.id, t.user_id, t.team_id, t.title, t.description, t.variables, ''::text, t.created_at, t.updated_at
+		FROM mindmap_templates t
+		WHERE` + templateAccess("$1") + `
+		ORDER BY t.team_id NULLS FIRST, t.title, t.id`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list mindmap templates: %w", err)
+	}
+	defer rows.Close()
+
+	result := []*models.MindMapTemplate{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetAccessible возвращает шаблон с документом. nil - шаблона нет или он недоступен пользователю.
+func (r *MindMapTemplateRepository) GetAccessible(ctx context.Context, id, userID int) (*models.MindMapTemplate, error) {
+	query := `
+		SELECT t.id, t.user_id, t.team_id, t.title, t.description, t.variables, t.data, t.created_at, t.updated_at
+		FROM mindmap_templates t
+		WHERE t.id = $1 AND` + templateAccess("$2")
+
+	t, err := scanTemplate(r.db.QueryRow(ctx, query, id, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Delete удаляет шаблон
+func (r *MindMapTemplateRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM mindmap_templates WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete mindmap template: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+func scanTemplate(row pgx.Row) (*models.MindMapTemplate, error) {
+	var (
+		id                   int
+		userID               int
+		createdAt, updatedAt time.Time
+	)
+	t := new(models.MindMapTemplate)
+	err := row.Scan(&id, &userID, &t.TeamID, &t.Title, &t.Description, &t.Variables, &t.Data, &createdAt, &updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan mindmap template: %w", err)
+	}
+
+	t.ID = strconv.Itoa(id)
+	t.Scope = templateScope(t.TeamID)
+	t.UserID = &userID
+	t.CreatedAt = &createdAt
+	t.UpdatedAt = &updatedAt
+	return t, nil
+}
+
+func templateScope(teamID *int) string {
+	if teamID != nil {
+		return models.TemplateScopeTeam
+	}
+	return models.TemplateScopeUser
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/models"
+)
+
+// thumbnailContentType - превью рисуются в PNG
+const thumbnailContentType = "image/png"
+
+type MindMapThumbnailRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMindMapThumbnailRepository(db *pgxpool.Pool) *MindMapThumbnailRepository {
+	return &MindMapThumbnailRepository{db: db}
+}
+
+// PendingThumbnails возвращает карты, превью которых устарело, давно измененные первыми.
+// У карт заполнены только ID, Version и Data.
+func (r *MindMapThumbnailRepository) PendingThumbnails(ctx context.Context, limit int) ([]*models.MindMap, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, version, data
+		FROM mindmaps
+		WHERE thumbnail_version IS DISTINCT FROM version AND deleted_at IS NULL
+		ORDER BY updated_at
+		LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list pending thumbnails: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*models.MindMap
+	for rows.Next() {
+		m := new(models.MindMap)
+		if err := rows.Scan(&m.ID, &m.Version, &m.Data); err != nil {
+			return nil, fmt.Errorf("scan pending thumbnail: %w", err)
+		}
+		result = append(result, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return result, nil
+}
+
+// SaveThumbnail сохраняет превью версии version и ссылку на него в карте.
+// image == nil - превью нарисовать не удалось: версия отмечается обработанной,
+// а старое превью удаляется. Превью более новой версии не перезаписывается.
+func (r *MindMapThumbnailRepository) SaveThumbnail(ctx context.Context, mindMapID, version int, image []byte) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("save thumbnail: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var link *string
+	if image != nil {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO mindmap_thumbnails (mindmap_id, version, content_type, content, updated_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (mindmap_id) DO UPDATE
+			SET version = EXCLUDED.version, content_type = EXCLUDED.content_type,
+				content = EXCLUDED.content, updated_at = EXCLUDED.updated_at
+			WHERE mindmap_thumbnails.version <= EXCLUDED.version`,
+			mindMapID, version, thumbnailContentType, image, time.Now(),
+		)
+		url := fmt.Sprintf("/api/mindmaps/%d/thumbnail?v=%d", mindMapID, version)
+		link = &url
+	} else {
+		_, err = tx.Exec(ctx,
+			`DELETE FROM mindmap_thumbnails WHERE mindmap_id = $1 AND version <= $2`,
+			mindMapID, version,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("save thumbnail: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE mindmaps SET thumbnail = $1, thumbnail_version = $2
+		WHERE id = $3 AND (thumbnail_version IS NULL OR thumbnail_version < $2)`,
+		link, version, mindMapID,
+	)
+	if err != nil {
+		return fmt.Errorf("save thumbnail: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetThumbnail возвращает превью карты. Возвращает nil, если превью нет.
+func (r *MindMapThumbnailRepository) GetThumbnail(ctx context.Context, mindMapID int) (*models.MindMapThumbnail, error) {
+	t := &models.MindMapThumbnail{MindMapID: mindMapID}
+	err := r.db.QueryRow(ctx, `
+		SELECT version, content_type, content, updated_at
+		FROM mindmap_thumbnails
+		WHERE mindmap_id = $1`,
+		mindMapID,
+	).Scan(&t.Version, &t.ContentType, &t.Content, &t.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get thumbnail: %w", err)
+	}
+	return t, nil
+}
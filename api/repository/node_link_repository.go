@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/internal/maplinks"
+	"github.com/mymindmap/api/internal/mindmap"
+	"github.com/mymindmap/api/models"
+)
+
+// Ограничения выборок ссылок
+const (
+	maxBacklinks  = 500
+	maxGraphEdges = 2000
+)
+
+// NodeLinkRepository - индекс ссылок между узлами карт
+type NodeLinkRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNodeLinkRepository(db *pgxpool.Pool) *NodeLinkRepository {
+	return &NodeLinkRepository{db: db}
+}
+
+// visibleMap - условие доступа к карте alias для пользователя $1: публичная, своя,
+// участник или $2 (администратор). Карты в корзине недоступны.
+func visibleMap(alias string) string {
+	return `(` + alias + `.deleted_at IS NULL AND ($2 OR ` + alias + `.is_public OR ` + alias + `.user_id = $1 OR EXISTS (
+		SELECT 1 FROM mindmap_members mm WHERE mm.mindmap_id = ` + alias + `.id AND mm.user_id = $1)))`
+}
+
+// Backlinks возвращает ссылки на карту (nodeUID не пустой - на ее узел) из карт,
+// доступных пользователю. all - из любых карт (для администратора).
+func (r *NodeLinkRepository) Backlinks(ctx context.Context, userID int, all bool, mindMapID int, nodeUID string) ([]*models.NodeLink, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT l.source_mindmap_id, s.title, l.source_node_uid, l.source_text, l.target_mindmap_id, l.target_node_uid
+		FROM node_links l
+		JOIN mindmaps s ON s.id = l.source_mindmap_id
+		WHERE l.target_mindmap_id = $3 AND ($4 = '' OR l.target_node_uid = $4) AND `+visibleMap("s")+`
+		ORDER BY s.title, s.id, l.source_node_uid
+		LIMIT $5`,
+		userID, all, mindMapID, nodeUID, maxBacklinks,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list backlinks: %w", err)
+	}
+	defer rows.Close()
+
+	links := []*models.NodeLink{}
+	for rows.Next() {
+		link := new(models.NodeLink)
+		if err := rows.Scan(
+			&link.SourceMindMapID,
+			&link.SourceTitle,
+			&link.SourceNodeUID,
+			&link.SourceText,
+			&link.TargetMindMapID,
+			&link.TargetNodeUID,
+		); err != nil {
+			return nil, fmt.Errorf("scan backlink: %w", err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list backlinks: %w", err)
+	}
+	return links, nil
+}
+
+// Outgoing возвращает ссылки из узлов карты с состоянием цели. Для недоступной
+// пользователю карты название не возвращается, а удаленная и чужая карты не различаются.
+func (r *NodeLinkRepository) Outgoing(ctx context.Context, userID int, all bool, mindMapID int) ([]*models.NodeLink, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT l.source_mindmap_id, l.source_node_uid, l.source_text, l.target_mindmap_id, l.target_node_uid,
+			t.id IS NOT NULL, COALESCE(t.title, '')
+		FROM node_links l
+		LEFT JOIN mindmaps t ON t.id = l.target_mindmap_id AND `+visibleMap("t")+`
+		WHERE l.source_mindmap_id = $3
+		ORDER BY l.source_node_uid`,
+		userID, all, mindMapID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list node links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []*models.NodeLink{}
+	var targets []int // Доступные карты, в которых нужно проверить узлы
+	for rows.Next() {
+		link := new(models.NodeLink)
+		var visible bool
+		if err := rows.Scan(
+			&link.SourceMindMapID,
+			&link.SourceNodeUID,
+			&link.SourceText,
+			&link.TargetMindMapID,
+			&link.TargetNodeUID,
+			&visible,
+			&link.TargetTitle,
+		); err != nil {
+			return nil, fmt.Errorf("scan node link: %w", err)
+		}
+		link.Status = models.NodeLinkStatusUnavailable
+		if visible {
+			link.Status = models.NodeLinkStatusOK
+			if link.TargetNodeUID != "" {
+				targets = append(targets, link.TargetMindMapID)
+			}
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list node links: %w", err)
+	}
+	if len(targets) == 0 {
+		return links, nil
+	}
+
+	uids, err := r.nodeUIDs(ctx, targets)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		if link.Status == models.NodeLinkStatusOK && link.TargetNodeUID != "" && !uids[link.TargetMindMapID][link.TargetNodeUID] {
+			link.Status = models.NodeLinkStatusNodeMissing
+		}
+	}
+	return links, nil
+}
+
+// nodeUIDs возвращает uid узлов карт
+func (r *NodeLinkRepository) nodeUIDs(ctx context.Context, mindMapIDs []int) (map[int]map[string]bool, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, data FROM mindmaps WHERE id = ANY($1)`, mindMapIDs)
+	if err != nil {
+		return nil, fmt.Errorf("load link targets: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int]map[string]bool, len(mindMapIDs))
+	for rows.Next() {
+		var (
+			id   int
+			data string
+		)
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("scan link target: %w", err)
+		}
+		uids := make(map[string]bool)
+		if doc, err := mindmap.ParseString(data, mindmap.Limits{}); err == nil {
+			doc.Walk(func(n, _ *mindmap.Node, _ int) bool {
+				uids[n.Data.UID] = true
+				return true
+			})
+		}
+		result[id] = uids
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load link targets: %w", err)
+	}
+	return result, nil
+}
+
+// Graph возвращает граф ссылок между доступными пользователю картами. Ссылки
+// карты на саму себя не учитываются. mindMapID не nil - только связи этой карты.
+func (r *NodeLinkRepository) Graph(ctx context.Context, userID int, all bool, mindMapID *int) (*models.MapGraph, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT s.id, s.title, t.id, t.title, COUNT(*)
+		FROM node_links l
+		JOIN mindmaps s ON s.id = l.source_mindmap_id
+		JOIN mindmaps t ON t.id = l.target_mindmap_id
+		WHERE l.source_mindmap_id <> l.target_mindmap_id
+			AND ($3::int IS NULL OR $3 IN (s.id, t.id))
+			AND `+visibleMap("s")+` AND `+visibleMap("t")+`
+		GROUP BY s.id, s.title, t.id, t.title
+		ORDER BY s.id, t.id
+		LIMIT $4`,
+		userID, all, mindMapID, maxGraphEdges,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("map graph: %w", err)
+	}
+	defer rows.Close()
+
+	graph := &models.MapGraph{Nodes: []models.MapGraphNode{}, Edges: []models.MapGraphEdge{}}
+	seen := make(map[int]bool)
+	addNode := func(id int, title string) {
+		if !seen[id] {
+			seen[id] = true
+			graph.Nodes = append(graph.Nodes, models.MapGraphNode{ID: id, Title: title})
+		}
+	}
+	for rows.Next() {
+		var (
+			edge                     models.MapGraphEdge
+			sourceTitle, targetTitle string
+		)
+		if err := rows.Scan(&edge.Source, &sourceTitle, &edge.Target, &targetTitle, &edge.Links); err != nil {
+			return nil, fmt.Errorf("scan map graph: %w", err)
+		}
+		addNode(edge.Source, sourceTitle)
+		addNode(edge.Target, targetTitle)
+		graph.Edges = append(graph.Edges, edge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("map graph: %w", err)
+	}
+	return graph, nil
+}
+
+// syncNodeLinks заменяет ссылки из узлов карты ссылками из документа
+func syncNodeLinks(ctx context.Context, q querier, mindMapID int, refs []maplinks.Ref) error {
+	if _, err := q.Exec(ctx, `DELETE FROM node_links WHERE source_mindmap_id = $1`, mindMapID); err != nil {
+		return fmt.Errorf("sync node links: %w", err)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	uids := make([]string, len(refs))
+	texts := make([]string, len(refs))
+	targets := make([]int, len(refs))
+	targetUIDs := make([]string, len(refs))
+	for i, ref := range refs {
+		uids[i], texts[i], targets[i], targetUIDs[i] = ref.SourceUID, ref.SourceText, ref.MindMapID, ref.NodeUID
+	}
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO node_links (source_mindmap_id, source_node_uid, source_text, target_mindmap_id, target_node_uid)
+		SELECT $1, t.uid, t.text, t.target, t.target_uid
+		FROM unnest($2::text[], $3::text[], $4::int[], $5::text[]) AS t(uid, text, target, target_uid)
+		ON CONFLICT (source_mindmap_id, source_node_uid) DO NOTHING`,
+		mindMapID, uids, texts, targets, targetUIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("sync node links: %w", err)
+	}
+	return nil
+}
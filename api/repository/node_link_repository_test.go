@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymindmap/api/internal/dbtest"
+	"github.com/mymindmap/api/models"
+)
+
+func linkDoc(target int) string {
+	return fmt.Sprintf(`{"root":{"data":{"text":"Root","uid":"r","mapLink":{"mindmapId":%d}},"children":[]}}`, target)
+}
+
+func TestBacklinksIncludePublicMaps(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	mindMaps := NewMindMapRepository(db)
+	links := NewNodeLinkRepository(db)
+
+	alice := dbtest.CreateUser(t, db, "alice")
+	bob := dbtest.CreateUser(t, db, "bob")
+
+	target := &models.MindMap{Title: "target", Data: `{"root":{"data":{"text":"Root","uid":"r"},"children":[]}}`, UserID: bob}
+	require.NoError(t, mindMaps.CreateMindMap(ctx, target))
+	public := &models.MindMap{Title: "public", Data: linkDoc(target.ID), UserID: alice, IsPublic: true}
+	require.NoError(t, mindMaps.CreateMindMap(ctx, public))
+	private := &models.MindMap{Title: "private", Data: linkDoc(target.ID), UserID: alice}
+	require.NoError(t, mindMaps.CreateMindMap(ctx, private))
+
+	backlinks, err := links.Backlinks(ctx, bob, false, target.ID, "")
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, public.ID, backlinks[0].SourceMindMapID)
+
+	// Гость видит название цели ссылки, только если цель тоже публичная
+	outgoing, err := links.Outgoing(ctx, 0, false, public.ID)
+	require.NoError(t, err)
+	require.Len(t, outgoing, 1)
+	assert.Empty(t, outgoing[0].TargetTitle)
+
+	target.IsPublic = true
+	require.NoError(t, mindMaps.Update(ctx, target, bob))
+	outgoing, err = links.Outgoing(ctx, 0, false, public.ID)
+	require.NoError(t, err)
+	require.Len(t, outgoing, 1)
+	assert.Equal(t, "target", outgoing[0].TargetTitle)
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -25,6 +27,7 @@ func (pr *PostRepository) CreatePost(ctx context.Context, post *models.Post) err
 		RETURNING id`
 
 	now := time.Now()
+	post.Version = 1
 	post.CreatedAt = now
 	post.UpdatedAt = now
 
@@ -40,7 +43,7 @@ func (pr *PostRepository) CreatePost(ctx context.Context, post *models.Post) err
 func (pr *PostRepository) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
 	rows, err := pr.dbpool.Query(
 		ctx,
-		"SELECT id, title, content, user_id, created_at, updated_at FROM posts ORDER BY created_at DESC",
+		"SELECT id, title, content, user_id, version, created_at, updated_at FROM posts WHERE deleted_at IS NULL ORDER BY created_at DESC",
 	)
 	if err != nil {
 		return nil, err
@@ -55,6 +58,7 @@ func (pr *PostRepository) GetAllPosts(ctx context.Context) ([]*models.Post, erro
 			&post.Title, 
 			&post.Content,
 			&post.UserID,
+			&post.Version,
 			&post.CreatedAt,
 			&post.UpdatedAt,
 		); err != nil {
@@ -69,15 +73,16 @@ func (pr *PostRepository) GetAllPosts(ctx context.Context) ([]*models.Post, erro
 func (pr *PostRepository) GetPostByID(ctx context.Context, id int) (*models.Post, error) {
 	var post models.Post
 	query := `
-		SELECT id, title, content, user_id, created_at, updated_at 
+		SELECT id, title, content, user_id, version, created_at, updated_at 
 		FROM posts 
-		WHERE id = $1`
+		WHERE id = $1 AND deleted_at IS NULL`
 	
 	err := pr.dbpool.QueryRow(ctx, query, id).Scan(
 		&post.ID, 
 		&post.Title, 
 		&post.Content,
 		&post.UserID,
+		&post.Version,
 		&post.CreatedAt,
 		&post.UpdatedAt,
 	)
@@ -92,28 +97,41 @@ func (pr *PostRepository) GetPostByID(ctx context.Context, id int) (*models.Post
 	return &post, nil
 }
 
+// UpdatePost сохраняет пост, если post.Version совпадает с версией в БД,
+// иначе возвращает ErrVersionConflict. После сохранения post.Version увеличивается.
 func (pr *PostRepository) UpdatePost(ctx context.Context, post *models.Post) error {
 	query := `
 		UPDATE posts 
-		SET title = $1, content = $2, updated_at = $3 
-		WHERE id = $4`
+		SET title = $1, content = $2, updated_at = $3, version = version + 1
+		WHERE id = $4 AND version = $5 AND deleted_at IS NULL
+		RETURNING version`
 	
-	post.UpdatedAt = time.Now()
+	updatedAt := time.Now()
 	
-	_, err := pr.dbpool.Exec(
+	err := pr.dbpool.QueryRow(
 		ctx,
 		query,
 		post.Title,
 		post.Content,
-		post.UpdatedAt,
+		updatedAt,
 		post.ID,
-	)
-	return err
+		post.Version,
+	).Scan(&post.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	post.UpdatedAt = updatedAt
+	return nil
 }
 
+// DeletePost переносит пост в корзину
 func (pr *PostRepository) DeletePost(ctx context.Context, id int) error {
-	query := "DELETE FROM posts WHERE id = $1"
-	_, err := pr.dbpool.Exec(ctx, query, id)
+	query := "UPDATE posts SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL"
+	_, err := pr.dbpool.Exec(ctx, query, id, time.Now())
 	return err
 }
 
@@ -121,7 +139,7 @@ func (pr *PostRepository) DeletePost(ctx context.Context, id int) error {
 func (pr *PostRepository) GetPostsByUser(ctx context.Context, userID int) ([]*models.Post, error) {
 	rows, err := pr.dbpool.Query(
 		ctx,
-		"SELECT id, title, content, user_id, created_at, updated_at FROM posts WHERE user_id = $1 ORDER BY created_at DESC",
+		"SELECT id, title, content, user_id, version, created_at, updated_at FROM posts WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC",
 		userID,
 	)
 	if err != nil {
@@ -137,6 +155,7 @@ func (pr *PostRepository) GetPostsByUser(ctx context.Context, userID int) ([]*mo
 			&post.Title, 
 			&post.Content,
 			&post.UserID,
+			&post.Version,
 			&post.CreatedAt,
 			&post.UpdatedAt,
 		); err != nil {
@@ -147,3 +166,88 @@ func (pr *PostRepository) GetPostsByUser(ctx context.Context, userID int) ([]*mo
 
 	return posts, nil
 }
+
+// ListTrashed возвращает посты пользователя в корзине, недавно удаленные первыми.
+// PurgeAt заполняет вызывающий.
+func (pr *PostRepository) ListTrashed(ctx context.Context, userID int) ([]*models.TrashItem, error) {
+	rows, err := pr.dbpool.Query(ctx, `
+		SELECT id, title, user_id, deleted_at
+		FROM posts
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list trashed posts: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*models.TrashItem{}
+	for rows.Next() {
+		item := &models.TrashItem{Type: models.TrashTypePost}
+		if err := rows.Scan(&item.ID, &item.Title, &item.UserID, &item.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan trashed post: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetTrashed возвращает пост из корзины. nil - поста нет или он не в корзине.
+func (pr *PostRepository) GetTrashed(ctx context.Context, id int) (*models.TrashItem, error) {
+	item := &models.TrashItem{Type: models.TrashTypePost}
+	err := pr.dbpool.QueryRow(ctx, `
+		SELECT id, title, user_id, deleted_at
+		FROM posts
+		WHERE id = $1 AND deleted_at IS NOT NULL`,
+		id,
+	).Scan(&item.ID, &item.Title, &item.UserID, &item.DeletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get trashed post: %w", err)
+	}
+	return item, nil
+}
+
+// Restore возвращает пост из корзины
+func (pr *PostRepository) Restore(ctx context.Context, id int) error {
+	result, err := pr.dbpool.Exec(ctx,
+		`UPDATE posts SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("restore post: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotInTrash
+	}
+	return nil
+}
+
+// Purge окончательно удаляет пост из корзины
+func (pr *PostRepository) Purge(ctx context.Context, id int) error {
+	result, err := pr.dbpool.Exec(ctx, `DELETE FROM posts WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return fmt.Errorf("purge post: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotInTrash
+	}
+	return nil
+}
+
+// PurgeTrashed окончательно удаляет посты, попавшие в корзину раньше before
+func (pr *PostRepository) PurgeTrashed(ctx context.Context, before time.Time) (int64, error) {
+	result, err := pr.dbpool.Exec(ctx, `DELETE FROM posts WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge trashed posts: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
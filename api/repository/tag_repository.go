@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/models"
+)
+
+type TagRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTagRepository(db *pgxpool.Pool) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// tagColumns - поля тега t и число доступных владельцу тега карт с ним
+const tagColumns = `
+	t.id, t.user_id, t.name, t.color,
+	(SELECT COUNT(*) FROM mindmap_tags mt
+	 JOIN mindmaps m ON m.id = mt.mindmap_id
+	 WHERE mt.tag_id = t.id AND m.deleted_at IS NULL
+	   AND (m.user_id = t.user_id
+	        OR EXISTS (SELECT 1 FROM mindmap_members mm WHERE mm.mindmap_id = m.id AND mm.user_id = t.user_id))),
+	t.created_at, t.updated_at`
+
+// List возвращает теги пользователя. Если задан prefix, только теги, название которых
+// начинается с него (без учета регистра), сначала самые используемые - для автодополнения.
+// limit <= 0 - без ограничения.
+func (r *TagRepository) List(ctx context.Context, userID int, prefix string, limit int) ([]*models.Tag, error) {
+	query := `SELECT ` + tagColumns + ` FROM tags t WHERE t.user_id = $1`
+	args := []any{userID}
+	if prefix != "" {
+		args = append(args, likePrefix(prefix))
+		query += ` AND lower(t.name) LIKE lower($2) ESCAPE '\'
+			ORDER BY 5 DESC, lower(t.name), t.id`
+	} else {
+		query += ` ORDER BY lower(t.name), t.id`
+	}
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []*models.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return tags, nil
+}
+
+// Create создает тег. Тег с таким же названием (без учета регистра) - ErrTagExists.
+func (r *TagRepository) Create(ctx context.Context, tag *models.Tag) error {
+	now := time.Now()
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO tags (user_id, name, color, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (user_id, lower(name)) DO NOTHING
+		RETURNING id`,
+		tag.UserID, tag.Name, tag.Color, now,
+	).Scan(&tag.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTagExists
+	}
+	if err != nil {
+		return fmt.Errorf("create tag: %w", err)
+	}
+
+	tag.CreatedAt = now
+	tag.UpdatedAt = now
+	return nil
+}
+
+// Update меняет название и цвет тега
+func (r *TagRepository) Update(ctx context.Context, tag *models.Tag) error {
+	now := time.Now()
+	result, err := r.db.Exec(ctx,
+		`UPDATE tags SET name = $1, color = $2, updated_at = $3 WHERE id = $4 AND user_id = $5`,
+		tag.Name, tag.Color, now, tag.ID, tag.UserID,
+	)
+	if isUniqueViolation(err) {
+		return ErrTagExists
+	}
+	if err != nil {
+		return fmt.Errorf("update tag: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTagNotFound
+	}
+
+	tag.UpdatedAt = now
+	return nil
+}
+
+// Delete удаляет тег и снимает его со всех карт
+func (r *TagRepository) Delete(ctx context.Context, id, userID int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete tag: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// SetMindMapTags заменяет теги пользователя на карте набором names. Теги, которых
+// у пользователя еще нет, создаются. Теги других пользователей на карте не меняются.
+func (r *TagRepository) SetMindMapTags(ctx context.Context, userID, mindMapID int, names []string) ([]*models.Tag, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("set mindmap tags: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		var id int
+		// DO UPDATE без изменений нужен, чтобы RETURNING вернул существующий тег
+		err := tx.QueryRow(ctx, `
+			INSERT INTO tags (user_id, name, created_at, updated_at)
+			VALUES ($1, $2, $3, $3)
+			ON CONFLICT (user_id, lower(name)) DO UPDATE SET name = tags.name
+			RETURNING id`,
+			userID, name, now,
+		).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("set mindmap tags: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM mindmap_tags mt
+		USING tags t
+		WHERE mt.tag_id = t.id AND t.user_id = $1 AND mt.mindmap_id = $2 AND NOT (mt.tag_id = ANY($3))`,
+		userID, mindMapID, ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("set mindmap tags: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO mindmap_tags (tag_id, mindmap_id, created_at)
+		SELECT unnest($1::int[]), $2, $3
+		ON CONFLICT (tag_id, mindmap_id) DO NOTHING`,
+		ids, mindMapID, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("set mindmap tags: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+tagColumns+`
+		FROM tags t
+		WHERE t.id = ANY($1)
+		ORDER BY lower(t.name), t.id`,
+		ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("set mindmap tags: %w", err)
+	}
+	tags := []*models.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("set mindmap tags: %w", err)
+	}
+	return tags, nil
+}
+
+func scanTag(row pgx.Row) (*models.Tag, error) {
+	tag := new(models.Tag)
+	if err := row.Scan(
+		&tag.ID,
+		&tag.UserID,
+		&tag.Name,
+		&tag.Color,
+		&tag.MapCount,
+		&tag.CreatedAt,
+		&tag.UpdatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("scan tag: %w", err)
+	}
+	return tag, nil
+}
+
+// likePrefix - шаблон LIKE для строк, начинающихся с prefix
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+// isUniqueViolation - ошибка нарушения уникального индекса
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
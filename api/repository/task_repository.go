@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/internal/tasks"
+	"github.com/mymindmap/api/models"
+)
+
+// calendarTokenBytes - энтропия токена подписки на календарь (256 бит)
+const calendarTokenBytes = 32
+
+// TaskRepository - задачи из узлов карт и подписки на календарь задач
+type TaskRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTaskRepository(db *pgxpool.Pool) *TaskRepository {
+	return &TaskRepository{db: db}
+}
+
+const taskColumns = `t.mindmap_id, m.title, t.node_uid, t.title, t.done, t.priority, t.assignee_id, t.due_at, t.due_all_day, t.updated_at`
+
+// taskAccess - карты, доступные пользователю $1: свои и те, где он участник
+const taskAccess = `
+		FROM tasks t
+		JOIN mindmaps m ON m.id = t.mindmap_id
+		LEFT JOIN mindmap_members mm ON mm.mindmap_id = m.id AND mm.user_id = $1
+		WHERE m.deleted_at IS NULL AND (m.user_id = $1 OR mm.user_id IS NOT NULL)`
+
+// List возвращает задачи по фильтру: сначала с ближайшим сроком, затем по приоритету
+func (r *TaskRepository) List(ctx context.Context, filter models.TaskFilter) ([]*models.Task, error) {
+	query := `SELECT ` + taskColumns + taskAccess
+	args := []interface{}{filter.UserID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	switch {
+	case filter.Unassigned:
+		query += ` AND t.assignee_id IS NULL`
+	case filter.AssigneeID != nil:
+		query += ` AND t.assignee_id = ` + arg(*filter.AssigneeID)
+	}
+	if filter.MindMapID != nil {
+		query += ` AND t.mindmap_id = ` + arg(*filter.MindMapID)
+	}
+	switch filter.Status {
+	case models.TaskStatusOpen:
+		query += ` AND NOT t.done`
+	case models.TaskStatusDone:
+		query += ` AND t.done`
+	}
+	if filter.DueFrom != nil {
+		query += ` AND t.due_at >= ` + arg(*filter.DueFrom)
+	}
+	if filter.DueTo != nil {
+		query += ` AND t.due_at < ` + arg(*filter.DueTo)
+	}
+	query += ` ORDER BY t.due_at NULLS LAST, t.priority NULLS LAST, t.mindmap_id, t.title, t.node_uid`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + arg(filter.Limit)
+	}
+
+	return r.query(ctx, query, args...)
+}
+
+// CalendarTasks возвращает открытые задачи со сроком для календаря пользователя:
+// назначенные ему и задачи без ответственного в его картах
+func (r *TaskRepository) CalendarTasks(ctx context.Context, userID int) ([]*models.Task, error) {
+	query := `SELECT ` + taskColumns + taskAccess + `
+			AND NOT t.done AND t.due_at IS NOT NULL
+			AND (t.assignee_id = $1 OR (t.assignee_id IS NULL AND m.user_id = $1))
+		ORDER BY t.due_at, t.mindmap_id, t.node_uid`
+	return r.query(ctx, query, userID)
+}
+
+func (r *TaskRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.Task, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*models.Task{}
+	for rows.Next() {
+		task := new(models.Task)
+		if err := rows.Scan(
+			&task.MindMapID,
+			&task.MindMapTitle,
+			&task.NodeUID,
+			&task.Title,
+			&task.Done,
+			&task.Priority,
+			&task.AssigneeID,
+			&task.DueAt,
+			&task.DueAllDay,
+			&task.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+		list = append(list, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list tasks: %w", err)
+	}
+	return list, nil
+}
+
+// CreateCalendarToken выпускает новый токен подписки на календарь; прежний
+// токен пользователя перестает действовать. В БД сохраняется только хеш токена.
+func (r *TaskRepository) CreateCalendarToken(ctx context.Context, userID int) (string, error) {
+	raw := make([]byte, calendarTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate calendar token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO calendar_feeds (user_id, token_hash, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = EXCLUDED.created_at`,
+		userID, hashShareToken(token), time.Now(),
+	)
+	if err != nil {
+		return "", fmt.Errorf("create calendar token: %w", err)
+	}
+	return token, nil
+}
+
+// DeleteCalendarToken отзывает токен подписки; false - токена не было
+func (r *TaskRepository) DeleteCalendarToken(ctx context.Context, userID int) (bool, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM calendar_feeds WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("delete calendar token: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// CalendarUser возвращает id владельца токена подписки, 0 - токен не найден
+func (r *TaskRepository) CalendarUser(ctx context.Context, token string) (int, error) {
+	var userID int
+	err := r.db.QueryRow(ctx, `SELECT user_id FROM calendar_feeds WHERE token_hash = $1`, hashShareToken(token)).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("get calendar feed: %w", err)
+	}
+	return userID, nil
+}
+
+// syncTasks приводит задачи карты в соответствие с документом. Неизмененные
+// задачи не трогаются, чтобы календари не получали лишних обновлений.
+// Ответственным может быть только участник карты.
+func syncTasks(ctx context.Context, q querier, mindMapID int, list []tasks.Task) error {
+	seen := make(map[string]bool, len(list))
+	uids := []string{} // Пустой массив, а не NULL: иначе удаление ничего не найдет
+	var (
+		titles    []string
+		done      []bool
+		priority  []*int
+		assignees []*int
+		dueAt     []*time.Time
+		allDay    []bool
+	)
+	for _, t := range list {
+		if t.NodeUID == "" || seen[t.NodeUID] {
+			continue
+		}
+		seen[t.NodeUID] = true
+		uids = append(uids, t.NodeUID)
+		titles = append(titles, t.Title)
+		done = append(done, t.Done)
+		priority = append(priority, t.Priority)
+		assignees = append(assignees, t.AssigneeID)
+		dueAt = append(dueAt, t.Due)
+		allDay = append(allDay, t.DueAllDay)
+	}
+
+	if _, err := q.Exec(ctx, `DELETE FROM tasks WHERE mindmap_id = $1 AND NOT (node_uid = ANY($2))`, mindMapID, uids); err != nil {
+		return fmt.Errorf("sync tasks: %w", err)
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO tasks (mindmap_id, node_uid, title, done, priority, assignee_id, due_at, due_all_day, updated_at)
+		SELECT $1, t.node_uid, t.title, t.done, t.priority, mm.user_id, t.due_at, t.due_all_day, $9
+		FROM unnest($2::text[], $3::text[], $4::bool[], $5::int[], $6::int[], $7::timestamp[], $8::bool[])
+			AS t(node_uid, title, done, priority, assignee_id, due_at, due_all_day)
+		LEFT JOIN mindmap_members mm ON mm.mindmap_id = $1 AND mm.user_id = t.assignee_id
+		ON CONFLICT (mindmap_id, node_uid) DO UPDATE SET
+			title = EXCLUDED.title, done = EXCLUDED.done, priority = EXCLUDED.priority,
+			assignee_id = EXCLUDED.assignee_id, due_at = EXCLUDED.due_at,
+			due_all_day = EXCLUDED.due_all_day, updated_at = EXCLUDED.updated_at
+		WHERE (tasks.title, tasks.done, tasks.priority, tasks.assignee_id, tasks.due_at, tasks.due_all_day)
+			IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.done, EXCLUDED.priority, EXCLUDED.assignee_id, EXCLUDED.due_at, EXCLUDED.due_all_day)`,
+		mindMapID, uids, titles, done, priority, assignees, dueAt, allDay, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("sync tasks: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mymindmap/api/models"
+)
+
+type TeamRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTeamRepository(db *pgxpool.Pool) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+// Create создает команду и записывает создателя ее владельцем
+func (r *TeamRepository) Create(ctx context.Context, team *models.Team, ownerID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("create team: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	err = tx.QueryRow(ctx, `
+		INSERT INTO teams (name, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		RETURNING id`,
+		team.Name, ownerID, now,
+	).Scan(&team.ID)
+	if err != nil {
+		return fmt.Errorf("create team: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO team_members (team_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, $4)`,
+		team.ID, ownerID, models.TeamRoleOwner, now,
+	)
+	if err != nil {
+		return fmt.Errorf("add team owner: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("create team: %w", err)
+	}
+
+	team.CreatedBy = &ownerID
+	team.Role = models.TeamRoleOwner
+	team.MemberCount = 1
+	team.CreatedAt = now
+	team.UpdatedAt = now
+	return nil
+}
+
+// ListForUser возвращает команды пользователя с его ролью, по названию
+func (r *TeamRepository) ListForUser(ctx context.Context, userID int) ([]*models.Team, error) {
+	query := `
+		SELECT t.id, t.name, t.created_by, tm.role,
+		       (SELECT COUNT(*) FROM team_members c WHERE c.team_id = t.id),
+		       t.created_at, t.updated_at
+		FROM teams t
+		JOIN team_members tm ON tm.team_id = t.id AND tm.user_id = $1
+		ORDER BY t.name, t.id`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list teams: %w", err)
+	}
+	defer rows.Close()
+
+	teams := []*models.Team{}
+	for rows.Next() {
+		team := new(models.Team)
+		if err := rows.Scan(
+			&team.ID,
+			&team.Name,
+			&team.CreatedBy,
+			&team.Role,
+			&team.MemberCount,
+			&team.CreatedAt,
+			&team.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan team: %w", err)
+		}
+		teams = append(teams, team)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return teams, nil
+}
+
+// GetRole возвращает роль пользователя в команде или пустую строку, если он не участник
+func (r *TeamRepository) GetRole(ctx context.Context, teamID, userID int) (string, error) {
+	var role string
+	err := r.db.QueryRow(ctx,
+		`SELECT role FROM team_members WHERE team_id = $1 AND user_id = $2`,
+		teamID, userID,
+	).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get team role: %w", err)
+	}
+	return role, nil
+}
+
+// Delete удаляет команду вместе с ее шаблонами
+func (r *TeamRepository) Delete(ctx context.Context, teamID int) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM teams WHERE id = $1`, teamID); err != nil {
+		return fmt.Errorf("delete team: %w", err)
+	}
+	return nil
+}
+
+// ListMembers возвращает участников команды: сначала владельцы, затем по дате добавления
+func (r *TeamRepository) ListMembers(ctx context.Context, teamID int) ([]*models.TeamMember, error) {
+	query := `
+		SELECT tm.team_id, tm.user_id, u.name, u.email, tm.role, tm.created_at
+		FROM team_members tm
+		JOIN users u ON u.id = tm.user_id
+		WHERE tm.team_id = $1
+		ORDER BY tm.role = 'owner' DESC, tm.created_at, tm.user_id`
+
+	rows, err := r.db.Query(ctx, query, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("list team members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*models.TeamMember{}
+	for rows.Next() {
+		member := new(models.TeamMember)
+		if err := rows.Scan(
+			&member.TeamID,
+			&member.UserID,
+			&member.Name,
+			&member.Email,
+			&member.Role,
+			&member.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan team member: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return members, nil
+}
+
+// AddMember добавляет участника. Если пользователь уже в команде, возвращает ErrMemberExists.
+func (r *TeamRepository) AddMember(ctx context.Context, member *models.TeamMember) error {
+	now := time.Now()
+	result, err := r.db.Exec(ctx, `
+		INSERT INTO team_members (team_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (team_id, user_id) DO NOTHING`,
+		member.TeamID, member.UserID, member.Role, now,
+	)
+	if err != nil {
+		return fmt.Errorf("add team member: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMemberExists
+	}
+
+	member.CreatedAt = now
+	return nil
+}
+
+// RemoveMember удаляет участника. Владельца удалить нельзя - команду удаляют целиком.
+func (r *TeamRepository) RemoveMember(ctx context.Context, teamID, userID int) error {
+	result, err := r.db.Exec(ctx,
+		`DELETE FROM team_members WHERE team_id = $1 AND user_id = $2 AND role <> 'owner'`,
+		teamID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("remove team member: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}